@@ -0,0 +1,446 @@
+package main
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/config"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/database"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/handlers"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/mailer"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/middleware"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/ratelimit"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/storage"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/util"
+)
+
+// connectTestDB conecta ao banco de dados de teste e fecha a conexão no fim do teste
+func connectTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	config.LoadConfig()
+	db, err := database.ConnectTest()
+	if err != nil {
+		t.Fatal("Falha ao conectar ao banco de dados de teste:", err)
+	}
+	t.Cleanup(func() { database.CloseTest(db) })
+	return db
+}
+
+// createTestUser cria um usuário com o e-mail verificado no plano Free
+func createTestUser(t *testing.T, db *gorm.DB, email string) *models.User {
+	t.Helper()
+	var plan models.Plan
+	if err := db.First(&plan, "name = ?", models.FreePlanName).Error; err != nil {
+		t.Fatal(err)
+	}
+	verified := time.Now()
+	user := models.User{
+		Name:            "Test User",
+		WhatsappNumber:  "+1234567890",
+		Email:           email,
+		Password:        "not-used",
+		PlanID:          plan.ID,
+		EmailVerifiedAt: &verified,
+	}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &user
+}
+
+// createTestProject cria um projeto pessoal do usuário
+func createTestProject(t *testing.T, db *gorm.DB, owner *models.User, name string) *models.Project {
+	t.Helper()
+	project := models.Project{Name: name, UserID: owner.ID}
+	if err := db.Create(&project).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &project
+}
+
+// createTestAPIKey cria uma chave de API com os escopos informados, restrita ao projeto
+// quando ele não é nil, e retorna o segredo
+func createTestAPIKey(t *testing.T, db *gorm.DB, user *models.User, scopes string, project *models.Project) string {
+	t.Helper()
+	plainKey, prefix, hash, err := util.GenerateAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := models.APIKey{UserID: user.ID, Name: "test-" + prefix, Prefix: prefix, KeyHash: hash, Scopes: scopes}
+	if project != nil {
+		key.ProjectID = &project.ID
+	}
+	if err := db.Create(&key).Error; err != nil {
+		t.Fatal(err)
+	}
+	return plainKey
+}
+
+// createTestOrg cria uma organização no plano Free com os membros e papéis informados
+func createTestOrg(t *testing.T, db *gorm.DB, slug string, roles map[*models.User]string) *models.Organization {
+	t.Helper()
+	var plan models.Plan
+	if err := db.First(&plan, "name = ?", models.FreePlanName).Error; err != nil {
+		t.Fatal(err)
+	}
+	org := models.Organization{Name: slug, Slug: slug, PlanID: plan.ID}
+	if err := db.Create(&org).Error; err != nil {
+		t.Fatal(err)
+	}
+	for user, role := range roles {
+		if err := db.Create(&models.Membership{OrganizationID: org.ID, UserID: user.ID, Role: role}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &org
+}
+
+// shareTestProject compartilha o projeto com o usuário. accepted indica se o convite já
+// foi aceito.
+func shareTestProject(t *testing.T, db *gorm.DB, project *models.Project, user *models.User, permission string, accepted bool) {
+	t.Helper()
+	share := models.ProjectMember{ProjectID: project.ID, UserID: user.ID, Permission: permission}
+	if accepted {
+		now := time.Now()
+		share.AcceptedAt = &now
+	}
+	if err := db.Create(&share).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newUploadRequest monta o multipart de um upload para o projeto informado
+func newUploadRequest(t *testing.T, target, project string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("project", project)
+	part, err := form.CreateFormFile("file", "notes.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write([]byte("hello"))
+	form.Close()
+	req := httptest.NewRequest(http.MethodPost, target, &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	return req
+}
+
+// newTestStore cria um driver local em um diretório temporário do teste
+func newTestStore(t *testing.T) storage.Driver {
+	t.Helper()
+	store, err := storage.NewLocalDriver(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// serveAPI executa a requisição no handler com a autenticação e o escopo exigido, como
+// as rotas de /api em main.go
+func serveAPI(db *gorm.DB, scope string, h http.HandlerFunc, req *http.Request, apiKey string) *httptest.ResponseRecorder {
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	rr := httptest.NewRecorder()
+	middleware.AuthMiddleware(db, middleware.RequireScope(scope, h)).ServeHTTP(rr, req)
+	return rr
+}
+
+// serveAdmin executa a requisição como as rotas de /api/admin em main.go
+func serveAdmin(db *gorm.DB, h http.HandlerFunc, req *http.Request, apiKey string) *httptest.ResponseRecorder {
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	rr := httptest.NewRecorder()
+	middleware.AuthMiddleware(db, middleware.RequireAdmin(middleware.RequireScope(models.ScopeAdmin, h))).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestRotateAPIKeyRejectsRestrictedKey(t *testing.T) {
+	db := connectTestDB(t)
+	user := createTestUser(t, db, "owner@example.com")
+	project := createTestProject(t, db, user, "site")
+
+	// Mesmo com o escopo admin, uma chave restrita a um projeto não pode obter a chave padrão
+	restricted := createTestAPIKey(t, db, user, models.ScopeAdmin, project)
+	req := httptest.NewRequest(http.MethodPost, "/user/rotate-api-key", nil)
+	rr := serveAPI(db, models.ScopeAdmin, handlers.RotateAPIKeyHandler(db), req, restricted)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.NotContains(t, rr.Body.String(), "new_api_key")
+
+	var defaults int64
+	db.Model(&models.APIKey{}).Where("user_id = ? AND project_id IS NULL", user.ID).Count(&defaults)
+	assert.Equal(t, int64(0), defaults, "nenhuma chave sem restrição deveria ter sido criada")
+
+	unrestricted := createTestAPIKey(t, db, user, models.ScopeAdmin, nil)
+	req = httptest.NewRequest(http.MethodPost, "/user/rotate-api-key", nil)
+	rr = serveAPI(db, models.ScopeAdmin, handlers.RotateAPIKeyHandler(db), req, unrestricted)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "new_api_key")
+}
+
+func TestOrganizationRolesAreEnforced(t *testing.T) {
+	db := connectTestDB(t)
+	store := newTestStore(t)
+	owner := createTestUser(t, db, "owner@example.com")
+	viewer := createTestUser(t, db, "viewer@example.com")
+	outsider := createTestUser(t, db, "outsider@example.com")
+	org := createTestOrg(t, db, "acme", map[*models.User]string{owner: models.RoleOwner, viewer: models.RoleViewer})
+	if err := db.Create(&models.Project{Name: "site", UserID: owner.ID, OrganizationID: &org.ID}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// As chaves têm todos os escopos: as recusas vêm do papel na organização
+	viewerKey := createTestAPIKey(t, db, viewer, models.ScopeAdmin, nil)
+	outsiderKey := createTestAPIKey(t, db, outsider, models.ScopeAdmin, nil)
+
+	t.Run("viewer lists files", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/list?org=acme&project=site", nil)
+		rr := serveAPI(db, models.ScopeRead, handlers.ListHandler(db), req, viewerKey)
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("viewer cannot upload", func(t *testing.T) {
+		req := newUploadRequest(t, "/upload?org=acme", "site")
+		rr := serveAPI(db, models.ScopeUpload, handlers.UploadHandler(db, store), req, viewerKey)
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+
+		var files int64
+		db.Model(&models.File{}).Count(&files)
+		assert.Equal(t, int64(0), files)
+	})
+
+	t.Run("viewer cannot delete files", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodDelete, "/delete?org=acme&project=site&file=notes.txt", nil)
+		rr := serveAPI(db, models.ScopeDelete, handlers.DeleteHandler(db, store, store), req, viewerKey)
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("viewer cannot add members", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/orgs/acme/members",
+			bytes.NewBufferString(`{"email": "outsider@example.com", "role": "member"}`))
+		req.SetPathValue("org", "acme")
+		rr := serveAPI(db, models.ScopeAdmin, handlers.MembersHandler(db), req, viewerKey)
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+
+		var memberships int64
+		db.Model(&models.Membership{}).Where("user_id = ?", outsider.ID).Count(&memberships)
+		assert.Equal(t, int64(0), memberships)
+	})
+
+	t.Run("non-member does not see the organization", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/list?org=acme&project=site", nil)
+		rr := serveAPI(db, models.ScopeRead, handlers.ListHandler(db), req, outsiderKey)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
+
+func TestSharedProjectPermissionsAreEnforced(t *testing.T) {
+	db := connectTestDB(t)
+	store := newTestStore(t)
+	owner := createTestUser(t, db, "owner@example.com")
+	reader := createTestUser(t, db, "reader@example.com")
+	uploader := createTestUser(t, db, "uploader@example.com")
+	invitee := createTestUser(t, db, "invitee@example.com")
+	stranger := createTestUser(t, db, "stranger@example.com")
+	site := createTestProject(t, db, owner, "site")
+	createTestProject(t, db, owner, "private")
+	shareTestProject(t, db, site, reader, models.PermissionRead, true)
+	shareTestProject(t, db, site, uploader, models.PermissionUpload, true)
+	shareTestProject(t, db, site, invitee, models.PermissionManage, false)
+
+	readerKey := createTestAPIKey(t, db, reader, models.ScopeAdmin, nil)
+	uploaderKey := createTestAPIKey(t, db, uploader, models.ScopeAdmin, nil)
+	inviteeKey := createTestAPIKey(t, db, invitee, models.ScopeAdmin, nil)
+	strangerKey := createTestAPIKey(t, db, stranger, models.ScopeAdmin, nil)
+	m := mailer.NewLogMailer(t.TempDir(), config.AppConfig.MailFrom)
+
+	t.Run("upload collaborator lists files", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/list?owner=owner@example.com&project=site", nil)
+		rr := serveAPI(db, models.ScopeRead, handlers.ListHandler(db), req, uploaderKey)
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("read collaborator cannot upload", func(t *testing.T) {
+		req := newUploadRequest(t, "/upload?owner=owner@example.com", "site")
+		rr := serveAPI(db, models.ScopeUpload, handlers.UploadHandler(db, store), req, readerKey)
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("upload collaborator cannot delete the project", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodDelete, "/project/delete?owner=owner@example.com&project=site", nil)
+		rr := serveAPI(db, models.ScopeDelete, handlers.DeleteProjectHandler(db), req, uploaderKey)
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+
+		var projects int64
+		db.Model(&models.Project{}).Where("id = ?", site.ID).Count(&projects)
+		assert.Equal(t, int64(1), projects)
+	})
+
+	t.Run("upload collaborator cannot invite", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/project/members?owner=owner@example.com&project=site",
+			bytes.NewBufferString(`{"email": "stranger@example.com", "permission": "read"}`))
+		rr := serveAPI(db, models.ScopeAdmin, handlers.ProjectMembersHandler(db, m), req, uploaderKey)
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("collaborator does not see projects that were not shared", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/list?owner=owner@example.com&project=private", nil)
+		rr := serveAPI(db, models.ScopeRead, handlers.ListHandler(db), req, uploaderKey)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("pending invitation gives no access", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/list?owner=owner@example.com&project=site", nil)
+		rr := serveAPI(db, models.ScopeRead, handlers.ListHandler(db), req, inviteeKey)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("stranger cannot use owner", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/list?owner=owner@example.com&project=site", nil)
+		rr := serveAPI(db, models.ScopeRead, handlers.ListHandler(db), req, strangerKey)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("restricted key cannot manage collaborators", func(t *testing.T) {
+		restricted := createTestAPIKey(t, db, owner, models.ScopeAdmin, site)
+		req := httptest.NewRequest(http.MethodPost, "/project/members?project=site",
+			bytes.NewBufferString(`{"email": "stranger@example.com", "permission": "manage"}`))
+		rr := serveAPI(db, models.ScopeAdmin, handlers.ProjectMembersHandler(db, m), req, restricted)
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+
+		var shares int64
+		db.Model(&models.ProjectMember{}).Where("user_id = ?", stranger.ID).Count(&shares)
+		assert.Equal(t, int64(0), shares)
+	})
+}
+
+func TestAdminRoutesRequireAdministrators(t *testing.T) {
+	db := connectTestDB(t)
+	user := createTestUser(t, db, "user@example.com")
+	admin := createTestUser(t, db, "admin@example.com")
+	if err := db.Model(admin).Update("is_admin", true).Error; err != nil {
+		t.Fatal(err)
+	}
+	userKey := createTestAPIKey(t, db, user, models.ScopeAdmin, nil)
+
+	t.Run("admin lists users", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/admin/users", nil)
+		rr := serveAdmin(db, handlers.AdminUsersHandler(db), req, createTestAPIKey(t, db, admin, models.ScopeAdmin, nil))
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("regular user cannot list users", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/admin/users", nil)
+		rr := serveAdmin(db, handlers.AdminUsersHandler(db), req, userKey)
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.NotContains(t, rr.Body.String(), "admin@example.com")
+	})
+
+	t.Run("regular user cannot suspend accounts", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/admin/users/"+admin.ID.String()+"/suspend", nil)
+		req.SetPathValue("id", admin.ID.String())
+		rr := serveAdmin(db, handlers.SuspendUserHandler(db), req, userKey)
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+
+		var reloaded models.User
+		db.First(&reloaded, "id = ?", admin.ID)
+		assert.Nil(t, reloaded.SuspendedAt)
+	})
+
+	t.Run("admin key without the admin scope is refused", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/admin/users", nil)
+		rr := serveAdmin(db, handlers.AdminUsersHandler(db), req, createTestAPIKey(t, db, admin, models.ScopeRead, nil))
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+}
+
+func TestSuspendedUsersAndKeyLimitsAreEnforced(t *testing.T) {
+	db := connectTestDB(t)
+	store := newTestStore(t)
+	user := createTestUser(t, db, "owner@example.com")
+	site := createTestProject(t, db, user, "site")
+	createTestProject(t, db, user, "blog")
+
+	t.Run("read-only key cannot upload", func(t *testing.T) {
+		req := newUploadRequest(t, "/upload", "site")
+		rr := serveAPI(db, models.ScopeUpload, handlers.UploadHandler(db, store), req, createTestAPIKey(t, db, user, models.ScopeRead, nil))
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("restricted key cannot upload to another project", func(t *testing.T) {
+		req := newUploadRequest(t, "/upload", "blog")
+		rr := serveAPI(db, models.ScopeUpload, handlers.UploadHandler(db, store), req, createTestAPIKey(t, db, user, models.ScopeAdmin, site))
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+
+		var files int64
+		db.Model(&models.File{}).Count(&files)
+		assert.Equal(t, int64(0), files)
+	})
+
+	t.Run("restricted key cannot create organizations", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/orgs", bytes.NewBufferString(`{"name": "Acme", "slug": "acme"}`))
+		rr := serveAPI(db, models.ScopeAdmin, handlers.OrganizationsHandler(db), req, createTestAPIKey(t, db, user, models.ScopeAdmin, site))
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+
+		var orgs int64
+		db.Model(&models.Organization{}).Count(&orgs)
+		assert.Equal(t, int64(0), orgs)
+	})
+
+	t.Run("suspended user is refused", func(t *testing.T) {
+		key := createTestAPIKey(t, db, user, models.ScopeAdmin, nil)
+		if err := db.Model(user).Update("suspended_at", time.Now()).Error; err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/list?project=site", nil)
+		rr := serveAPI(db, models.ScopeRead, handlers.ListHandler(db), req, key)
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		assert.Contains(t, rr.Body.String(), "Account suspended")
+	})
+}
+
+func TestRateLimitIgnoresForgedForwardedFor(t *testing.T) {
+	limited := middleware.RateLimit(ratelimit.NewMemoryBackend(), "login",
+		func(*http.Request) ratelimit.Limit { return ratelimit.Limit{Requests: 1, Per: time.Minute} },
+		middleware.ByIP, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	// Sem TRUSTED_PROXIES, trocar o header a cada requisição não gera um balde novo
+	for i, forwarded := range []string{"198.51.100.1", "198.51.100.2"} {
+		req := httptest.NewRequest(http.MethodPost, "/login", nil)
+		req.RemoteAddr = "203.0.113.7:5123"
+		req.Header.Set("X-Forwarded-For", forwarded)
+		rr := httptest.NewRecorder()
+		limited.ServeHTTP(rr, req)
+		if i == 0 {
+			assert.Equal(t, http.StatusOK, rr.Code)
+		} else {
+			assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		}
+	}
+}
+
+func TestLoginFailuresIgnoreForgedForwardedFor(t *testing.T) {
+	db := connectTestDB(t)
+	createTestUser(t, db, "owner@example.com")
+
+	// Um cliente que não passa por um proxy confiável não escolhe o IP que leva a falha
+	req := httptest.NewRequest(http.MethodPost, "/login",
+		bytes.NewBufferString(`{"email": "owner@example.com", "password": "wrong-password"}`))
+	req.RemoteAddr = "203.0.113.7:5123"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	rr := httptest.NewRecorder()
+	handlers.LoginHandler(db)(rr, req)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	var attacker, victim int64
+	db.Model(&models.IPLoginFailure{}).Where("ip = ?", "203.0.113.7").Count(&attacker)
+	db.Model(&models.IPLoginFailure{}).Where("ip = ?", "198.51.100.1").Count(&victim)
+	assert.Equal(t, int64(1), attacker)
+	assert.Equal(t, int64(0), victim)
+}
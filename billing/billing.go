@@ -0,0 +1,71 @@
+// Package billing cobra as assinaturas dos planos pagos e calcula e renderiza as faturas
+// mensais (excedentes de armazenamento e de tráfego). O provedor de pagamentos é
+// escolhido em BILLING_PROVIDER; por enquanto só existe "fake", que aprova as cobranças
+// em memória para testes locais. Provedores reais implementam a interface Provider.
+package billing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/config"
+)
+
+// ErrDeclined indica que o provedor recusou a cobrança (cartão recusado, saldo etc.)
+var ErrDeclined = errors.New("billing: payment declined")
+
+// ChargeRequest descreve uma cobrança. IdempotencyKey identifica a operação: repetir
+// a mesma chave não cobra de novo.
+type ChargeRequest struct {
+	CustomerID     string // ID do usuário
+	Email          string
+	Amount         int64 // Em centavos
+	Currency       string
+	Description    string
+	IdempotencyKey string
+}
+
+// Payment é uma cobrança aprovada
+type Payment struct {
+	ID          string
+	CustomerID  string
+	Amount      int64
+	Currency    string
+	Description string
+	CreatedAt   time.Time
+}
+
+// Provider é a interface comum aos provedores de pagamento
+type Provider interface {
+	Charge(ctx context.Context, req ChargeRequest) (*Payment, error)
+}
+
+// New cria o provedor configurado em cfg.BillingProvider
+func New(cfg *config.Config) (Provider, error) {
+	switch strings.ToLower(cfg.BillingProvider) {
+	case "", "fake":
+		return NewFakeProvider(splitList(cfg.BillingFakeDecline)...), nil
+	default:
+		return nil, fmt.Errorf("billing: unknown provider %q", cfg.BillingProvider)
+	}
+}
+
+// Cents converte um preço (Plan.Price) em centavos, arredondando
+func Cents(price float64) int64 {
+	return int64(math.Round(price * 100))
+}
+
+// splitList separa uma lista separada por vírgulas, ignorando itens vazios
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,111 @@
+package billing
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/config"
+)
+
+func TestFakeProviderCharges(t *testing.T) {
+	p := NewFakeProvider()
+	req := ChargeRequest{CustomerID: "u1", Email: "ana@example.com", Amount: 990, Currency: "USD", Description: "Pro", IdempotencyKey: "change-1"}
+
+	payment, err := p.Charge(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, int64(990), payment.Amount)
+	assert.Equal(t, "u1", payment.CustomerID)
+
+	// A mesma chave devolve a mesma cobrança, sem cobrar de novo
+	again, err := p.Charge(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, payment.ID, again.ID)
+	assert.Len(t, p.Payments(), 1)
+
+	_, err = p.Charge(context.Background(), ChargeRequest{Email: "ana@example.com", Amount: 0})
+	assert.Error(t, err)
+}
+
+func TestFakeProviderDeclines(t *testing.T) {
+	p := NewFakeProvider("Bia@Example.com")
+	_, err := p.Charge(context.Background(), ChargeRequest{Email: "bia@example.com", Amount: 100})
+	assert.ErrorIs(t, err, ErrDeclined)
+
+	p.Decline("ana@example.com")
+	_, err = p.Charge(context.Background(), ChargeRequest{Email: "ana@example.com", Amount: 100})
+	assert.ErrorIs(t, err, ErrDeclined)
+	assert.Empty(t, p.Payments())
+}
+
+func TestCents(t *testing.T) {
+	assert.Equal(t, int64(990), Cents(9.9))
+	assert.Equal(t, int64(1999), Cents(19.99))
+	assert.Equal(t, int64(0), Cents(0))
+}
+
+func TestNew(t *testing.T) {
+	p, err := New(&config.Config{BillingProvider: "fake", BillingFakeDecline: "a@example.com, b@example.com"})
+	require.NoError(t, err)
+	require.IsType(t, &FakeProvider{}, p)
+	assert.True(t, p.(*FakeProvider).declined["b@example.com"])
+
+	_, err = New(&config.Config{BillingProvider: "stripe"})
+	assert.Error(t, err)
+}
+
+func TestOverage(t *testing.T) {
+	// 2 GB acima do limite durante um mês inteiro = 2 GB-mês
+	gbMonths, amount := StorageOverage(2*GB*HoursPerMonth, 0.5)
+	assert.InDelta(t, 2, gbMonths, 1e-9)
+	assert.Equal(t, 1.0, amount)
+
+	gbMonths, amount = StorageOverage(0, 0.5)
+	assert.Zero(t, gbMonths)
+	assert.Zero(t, amount)
+
+	gb, amount := EgressOverage(15*GB, 10*GB, 0.09)
+	assert.InDelta(t, 5, gb, 1e-9)
+	assert.Equal(t, 0.45, amount)
+
+	gb, amount = EgressOverage(5*GB, 10*GB, 0.09)
+	assert.Zero(t, gb)
+	assert.Zero(t, amount)
+}
+
+func TestInvoicePDF(t *testing.T) {
+	inv := &Invoice{
+		Number:      "INV-202405-0001",
+		IssuedAt:    time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+		PeriodStart: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		PeriodEnd:   time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+		Customer:    "João (Acme)",
+		Email:       "joao@example.com",
+		Currency:    "USD",
+		Lines:       []Line{{Description: "Pro plan", Quantity: 1, Unit: "month", UnitPrice: 29.9, Amount: 29.9}},
+		Total:       29.9,
+		Status:      "paid",
+	}
+	var buf bytes.Buffer
+	require.NoError(t, inv.WritePDF(&buf))
+	pdf := buf.String()
+
+	assert.True(t, strings.HasPrefix(pdf, "%PDF-1.4\n"))
+	assert.True(t, strings.HasSuffix(pdf, "%%EOF\n"))
+	assert.Contains(t, pdf, "(Invoice INV-202405-0001) Tj")
+	assert.Contains(t, pdf, `Jo\343o \(Acme\)`)
+	assert.Contains(t, pdf, "Period: 2024-05-01 to 2024-05-31")
+
+	// O deslocamento em startxref aponta para a tabela xref
+	idx := strings.LastIndex(pdf, "startxref\n")
+	var offset int
+	_, err := fmt.Sscanf(pdf[idx+len("startxref\n"):], "%d", &offset)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(pdf[offset:], "xref\n"))
+}
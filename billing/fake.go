@@ -0,0 +1,91 @@
+package billing
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FakeProvider não cobra nada: aprova as cobranças e as guarda em memória. As cobranças
+// de clientes cujo e-mail está em declined são recusadas com ErrDeclined, o que permite
+// testar o caminho de falha localmente (BILLING_FAKE_DECLINE).
+type FakeProvider struct {
+	mu       sync.Mutex
+	declined map[string]bool
+	payments map[string]*Payment // Por IdempotencyKey
+	order    []string
+	now      func() time.Time
+}
+
+func NewFakeProvider(declined ...string) *FakeProvider {
+	p := &FakeProvider{
+		declined: make(map[string]bool),
+		payments: make(map[string]*Payment),
+		now:      time.Now,
+	}
+	for _, email := range declined {
+		p.declined[strings.ToLower(email)] = true
+	}
+	return p
+}
+
+func (p *FakeProvider) Charge(ctx context.Context, req ChargeRequest) (*Payment, error) {
+	if req.Amount <= 0 {
+		return nil, errors.New("billing: amount must be positive")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if req.IdempotencyKey != "" {
+		if payment, ok := p.payments[req.IdempotencyKey]; ok {
+			return payment, nil
+		}
+	}
+	if p.declined[strings.ToLower(req.Email)] {
+		log.Printf("💳 Cobrança simulada recusada: %s (%d %s)", req.Email, req.Amount, req.Currency)
+		return nil, ErrDeclined
+	}
+
+	payment := &Payment{
+		ID:          "fake_" + uuid.NewString(),
+		CustomerID:  req.CustomerID,
+		Amount:      req.Amount,
+		Currency:    req.Currency,
+		Description: req.Description,
+		CreatedAt:   p.now(),
+	}
+	key := req.IdempotencyKey
+	if key == "" {
+		key = payment.ID
+	}
+	p.payments[key] = payment
+	p.order = append(p.order, key)
+	log.Printf("💳 Cobrança simulada aprovada: %s (%d %s) - %s", req.Email, req.Amount, req.Currency, req.Description)
+	return payment, nil
+}
+
+// Payments retorna as cobranças aprovadas, na ordem em que foram feitas
+func (p *FakeProvider) Payments() []Payment {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	payments := make([]Payment, 0, len(p.order))
+	for _, key := range p.order {
+		payments = append(payments, *p.payments[key])
+	}
+	return payments
+}
+
+// Decline passa a recusar as cobranças do e-mail informado
+func (p *FakeProvider) Decline(email string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.declined[strings.ToLower(email)] = true
+}
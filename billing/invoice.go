@@ -0,0 +1,116 @@
+package billing
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Line é um item da fatura
+type Line struct {
+	Description string  `json:"description"`
+	Quantity    float64 `json:"quantity"`
+	Unit        string  `json:"unit"` // "month", "GB-month", "GB" ou "payment"
+	UnitPrice   float64 `json:"unit_price"`
+	Amount      float64 `json:"amount"`
+}
+
+// Invoice é o documento de uma fatura mensal, renderizado em JSON pela API ou em PDF
+type Invoice struct {
+	Number      string
+	IssuedAt    time.Time
+	PeriodStart time.Time
+	PeriodEnd   time.Time // Exclusivo
+	Customer    string
+	Email       string
+	Currency    string
+	Lines       []Line
+	Total       float64
+	AmountDue   float64 // Parte do total cobrada com a fatura (o excedente)
+	Status      string
+}
+
+// WritePDF grava a fatura como um PDF de uma página, em texto, com a fonte Helvetica
+// embutida em todo leitor de PDF
+func (inv *Invoice) WritePDF(w io.Writer) error {
+	var content bytes.Buffer
+	y := 790
+	text := func(x, size int, s string) {
+		fmt.Fprintf(&content, "BT /F1 %d Tf %d %d Td (%s) Tj ET\n", size, x, y, pdfString(s))
+	}
+	line := func(size, advance int, s string) {
+		text(50, size, s)
+		y -= advance
+	}
+
+	line(20, 30, "Invoice "+inv.Number)
+	line(10, 14, "Issued: "+inv.IssuedAt.UTC().Format("2006-01-02"))
+	line(10, 14, fmt.Sprintf("Period: %s to %s", inv.PeriodStart.UTC().Format("2006-01-02"), inv.PeriodEnd.Add(-time.Second).UTC().Format("2006-01-02")))
+	line(10, 14, fmt.Sprintf("Customer: %s <%s>", inv.Customer, inv.Email))
+	line(10, 30, "Status: "+inv.Status)
+
+	row := func(cols ...string) {
+		for i, c := range cols {
+			text([]int{50, 300, 380, 470}[i], 10, c)
+		}
+		y -= 16
+	}
+	row("Description", "Quantity", "Unit price", "Amount")
+	for _, l := range inv.Lines {
+		row(l.Description, fmt.Sprintf("%.2f %s", l.Quantity, l.Unit), fmt.Sprintf("%.4f", l.UnitPrice), fmt.Sprintf("%.2f", l.Amount))
+		if y < 120 {
+			break
+		}
+	}
+	y -= 14
+	row("", "", "Total", fmt.Sprintf("%.2f %s", inv.Total, inv.Currency))
+	row("", "", "Amount due", fmt.Sprintf("%.2f %s", inv.AmountDue, inv.Currency))
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Resources << /Font << /F1 4 0 R >> >> /Contents 5 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+	}
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+// pdfString escapa o texto para uma string literal do PDF em WinAnsi (Latin-1);
+// caracteres fora dela viram "?"
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 32:
+			b.WriteByte(' ')
+		case r < 128:
+			b.WriteRune(r)
+		case r < 256:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,33 @@
+package billing
+
+import "math"
+
+const (
+	// GB é a unidade das tarifas de excedente (GiB)
+	GB = 1 << 30
+	// HoursPerMonth converte GB-hora em GB-mês, a unidade da tarifa de armazenamento
+	HoursPerMonth = 730
+)
+
+// StorageOverage cobra os byte-hora acima do limite do plano, convertidos em GB-mês
+func StorageOverage(byteHoursOver int64, rate float64) (gbMonths, amount float64) {
+	if byteHoursOver <= 0 {
+		return 0, 0
+	}
+	gbMonths = float64(byteHoursOver) / GB / HoursPerMonth
+	return gbMonths, RoundAmount(gbMonths * rate)
+}
+
+// EgressOverage cobra os bytes servidos além do incluído no plano, em GB
+func EgressOverage(egress, included int64, rate float64) (gb, amount float64) {
+	if egress <= included {
+		return 0, 0
+	}
+	gb = float64(egress-included) / GB
+	return gb, RoundAmount(gb * rate)
+}
+
+// RoundAmount arredonda um valor para centavos
+func RoundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
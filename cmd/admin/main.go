@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/config"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/database"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+const usage = `Uso: go run ./cmd/admin <comando> [argumento]
+
+Comandos:
+  grant <email>     torna o usuário administrador
+  revoke <email>    remove o acesso de administrador
+  unlock <email>    desbloqueia o login da conta e zera as falhas
+  unlock-ip <ip>    desbloqueia os logins vindos do IP
+  locked            lista as contas e IPs bloqueados
+  disable-2fa <email>
+                    desativa o 2FA da conta (aparelho e códigos de recuperação perdidos)`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+	command := os.Args[1]
+	arg := ""
+	if len(os.Args) > 2 {
+		arg = os.Args[2]
+	}
+	if command != "locked" && arg == "" {
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+
+	// Carrega configuração
+	config.LoadConfig()
+
+	// Conecta ao banco
+	db, err := database.Connect()
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+
+	switch command {
+	case "grant", "revoke":
+		user := findUser(db, arg)
+		if err := db.Model(user).Update("is_admin", command == "grant").Error; err != nil {
+			log.Fatalf("❌ Could not update %s: %v", user.Email, err)
+		}
+		if command == "grant" {
+			fmt.Printf("✅ %s is now an administrator.\n", user.Email)
+		} else {
+			fmt.Printf("✅ %s is no longer an administrator.\n", user.Email)
+		}
+	case "unlock":
+		user := findUser(db, arg)
+		err := db.Model(user).Updates(map[string]interface{}{
+			"failed_logins":      0,
+			"locked_until":       nil,
+			"permanently_locked": false,
+		}).Error
+		if err != nil {
+			log.Fatalf("❌ Could not unlock %s: %v", user.Email, err)
+		}
+		db.Create(&models.AuditEvent{
+			UserID:  &user.ID,
+			Actor:   "cmd/admin",
+			Action:  models.AuditAccountUnlock,
+			Target:  user.Email,
+			Outcome: models.AuditSuccess,
+		})
+		fmt.Printf("🔓 %s unlocked.\n", user.Email)
+	case "disable-2fa":
+		user := findUser(db, arg)
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(user).Updates(map[string]interface{}{
+				"totp_secret":     "",
+				"totp_enabled_at": nil,
+				"totp_last_step":  0,
+			}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error; err != nil {
+				return err
+			}
+			return tx.Where("user_id = ?", user.ID).Delete(&models.LoginChallenge{}).Error
+		})
+		if err != nil {
+			log.Fatalf("❌ Could not disable two-factor authentication for %s: %v", user.Email, err)
+		}
+		db.Create(&models.AuditEvent{
+			UserID:  &user.ID,
+			Actor:   "cmd/admin",
+			Action:  models.AuditTwoFactorOff,
+			Target:  user.Email,
+			Outcome: models.AuditSuccess,
+		})
+		fmt.Printf("🔓 Two-factor authentication disabled for %s.\n", user.Email)
+	case "unlock-ip":
+		result := db.Where("ip = ?", arg).Delete(&models.IPLoginFailure{})
+		if result.Error != nil {
+			log.Fatalf("❌ Could not unlock %s: %v", arg, result.Error)
+		}
+		if result.RowsAffected == 0 {
+			fmt.Printf("✓ %s has no recorded login failures.\n", arg)
+			return
+		}
+		fmt.Printf("🔓 %s unlocked.\n", arg)
+	case "locked":
+		now := time.Now()
+		var users []models.User
+		db.Where("permanently_locked = ? OR locked_until > ?", true, now).Order("email").Find(&users)
+		for _, u := range users {
+			if u.PermanentlyLocked {
+				fmt.Printf("🔒 %-40s permanently locked (%d failures)\n", u.Email, u.FailedLogins)
+			} else {
+				fmt.Printf("⏳ %-40s locked until %s (%d failures)\n", u.Email, u.LockedUntil.Format("2006-01-02 15:04:05"), u.FailedLogins)
+			}
+		}
+		var ips []models.IPLoginFailure
+		db.Where("locked_until > ?", now).Order("locked_until DESC").Find(&ips)
+		for _, e := range ips {
+			fmt.Printf("⏳ %-40s locked until %s (%d failures)\n", e.IP, e.LockedUntil.Format("2006-01-02 15:04:05"), e.Failures)
+		}
+		fmt.Printf("\n📊 %d account(s) and %d IP(s) locked.\n", len(users), len(ips))
+	default:
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+}
+
+// findUser busca o usuário pelo e-mail ou encerra o programa
+func findUser(db *gorm.DB, email string) *models.User {
+	var user models.User
+	if err := db.First(&user, "email = ?", email).Error; err != nil {
+		log.Fatalf("❌ User %s not found", email)
+	}
+	return &user
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/config"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/database"
+)
+
+const usage = `Uso: go run ./cmd/migrate <comando> [passos]
+
+Comandos:
+  up [n]     aplica n migrações pendentes (todas, se omitido)
+  down [n]   reverte as n migrações mais recentes (1, se omitido)
+  status     lista as migrações e se já foram aplicadas`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+
+	steps := 0
+	if len(os.Args) > 2 {
+		n, err := strconv.Atoi(os.Args[2])
+		if err != nil || n <= 0 {
+			log.Fatalf("❌ Invalid number of steps: %q", os.Args[2])
+		}
+		steps = n
+	}
+
+	// Carrega configuração
+	config.LoadConfig()
+
+	// Abre a conexão sem a verificação de migrações pendentes feita por database.Connect
+	db, err := database.Open()
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+
+	switch os.Args[1] {
+	case "up":
+		applied, err := database.MigrateUp(db, steps)
+		for _, m := range applied {
+			fmt.Printf("✅ Applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("✓ Database is already up to date.")
+		}
+	case "down":
+		if steps == 0 {
+			steps = 1
+		}
+		reverted, err := database.MigrateDown(db, steps)
+		for _, m := range reverted {
+			fmt.Printf("↩️  Reverted %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		if len(reverted) == 0 {
+			fmt.Println("✓ No applied migrations to revert.")
+		}
+	case "status":
+		statuses, err := database.MigrationStatuses(db)
+		if err != nil {
+			log.Fatalf("❌ Could not read migration status: %v", err)
+		}
+		pending := 0
+		for _, s := range statuses {
+			if s.Applied {
+				fmt.Printf("✅ %04d_%-40s applied at %s\n", s.Version, s.Name, s.AppliedAt.Format("2006-01-02 15:04:05"))
+			} else {
+				pending++
+				fmt.Printf("⏳ %04d_%-40s pending\n", s.Version, s.Name)
+			}
+		}
+		fmt.Printf("\n📊 %d migration(s), %d pending.\n", len(statuses), pending)
+	default:
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+}
@@ -25,7 +25,8 @@ func main() {
 	fmt.Println("✅ Recalculation complete!")
 }
 
-// RecalculateStorageUsage recalcula o uso de armazenamento de todos os usuários
+// RecalculateStorageUsage recalcula o uso de armazenamento de todos os usuários e
+// organizações
 func RecalculateStorageUsage(db *gorm.DB) {
 	var users []models.User
 	if err := db.Find(&users).Error; err != nil {
@@ -34,15 +35,31 @@ func RecalculateStorageUsage(db *gorm.DB) {
 
 	fmt.Printf("📊 Found %d users to process.\n\n", len(users))
 
+	// Corrige as referências dos blobs antes de somar, removendo blobs sem arquivos
+	if err := db.Exec(`UPDATE blobs SET ref_count = (SELECT count(*) FROM files WHERE files.blob_id = blobs.id)`).Error; err != nil {
+		log.Fatalf("❌ Error recalculating blob references: %v", err)
+	}
+	var orphans int64
+	db.Model(&models.Blob{}).Where("ref_count = 0").Count(&orphans)
+	if orphans > 0 {
+		fmt.Printf("⚠️  Found %d blob(s) without files; they are not counted and can be removed from storage.\n\n", orphans)
+	}
+
 	for _, user := range users {
-		var totalSize int64
+		var totalSize, physicalSize int64
 		
-		// Subquery: busca todos os IDs de projetos do usuário
-		projectIDs := db.Model(&models.Project{}).Select("id").Where("user_id = ?", user.ID)
+		// Subquery: busca todos os IDs de projetos pessoais do usuário (os de organizações
+		// contam no uso da organização)
+		projectIDs := db.Model(&models.Project{}).Select("id").Where("user_id = ? AND organization_id IS NULL", user.ID)
 
-		// Soma o tamanho de todos os arquivos desses projetos
-		if err := db.Model(&models.File{}).
+		// Variantes redimensionadas dos arquivos desses projetos
+		variantSize := db.Model(&models.Variant{}).
 			Select("COALESCE(sum(size), 0)").
+			Where("file_id IN (?)", db.Model(&models.File{}).Select("id").Where("project_id IN (?)", projectIDs))
+
+		// Uso lógico: soma o tamanho de todos os arquivos desses projetos e de suas variantes
+		if err := db.Model(&models.File{}).
+			Select("COALESCE(sum(size), 0) + (?)", variantSize).
 			Where("project_id IN (?)", projectIDs).
 			Row().
 			Scan(&totalSize); err != nil {
@@ -51,19 +68,94 @@ func RecalculateStorageUsage(db *gorm.DB) {
 			continue
 		}
 
+		// Uso físico: blobs referenciados (cada conteúdo uma vez), arquivos anteriores à
+		// deduplicação e variantes
+		if err := db.Raw(`SELECT
+				COALESCE((SELECT sum(size) FROM blobs WHERE user_id = ? AND organization_id IS NULL AND ref_count > 0), 0) +
+				COALESCE((SELECT sum(size) FROM files WHERE blob_id IS NULL AND project_id IN (?)), 0) +
+				(?)`,
+			user.ID, projectIDs, variantSize).
+			Row().
+			Scan(&physicalSize); err != nil {
+			log.Printf("⚠️  Warning: Could not calculate storage for user %s (%s): %v", 
+				user.Email, user.ID, err)
+			continue
+		}
+
 		// Verifica se precisa atualizar
-		if user.StorageUsage != totalSize {
+		if user.StorageUsage != physicalSize || user.LogicalStorageUsage != totalSize {
 			fmt.Printf("🔧 Updating user: %s\n", user.Email)
-			fmt.Printf("   Old storage: %d bytes (%.2f MB)\n", user.StorageUsage, float64(user.StorageUsage)/(1024*1024))
-			fmt.Printf("   New storage: %d bytes (%.2f MB)\n", totalSize, float64(totalSize)/(1024*1024))
+			fmt.Printf("   Old storage: %d bytes (%.2f MB), logical %d bytes\n", user.StorageUsage, float64(user.StorageUsage)/(1024*1024), user.LogicalStorageUsage)
+			fmt.Printf("   New storage: %d bytes (%.2f MB), logical %d bytes\n", physicalSize, float64(physicalSize)/(1024*1024), totalSize)
 			
-			if err := db.Model(&user).Update("storage_usage", totalSize).Error; err != nil {
+			if err := db.Model(&user).Updates(map[string]interface{}{
+				"storage_usage":         physicalSize,
+				"logical_storage_usage": totalSize,
+			}).Error; err != nil {
 				log.Printf("❌ Failed to update storage for user %s: %v\n", user.Email, err)
 			} else {
 				fmt.Printf("✅ Successfully updated!\n\n")
 			}
 		} else {
-			fmt.Printf("✓ User %s storage is already correct (%d bytes)\n\n", user.Email, user.StorageUsage)
+			fmt.Printf("✓ User %s storage is already correct (%d bytes, %d logical)\n\n", user.Email, user.StorageUsage, user.LogicalStorageUsage)
+		}
+	}
+
+	recalculateOrganizationStorage(db)
+}
+
+// recalculateOrganizationStorage recalcula o uso compartilhado de cada organização a
+// partir dos arquivos, blobs e variantes dos seus projetos
+func recalculateOrganizationStorage(db *gorm.DB) {
+	var orgs []models.Organization
+	if err := db.Find(&orgs).Error; err != nil {
+		log.Fatalf("❌ Error fetching organizations: %v", err)
+	}
+
+	fmt.Printf("📊 Found %d organizations to process.\n\n", len(orgs))
+
+	for _, org := range orgs {
+		var totalSize, physicalSize int64
+
+		projectIDs := db.Model(&models.Project{}).Select("id").Where("organization_id = ?", org.ID)
+		variantSize := db.Model(&models.Variant{}).
+			Select("COALESCE(sum(size), 0)").
+			Where("file_id IN (?)", db.Model(&models.File{}).Select("id").Where("project_id IN (?)", projectIDs))
+
+		if err := db.Model(&models.File{}).
+			Select("COALESCE(sum(size), 0) + (?)", variantSize).
+			Where("project_id IN (?)", projectIDs).
+			Row().
+			Scan(&totalSize); err != nil {
+			log.Printf("⚠️  Warning: Could not calculate storage for organization %s (%s): %v", org.Slug, org.ID, err)
+			continue
+		}
+
+		if err := db.Raw(`SELECT
+				COALESCE((SELECT sum(size) FROM blobs WHERE organization_id = ? AND ref_count > 0), 0) +
+				(?)`,
+			org.ID, variantSize).
+			Row().
+			Scan(&physicalSize); err != nil {
+			log.Printf("⚠️  Warning: Could not calculate storage for organization %s (%s): %v", org.Slug, org.ID, err)
+			continue
+		}
+
+		if org.StorageUsage != physicalSize || org.LogicalStorageUsage != totalSize {
+			fmt.Printf("🔧 Updating organization: %s\n", org.Slug)
+			fmt.Printf("   Old storage: %d bytes, logical %d bytes\n", org.StorageUsage, org.LogicalStorageUsage)
+			fmt.Printf("   New storage: %d bytes, logical %d bytes\n", physicalSize, totalSize)
+
+			if err := db.Model(&org).Updates(map[string]interface{}{
+				"storage_usage":         physicalSize,
+				"logical_storage_usage": totalSize,
+			}).Error; err != nil {
+				log.Printf("❌ Failed to update storage for organization %s: %v\n", org.Slug, err)
+			} else {
+				fmt.Printf("✅ Successfully updated!\n\n")
+			}
+		} else {
+			fmt.Printf("✓ Organization %s storage is already correct (%d bytes, %d logical)\n\n", org.Slug, org.StorageUsage, org.LogicalStorageUsage)
 		}
 	}
 }
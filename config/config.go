@@ -2,9 +2,16 @@ package config
 
 import (
 	"log"
+	"net"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/imaging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/ratelimit"
 )
 
 // Config holds all configuration for the application
@@ -13,6 +20,104 @@ type Config struct {
 	Port        string
 	JWTSecret   string
 	DatabaseURL string
+
+	// Sessões: access tokens curtos renovados por refresh tokens rotativos
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+
+	// Proxies reversos cujo X-Forwarded-For é aceito para identificar o IP do cliente. Sem
+	// nenhum, vale o endereço da conexão e o header é ignorado.
+	TrustedProxies []*net.IPNet
+
+	// MigrateOnStart aplica migrações pendentes na inicialização em vez de recusar subir
+	MigrateOnStart bool
+
+	// Armazenamento de arquivos
+	StorageDriver    string // "local" ou "s3"
+	StorageLocalPath string
+	S3Endpoint       string
+	S3Region         string
+	S3Bucket         string
+	S3AccessKey      string
+	S3SecretKey      string
+	S3UsePathStyle   bool
+
+	// Uploads resumíveis
+	UploadSessionDir       string // Arquivos temporários de cada PATCH; as partes ficam no driver de armazenamento
+	UploadSessionTTL       time.Duration
+	MaxResumableUploadSize int64
+
+	// URLs assinadas para arquivos de projetos privados
+	URLSigningSecret string
+	SignedURLTTL     time.Duration // Validade padrão
+	SignedURLMaxTTL  time.Duration // Validade máxima aceita em /api/sign
+
+	// Variantes redimensionadas de imagens (miniaturas e /files/...?w=&h=&fit=)
+	VariantCacheDir    string         // Diretório local onde as variantes são gravadas
+	ThumbnailSizes     []imaging.Size // Geradas no upload de imagens JPEG e PNG
+	MaxVariantsPerFile int64          // Acima disso as variantes pedidas não são mais guardadas
+	MaxImagePixels     int64          // Imagens maiores não são decodificadas
+
+	// Verificação de malware: "builtin", "clamd" ou "none"
+	Scanner      string
+	ClamdAddress string
+	ClamdTimeout time.Duration
+
+	// Fila de jobs em segundo plano
+	JobWorkers      int
+	JobPollInterval time.Duration
+	JobMaxAttempts  int
+	JobTimeout      time.Duration // Jobs em execução há mais tempo voltam para a fila
+	JobRetention    time.Duration // Por quanto tempo jobs concluídos ficam na tabela
+
+	// Webhooks
+	WebhookTimeout      time.Duration
+	WebhookMaxAttempts  int
+	WebhookAllowPrivate bool // Permite entregas para endereços internos (desenvolvimento)
+
+	// Limite de requisições (token bucket): "memory" ou "postgres" para dividir entre réplicas
+	RateLimitBackend    string
+	RateLimitLoginIP    ratelimit.Limit // /login e /token/refresh, por IP
+	RateLimitLoginEmail ratelimit.Limit // /login, por e-mail informado
+	RateLimitRegister   ratelimit.Limit // /register, por IP
+	RateLimitAPIIP      ratelimit.Limit // /api/*, por IP, antes da autenticação
+	RateLimitAPI        ratelimit.Limit // /api/*, por usuário, quando o plano não define o seu
+	RateLimitUpload     ratelimit.Limit // /api/upload e /api/uploads, por usuário
+
+	// Bloqueio do login após falhas seguidas. A cada LockoutThreshold falhas a conta fica
+	// bloqueada por LockoutDuration, dobrando a cada novo bloqueio; com
+	// LockoutPermanentThreshold falhas só um administrador a desbloqueia.
+	LockoutThreshold          int
+	LockoutDuration           time.Duration
+	LockoutMaxDuration        time.Duration
+	LockoutPermanentThreshold int // 0 desativa o bloqueio permanente
+	LockoutIPThreshold        int // Falhas de um IP, em qualquer conta, até bloqueá-lo
+
+	// E-mails transacionais: "smtp" ou "log" (grava em MailLogDir, para testes locais)
+	Mailer       string
+	MailLogDir   string
+	MailFrom     string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+
+	// Verificação de e-mail e recuperação de senha
+	EmailVerificationTTL     time.Duration
+	PasswordResetTTL         time.Duration
+	RequireEmailVerification bool // Bloqueia uploads de contas com e-mail não verificado
+
+	// Autenticação em dois fatores (TOTP)
+	TOTPIssuer                 string        // Nome exibido no aplicativo autenticador
+	TwoFactorChallengeTTL      time.Duration // Prazo para informar o código depois da senha
+	TwoFactorChallengeAttempts int           // Códigos errados aceitos por desafio
+
+	// Assinaturas dos planos pagos
+	BillingProvider      string        // "fake" (aprova as cobranças em memória, para testes locais)
+	BillingFakeDecline   string        // E-mails cujas cobranças o provedor fake recusa, separados por vírgulas
+	BillingCurrency      string        // Moeda de Plan.Price
+	BillingPeriod        time.Duration // Duração de cada período pago
+	OverQuotaGracePeriod time.Duration // Prazo para voltar ao limite depois de um downgrade, antes de os arquivos deixarem de ser servidos
 }
 
 var AppConfig *Config
@@ -30,6 +135,85 @@ func LoadConfig() {
 		Port:        getEnv("PORT", "8002"),
 		JWTSecret:   getEnv("JWT_SECRET", "a-very-secret-key"), // Default for development
 		DatabaseURL: getEnv("DATABASE_URL", "forge.db"),
+
+		AccessTokenTTL:  getEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL: getEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+
+		TrustedProxies: getEnvNetworks("TRUSTED_PROXIES"),
+
+		MigrateOnStart: getEnvBool("MIGRATE_ON_START", false),
+
+		StorageDriver:    getEnv("STORAGE_DRIVER", "local"),
+		StorageLocalPath: getEnv("STORAGE_LOCAL_PATH", "./uploads"),
+		S3Endpoint:       getEnv("S3_ENDPOINT", ""),
+		S3Region:         getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:         getEnv("S3_BUCKET", ""),
+		S3AccessKey:      getEnv("S3_ACCESS_KEY", ""),
+		S3SecretKey:      getEnv("S3_SECRET_KEY", ""),
+		S3UsePathStyle:   getEnvBool("S3_USE_PATH_STYLE", true), // MinIO usa path-style por padrão
+
+		UploadSessionDir:       getEnv("UPLOAD_SESSION_DIR", "./upload-sessions"),
+		UploadSessionTTL:       getEnvDuration("UPLOAD_SESSION_TTL", 24*time.Hour),
+		MaxResumableUploadSize: getEnvInt64("MAX_RESUMABLE_UPLOAD_SIZE", 5*1024*1024*1024), // 5 GB
+
+		URLSigningSecret: getURLSigningSecret(),
+		SignedURLTTL:     getEnvDuration("SIGNED_URL_TTL", 1*time.Hour),
+		SignedURLMaxTTL:  getEnvDuration("SIGNED_URL_MAX_TTL", 7*24*time.Hour),
+
+		VariantCacheDir:    getEnv("VARIANT_CACHE_DIR", "./variant-cache"),
+		ThumbnailSizes:     getEnvSizes("THUMBNAIL_SIZES", "150x150:cover,600x600"),
+		MaxVariantsPerFile: getEnvInt64("MAX_VARIANTS_PER_FILE", 20),
+		MaxImagePixels:     getEnvInt64("MAX_IMAGE_PIXELS", 50_000_000), // ~50 megapixels
+
+		Scanner:      getEnv("SCANNER", "builtin"),
+		ClamdAddress: getEnv("CLAMD_ADDRESS", "localhost:3310"),
+		ClamdTimeout: getEnvDuration("CLAMD_TIMEOUT", 2*time.Minute),
+
+		JobWorkers:      int(getEnvInt64("JOB_WORKERS", 4)),
+		JobPollInterval: getEnvDuration("JOB_POLL_INTERVAL", 1*time.Second),
+		JobMaxAttempts:  int(getEnvInt64("JOB_MAX_ATTEMPTS", 5)),
+		JobTimeout:      getEnvDuration("JOB_TIMEOUT", 15*time.Minute),
+		JobRetention:    getEnvDuration("JOB_RETENTION", 7*24*time.Hour),
+
+		WebhookTimeout:      getEnvDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+		WebhookMaxAttempts:  int(getEnvInt64("WEBHOOK_MAX_ATTEMPTS", 8)),
+		WebhookAllowPrivate: getEnvBool("WEBHOOK_ALLOW_PRIVATE_NETWORKS", false),
+
+		RateLimitBackend:    getEnv("RATE_LIMIT_BACKEND", "memory"),
+		RateLimitLoginIP:    getEnvLimit("RATE_LIMIT_LOGIN_IP", "20/1m"),
+		RateLimitLoginEmail: getEnvLimit("RATE_LIMIT_LOGIN_EMAIL", "5/1m"),
+		RateLimitRegister:   getEnvLimit("RATE_LIMIT_REGISTER", "5/1h"),
+		RateLimitAPIIP:      getEnvLimit("RATE_LIMIT_API_IP", "1200/1m"),
+		RateLimitAPI:        getEnvLimit("RATE_LIMIT_API", "600/1m"),
+		RateLimitUpload:     getEnvLimit("RATE_LIMIT_UPLOAD", "60/1m"),
+
+		LockoutThreshold:          int(getEnvInt64("LOCKOUT_THRESHOLD", 10)),
+		LockoutDuration:           getEnvDuration("LOCKOUT_DURATION", 30*time.Minute),
+		LockoutMaxDuration:        getEnvDuration("LOCKOUT_MAX_DURATION", 24*time.Hour),
+		LockoutPermanentThreshold: int(getEnvInt64("LOCKOUT_PERMANENT_THRESHOLD", 20)),
+		LockoutIPThreshold:        int(getEnvInt64("LOCKOUT_IP_THRESHOLD", 50)),
+
+		Mailer:       getEnv("MAILER", "log"),
+		MailLogDir:   getEnv("MAIL_LOG_DIR", "./mail"),
+		MailFrom:     getEnv("MAIL_FROM", "Forge Uploader <no-reply@localhost>"),
+		SMTPHost:     getEnv("SMTP_HOST", ""),
+		SMTPPort:     int(getEnvInt64("SMTP_PORT", 587)),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+
+		EmailVerificationTTL:     getEnvDuration("EMAIL_VERIFICATION_TTL", 48*time.Hour),
+		PasswordResetTTL:         getEnvDuration("PASSWORD_RESET_TTL", 1*time.Hour),
+		RequireEmailVerification: getEnvBool("REQUIRE_EMAIL_VERIFICATION", true),
+
+		TOTPIssuer:                 getEnv("TOTP_ISSUER", "Forge Uploader"),
+		TwoFactorChallengeTTL:      getEnvDuration("TWO_FACTOR_CHALLENGE_TTL", 5*time.Minute),
+		TwoFactorChallengeAttempts: int(getEnvInt64("TWO_FACTOR_CHALLENGE_ATTEMPTS", 5)),
+
+		BillingProvider:      getEnv("BILLING_PROVIDER", "fake"),
+		BillingFakeDecline:   getEnv("BILLING_FAKE_DECLINE", ""),
+		BillingCurrency:      getEnv("BILLING_CURRENCY", "USD"),
+		BillingPeriod:        getEnvDuration("BILLING_PERIOD", 30*24*time.Hour),
+		OverQuotaGracePeriod: getEnvDuration("OVER_QUOTA_GRACE_PERIOD", 7*24*time.Hour),
 	}
 }
 
@@ -57,6 +241,14 @@ func getDomain() string {
 	return "http://localhost:" + port
 }
 
+// getURLSigningSecret obtém o segredo das URLs assinadas, usando o JWT_SECRET quando não definido
+func getURLSigningSecret() string {
+	if value := os.Getenv("URL_SIGNING_SECRET"); value != "" {
+		return value
+	}
+	return getEnv("JWT_SECRET", "a-very-secret-key")
+}
+
 // getEnv retrieves an environment variable or returns a default value
 func getEnv(key, fallback string) string {
 	if value, ok := os.LookupEnv(key); ok {
@@ -64,3 +256,91 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+// getEnvBool retrieves a boolean environment variable or returns a default value
+func getEnvBool(key string, fallback bool) bool {
+	if value, ok := os.LookupEnv(key); ok {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			return parsed
+		}
+		log.Printf("Valor inválido para %s: %q, usando padrão %v", key, value, fallback)
+	}
+	return fallback
+}
+
+// getEnvInt64 retrieves an integer environment variable or returns a default value
+func getEnvInt64(key string, fallback int64) int64 {
+	if value, ok := os.LookupEnv(key); ok {
+		if parsed, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return parsed
+		}
+		log.Printf("Valor inválido para %s: %q, usando padrão %v", key, value, fallback)
+	}
+	return fallback
+}
+
+// getEnvDuration retrieves a duration (e.g. "30m", "24h") environment variable or returns a default value
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	if value, ok := os.LookupEnv(key); ok {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			return parsed
+		}
+		log.Printf("Valor inválido para %s: %q, usando padrão %v", key, value, fallback)
+	}
+	return fallback
+}
+
+// getEnvSizes retrieves a list of image sizes (e.g. "150x150:cover,600x600") or returns the default
+func getEnvSizes(key, fallback string) []imaging.Size {
+	if value, ok := os.LookupEnv(key); ok {
+		sizes, err := imaging.ParseSizes(value)
+		if err == nil {
+			return sizes
+		}
+		log.Printf("Valor inválido para %s: %v, usando padrão %q", key, err, fallback)
+	}
+	sizes, _ := imaging.ParseSizes(fallback)
+	return sizes
+}
+
+// getEnvLimit retrieves a rate limit (e.g. "10/1m"; "0" disables it) or returns the default
+func getEnvLimit(key, fallback string) ratelimit.Limit {
+	if value, ok := os.LookupEnv(key); ok {
+		limit, err := ratelimit.ParseLimit(value)
+		if err == nil {
+			return limit
+		}
+		log.Printf("Valor inválido para %s: %v, usando padrão %q", key, err, fallback)
+	}
+	limit, _ := ratelimit.ParseLimit(fallback)
+	return limit
+}
+
+// getEnvNetworks retrieves a comma-separated list of IPs or CIDRs (e.g. "10.0.0.0/8,127.0.0.1").
+// Invalid entries are logged and skipped.
+func getEnvNetworks(key string) []*net.IPNet {
+	var networks []*net.IPNet
+	for _, entry := range strings.Split(os.Getenv(key), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil {
+				bits := 8 * len(ip.To16())
+				if ip.To4() != nil {
+					ip, bits = ip.To4(), 32
+				}
+				networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+				continue
+			}
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			log.Printf("Valor inválido em %s: %q, ignorado", key, entry)
+			continue
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
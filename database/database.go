@@ -1,6 +1,7 @@
 package database
 
 import (
+	"fmt"
 	"log"
 
 	"gorm.io/driver/postgres"
@@ -11,24 +12,38 @@ import (
 	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
 )
 
-// Connect abre a conexão com o banco de dados e executa as migrações
-func Connect() (*gorm.DB, error) {
-	db, err := gorm.Open(postgres.Open(config.AppConfig.DatabaseURL), &gorm.Config{
+// Open abre a conexão com o banco de dados sem verificar o estado das migrações
+func Open() (*gorm.DB, error) {
+	return gorm.Open(postgres.Open(config.AppConfig.DatabaseURL), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Info),
 	})
+}
+
+// Connect abre a conexão com o banco de dados e garante que o esquema está atualizado.
+// Se houver migrações pendentes, a inicialização é recusada, a menos que
+// MIGRATE_ON_START esteja habilitado.
+func Connect() (*gorm.DB, error) {
+	db, err := Open()
 	if err != nil {
 		return nil, err
 	}
 
-	log.Println("Executando migrações do banco de dados...")
-	// Em desenvolvimento, podemos dropar as tabelas para garantir a atualização do esquema.
-	// CUIDADO: Isso apagará todos os dados. Não use em produção.
-	db.Migrator().DropTable(&models.User{}, &models.Project{}, &models.File{}, &models.Plan{})
-	err = db.AutoMigrate(&models.User{}, &models.Project{}, &models.File{}, &models.Plan{})
+	pending, err := PendingMigrations(db)
 	if err != nil {
-		log.Println("Erro ao executar migrações:", err)
+		log.Println("Erro ao verificar migrações:", err)
 		return nil, err
 	}
+	if len(pending) > 0 {
+		if !config.AppConfig.MigrateOnStart {
+			return nil, fmt.Errorf("%d pending migration(s), starting at %04d_%s: run `go run ./cmd/migrate up` or set MIGRATE_ON_START=true",
+				len(pending), pending[0].Version, pending[0].Name)
+		}
+		log.Printf("Aplicando %d migração(ões) pendente(s)...", len(pending))
+		if _, err := MigrateUp(db, 0); err != nil {
+			log.Println("Erro ao executar migrações:", err)
+			return nil, err
+		}
+	}
 
 	// Cria o plano padrão se não existir
 	CreateDefaultPlan(db)
@@ -40,13 +55,16 @@ func Connect() (*gorm.DB, error) {
 func CreateDefaultPlan(db *gorm.DB) {
 	var freePlan models.Plan
 	// Verifica se o plano "Free" já existe
-	if err := db.Where("name = ?", "Free").First(&freePlan).Error; err == gorm.ErrRecordNotFound {
+	if err := db.Where("name = ?", models.FreePlanName).First(&freePlan).Error; err == gorm.ErrRecordNotFound {
 		// Plano não encontrado, então cria um novo
 		log.Println("Criando plano 'Free' padrão...")
 		newFreePlan := models.Plan{
-			Name:         "Free",
-			Price:        0,
-			StorageLimit: models.FreePlanStorageLimit, // 1 GB
+			Name:             models.FreePlanName,
+			Price:            0,
+			StorageLimit:     models.FreePlanStorageLimit, // 1 GB
+			MaxFileSize:      models.FreePlanMaxFileSize,  // 10 MB
+			AllowedMimeTypes: models.FreePlanAllowedMimeTypes,
+			DailyUploadLimit: models.FreePlanDailyUploadLimit,
 		}
 		if err := db.Create(&newFreePlan).Error; err != nil {
 			log.Fatalf("Falha ao criar o plano 'Free': %v", err)
@@ -70,14 +88,17 @@ func ConnectTest() (*gorm.DB, error) {
 		return nil, err
 	}
 
-	// Limpa o banco de dados de teste antes de executar as migrações
-	err = db.Migrator().DropTable(&models.User{}, &models.Project{}, &models.File{}, &models.Plan{})
+	// Limpa o banco de dados de teste: reverte todas as migrações e remove tabelas
+	// criadas pelo antigo AutoMigrate, que não aparecem em schema_migrations
+	if _, err := MigrateDown(db, 0); err != nil {
+		return nil, err
+	}
+	err = db.Migrator().DropTable(&models.File{}, &models.Project{}, &models.User{}, &models.Plan{})
 	if err != nil {
 		return nil, err
 	}
 
-	err = db.AutoMigrate(&models.User{}, &models.Project{}, &models.File{}, &models.Plan{})
-	if err != nil {
+	if _, err := MigrateUp(db, 0); err != nil {
 		return nil, err
 	}
 
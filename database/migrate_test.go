@@ -0,0 +1,22 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrationsAreWellFormed(t *testing.T) {
+	seen := map[int]bool{}
+	for i, m := range migrations {
+		assert.Greater(t, m.Version, 0, "migration %q must have a positive version", m.Name)
+		assert.False(t, seen[m.Version], "duplicate migration version %d", m.Version)
+		seen[m.Version] = true
+		if i > 0 {
+			assert.Greater(t, m.Version, migrations[i-1].Version, "migrations must be listed in ascending order")
+		}
+		assert.NotEmpty(t, m.Name)
+		assert.NotNil(t, m.Up, "migration %04d_%s has no up step", m.Version, m.Name)
+		assert.NotNil(t, m.Down, "migration %04d_%s has no down step", m.Version, m.Name)
+	}
+}
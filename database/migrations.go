@@ -0,0 +1,750 @@
+package database
+
+// migrations contém todas as alterações de esquema, em ordem de versão.
+// Migrações já publicadas nunca devem ser editadas: crie uma nova versão.
+var migrations = []Migration{
+	{
+		// Esquema que antes era criado pelo AutoMigrate. Usa IF NOT EXISTS para que
+		// bancos criados pela versão anterior sejam adotados sem perda de dados.
+		Version: 1,
+		Name:    "create_initial_schema",
+		Up: execSQL(
+			`CREATE TABLE IF NOT EXISTS plans (
+				id uuid PRIMARY KEY,
+				name text NOT NULL,
+				price decimal NOT NULL DEFAULT 0,
+				storage_limit bigint NOT NULL,
+				created_at timestamptz
+			)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_plans_name ON plans (name)`,
+			`CREATE TABLE IF NOT EXISTS users (
+				id uuid PRIMARY KEY,
+				name text NOT NULL,
+				whatsapp_number text NOT NULL,
+				email text NOT NULL,
+				password text NOT NULL,
+				forge_api_key text NOT NULL,
+				storage_usage bigint DEFAULT 0,
+				plan_id uuid,
+				created_at timestamptz,
+				CONSTRAINT fk_users_plan FOREIGN KEY (plan_id) REFERENCES plans (id)
+			)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_forge_api_key ON users (forge_api_key)`,
+			`CREATE TABLE IF NOT EXISTS projects (
+				id uuid PRIMARY KEY,
+				name text NOT NULL,
+				user_id uuid NOT NULL,
+				created_at timestamptz,
+				CONSTRAINT fk_users_projects FOREIGN KEY (user_id) REFERENCES users (id)
+			)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_user_project ON projects (name, user_id)`,
+			`CREATE TABLE IF NOT EXISTS files (
+				id uuid PRIMARY KEY,
+				name text NOT NULL,
+				path text NOT NULL,
+				size bigint NOT NULL,
+				mime_type text NOT NULL,
+				project_id uuid NOT NULL,
+				uploaded_at timestamptz,
+				CONSTRAINT fk_projects_files FOREIGN KEY (project_id) REFERENCES projects (id)
+			)`,
+		),
+		Down: execSQL(
+			`DROP TABLE IF EXISTS files`,
+			`DROP TABLE IF EXISTS projects`,
+			`DROP TABLE IF EXISTS users`,
+			`DROP TABLE IF EXISTS plans`,
+		),
+	},
+	{
+		// Arquivos antigos guardavam o caminho local (uploads/user_<id>/...); o driver
+		// de armazenamento espera apenas a chave relativa.
+		Version: 2,
+		Name:    "normalize_file_paths",
+		Up: execSQL(
+			`UPDATE files SET path = regexp_replace(path, '^(\./)?uploads/', '') WHERE path ~ '^(\./)?uploads/'`,
+		),
+		Down: execSQL(
+			`UPDATE files SET path = 'uploads/' || path WHERE path NOT LIKE 'uploads/%'`,
+		),
+	},
+	{
+		Version: 3,
+		Name:    "create_upload_sessions",
+		Up: execSQL(
+			`CREATE TABLE upload_sessions (
+				id uuid PRIMARY KEY,
+				user_id uuid NOT NULL,
+				project_name text NOT NULL,
+				file_name text NOT NULL,
+				mime_type text NOT NULL,
+				upload_length bigint NOT NULL,
+				upload_offset bigint NOT NULL DEFAULT 0,
+				expires_at timestamptz NOT NULL,
+				created_at timestamptz,
+				updated_at timestamptz,
+				CONSTRAINT fk_upload_sessions_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+			)`,
+			`CREATE INDEX idx_upload_sessions_user_id ON upload_sessions (user_id)`,
+			`CREATE INDEX idx_upload_sessions_expires_at ON upload_sessions (expires_at)`,
+		),
+		Down: execSQL(
+			`DROP TABLE IF EXISTS upload_sessions`,
+		),
+	},
+	{
+		// Os valores padrão reproduzem as antigas constantes globais de handlers, de modo
+		// que os planos existentes mantêm o comportamento anterior
+		Version: 4,
+		Name:    "add_plan_upload_policy",
+		Up: execSQL(
+			`ALTER TABLE plans
+				ADD COLUMN max_file_size bigint NOT NULL DEFAULT 10485760,
+				ADD COLUMN allowed_mime_types text NOT NULL DEFAULT 'image/jpeg,image/png,application/pdf',
+				ADD COLUMN daily_upload_limit integer NOT NULL DEFAULT 100`,
+			`CREATE TABLE daily_upload_counts (
+				user_id uuid NOT NULL,
+				day date NOT NULL,
+				uploads integer NOT NULL DEFAULT 0,
+				PRIMARY KEY (user_id, day),
+				CONSTRAINT fk_daily_upload_counts_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+			)`,
+		),
+		Down: execSQL(
+			`DROP TABLE IF EXISTS daily_upload_counts`,
+			`ALTER TABLE plans
+				DROP COLUMN IF EXISTS max_file_size,
+				DROP COLUMN IF EXISTS allowed_mime_types,
+				DROP COLUMN IF EXISTS daily_upload_limit`,
+		),
+	},
+	{
+		// Projetos existentes continuam públicos, como eram servidos até aqui
+		Version: 5,
+		Name:    "add_project_visibility",
+		Up: execSQL(
+			`ALTER TABLE projects ADD COLUMN visibility text NOT NULL DEFAULT 'public'`,
+			`ALTER TABLE projects ADD CONSTRAINT chk_projects_visibility CHECK (visibility IN ('public', 'private'))`,
+		),
+		Down: execSQL(
+			`ALTER TABLE projects DROP COLUMN IF EXISTS visibility`,
+		),
+	},
+	{
+		Version: 6,
+		Name:    "create_refresh_and_revoked_tokens",
+		Up: execSQL(
+			`CREATE TABLE refresh_tokens (
+				id uuid PRIMARY KEY,
+				user_id uuid NOT NULL,
+				family_id uuid NOT NULL,
+				token_hash text NOT NULL,
+				access_jti text NOT NULL,
+				user_agent text,
+				ip_address text,
+				session_started_at timestamptz NOT NULL,
+				expires_at timestamptz NOT NULL,
+				revoked_at timestamptz,
+				created_at timestamptz,
+				CONSTRAINT fk_refresh_tokens_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+			)`,
+			`CREATE UNIQUE INDEX idx_refresh_tokens_token_hash ON refresh_tokens (token_hash)`,
+			`CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens (user_id)`,
+			`CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens (family_id)`,
+			`CREATE INDEX idx_refresh_tokens_access_jti ON refresh_tokens (access_jti)`,
+			`CREATE TABLE revoked_tokens (
+				jti text PRIMARY KEY,
+				user_id uuid NOT NULL,
+				expires_at timestamptz NOT NULL,
+				created_at timestamptz
+			)`,
+			`CREATE INDEX idx_revoked_tokens_expires_at ON revoked_tokens (expires_at)`,
+		),
+		Down: execSQL(
+			`DROP TABLE IF EXISTS revoked_tokens`,
+			`DROP TABLE IF EXISTS refresh_tokens`,
+		),
+	},
+	{
+		Version: 7,
+		Name:    "create_api_keys",
+		Up: execSQL(
+			`CREATE TABLE api_keys (
+				id uuid PRIMARY KEY,
+				user_id uuid NOT NULL,
+				name text NOT NULL,
+				prefix text NOT NULL,
+				key_hash text NOT NULL,
+				scopes text NOT NULL,
+				project_id uuid,
+				last_used_at timestamptz,
+				expires_at timestamptz,
+				created_at timestamptz,
+				CONSTRAINT fk_api_keys_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+				CONSTRAINT fk_api_keys_project FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE
+			)`,
+			`CREATE UNIQUE INDEX idx_api_keys_key_hash ON api_keys (key_hash)`,
+			`CREATE INDEX idx_api_keys_user_id ON api_keys (user_id)`,
+			`CREATE INDEX idx_api_keys_project_id ON api_keys (project_id)`,
+		),
+		Down: execSQL(
+			`DROP TABLE IF EXISTS api_keys`,
+		),
+	},
+	{
+		// Converte a ForgeAPIKey em texto puro de cada usuário em uma chave "default" com
+		// escopo admin, guardando apenas o SHA-256, e remove a coluna. As chaves existentes
+		// continuam funcionando. O Down não consegue restaurar os valores originais: gera
+		// novas chaves aleatórias para a coluna recriada.
+		Version: 8,
+		Name:    "hash_legacy_api_keys",
+		Up: execSQL(
+			`CREATE INDEX idx_api_keys_prefix ON api_keys (prefix)`,
+			`INSERT INTO api_keys (id, user_id, name, prefix, key_hash, scopes, created_at)
+				SELECT gen_random_uuid(), id, 'default', left(forge_api_key, 8),
+					encode(sha256(convert_to(forge_api_key, 'UTF8')), 'hex'), 'admin', now()
+				FROM users WHERE forge_api_key <> ''`,
+			`DROP INDEX IF EXISTS idx_users_forge_api_key`,
+			`ALTER TABLE users DROP COLUMN forge_api_key`,
+		),
+		Down: execSQL(
+			`ALTER TABLE users ADD COLUMN forge_api_key text`,
+			`UPDATE users SET forge_api_key = gen_random_uuid()::text`,
+			`ALTER TABLE users ALTER COLUMN forge_api_key SET NOT NULL`,
+			`CREATE UNIQUE INDEX idx_users_forge_api_key ON users (forge_api_key)`,
+			`DELETE FROM api_keys WHERE name = 'default' AND prefix NOT LIKE 'fk\_%'`,
+			`DROP INDEX IF EXISTS idx_api_keys_prefix`,
+		),
+	},
+	{
+		// Arquivos existentes continuam sem blob (blob_id nulo) e são servidos pelo caminho
+		// antigo; até aqui o uso lógico e o físico eram iguais
+		Version: 9,
+		Name:    "create_blobs",
+		Up: execSQL(
+			`CREATE TABLE blobs (
+				id uuid PRIMARY KEY,
+				user_id uuid NOT NULL,
+				hash text NOT NULL,
+				size bigint NOT NULL,
+				mime_type text NOT NULL,
+				storage_key text NOT NULL,
+				ref_count integer NOT NULL DEFAULT 0,
+				created_at timestamptz,
+				CONSTRAINT fk_blobs_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+			)`,
+			`CREATE UNIQUE INDEX idx_blobs_user_hash ON blobs (user_id, hash)`,
+			`ALTER TABLE files ADD COLUMN blob_id uuid,
+				ADD CONSTRAINT fk_files_blob FOREIGN KEY (blob_id) REFERENCES blobs (id)`,
+			`CREATE INDEX idx_files_blob_id ON files (blob_id)`,
+			`ALTER TABLE users ADD COLUMN logical_storage_usage bigint DEFAULT 0`,
+			`UPDATE users SET logical_storage_usage = storage_usage`,
+		),
+		Down: execSQL(
+			// Sem blobs, o caminho do arquivo volta a ser a chave no armazenamento
+			`UPDATE files SET path = blobs.storage_key FROM blobs WHERE files.blob_id = blobs.id`,
+			`ALTER TABLE users DROP COLUMN IF EXISTS logical_storage_usage`,
+			`ALTER TABLE files DROP COLUMN IF EXISTS blob_id`,
+			`DROP TABLE IF EXISTS blobs`,
+		),
+	},
+	{
+		Version: 10,
+		Name:    "create_variants",
+		Up: execSQL(
+			`CREATE TABLE variants (
+				id uuid PRIMARY KEY,
+				file_id uuid NOT NULL,
+				width integer NOT NULL,
+				height integer NOT NULL,
+				fit text NOT NULL,
+				storage_key text NOT NULL,
+				size bigint NOT NULL,
+				mime_type text NOT NULL,
+				created_at timestamptz,
+				CONSTRAINT fk_variants_file FOREIGN KEY (file_id) REFERENCES files (id) ON DELETE CASCADE
+			)`,
+			`CREATE UNIQUE INDEX idx_variants_file_size ON variants (file_id, width, height, fit)`,
+		),
+		Down: execSQL(
+			`DROP TABLE IF EXISTS variants`,
+		),
+	},
+	{
+		Version: 11,
+		Name:    "create_jobs",
+		Up: execSQL(
+			`CREATE TABLE jobs (
+				id uuid PRIMARY KEY,
+				type text NOT NULL,
+				payload jsonb NOT NULL,
+				status text NOT NULL DEFAULT 'queued',
+				attempts integer NOT NULL DEFAULT 0,
+				max_attempts integer NOT NULL,
+				run_at timestamptz NOT NULL,
+				locked_at timestamptz,
+				last_error text,
+				created_at timestamptz,
+				updated_at timestamptz
+			)`,
+			`CREATE INDEX idx_jobs_type ON jobs (type)`,
+			// Índice parcial usado pelos workers para encontrar o próximo job pendente
+			`CREATE INDEX idx_jobs_queued_run_at ON jobs (run_at) WHERE status = 'queued'`,
+			// Arquivos existentes já foram processados
+			`ALTER TABLE files ADD COLUMN status text NOT NULL DEFAULT 'ready'`,
+		),
+		Down: execSQL(
+			`ALTER TABLE files DROP COLUMN IF EXISTS status`,
+			`DROP TABLE IF EXISTS jobs`,
+		),
+	},
+	{
+		Version: 12,
+		Name:    "add_file_scan_result",
+		Up: execSQL(
+			`ALTER TABLE files ADD COLUMN scan_result text`,
+		),
+		Down: execSQL(
+			`ALTER TABLE files DROP COLUMN IF EXISTS scan_result`,
+		),
+	},
+	{
+		Version: 13,
+		Name:    "create_webhooks",
+		Up: execSQL(
+			`CREATE TABLE webhooks (
+				id uuid PRIMARY KEY,
+				user_id uuid NOT NULL,
+				project_id uuid,
+				url text NOT NULL,
+				secret text NOT NULL,
+				events text NOT NULL,
+				active boolean NOT NULL DEFAULT true,
+				created_at timestamptz,
+				CONSTRAINT fk_webhooks_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+				CONSTRAINT fk_webhooks_project FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE
+			)`,
+			`CREATE INDEX idx_webhooks_user_id ON webhooks (user_id)`,
+			`CREATE INDEX idx_webhooks_project_id ON webhooks (project_id)`,
+			`CREATE TABLE webhook_deliveries (
+				id uuid PRIMARY KEY,
+				webhook_id uuid NOT NULL,
+				event text NOT NULL,
+				payload jsonb NOT NULL,
+				status text NOT NULL DEFAULT 'pending',
+				attempts integer NOT NULL DEFAULT 0,
+				response_status integer,
+				response_body text,
+				last_error text,
+				delivered_at timestamptz,
+				created_at timestamptz,
+				updated_at timestamptz,
+				CONSTRAINT fk_webhook_deliveries_webhook FOREIGN KEY (webhook_id) REFERENCES webhooks (id) ON DELETE CASCADE
+			)`,
+			`CREATE INDEX idx_webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id)`,
+		),
+		Down: execSQL(
+			`DROP TABLE IF EXISTS webhook_deliveries`,
+			`DROP TABLE IF EXISTS webhooks`,
+		),
+	},
+	{
+		// O log de auditoria sobrevive à remoção do usuário, apenas perdendo o vínculo
+		Version: 14,
+		Name:    "create_audit_events",
+		Up: execSQL(
+			`CREATE TABLE audit_events (
+				id uuid PRIMARY KEY,
+				user_id uuid,
+				api_key_id uuid,
+				actor text NOT NULL,
+				action text NOT NULL,
+				target text,
+				outcome text NOT NULL,
+				detail text,
+				ip text,
+				user_agent text,
+				created_at timestamptz,
+				CONSTRAINT fk_audit_events_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL
+			)`,
+			`CREATE INDEX idx_audit_events_user_created ON audit_events (user_id, created_at)`,
+			`CREATE INDEX idx_audit_events_action ON audit_events (action)`,
+		),
+		Down: execSQL(
+			`DROP TABLE IF EXISTS audit_events`,
+		),
+	},
+	{
+		// Buckets do limite de requisições compartilhados entre réplicas. UNLOGGED: perder
+		// os contadores em uma queda do banco apenas zera os limites.
+		Version: 15,
+		Name:    "create_rate_limits",
+		Up: execSQL(
+			`CREATE UNLOGGED TABLE rate_limits (
+				key text PRIMARY KEY,
+				tokens double precision NOT NULL,
+				allowed boolean NOT NULL,
+				updated_at timestamptz NOT NULL
+			)`,
+			`CREATE INDEX idx_rate_limits_updated_at ON rate_limits (updated_at)`,
+			`ALTER TABLE plans ADD COLUMN rate_limit text NOT NULL DEFAULT ''`,
+		),
+		Down: execSQL(
+			`ALTER TABLE plans DROP COLUMN IF EXISTS rate_limit`,
+			`DROP TABLE IF EXISTS rate_limits`,
+		),
+	},
+	{
+		Version: 16,
+		Name:    "add_login_lockout",
+		Up: execSQL(
+			`ALTER TABLE users ADD COLUMN is_admin boolean NOT NULL DEFAULT false`,
+			`ALTER TABLE users ADD COLUMN failed_logins integer NOT NULL DEFAULT 0`,
+			`ALTER TABLE users ADD COLUMN locked_until timestamptz`,
+			`ALTER TABLE users ADD COLUMN permanently_locked boolean NOT NULL DEFAULT false`,
+			`CREATE TABLE ip_login_failures (
+				ip text PRIMARY KEY,
+				failures integer NOT NULL DEFAULT 0,
+				locked_until timestamptz,
+				updated_at timestamptz
+			)`,
+			`CREATE INDEX idx_ip_login_failures_updated_at ON ip_login_failures (updated_at)`,
+		),
+		Down: execSQL(
+			`DROP TABLE IF EXISTS ip_login_failures`,
+			`ALTER TABLE users DROP COLUMN IF EXISTS permanently_locked`,
+			`ALTER TABLE users DROP COLUMN IF EXISTS locked_until`,
+			`ALTER TABLE users DROP COLUMN IF EXISTS failed_logins`,
+			`ALTER TABLE users DROP COLUMN IF EXISTS is_admin`,
+		),
+	},
+	{
+		// Contas criadas antes da verificação de e-mail são consideradas verificadas
+		Version: 17,
+		Name:    "add_email_verification",
+		Up: execSQL(
+			`ALTER TABLE users ADD COLUMN email_verified_at timestamptz`,
+			`UPDATE users SET email_verified_at = COALESCE(created_at, now())`,
+			`CREATE TABLE user_tokens (
+				id uuid PRIMARY KEY,
+				user_id uuid NOT NULL,
+				purpose text NOT NULL,
+				token_hash text NOT NULL,
+				expires_at timestamptz NOT NULL,
+				used_at timestamptz,
+				created_at timestamptz,
+				CONSTRAINT fk_user_tokens_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+			)`,
+			`CREATE UNIQUE INDEX idx_user_tokens_token_hash ON user_tokens (token_hash)`,
+			`CREATE INDEX idx_user_tokens_user_id ON user_tokens (user_id)`,
+		),
+		Down: execSQL(
+			`DROP TABLE IF EXISTS user_tokens`,
+			`ALTER TABLE users DROP COLUMN IF EXISTS email_verified_at`,
+		),
+	},
+	{
+		Version: 18,
+		Name:    "add_two_factor_auth",
+		Up: execSQL(
+			`ALTER TABLE users ADD COLUMN totp_secret text NOT NULL DEFAULT ''`,
+			`ALTER TABLE users ADD COLUMN totp_enabled_at timestamptz`,
+			`ALTER TABLE users ADD COLUMN totp_last_step bigint NOT NULL DEFAULT 0`,
+			`CREATE TABLE recovery_codes (
+				id uuid PRIMARY KEY,
+				user_id uuid NOT NULL,
+				code_hash text NOT NULL,
+				used_at timestamptz,
+				created_at timestamptz,
+				CONSTRAINT fk_recovery_codes_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+			)`,
+			`CREATE INDEX idx_recovery_codes_user_id ON recovery_codes (user_id)`,
+			`CREATE TABLE login_challenges (
+				id uuid PRIMARY KEY,
+				user_id uuid NOT NULL,
+				token_hash text NOT NULL,
+				attempts integer NOT NULL DEFAULT 0,
+				expires_at timestamptz NOT NULL,
+				created_at timestamptz,
+				CONSTRAINT fk_login_challenges_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+			)`,
+			`CREATE UNIQUE INDEX idx_login_challenges_token_hash ON login_challenges (token_hash)`,
+			`CREATE INDEX idx_login_challenges_user_id ON login_challenges (user_id)`,
+		),
+		Down: execSQL(
+			`DROP TABLE IF EXISTS login_challenges`,
+			`DROP TABLE IF EXISTS recovery_codes`,
+			`ALTER TABLE users DROP COLUMN IF EXISTS totp_last_step`,
+			`ALTER TABLE users DROP COLUMN IF EXISTS totp_enabled_at`,
+			`ALTER TABLE users DROP COLUMN IF EXISTS totp_secret`,
+		),
+	},
+	{
+		// Projetos e blobs sem organização continuam únicos por usuário; os de uma
+		// organização passam a ser únicos dentro dela. O Down devolve os projetos de
+		// organizações a quem os criou e falha se isso repetir o nome de um projeto.
+		Version: 19,
+		Name:    "create_organizations",
+		Up: execSQL(
+			`CREATE TABLE organizations (
+				id uuid PRIMARY KEY,
+				name text NOT NULL,
+				slug text NOT NULL,
+				plan_id uuid NOT NULL,
+				storage_usage bigint NOT NULL DEFAULT 0,
+				logical_storage_usage bigint NOT NULL DEFAULT 0,
+				created_at timestamptz,
+				CONSTRAINT fk_organizations_plan FOREIGN KEY (plan_id) REFERENCES plans (id)
+			)`,
+			`CREATE UNIQUE INDEX idx_organizations_slug ON organizations (slug)`,
+			`CREATE TABLE memberships (
+				id uuid PRIMARY KEY,
+				organization_id uuid NOT NULL,
+				user_id uuid NOT NULL,
+				role text NOT NULL,
+				created_at timestamptz,
+				CONSTRAINT fk_memberships_organization FOREIGN KEY (organization_id) REFERENCES organizations (id) ON DELETE CASCADE,
+				CONSTRAINT fk_memberships_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+				CONSTRAINT chk_memberships_role CHECK (role IN ('owner', 'admin', 'member', 'viewer'))
+			)`,
+			`CREATE UNIQUE INDEX idx_memberships_org_user ON memberships (organization_id, user_id)`,
+			`CREATE INDEX idx_memberships_user_id ON memberships (user_id)`,
+			`ALTER TABLE projects ADD COLUMN organization_id uuid,
+				ADD CONSTRAINT fk_projects_organization FOREIGN KEY (organization_id) REFERENCES organizations (id)`,
+			`DROP INDEX IF EXISTS idx_user_project`,
+			`CREATE UNIQUE INDEX idx_user_project ON projects (name, user_id) WHERE organization_id IS NULL`,
+			`CREATE UNIQUE INDEX idx_org_project ON projects (name, organization_id) WHERE organization_id IS NOT NULL`,
+			`ALTER TABLE blobs ADD COLUMN organization_id uuid,
+				ADD CONSTRAINT fk_blobs_organization FOREIGN KEY (organization_id) REFERENCES organizations (id) ON DELETE CASCADE`,
+			`DROP INDEX IF EXISTS idx_blobs_user_hash`,
+			`CREATE UNIQUE INDEX idx_blobs_user_hash ON blobs (user_id, hash) WHERE organization_id IS NULL`,
+			`CREATE UNIQUE INDEX idx_blobs_org_hash ON blobs (organization_id, hash) WHERE organization_id IS NOT NULL`,
+			`ALTER TABLE upload_sessions ADD COLUMN organization_id uuid,
+				ADD CONSTRAINT fk_upload_sessions_organization FOREIGN KEY (organization_id) REFERENCES organizations (id) ON DELETE CASCADE`,
+			`CREATE INDEX idx_upload_sessions_organization_id ON upload_sessions (organization_id)`,
+		),
+		Down: execSQL(
+			`ALTER TABLE upload_sessions DROP COLUMN IF EXISTS organization_id`,
+			`DROP INDEX IF EXISTS idx_blobs_org_hash`,
+			`DROP INDEX IF EXISTS idx_blobs_user_hash`,
+			`ALTER TABLE blobs DROP COLUMN IF EXISTS organization_id`,
+			`CREATE UNIQUE INDEX idx_blobs_user_hash ON blobs (user_id, hash)`,
+			`DROP INDEX IF EXISTS idx_org_project`,
+			`DROP INDEX IF EXISTS idx_user_project`,
+			`ALTER TABLE projects DROP COLUMN IF EXISTS organization_id`,
+			`CREATE UNIQUE INDEX idx_user_project ON projects (name, user_id)`,
+			`DROP TABLE IF EXISTS memberships`,
+			`DROP TABLE IF EXISTS organizations`,
+		),
+	},
+	{
+		Version: 20,
+		Name:    "create_project_members",
+		Up: execSQL(
+			`CREATE TABLE project_members (
+				id uuid PRIMARY KEY,
+				project_id uuid NOT NULL,
+				user_id uuid NOT NULL,
+				permission text NOT NULL,
+				invited_by_id uuid,
+				accepted_at timestamptz,
+				created_at timestamptz,
+				CONSTRAINT fk_project_members_project FOREIGN KEY (project_id) REFERENCES projects (id) ON DELETE CASCADE,
+				CONSTRAINT fk_project_members_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+				CONSTRAINT fk_project_members_invited_by FOREIGN KEY (invited_by_id) REFERENCES users (id) ON DELETE SET NULL,
+				CONSTRAINT chk_project_members_permission CHECK (permission IN ('read', 'upload', 'manage'))
+			)`,
+			`CREATE UNIQUE INDEX idx_project_members_project_user ON project_members (project_id, user_id)`,
+			`CREATE INDEX idx_project_members_user_id ON project_members (user_id)`,
+		),
+		Down: execSQL(
+			`DROP TABLE IF EXISTS project_members`,
+		),
+	},
+	{
+		Version: 21,
+		Name:    "add_user_suspension",
+		Up: execSQL(
+			`ALTER TABLE users ADD COLUMN suspended_at timestamptz,
+				ADD COLUMN suspension_reason text NOT NULL DEFAULT ''`,
+			`CREATE INDEX idx_users_created_at ON users (created_at)`,
+		),
+		Down: execSQL(
+			`DROP INDEX IF EXISTS idx_users_created_at`,
+			`ALTER TABLE users DROP COLUMN IF EXISTS suspension_reason`,
+			`ALTER TABLE users DROP COLUMN IF EXISTS suspended_at`,
+		),
+	},
+	{
+		Version: 22,
+		Name:    "create_plan_changes",
+		Up: execSQL(
+			`ALTER TABLE users ADD COLUMN plan_renews_at timestamptz,
+				ADD COLUMN grace_ends_at timestamptz,
+				ADD COLUMN quota_restricted_at timestamptz`,
+			`CREATE INDEX idx_users_plan_renews_at ON users (plan_renews_at) WHERE plan_renews_at IS NOT NULL`,
+			`CREATE INDEX idx_users_grace_ends_at ON users (grace_ends_at) WHERE grace_ends_at IS NOT NULL`,
+			`CREATE TABLE plan_changes (
+				id uuid PRIMARY KEY,
+				user_id uuid NOT NULL,
+				from_plan_id uuid NOT NULL,
+				to_plan_id uuid NOT NULL,
+				status text NOT NULL DEFAULT 'pending',
+				effective_at timestamptz NOT NULL,
+				applied_at timestamptz,
+				amount decimal NOT NULL DEFAULT 0,
+				payment_id text NOT NULL DEFAULT '',
+				failure_reason text NOT NULL DEFAULT '',
+				created_at timestamptz,
+				CONSTRAINT fk_plan_changes_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+				CONSTRAINT fk_plan_changes_from_plan FOREIGN KEY (from_plan_id) REFERENCES plans (id),
+				CONSTRAINT fk_plan_changes_to_plan FOREIGN KEY (to_plan_id) REFERENCES plans (id),
+				CONSTRAINT chk_plan_changes_status CHECK (status IN ('pending', 'applied', 'canceled', 'failed'))
+			)`,
+			`CREATE INDEX idx_plan_changes_user_id ON plan_changes (user_id)`,
+			`CREATE INDEX idx_plan_changes_due ON plan_changes (effective_at) WHERE status = 'pending'`,
+			`CREATE UNIQUE INDEX idx_plan_changes_one_pending ON plan_changes (user_id) WHERE status = 'pending'`,
+		),
+		Down: execSQL(
+			`DROP TABLE IF EXISTS plan_changes`,
+			`DROP INDEX IF EXISTS idx_users_grace_ends_at`,
+			`DROP INDEX IF EXISTS idx_users_plan_renews_at`,
+			`ALTER TABLE users DROP COLUMN IF EXISTS quota_restricted_at`,
+			`ALTER TABLE users DROP COLUMN IF EXISTS grace_ends_at`,
+			`ALTER TABLE users DROP COLUMN IF EXISTS plan_renews_at`,
+		),
+	},
+	{
+		Version: 23,
+		Name:    "create_usage_metering",
+		Up: execSQL(
+			`ALTER TABLE plans ADD COLUMN storage_overage_rate decimal NOT NULL DEFAULT 0,
+				ADD COLUMN included_egress bigint NOT NULL DEFAULT 0,
+				ADD COLUMN egress_overage_rate decimal NOT NULL DEFAULT 0`,
+			`CREATE TABLE payments (
+				id uuid PRIMARY KEY,
+				user_id uuid NOT NULL,
+				kind text NOT NULL,
+				amount decimal NOT NULL,
+				currency text NOT NULL,
+				description text NOT NULL,
+				provider_id text NOT NULL,
+				created_at timestamptz,
+				CONSTRAINT fk_payments_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+				CONSTRAINT chk_payments_kind CHECK (kind IN ('subscription', 'overage'))
+			)`,
+			`CREATE INDEX idx_payments_user_id ON payments (user_id)`,
+			`CREATE INDEX idx_payments_created_at ON payments (created_at)`,
+			`CREATE TABLE storage_snapshots (
+				user_id uuid NOT NULL,
+				day date NOT NULL,
+				storage_bytes bigint NOT NULL,
+				PRIMARY KEY (user_id, day),
+				CONSTRAINT fk_storage_snapshots_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+			)`,
+			`CREATE TABLE daily_egresses (
+				user_id uuid NOT NULL,
+				day date NOT NULL,
+				bytes bigint NOT NULL DEFAULT 0,
+				PRIMARY KEY (user_id, day),
+				CONSTRAINT fk_daily_egresses_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+			)`,
+			`CREATE TABLE usage_records (
+				id uuid PRIMARY KEY,
+				user_id uuid NOT NULL,
+				month date NOT NULL,
+				plan_id uuid NOT NULL,
+				days bigint NOT NULL,
+				storage_byte_hours bigint NOT NULL,
+				overage_byte_hours bigint NOT NULL,
+				peak_storage bigint NOT NULL,
+				egress_bytes bigint NOT NULL,
+				created_at timestamptz,
+				CONSTRAINT fk_usage_records_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+				CONSTRAINT fk_usage_records_plan FOREIGN KEY (plan_id) REFERENCES plans (id)
+			)`,
+			`CREATE UNIQUE INDEX idx_usage_records_user_month ON usage_records (user_id, month)`,
+			`CREATE TABLE invoices (
+				id uuid PRIMARY KEY,
+				user_id uuid NOT NULL,
+				number text NOT NULL,
+				usage_record_id uuid NOT NULL,
+				period_start timestamptz NOT NULL,
+				period_end timestamptz NOT NULL,
+				currency text NOT NULL,
+				lines jsonb NOT NULL,
+				total decimal NOT NULL,
+				amount_due decimal NOT NULL,
+				status text NOT NULL,
+				payment_id uuid,
+				created_at timestamptz,
+				CONSTRAINT fk_invoices_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+				CONSTRAINT fk_invoices_usage_record FOREIGN KEY (usage_record_id) REFERENCES usage_records (id) ON DELETE CASCADE,
+				CONSTRAINT fk_invoices_payment FOREIGN KEY (payment_id) REFERENCES payments (id) ON DELETE SET NULL,
+				CONSTRAINT chk_invoices_status CHECK (status IN ('paid', 'open'))
+			)`,
+			`CREATE UNIQUE INDEX idx_invoices_number ON invoices (number)`,
+			`CREATE UNIQUE INDEX idx_invoices_user_period ON invoices (user_id, period_start)`,
+			`CREATE INDEX idx_invoices_created_at ON invoices (created_at)`,
+		),
+		Down: execSQL(
+			`DROP TABLE IF EXISTS invoices`,
+			`DROP TABLE IF EXISTS usage_records`,
+			`DROP TABLE IF EXISTS daily_egresses`,
+			`DROP TABLE IF EXISTS storage_snapshots`,
+			`DROP TABLE IF EXISTS payments`,
+			`ALTER TABLE plans DROP COLUMN IF EXISTS egress_overage_rate,
+				DROP COLUMN IF EXISTS included_egress,
+				DROP COLUMN IF EXISTS storage_overage_rate`,
+		),
+	},
+	{
+		Version: 24,
+		Name:    "add_egress_limits",
+		Up: execSQL(
+			`ALTER TABLE plans ADD COLUMN egress_limit bigint NOT NULL DEFAULT 0,
+				ADD CONSTRAINT chk_plans_egress_limit CHECK (egress_limit >= 0)`,
+			`ALTER TABLE files ADD COLUMN downloads bigint NOT NULL DEFAULT 0,
+				ADD COLUMN bytes_served bigint NOT NULL DEFAULT 0`,
+		),
+		Down: execSQL(
+			`ALTER TABLE files DROP COLUMN IF EXISTS bytes_served,
+				DROP COLUMN IF EXISTS downloads`,
+			`ALTER TABLE plans DROP CONSTRAINT IF EXISTS chk_plans_egress_limit,
+				DROP COLUMN IF EXISTS egress_limit`,
+		),
+	},
+	{
+		Version: 25,
+		Name:    "add_organization_egress",
+		Up: execSQL(
+			`CREATE TABLE organization_daily_egresses (
+				organization_id uuid NOT NULL,
+				day date NOT NULL,
+				bytes bigint NOT NULL DEFAULT 0,
+				PRIMARY KEY (organization_id, day),
+				CONSTRAINT fk_organization_daily_egresses_organization FOREIGN KEY (organization_id) REFERENCES organizations (id) ON DELETE CASCADE
+			)`,
+		),
+		Down: execSQL(
+			`DROP TABLE IF EXISTS organization_daily_egresses`,
+		),
+	},
+	{
+		Version: 26,
+		Name:    "add_organization_storage_snapshots",
+		Up: execSQL(
+			`CREATE TABLE organization_storage_snapshots (
+				organization_id uuid NOT NULL,
+				day date NOT NULL,
+				storage_bytes bigint NOT NULL,
+				PRIMARY KEY (organization_id, day),
+				CONSTRAINT fk_organization_storage_snapshots_organization FOREIGN KEY (organization_id) REFERENCES organizations (id) ON DELETE CASCADE
+			)`,
+		),
+		Down: execSQL(
+			`DROP TABLE IF EXISTS organization_storage_snapshots`,
+		),
+	},
+}
@@ -24,35 +24,36 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/delete": {
-            "delete": {
-                "description": "Deletes a specific file from a project.",
+        "/api/admin/ips/{ip}/unlock": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "Lifts the login block of an IP and resets its failure counter. Administrators only.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "api"
+                    "admin"
                 ],
-                "summary": "Delete a file",
+                "summary": "Unblock an IP",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Project name",
-                        "name": "project",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "File name",
-                        "name": "file",
-                        "in": "query",
+                        "description": "IP address",
+                        "name": "ip",
+                        "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "message: File deleted successfully",
+                        "description": "message: IP unblocked",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -60,25 +61,23 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "400": {
-                        "description": "'project' and 'file' parameters are required",
+                    "403": {
+                        "description": "Administrator access required",
                         "schema": {
                             "type": "string"
                         }
                     },
                     "404": {
-                        "description": "Project not found or File not found",
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "500": {
-                        "description": "Could not delete file metadata",
+                        "description": "IP not found",
                         "schema": {
                             "type": "string"
                         }
                     }
-                },
+                }
+            }
+        },
+        "/api/admin/lockouts": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
@@ -86,60 +85,33 @@ const docTemplate = `{
                     {
                         "APIKeyAuth": []
                     }
-                ]
-            }
-        },
-        "/api/list": {
-            "get": {
-                "description": "Retrieves a paginated list of files within a specified project for the authenticated user.",
+                ],
+                "description": "Lists accounts whose login is locked (temporarily or permanently) after repeated failed logins, and IPs temporarily blocked for failing logins across accounts. Administrators only.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "api"
-                ],
-                "summary": "List files in a project",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Project name",
-                        "name": "project",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Page number for pagination",
-                        "name": "page",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Number of items per page",
-                        "name": "per_page",
-                        "in": "query"
-                    }
+                    "admin"
                 ],
+                "summary": "List locked accounts and IPs",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ListResponse"
-                        }
-                    },
-                    "400": {
-                        "description": "Project name is required",
-                        "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/handlers.LockoutsResponse"
                         }
                     },
-                    "404": {
-                        "description": "Project not found",
+                    "403": {
+                        "description": "Administrator access required",
                         "schema": {
                             "type": "string"
                         }
                     }
-                },
+                }
+            }
+        },
+        "/api/admin/plans": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
@@ -147,57 +119,62 @@ const docTemplate = `{
                     {
                         "APIKeyAuth": []
                     }
-                ]
-            }
-        },
-        "/api/project/delete": {
-            "delete": {
-                "description": "Deletes a project that has no files. Projects with files cannot be deleted.",
+                ],
+                "description": "GET lists all plans with how many users and organizations use each. POST creates a plan; name, storage_limit, max_file_size and allowed_mime_types are required. Administrators only.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "api"
+                    "admin"
                 ],
-                "summary": "Delete an empty project",
+                "summary": "List or create plans",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Project name",
-                        "name": "project",
-                        "in": "query",
-                        "required": true
+                        "description": "Plan fields (POST only)",
+                        "name": "plan_request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PlanRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "message: Project deleted successfully",
+                        "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/handlers.PlansResponse"
+                        }
+                    },
+                    "201": {
+                        "description": "Plan created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PlanInfo"
                         }
                     },
                     "400": {
-                        "description": "Project name is required or Project has files and cannot be deleted",
+                        "description": "Invalid request body or plan fields",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "404": {
-                        "description": "Project not found",
+                    "403": {
+                        "description": "Administrator access required",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "500": {
-                        "description": "Could not delete project",
+                    "409": {
+                        "description": "Plan name already taken",
                         "schema": {
                             "type": "string"
                         }
                     }
-                },
+                }
+            },
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
@@ -205,41 +182,64 @@ const docTemplate = `{
                     {
                         "APIKeyAuth": []
                     }
-                ]
-            }
-        },
-        "/api/projects": {
-            "get": {
-                "description": "Retrieves a paginated list of projects for the authenticated user.",
+                ],
+                "description": "GET lists all plans with how many users and organizations use each. POST creates a plan; name, storage_limit, max_file_size and allowed_mime_types are required. Administrators only.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "api"
+                    "admin"
                 ],
-                "summary": "List user's projects",
+                "summary": "List or create plans",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Page number for pagination",
-                        "name": "page",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Number of items per page",
-                        "name": "per_page",
-                        "in": "query"
+                        "description": "Plan fields (POST only)",
+                        "name": "plan_request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PlanRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ProjectsResponse"
+                            "$ref": "#/definitions/handlers.PlansResponse"
+                        }
+                    },
+                    "201": {
+                        "description": "Plan created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PlanInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or plan fields",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Administrator access required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Plan name already taken",
+                        "schema": {
+                            "type": "string"
                         }
                     }
-                },
+                }
+            }
+        },
+        "/api/admin/plans/{id}": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
@@ -247,76 +247,75 @@ const docTemplate = `{
                     {
                         "APIKeyAuth": []
                     }
-                ]
-            }
-        },
-        "/api/upload": {
-            "post": {
-                "description": "Uploads a file to a specified project. If the project doesn't exist, it will be created.",
+                ],
+                "description": "GET returns the plan. PATCH changes only the fields sent; the new limits apply immediately to everyone on the plan. DELETE removes a plan that no user or organization uses. The Free plan, assigned to new accounts and organizations, cannot be renamed or deleted. Administrators only.",
                 "consumes": [
-                    "multipart/form-data"
+                    "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "api"
+                    "admin"
                 ],
-                "summary": "Upload a file to a project",
+                "summary": "Get, update or delete a plan",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Project name",
-                        "name": "project",
-                        "in": "formData",
+                        "description": "Plan ID",
+                        "name": "id",
+                        "in": "path",
                         "required": true
                     },
                     {
-                        "type": "file",
-                        "description": "File to upload",
-                        "name": "file",
-                        "in": "formData",
-                        "required": true
+                        "description": "Fields to change (PATCH only)",
+                        "name": "plan_request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PlanRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "File uploaded successfully",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.UploadResponse"
+                            "$ref": "#/definitions/handlers.PlanInfo"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request: Error reading file or file is too large",
+                    "204": {
+                        "description": "Plan deleted",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "403": {
-                        "description": "Storage limit exceeded",
+                    "400": {
+                        "description": "Invalid request body or plan fields",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "413": {
-                        "description": "File is too large. Max size is 10MB.",
+                    "403": {
+                        "description": "Administrator access required",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "415": {
-                        "description": "Invalid file type. Allowed types are: jpeg, png, pdf.",
+                    "404": {
+                        "description": "Plan not found",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "409": {
+                        "description": "Plan name already taken, plan still in use or the Free plan cannot be renamed or deleted",
                         "schema": {
                             "type": "string"
                         }
                     }
-                },
+                }
+            },
+            "delete": {
                 "security": [
                     {
                         "BearerAuth": []
@@ -324,36 +323,75 @@ const docTemplate = `{
                     {
                         "APIKeyAuth": []
                     }
-                ]
-            }
-        },
-        "/api/user/rotate-api-key": {
-            "post": {
-                "description": "Generates a new API key for the authenticated user, invalidating the old one.",
+                ],
+                "description": "GET returns the plan. PATCH changes only the fields sent; the new limits apply immediately to everyone on the plan. DELETE removes a plan that no user or organization uses. The Free plan, assigned to new accounts and organizations, cannot be renamed or deleted. Administrators only.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "api"
+                    "admin"
+                ],
+                "summary": "Get, update or delete a plan",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Plan ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to change (PATCH only)",
+                        "name": "plan_request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PlanRequest"
+                        }
+                    }
                 ],
-                "summary": "Rotate user's API key",
                 "responses": {
                     "200": {
-                        "description": "message: API key rotated successfully, new_api_key: ...",
+                        "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/handlers.PlanInfo"
                         }
                     },
-                    "500": {
-                        "description": "Could not rotate API key",
+                    "204": {
+                        "description": "Plan deleted",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or plan fields",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Administrator access required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Plan not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Plan name already taken, plan still in use or the Free plan cannot be renamed or deleted",
                         "schema": {
                             "type": "string"
                         }
                     }
-                },
+                }
+            },
+            "patch": {
                 "security": [
                     {
                         "BearerAuth": []
@@ -361,12 +399,8 @@ const docTemplate = `{
                     {
                         "APIKeyAuth": []
                     }
-                ]
-            }
-        },
-        "/login": {
-            "post": {
-                "description": "Authenticates a user using email and password, then returns a JWT token.",
+                ],
+                "description": "GET returns the plan. PATCH changes only the fields sent; the new limits apply immediately to everyone on the plan. DELETE removes a plan that no user or organization uses. The Free plan, assigned to new accounts and organizations, cannot be renamed or deleted. Administrators only.",
                 "consumes": [
                     "application/json"
                 ],
@@ -374,41 +408,59 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "Log in a user",
+                "summary": "Get, update or delete a plan",
                 "parameters": [
                     {
-                        "description": "User login credentials (email and password)",
-                        "name": "auth_request",
+                        "type": "string",
+                        "description": "Plan ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to change (PATCH only)",
+                        "name": "plan_request",
                         "in": "body",
-                        "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.LoginRequest"
+                            "$ref": "#/definitions/handlers.PlanRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Logged in successfully",
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.AuthResponse"
+                            "$ref": "#/definitions/handlers.PlanInfo"
+                        }
+                    },
+                    "204": {
+                        "description": "Plan deleted",
+                        "schema": {
+                            "type": "string"
                         }
                     },
                     "400": {
-                        "description": "Invalid request body",
+                        "description": "Invalid request body or plan fields",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "401": {
-                        "description": "Invalid credentials",
+                    "403": {
+                        "description": "Administrator access required",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "500": {
-                        "description": "Could not generate token",
+                    "404": {
+                        "description": "Plan not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Plan name already taken, plan still in use or the Free plan cannot be renamed or deleted",
                         "schema": {
                             "type": "string"
                         }
@@ -416,9 +468,186 @@ const docTemplate = `{
                 }
             }
         },
-        "/register": {
+        "/api/admin/stats": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "Returns global counts: users (verified, suspended, administrators), organizations, projects, files, stored bytes (logical, physical after deduplication and variant cache), files and bytes per MIME type, users per plan and signups per day over the last days (UTC). Administrators only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Global statistics",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Days of signups to return (default 30, max 365)",
+                        "name": "days",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.AdminStatsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid days",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Administrator access required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/admin/users": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "Lists users, newest first. q searches name and e-mail (case-insensitive); plan filters by plan name; status filters by active, suspended, locked or admin. Administrators only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List and search users",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Text to search in name and e-mail",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Plan name",
+                        "name": "plan",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "active, suspended, locked or admin",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number for pagination",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of items per page",
+                        "name": "per_page",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.AdminUsersResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid status",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Administrator access required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/admin/users/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "Returns a user's account details, plan and storage usage. Administrators only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get a user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.AdminUserInfo"
+                        }
+                    },
+                    "403": {
+                        "description": "Administrator access required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/admin/users/{id}/plan": {
             "post": {
-                "description": "Creates a new user account and returns the user info along with an API key.",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "Moves the user to another plan, identified by name or ID, without charging and cancelling any scheduled plan change. The new limits apply immediately; files above a smaller storage limit are kept, but uploads are refused until usage drops and the grace period starts. The user is notified and the change is recorded in the user's audit log. Administrators only.",
                 "consumes": [
                     "application/json"
                 ],
@@ -426,179 +655,6613 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "Register a new user",
+                "summary": "Change a user's plan",
                 "parameters": [
                     {
-                        "description": "User registration details (name, email, password, whatsapp_number)",
-                        "name": "auth_request",
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Plan name or ID",
+                        "name": "plan_request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.AuthRequest"
+                            "$ref": "#/definitions/handlers.UserPlanRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "User created successfully",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.AuthResponse"
+                            "$ref": "#/definitions/handlers.AdminUserInfo"
                         }
                     },
                     "400": {
-                        "description": "Invalid request body or missing fields",
+                        "description": "Invalid request body or plan not found",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "500": {
-                        "description": "Could not create user or find default plan",
+                    "403": {
+                        "description": "Administrator access required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
                         "schema": {
                             "type": "string"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "handlers.AuthRequest": {
+        },
+        "/api/admin/users/{id}/suspend": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "Suspends the account: its sessions are ended and its tokens and API keys are refused with 403 until it is unsuspended. Files stay stored and public links keep working. Administrators cannot suspend themselves. Administrators only.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Suspend an account",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Reason shown to administrators",
+                        "name": "suspend_request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SuspendRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.AdminUserInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or cannot suspend your own account",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Administrator access required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/admin/users/{id}/unlock": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "Removes a temporary or permanent login lock from an account and resets its failed login counter. The unlock is recorded in the account's audit log. Administrators only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Unlock an account",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: Account unlocked",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Administrator access required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not unlock account",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/admin/users/{id}/unsuspend": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "Lifts the suspension of an account. The user has to log in again. Administrators only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Unsuspend an account",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.AdminUserInfo"
+                        }
+                    },
+                    "403": {
+                        "description": "Administrator access required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/audit": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "Returns the audit log of the authenticated user's account, newest first: uploads, deletions, API key rotations, failed logins and rejected requests, with the actor, IP, user agent, target and outcome of each.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "audit"
+                ],
+                "summary": "List audit events",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only events of this action (e.g. file.upload, auth.login)",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "success or failure",
+                        "name": "outcome",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only events at or after this RFC 3339 timestamp",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only events before this RFC 3339 timestamp",
+                        "name": "until",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number for pagination",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of items per page",
+                        "name": "per_page",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.AuditResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filter",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "API key lacks the 'admin' scope or is restricted to a project",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/audit/export": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "Streams every audit event matching the filters as JSON Lines (one JSON object per line), oldest first, for archiving or ingestion into a SIEM.",
+                "produces": [
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "audit"
+                ],
+                "summary": "Export audit events as JSON Lines",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only events of this action",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "success or failure",
+                        "name": "outcome",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only events at or after this RFC 3339 timestamp",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only events before this RFC 3339 timestamp",
+                        "name": "until",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "One AuditEventInfo per line",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid filter",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "API key lacks the 'admin' scope or is restricted to a project",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not export audit log",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/billing/invoices": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "Lists the user's monthly invoices, newest first. An invoice is issued when a month closes and lists the plan payments made in the month plus the storage and data transfer overage, which is charged with the invoice.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "billing"
+                ],
+                "summary": "List invoices",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of items per page",
+                        "name": "per_page",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.InvoicesResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/billing/invoices/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "Returns one of the user's invoices with its lines.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "billing"
+                ],
+                "summary": "Get an invoice",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Invoice ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.InvoiceInfo"
+                        }
+                    },
+                    "404": {
+                        "description": "Invoice not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/billing/invoices/{id}/pdf": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "Renders one of the user's invoices as a PDF document.",
+                "produces": [
+                    "application/pdf"
+                ],
+                "tags": [
+                    "billing"
+                ],
+                "summary": "Download an invoice as PDF",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Invoice ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Invoice PDF",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "404": {
+                        "description": "Invoice not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not render invoice",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/billing/usage": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "Returns the storage and data transfer of a month: daily storage snapshots, storage in GB-hours, bytes served from /files/ and the overage above the plan. Open months are estimated with the current plan; closed months show what was invoiced. With org, returns the usage of the organization's projects against its plan; organizations are not invoiced, so there is no overage.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "billing"
+                ],
+                "summary": "Get monthly usage",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Month as YYYY-MM (defaults to the current month, UTC)",
+                        "name": "month",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization slug (the user's own usage when omitted)",
+                        "name": "org",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UsageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "month must be in the YYYY-MM format and not in the future",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Organization not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not compute usage",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/delete": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "Deletes a specific file from a project, along with its resized image variants. In an organization's project (org) the member role or higher is required; in a project shared by another user (owner), the upload permission or higher.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api"
+                ],
+                "summary": "Delete a file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Project name",
+                        "name": "project",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "File name",
+                        "name": "file",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization slug (personal projects when omitted)",
+                        "name": "org",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "E-mail of the user who shared the project",
+                        "name": "owner",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: File deleted successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "'project' and 'file' parameters are required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "API key is restricted to another project, organization role below member or share permission below upload",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Organization not found, Project not found or File not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not delete file metadata",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/invitations": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "Lists the invitations to other users' projects that the authenticated user has not accepted yet.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sharing"
+                ],
+                "summary": "List pending project invitations",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.InvitationsResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "API key is restricted to a project",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/invitations/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "Declines and removes a pending invitation. To leave a project after accepting, use DELETE /api/project/members/{user_id} with your own user ID.",
+                "tags": [
+                    "sharing"
+                ],
+                "summary": "Decline a project invitation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Invitation ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Invitation declined",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "API key is restricted to a project",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Invitation not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/invitations/{id}/accept": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "Accepts a pending invitation. The project then shows up in /api/projects and is reachable by the project endpoints with owner set to the owner's e-mail.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sharing"
+                ],
+                "summary": "Accept a project invitation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Invitation ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: Invitation accepted, project, owner, permission",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "API key is restricted to a project",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Invitation not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/keys": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "GET lists the user's API keys (only the prefix of each key is shown). POST creates a named key with the given scopes (upload, read, delete, admin), optionally restricted to one project and with an expiry. Keys for organization projects (org) must be restricted to the project and act with the user's current role in the organization. The full key is returned only once, in the creation response.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "keys"
+                ],
+                "summary": "List or create API keys",
+                "parameters": [
+                    {
+                        "description": "Key name, scopes, optional project, org and expiry (POST only)",
+                        "name": "key_request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIKeyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "API keys",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIKeysResponse"
+                        }
+                    },
+                    "201": {
+                        "description": "API key created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIKeyCreatedResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body, name, scopes or expiry",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "API key lacks the 'admin' scope",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Project or organization not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not create API key",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "GET lists the user's API keys (only the prefix of each key is shown). POST creates a named key with the given scopes (upload, read, delete, admin), optionally restricted to one project and with an expiry. Keys for organization projects (org) must be restricted to the project and act with the user's current role in the organization. The full key is returned only once, in the creation response.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "keys"
+                ],
+                "summary": "List or create API keys",
+                "parameters": [
+                    {
+                        "description": "Key name, scopes, optional project, org and expiry (POST only)",
+                        "name": "key_request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIKeyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "API keys",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIKeysResponse"
+                        }
+                    },
+                    "201": {
+                        "description": "API key created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIKeyCreatedResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body, name, scopes or expiry",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "API key lacks the 'admin' scope",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Project or organization not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not create API key",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/keys/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "GET returns one key. PATCH changes its name, scopes or expiry (the project restriction cannot be changed). DELETE revokes the key immediately.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "keys"
+                ],
+                "summary": "Get, update or delete an API key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to change (PATCH only)",
+                        "name": "key_request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIKeyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIKeyInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body, name, scopes or expiry",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "API key lacks the 'admin' scope",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "API key not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not update API key",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "GET returns one key. PATCH changes its name, scopes or expiry (the project restriction cannot be changed). DELETE revokes the key immediately.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "keys"
+                ],
+                "summary": "Get, update or delete an API key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to change (PATCH only)",
+                        "name": "key_request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIKeyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIKeyInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body, name, scopes or expiry",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "API key lacks the 'admin' scope",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "API key not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not update API key",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "GET returns one key. PATCH changes its name, scopes or expiry (the project restriction cannot be changed). DELETE revokes the key immediately.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "keys"
+                ],
+                "summary": "Get, update or delete an API key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to change (PATCH only)",
+                        "name": "key_request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIKeyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIKeyInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body, name, scopes or expiry",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "API key lacks the 'admin' scope",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "API key not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not update API key",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/keys/{id}/rotate": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "Replaces the secret of an API key, keeping its name, scopes, project and expiry. The old secret stops working immediately. The new key is returned only once.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "keys"
+                ],
+                "summary": "Rotate an API key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.APIKeyCreatedResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "API key lacks the 'admin' scope",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "API key not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not rotate API key",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/list": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves a paginated list of files within a specified project for the authenticated user, within an organization's project with org (viewer role or higher) or within a project another user shared with the caller with owner (read permission or higher). Files in private projects are returned with signed URLs that expire after the default signed URL lifetime. Images include the URLs of their generated thumbnails and variants. Each file has a status: pending while post-upload processing runs, then ready, failed or quarantined (malware found; the file is no longer served). downloads and bytes_served count how often each file was served from /files/, variants included.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api"
+                ],
+                "summary": "List files in a project",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Project name",
+                        "name": "project",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization slug (personal projects when omitted)",
+                        "name": "org",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "E-mail of the user who shared the project",
+                        "name": "owner",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number for pagination",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of items per page",
+                        "name": "per_page",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Project name is required",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "API key is restricted to another project",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Project or organization not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/orgs": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "GET lists the organizations the user belongs to, with their role. POST creates an organization on the Free plan with the user as its owner. Organization projects are managed with the org parameter of the project endpoints and share the organization's plan and storage quota.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orgs"
+                ],
+                "summary": "List or create organizations",
+                "parameters": [
+                    {
+                        "description": "Name and optional slug (POST only)",
+                        "name": "org_request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.OrganizationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Organizations",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.OrganizationsResponse"
+                        }
+                    },
+                    "201": {
+                        "description": "Organization created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.OrganizationInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body, name or slug",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "API key lacks the 'admin' scope or is restricted to a project",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Slug already taken",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not create organization",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "GET lists the organizations the user belongs to, with their role. POST creates an organization on the Free plan with the user as its owner. Organization projects are managed with the org parameter of the project endpoints and share the organization's plan and storage quota.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orgs"
+                ],
+                "summary": "List or create organizations",
+                "parameters": [
+                    {
+                        "description": "Name and optional slug (POST only)",
+                        "name": "org_request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.OrganizationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Organizations",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.OrganizationsResponse"
+                        }
+                    },
+                    "201": {
+                        "description": "Organization created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.OrganizationInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body, name or slug",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "API key lacks the 'admin' scope or is restricted to a project",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Slug already taken",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not create organization",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/orgs/{org}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "GET returns the organization and its storage usage (any member). PATCH renames it or changes its slug (admin or owner). DELETE removes it (owner only); it must have no projects left.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orgs"
+                ],
+                "summary": "Get, update or delete an organization",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization slug",
+                        "name": "org",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to change (PATCH only)",
+                        "name": "org_request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.OrganizationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.OrganizationInfo"
+                        }
+                    },
+                    "204": {
+                        "description": "Organization deleted",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body, name or slug",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Role does not allow this action",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Organization not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Slug already taken or organization still has projects",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "GET returns the organization and its storage usage (any member). PATCH renames it or changes its slug (admin or owner). DELETE removes it (owner only); it must have no projects left.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orgs"
+                ],
+                "summary": "Get, update or delete an organization",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization slug",
+                        "name": "org",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to change (PATCH only)",
+                        "name": "org_request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.OrganizationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.OrganizationInfo"
+                        }
+                    },
+                    "204": {
+                        "description": "Organization deleted",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body, name or slug",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Role does not allow this action",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Organization not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Slug already taken or organization still has projects",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "GET returns the organization and its storage usage (any member). PATCH renames it or changes its slug (admin or owner). DELETE removes it (owner only); it must have no projects left.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orgs"
+                ],
+                "summary": "Get, update or delete an organization",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization slug",
+                        "name": "org",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to change (PATCH only)",
+                        "name": "org_request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.OrganizationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.OrganizationInfo"
+                        }
+                    },
+                    "204": {
+                        "description": "Organization deleted",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body, name or slug",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Role does not allow this action",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Organization not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Slug already taken or organization still has projects",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/orgs/{org}/members": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "GET lists the members and their roles (any member). POST adds an existing user by email with a role (owner, admin, member or viewer; member by default). Admins can add members and viewers; only owners can add admins and owners.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orgs"
+                ],
+                "summary": "List or add organization members",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization slug",
+                        "name": "org",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Email and role (POST only)",
+                        "name": "member_request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MemberRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Members",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MembersResponse"
+                        }
+                    },
+                    "201": {
+                        "description": "Member added",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MemberInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or role",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Role does not allow this action",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Organization or user not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "User is already a member",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "GET lists the members and their roles (any member). POST adds an existing user by email with a role (owner, admin, member or viewer; member by default). Admins can add members and viewers; only owners can add admins and owners.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orgs"
+                ],
+                "summary": "List or add organization members",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization slug",
+                        "name": "org",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Email and role (POST only)",
+                        "name": "member_request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MemberRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Members",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MembersResponse"
+                        }
+                    },
+                    "201": {
+                        "description": "Member added",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MemberInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or role",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Role does not allow this action",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Organization or user not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "User is already a member",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/orgs/{org}/members/{user_id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "PATCH changes the member's role. DELETE removes the member; any member can remove themselves to leave the organization. Admins manage members and viewers; only owners can change or remove admins and owners, and the last owner can be neither demoted nor removed. Removed members lose their API keys and webhooks for the organization's projects.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orgs"
+                ],
+                "summary": "Change a member's role or remove a member",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization slug",
+                        "name": "org",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Member user ID",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New role (PATCH only)",
+                        "name": "member_request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MemberRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MemberInfo"
+                        }
+                    },
+                    "204": {
+                        "description": "Member removed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or role",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Role does not allow this action",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Organization or member not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "The organization must keep at least one owner",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "PATCH changes the member's role. DELETE removes the member; any member can remove themselves to leave the organization. Admins manage members and viewers; only owners can change or remove admins and owners, and the last owner can be neither demoted nor removed. Removed members lose their API keys and webhooks for the organization's projects.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "orgs"
+                ],
+                "summary": "Change a member's role or remove a member",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization slug",
+                        "name": "org",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Member user ID",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New role (PATCH only)",
+                        "name": "member_request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MemberRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MemberInfo"
+                        }
+                    },
+                    "204": {
+                        "description": "Member removed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or role",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Role does not allow this action",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Organization or member not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "The organization must keep at least one owner",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/plan": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "Returns the user's plan, when the paid period renews and the scheduled plan change, if any. read_only is true while storage usage is above the plan's limit (after a downgrade): uploads are refused, and once grace_ends_at passes the user's files are no longer served (restricted).",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "billing"
+                ],
+                "summary": "Get the current plan",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SubscriptionResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not retrieve user details",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/plan/change": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "POST changes the user's plan, identified by name or ID. Upgrades to a more expensive plan are charged and applied immediately, starting a new billing period (200). Other changes take effect at the end of the current paid period (202) or immediately when there is none. A new request replaces a scheduled one. If storage usage is above the new plan's limit, uploads are disabled and the files stop being served when the grace period ends, unless usage drops. DELETE cancels the scheduled change.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "billing"
+                ],
+                "summary": "Change or cancel a plan change",
+                "parameters": [
+                    {
+                        "description": "Plan name or ID (POST only)",
+                        "name": "plan_request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PlanChangeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Plan changed, or scheduled change cancelled",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PlanChangeInfo"
+                        }
+                    },
+                    "202": {
+                        "description": "Change scheduled for the end of the billing period",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PlanChangeInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body, plan not found or already on this plan",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "402": {
+                        "description": "Payment declined",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "No scheduled plan change",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Another plan change is in progress",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "502": {
+                        "description": "Could not charge the payment",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "POST changes the user's plan, identified by name or ID. Upgrades to a more expensive plan are charged and applied immediately, starting a new billing period (200). Other changes take effect at the end of the current paid period (202) or immediately when there is none. A new request replaces a scheduled one. If storage usage is above the new plan's limit, uploads are disabled and the files stop being served when the grace period ends, unless usage drops. DELETE cancels the scheduled change.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "billing"
+                ],
+                "summary": "Change or cancel a plan change",
+                "parameters": [
+                    {
+                        "description": "Plan name or ID (POST only)",
+                        "name": "plan_request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PlanChangeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Plan changed, or scheduled change cancelled",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PlanChangeInfo"
+                        }
+                    },
+                    "202": {
+                        "description": "Change scheduled for the end of the billing period",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PlanChangeInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body, plan not found or already on this plan",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "402": {
+                        "description": "Payment declined",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "No scheduled plan change",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Another plan change is in progress",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "502": {
+                        "description": "Could not charge the payment",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/plan/changes": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "Lists the user's most recent plan changes, including scheduled, cancelled and failed ones and changes made by administrators, newest first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "billing"
+                ],
+                "summary": "List plan changes",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PlanChangesResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/plans": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "Lists the plans a user can subscribe to, from the cheapest. Prices are per billing period.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "billing"
+                ],
+                "summary": "List plans",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.AvailablePlansResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/project/delete": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "Deletes a project that has no files. Projects with files cannot be deleted. In an organization's project (org) the admin role or higher is required; in a project shared by another user (owner), the manage permission.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api"
+                ],
+                "summary": "Delete an empty project",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Project name",
+                        "name": "project",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization slug (personal projects when omitted)",
+                        "name": "org",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "E-mail of the user who shared the project",
+                        "name": "owner",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: Project deleted successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Project name is required or Project has files and cannot be deleted",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "API key is restricted to another project, organization role below admin or share permission below manage",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Project or organization not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not delete project",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/project/members": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "Shares a single personal project with another user. GET lists the collaborators and pending invitations (any collaborator). POST invites an existing user by email with a permission: read (list files and sign URLs), upload (also upload and delete files) or manage (also delete the project and manage collaborators); read by default. The invitee is notified by e-mail and gets access after accepting. Collaborators with the manage permission can invite read and upload collaborators; only the owner grants manage. Collaborators pass owner with the owner's e-mail.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sharing"
+                ],
+                "summary": "List or invite project collaborators",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Project name",
+                        "name": "project",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "E-mail of the user who shared the project",
+                        "name": "owner",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Email and permission (POST only)",
+                        "name": "share_request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ShareRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Collaborators",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CollaboratorsResponse"
+                        }
+                    },
+                    "201": {
+                        "description": "Invitation sent",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CollaboratorInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Project name is required, invalid request body or permission, organization project or the owner was invited",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Permission does not allow this action or API key is restricted to a project",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Project or user not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "User is already a collaborator or has a pending invitation",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "Shares a single personal project with another user. GET lists the collaborators and pending invitations (any collaborator). POST invites an existing user by email with a permission: read (list files and sign URLs), upload (also upload and delete files) or manage (also delete the project and manage collaborators); read by default. The invitee is notified by e-mail and gets access after accepting. Collaborators with the manage permission can invite read and upload collaborators; only the owner grants manage. Collaborators pass owner with the owner's e-mail.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sharing"
+                ],
+                "summary": "List or invite project collaborators",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Project name",
+                        "name": "project",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "E-mail of the user who shared the project",
+                        "name": "owner",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Email and permission (POST only)",
+                        "name": "share_request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ShareRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Collaborators",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CollaboratorsResponse"
+                        }
+                    },
+                    "201": {
+                        "description": "Invitation sent",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CollaboratorInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Project name is required, invalid request body or permission, organization project or the owner was invited",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Permission does not allow this action or API key is restricted to a project",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Project or user not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "User is already a collaborator or has a pending invitation",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/project/members/{user_id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "PATCH changes the collaborator's permission. DELETE revokes the access or cancels a pending invitation; collaborators can remove themselves to leave the project. Collaborators with the manage permission manage read and upload collaborators; only the owner changes or removes manage collaborators.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sharing"
+                ],
+                "summary": "Change a collaborator's permission or revoke access",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Project name",
+                        "name": "project",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "E-mail of the user who shared the project",
+                        "name": "owner",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Collaborator user ID",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New permission (PATCH only)",
+                        "name": "share_request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ShareRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CollaboratorInfo"
+                        }
+                    },
+                    "204": {
+                        "description": "Access revoked",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Project name is required, invalid request body or permission",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Permission does not allow this action or API key is restricted to a project",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Project or collaborator not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "PATCH changes the collaborator's permission. DELETE revokes the access or cancels a pending invitation; collaborators can remove themselves to leave the project. Collaborators with the manage permission manage read and upload collaborators; only the owner changes or removes manage collaborators.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sharing"
+                ],
+                "summary": "Change a collaborator's permission or revoke access",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Project name",
+                        "name": "project",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "E-mail of the user who shared the project",
+                        "name": "owner",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Collaborator user ID",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New permission (PATCH only)",
+                        "name": "share_request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ShareRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CollaboratorInfo"
+                        }
+                    },
+                    "204": {
+                        "description": "Access revoked",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Project name is required, invalid request body or permission",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Permission does not allow this action or API key is restricted to a project",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Project or collaborator not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/project/visibility": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "Makes a project public (files served by direct URL) or private (files only reachable through signed, expiring URLs). The project is created if it does not exist yet, so it can be made private before the first upload. In an organization (org) the admin role or higher is required.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api"
+                ],
+                "summary": "Set a project's visibility",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Project name",
+                        "name": "project",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "public or private",
+                        "name": "visibility",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization slug (personal projects when omitted)",
+                        "name": "org",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.VisibilityResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Project name is required or invalid visibility",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "API key is restricted to another project or organization role below admin",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Organization not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not update project visibility",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/projects": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves a paginated list of the authenticated user's personal projects together with the projects other users shared with them, each with the caller's role (owner, or the share permission: read, upload or manage) and, for shared projects, the owner's e-mail. With org, lists an organization's projects (viewer role or higher); with owner, only the projects that user shared with the caller.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api"
+                ],
+                "summary": "List user's projects",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization slug (personal projects when omitted)",
+                        "name": "org",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "E-mail of the user who shared the projects",
+                        "name": "owner",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number for pagination",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of items per page",
+                        "name": "per_page",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ProjectsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Use either org or owner, not both",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Organization not found or no projects shared by owner",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sessions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "Lists the user's active login sessions (one per device/login), with the most recent activity first.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api"
+                ],
+                "summary": "List active sessions",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SessionsResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sessions/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "Ends one of the user's sessions, e.g. on a lost or stolen device. Its refresh token stops working immediately and its access token is rejected.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api"
+                ],
+                "summary": "Revoke a session",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: Session revoked",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Session not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not revoke session",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/sign": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "Mints an HMAC-signed URL for a file that stays valid until it expires. Required to download files from private projects; also works for public ones. Collaborators sign URLs for a project shared with them by passing owner.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api"
+                ],
+                "summary": "Create a signed download URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Project name",
+                        "name": "project",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "File name",
+                        "name": "file",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Lifetime of the URL in seconds (defaults to the server's signed URL lifetime)",
+                        "name": "expires_in",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization slug (personal projects when omitted)",
+                        "name": "org",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "E-mail of the user who shared the project",
+                        "name": "owner",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SignResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "'project' and 'file' parameters are required or invalid expires_in",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "API key is restricted to another project",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Organization not found, Project not found or File not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "Mints an HMAC-signed URL for a file that stays valid until it expires. Required to download files from private projects; also works for public ones. Collaborators sign URLs for a project shared with them by passing owner.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api"
+                ],
+                "summary": "Create a signed download URL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Project name",
+                        "name": "project",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "File name",
+                        "name": "file",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Lifetime of the URL in seconds (defaults to the server's signed URL lifetime)",
+                        "name": "expires_in",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization slug (personal projects when omitted)",
+                        "name": "org",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "E-mail of the user who shared the project",
+                        "name": "owner",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SignResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "'project' and 'file' parameters are required or invalid expires_in",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "API key is restricted to another project",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Organization not found, Project not found or File not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/upload": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "Uploads a file to a specified project. If the project doesn't exist, it will be created. With org, the project belongs to that organization (member role or higher) and the organization's plan applies instead of the user's. With owner, the file goes to a project that user shared with the caller (upload permission or higher; the project must exist) and the owner's plan applies. The plan's max file size, allowed types and daily upload quota apply. The file type is detected from its content (magic bytes) and must match the file extension; the Content-Type sent by the client is ignored.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api"
+                ],
+                "summary": "Upload a file to a project",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Project name",
+                        "name": "project",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "File to upload",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization slug (personal projects when omitted)",
+                        "name": "org",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "E-mail of the user who shared the project",
+                        "name": "owner",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "File uploaded successfully",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UploadResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request: Error reading file",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Email address not verified, storage limit exceeded, organization role below member, share permission below upload or API key restricted to another project",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Organization not found or shared project not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "413": {
+                        "description": "File is larger than the plan's max file size",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "415": {
+                        "description": "File type not allowed by the plan (detected from the file content) or content does not match the file extension",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "429": {
+                        "description": "Daily upload limit reached for the plan or too many requests (see Retry-After)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/uploads": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "Starts a tus-style resumable upload. Send the total size in Upload-Length and the file name and project in Upload-Metadata (base64 values); add org to upload to an organization's project (member role or higher), which uses the organization's plan. The file type is detected from the first bytes received and must match the file extension. The declared size is reserved against the plan's storage limit until the session completes or expires, must not exceed the plan's max file size, and each session counts toward the daily upload quota.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "uploads"
+                ],
+                "summary": "Create a resumable upload session",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Total file size in bytes",
+                        "name": "Upload-Length",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "tus metadata: filename, project and optional org (base64 encoded)",
+                        "name": "Upload-Metadata",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Upload session created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UploadSessionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid Upload-Length or Upload-Metadata",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Email address not verified, storage limit exceeded, organization role below member or API key restricted to another project",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Organization not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "413": {
+                        "description": "File is too large",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "415": {
+                        "description": "File type not allowed by the plan",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "429": {
+                        "description": "Daily upload limit reached for the plan or too many requests (see Retry-After)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/uploads/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "HEAD returns the current Upload-Offset. PATCH appends a chunk (Content-Type application/offset+octet-stream) at the given Upload-Offset; when the last byte arrives the file is stored and the upload response is returned. DELETE cancels the session.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "uploads"
+                ],
+                "summary": "Query, append to or cancel a resumable upload",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Upload session ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset of the chunk being sent (PATCH only)",
+                        "name": "Upload-Offset",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Upload completed",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UploadResponse"
+                        }
+                    },
+                    "204": {
+                        "description": "Chunk accepted or session cancelled",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid chunk",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "API key is restricted to another project",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Upload session not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Upload-Offset does not match the current offset",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "410": {
+                        "description": "Upload session expired",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "415": {
+                        "description": "Content-Type must be application/offset+octet-stream, or the file content does not match its extension",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "423": {
+                        "description": "Upload session is busy",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "head": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "HEAD returns the current Upload-Offset. PATCH appends a chunk (Content-Type application/offset+octet-stream) at the given Upload-Offset; when the last byte arrives the file is stored and the upload response is returned. DELETE cancels the session.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "uploads"
+                ],
+                "summary": "Query, append to or cancel a resumable upload",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Upload session ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset of the chunk being sent (PATCH only)",
+                        "name": "Upload-Offset",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Upload completed",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UploadResponse"
+                        }
+                    },
+                    "204": {
+                        "description": "Chunk accepted or session cancelled",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid chunk",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "API key is restricted to another project",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Upload session not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Upload-Offset does not match the current offset",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "410": {
+                        "description": "Upload session expired",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "415": {
+                        "description": "Content-Type must be application/offset+octet-stream, or the file content does not match its extension",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "423": {
+                        "description": "Upload session is busy",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "HEAD returns the current Upload-Offset. PATCH appends a chunk (Content-Type application/offset+octet-stream) at the given Upload-Offset; when the last byte arrives the file is stored and the upload response is returned. DELETE cancels the session.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "uploads"
+                ],
+                "summary": "Query, append to or cancel a resumable upload",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Upload session ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Offset of the chunk being sent (PATCH only)",
+                        "name": "Upload-Offset",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Upload completed",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UploadResponse"
+                        }
+                    },
+                    "204": {
+                        "description": "Chunk accepted or session cancelled",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid chunk",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "API key is restricted to another project",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Upload session not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Upload-Offset does not match the current offset",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "410": {
+                        "description": "Upload session expired",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "415": {
+                        "description": "Content-Type must be application/offset+octet-stream, or the file content does not match its extension",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "423": {
+                        "description": "Upload session is busy",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/2fa": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Shows whether two-factor authentication is enabled and how many unused recovery codes remain.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api"
+                ],
+                "summary": "Two-factor authentication status",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TwoFactorStatusResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not retrieve user details",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/2fa/confirm": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Enables two-factor authentication after checking a code generated from the secret returned by /api/user/2fa/enroll, and returns the recovery codes. The recovery codes are shown only in this response; each one works once.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api"
+                ],
+                "summary": "Confirm two-factor enrollment",
+                "parameters": [
+                    {
+                        "description": "Code from the authenticator app",
+                        "name": "confirm_request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SecondFactorRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.RecoveryCodesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body, invalid code or enrollment not started",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Not allowed with an API key",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Two-factor authentication already enabled",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not enable two-factor authentication",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/2fa/disable": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Disables two-factor authentication and deletes the recovery codes. Requires the current password and a code from the authenticator app or a recovery code.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api"
+                ],
+                "summary": "Disable two-factor authentication",
+                "parameters": [
+                    {
+                        "description": "Current password and code or recovery code",
+                        "name": "disable_request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SecondFactorRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: Two-factor authentication disabled",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or two-factor authentication not enabled",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Wrong password or invalid code",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Not allowed with an API key",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not disable two-factor authentication",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/2fa/enroll": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Generates a new TOTP secret and its otpauth:// URI (to be shown as a QR code in an authenticator app). Two-factor authentication is only enabled after a code is confirmed at /api/user/2fa/confirm. Requires the current password and a logged-in session; API keys are refused.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api"
+                ],
+                "summary": "Start two-factor enrollment",
+                "parameters": [
+                    {
+                        "description": "Current password",
+                        "name": "enroll_request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TwoFactorEnrollRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TwoFactorEnrollResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Wrong password",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Not allowed with an API key",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Two-factor authentication already enabled",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not start enrollment",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/2fa/recovery-codes": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replaces all recovery codes with new ones, which are shown only in this response. Requires a code from the authenticator app or an unused recovery code.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api"
+                ],
+                "summary": "Regenerate recovery codes",
+                "parameters": [
+                    {
+                        "description": "Code or recovery code",
+                        "name": "codes_request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SecondFactorRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.RecoveryCodesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or two-factor authentication not enabled",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid code",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Not allowed with an API key",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not generate recovery codes",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/resend-verification": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "Sends a new verification link to the user's e-mail address. Links sent before stop working.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api"
+                ],
+                "summary": "Resend the verification e-mail",
+                "responses": {
+                    "202": {
+                        "description": "message: Verification email sent",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Email address already verified",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests (see Retry-After)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not send verification email",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/rotate-api-key": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "Generates a new secret for the user's \"default\" API key (the one created at registration), invalidating the old one. The key is created if it no longer exists. The new key is returned only once. Use /api/keys/{id}/rotate for other keys. The default key has full access, so keys restricted to a project cannot rotate it.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api"
+                ],
+                "summary": "Rotate user's default API key",
+                "responses": {
+                    "200": {
+                        "description": "message: API key rotated successfully, new_api_key: ...",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "API key is restricted to another project",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not rotate API key",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/status": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves the current user's information, including plan, storage usage and the remaining daily upload quota.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "api"
+                ],
+                "summary": "Get user status",
+                "responses": {
+                    "200": {
+                        "description": "User status",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserStatusResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not retrieve user details",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/webhooks": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "GET lists the user's webhooks. POST subscribes a URL to events (file.uploaded, file.deleted, project.created, project.deleted, quota.exceeded), optionally for a single project. Webhooks for organization projects (org) require the admin role and must be restricted to the project; they receive the events of every member. Each delivery is a JSON POST signed in X-Forge-Signature (\"t=\u003cunix\u003e,v1=\u003chex HMAC-SHA256 of \"\u003ct\u003e.\u003cbody\u003e\"\u003e\"). The signing secret is returned only once, in the creation response.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "List or create webhooks",
+                "parameters": [
+                    {
+                        "description": "URL, events and optional project and org (POST only)",
+                        "name": "webhook_request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.WebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Webhooks",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.WebhooksResponse"
+                        }
+                    },
+                    "201": {
+                        "description": "Webhook created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.WebhookCreatedResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body, URL or events",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "API key lacks the 'admin' scope or is restricted to another project, or the organization role is below admin",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Project or organization not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not create webhook",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "GET lists the user's webhooks. POST subscribes a URL to events (file.uploaded, file.deleted, project.created, project.deleted, quota.exceeded), optionally for a single project. Webhooks for organization projects (org) require the admin role and must be restricted to the project; they receive the events of every member. Each delivery is a JSON POST signed in X-Forge-Signature (\"t=\u003cunix\u003e,v1=\u003chex HMAC-SHA256 of \"\u003ct\u003e.\u003cbody\u003e\"\u003e\"). The signing secret is returned only once, in the creation response.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "List or create webhooks",
+                "parameters": [
+                    {
+                        "description": "URL, events and optional project and org (POST only)",
+                        "name": "webhook_request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.WebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Webhooks",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.WebhooksResponse"
+                        }
+                    },
+                    "201": {
+                        "description": "Webhook created",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.WebhookCreatedResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body, URL or events",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "API key lacks the 'admin' scope or is restricted to another project, or the organization role is below admin",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Project or organization not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not create webhook",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/webhooks/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "GET returns one webhook. PATCH changes its URL, events or active flag (the project cannot be changed). DELETE removes the webhook and its delivery log.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Get, update or delete a webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to change (PATCH only)",
+                        "name": "webhook_request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.WebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.WebhookInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body, URL or events",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "API key lacks the 'admin' scope",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not update webhook",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "GET returns one webhook. PATCH changes its URL, events or active flag (the project cannot be changed). DELETE removes the webhook and its delivery log.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Get, update or delete a webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to change (PATCH only)",
+                        "name": "webhook_request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.WebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.WebhookInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body, URL or events",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "API key lacks the 'admin' scope",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not update webhook",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "GET returns one webhook. PATCH changes its URL, events or active flag (the project cannot be changed). DELETE removes the webhook and its delivery log.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Get, update or delete a webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to change (PATCH only)",
+                        "name": "webhook_request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.WebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.WebhookInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body, URL or events",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "API key lacks the 'admin' scope",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not update webhook",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/webhooks/{id}/deliveries": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "Returns the delivery log of a webhook, newest first, with the status, number of attempts and the last response of each delivery.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "List a webhook's deliveries",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number for pagination",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of items per page",
+                        "name": "per_page",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.DeliveriesResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "API key lacks the 'admin' scope",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/webhooks/{id}/deliveries/{delivery_id}/redeliver": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "Schedules a new delivery of the same payload as an earlier delivery, with a fresh set of retries. The original delivery is kept in the log.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Redeliver a webhook event",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Delivery ID",
+                        "name": "delivery_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.DeliveryInfo"
+                        }
+                    },
+                    "403": {
+                        "description": "API key lacks the 'admin' scope",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook not found or Delivery not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not schedule delivery",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/webhooks/{id}/test": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "APIKeyAuth": []
+                    }
+                ],
+                "description": "Sends a signed webhook.test event to the webhook right away and returns the recorded delivery, including the response status and body. Test deliveries are not retried.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Send a test event to a webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.DeliveryInfo"
+                        }
+                    },
+                    "403": {
+                        "description": "API key lacks the 'admin' scope",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Webhook not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not record delivery",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/login": {
+            "post": {
+                "description": "Authenticates a user using email and password, then returns a short-lived JWT access token and a refresh token. Use /token/refresh to obtain new access tokens and /logout to end the session. Repeated failures lock the account: temporarily (the lock doubles each time) and, after more failures, permanently until an administrator unlocks it. A locked account answers 401 like wrong credentials, so the lock does not reveal which emails are registered. Failures from one IP across accounts also block that IP for a while. For accounts with two-factor authentication the response has no tokens: it sets two_factor_required and returns a challenge_token to be sent with a code to /login/2fa.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Log in a user",
+                "parameters": [
+                    {
+                        "description": "User login credentials (email and password)",
+                        "name": "auth_request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.LoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Logged in successfully",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.AuthResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid credentials (also returned while the account is locked)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Account suspended",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests or too many failed logins from this address (see Retry-After)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not generate token",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/login/2fa": {
+            "post": {
+                "description": "Second step of /login for accounts with two-factor authentication. Exchanges the challenge token returned by /login and a code from the authenticator app (or an unused recovery code) for the session tokens. A challenge expires after a few minutes and accepts a limited number of wrong codes; wrong codes also count toward the account lock.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Complete a login with two-factor authentication",
+                "parameters": [
+                    {
+                        "description": "Challenge token and code or recovery code",
+                        "name": "two_factor_request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TwoFactorLoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Logged in successfully",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.AuthResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired challenge, or invalid code",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests or too many failed logins from this address (see Retry-After)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not generate token",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/logout": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Ends the current session: the access token used in the request is revoked along with its refresh token. A refresh token may also be sent in the body to end that session instead (e.g. when authenticating with an API key).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Log out",
+                "parameters": [
+                    {
+                        "description": "Refresh token of the session to end",
+                        "name": "refresh_request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.RefreshRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: Logged out successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "No session to end",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not end session",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/password/forgot": {
+            "post": {
+                "description": "Sends a password reset token to the e-mail address if it belongs to an account. The response is the same whether or not the account exists. Requesting a new token invalidates the previous one.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Request a password reset",
+                "parameters": [
+                    {
+                        "description": "E-mail address of the account",
+                        "name": "email_request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.EmailRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "message: If the address belongs to an account, a reset token has been sent",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests (see Retry-After)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/password/reset": {
+            "post": {
+                "description": "Sets a new password with the token sent by /password/forgot. All of the user's sessions are ended, a temporary login lock is cleared and the e-mail address counts as verified. Permanent locks are kept. The token can also be sent as the ` + "`" + `token` + "`" + ` query parameter.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Reset the password",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Password reset token",
+                        "name": "token",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Token and new password",
+                        "name": "reset_request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ResetPasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: Password reset successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body, invalid password or invalid or expired token",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests (see Retry-After)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not reset password",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/register": {
+            "post": {
+                "description": "Creates a new user account and returns the user info along with its initial API key. The key is shown only in this response; only its hash is stored. A verification link is sent to the e-mail address; uploads are blocked until it is confirmed at /verify-email.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Register a new user",
+                "parameters": [
+                    {
+                        "description": "User registration details (name, email, password, whatsapp_number)",
+                        "name": "auth_request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.AuthRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "User created successfully",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.AuthResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body or missing fields",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests (see Retry-After)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not create user or find default plan",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/token/refresh": {
+            "post": {
+                "description": "Exchanges a refresh token for a new access token and a new refresh token. Each refresh token can be used only once; reusing an already exchanged token revokes the whole session.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Refresh the access token",
+                "parameters": [
+                    {
+                        "description": "Refresh token returned by /login or by a previous refresh",
+                        "name": "refresh_request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.RefreshRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TokenResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired refresh token",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests (see Retry-After)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not generate token",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/verify-email": {
+            "get": {
+                "description": "Confirms the account's e-mail address with the token sent by e-mail after registration. The token can be sent as the ` + "`" + `token` + "`" + ` query parameter (the link in the e-mail) or in a JSON body. Each token works once.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Verify the e-mail address",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Verification token",
+                        "name": "token",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Verification token",
+                        "name": "token_request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TokenRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: Email verified successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid or expired token",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not verify email",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Confirms the account's e-mail address with the token sent by e-mail after registration. The token can be sent as the ` + "`" + `token` + "`" + ` query parameter (the link in the e-mail) or in a JSON body. Each token works once.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Verify the e-mail address",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Verification token",
+                        "name": "token",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Verification token",
+                        "name": "token_request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TokenRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: Email verified successfully",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid or expired token",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Could not verify email",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "billing.Line": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "quantity": {
+                    "type": "number"
+                },
+                "unit": {
+                    "description": "\"month\", \"GB-month\", \"GB\" ou \"payment\"",
+                    "type": "string"
+                },
+                "unit_price": {
+                    "type": "number"
+                }
+            }
+        },
+        "handlers.APIKeyCreatedResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "key": {
+                    "description": "Exibida apenas nesta resposta",
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "org": {
+                    "description": "Slug da organização dona do projeto",
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "project": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "handlers.APIKeyInfo": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "org": {
+                    "description": "Slug da organização dona do projeto",
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "project": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "handlers.APIKeyRequest": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "description": "Sem expiração quando omitido",
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "org": {
+                    "description": "Organização do projeto; projetos pessoais quando omitido",
+                    "type": "string"
+                },
+                "project": {
+                    "description": "Restringe a chave a um projeto existente",
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "handlers.APIKeysResponse": {
+            "type": "object",
+            "properties": {
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.APIKeyInfo"
+                    }
+                }
+            }
+        },
+        "handlers.AdminStatsResponse": {
+            "type": "object",
+            "properties": {
+                "admins": {
+                    "type": "integer"
+                },
+                "files": {
+                    "type": "integer"
+                },
+                "files_by_mime_type": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.MimeTypeStats"
+                    }
+                },
+                "logical_bytes": {
+                    "description": "Soma dos tamanhos dos arquivos, contando duplicatas",
+                    "type": "integer"
+                },
+                "organizations": {
+                    "type": "integer"
+                },
+                "physical_bytes": {
+                    "description": "Bytes de fato armazenados (blobs deduplicados)",
+                    "type": "integer"
+                },
+                "projects": {
+                    "type": "integer"
+                },
+                "signups_per_day": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.DailyCount"
+                    }
+                },
+                "suspended_users": {
+                    "type": "integer"
+                },
+                "users": {
+                    "type": "integer"
+                },
+                "users_by_plan": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.PlanStats"
+                    }
+                },
+                "variant_bytes": {
+                    "description": "Miniaturas e variantes no cache",
+                    "type": "integer"
+                },
+                "verified_users": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.AdminUserInfo": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "email_verified": {
+                    "type": "boolean"
+                },
+                "grace_ends_at": {
+                    "description": "Definido enquanto o uso passa do limite",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "is_admin": {
+                    "type": "boolean"
+                },
+                "locked": {
+                    "type": "boolean"
+                },
+                "logical_storage_usage": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "plan": {
+                    "type": "string"
+                },
+                "plan_renews_at": {
+                    "type": "string"
+                },
+                "storage_limit": {
+                    "type": "integer"
+                },
+                "storage_usage": {
+                    "type": "integer"
+                },
+                "suspended_at": {
+                    "type": "string"
+                },
+                "suspension_reason": {
+                    "type": "string"
+                },
+                "two_factor_enabled": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "handlers.AdminUsersResponse": {
+            "type": "object",
+            "properties": {
+                "page": {
+                    "type": "integer"
+                },
+                "per_page": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "total_pages": {
+                    "type": "integer"
+                },
+                "users": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.AdminUserInfo"
+                    }
+                }
+            }
+        },
+        "handlers.AuditEventInfo": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "actor": {
+                    "type": "string"
+                },
+                "api_key_id": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "detail": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "ip": {
+                    "type": "string"
+                },
+                "outcome": {
+                    "type": "string"
+                },
+                "target": {
+                    "type": "string"
+                },
+                "user_agent": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.AuditResponse": {
+            "type": "object",
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.AuditEventInfo"
+                    }
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "per_page": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "total_pages": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.AuthRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                },
+                "whatsapp_number": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.AuthResponse": {
+            "type": "object",
+            "properties": {
+                "challenge_expires_at": {
+                    "type": "string"
+                },
+                "challenge_token": {
+                    "type": "string"
+                },
+                "expires_in": {
+                    "description": "Validade do access token, em segundos",
+                    "type": "integer"
+                },
+                "forge_api_key": {
+                    "description": "Apenas no registro; não é recuperável depois",
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "refresh_token": {
+                    "type": "string"
+                },
+                "refresh_token_expires_at": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                },
+                "two_factor_required": {
+                    "description": "Login com 2FA: em vez dos tokens, um desafio a ser trocado em /login/2fa",
+                    "type": "boolean"
+                },
+                "user": {
+                    "$ref": "#/definitions/models.User"
+                }
+            }
+        },
+        "handlers.AvailablePlan": {
+            "type": "object",
+            "properties": {
+                "allowed_mime_types": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "daily_upload_limit": {
+                    "type": "integer"
+                },
+                "egress_limit": {
+                    "description": "Bytes servidos por mês antes de /files/ recusar; 0 = ilimitado",
+                    "type": "integer"
+                },
+                "egress_overage_rate": {
+                    "description": "Por GB além de included_egress",
+                    "type": "number"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "included_egress": {
+                    "description": "Bytes servidos por mês incluídos no preço",
+                    "type": "integer"
+                },
+                "max_file_size": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "price": {
+                    "description": "Por período, em BILLING_CURRENCY",
+                    "type": "number"
+                },
+                "storage_limit": {
+                    "type": "integer"
+                },
+                "storage_overage_rate": {
+                    "description": "Excedentes cobrados na fatura mensal",
+                    "type": "number"
+                }
+            }
+        },
+        "handlers.AvailablePlansResponse": {
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "type": "string"
+                },
+                "plans": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.AvailablePlan"
+                    }
+                }
+            }
+        },
+        "handlers.CollaboratorInfo": {
+            "type": "object",
+            "properties": {
+                "accepted_at": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "permission": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "pending até o convite ser aceito, depois accepted",
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.CollaboratorsResponse": {
+            "type": "object",
+            "properties": {
+                "collaborators": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.CollaboratorInfo"
+                    }
+                },
+                "project": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.DailyCount": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "day": {
+                    "description": "AAAA-MM-DD, em UTC",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.DailyUsage": {
+            "type": "object",
+            "properties": {
+                "day": {
+                    "description": "YYYY-MM-DD (UTC)",
+                    "type": "string"
+                },
+                "egress_bytes": {
+                    "type": "integer"
+                },
+                "storage_bytes": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.DeliveriesResponse": {
+            "type": "object",
+            "properties": {
+                "deliveries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.DeliveryInfo"
+                    }
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "per_page": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "total_pages": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.DeliveryInfo": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "delivered_at": {
+                    "type": "string"
+                },
+                "event": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "payload": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "response_body": {
+                    "type": "string"
+                },
+                "response_status": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.EmailRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.FileInfo": {
+            "type": "object",
+            "properties": {
+                "bytes_served": {
+                    "type": "integer"
+                },
+                "downloads": {
+                    "description": "Acessos em /files/; continuações de downloads por partes não contam",
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "scan_result": {
+                    "description": "Assinatura encontrada em arquivos em quarentena",
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "status": {
+                    "description": "pending, ready, failed ou quarantined",
+                    "type": "string"
+                },
+                "uploaded_at": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                },
+                "url_expires_at": {
+                    "description": "Apenas para projetos privados",
+                    "type": "string"
+                },
+                "variants": {
+                    "description": "Miniaturas e variantes já geradas de imagens",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.VariantInfo"
+                    }
+                }
+            }
+        },
+        "handlers.InvitationInfo": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "invited_by": {
+                    "type": "string"
+                },
+                "owner": {
+                    "description": "E-mail do dono, usado no parâmetro owner depois de aceitar",
+                    "type": "string"
+                },
+                "permission": {
+                    "type": "string"
+                },
+                "project": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.InvitationsResponse": {
+            "type": "object",
+            "properties": {
+                "invitations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.InvitationInfo"
+                    }
+                }
+            }
+        },
+        "handlers.InvoiceInfo": {
+            "type": "object",
+            "properties": {
+                "amount_due": {
+                    "description": "Excedente cobrado com a fatura",
+                    "type": "number"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "lines": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/billing.Line"
+                    }
+                },
+                "number": {
+                    "type": "string"
+                },
+                "pdf_url": {
+                    "type": "string"
+                },
+                "period_end": {
+                    "description": "Exclusivo",
+                    "type": "string"
+                },
+                "period_start": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "paid ou open (cobrança recusada)",
+                    "type": "string"
+                },
+                "total": {
+                    "type": "number"
+                }
+            }
+        },
+        "handlers.InvoicesResponse": {
+            "type": "object",
+            "properties": {
+                "invoices": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.InvoiceInfo"
+                    }
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "per_page": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "total_pages": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.ListResponse": {
+            "type": "object",
+            "properties": {
+                "files": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.FileInfo"
+                    }
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "per_page": {
+                    "type": "integer"
+                },
+                "project": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "total_pages": {
+                    "type": "integer"
+                },
+                "visibility": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.LockedIPInfo": {
+            "type": "object",
+            "properties": {
+                "failures": {
+                    "type": "integer"
+                },
+                "ip": {
+                    "type": "string"
+                },
+                "locked_until": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.LockedUserInfo": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "failed_logins": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "locked_until": {
+                    "description": "Nulo nos bloqueios permanentes",
+                    "type": "string"
+                },
+                "permanent": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "handlers.LockoutsResponse": {
+            "type": "object",
+            "properties": {
+                "ips": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.LockedIPInfo"
+                    }
+                },
+                "users": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.LockedUserInfo"
+                    }
+                }
+            }
+        },
+        "handlers.LoginRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.MemberInfo": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.MemberRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "description": "Apenas ao adicionar",
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.MembersResponse": {
+            "type": "object",
+            "properties": {
+                "members": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.MemberInfo"
+                    }
+                }
+            }
+        },
+        "handlers.MimeTypeStats": {
+            "type": "object",
+            "properties": {
+                "bytes": {
+                    "type": "integer"
+                },
+                "files": {
+                    "type": "integer"
+                },
+                "mime_type": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.OrganizationInfo": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "egress_limit": {
+                    "description": "0 = ilimitado",
+                    "type": "integer"
+                },
+                "egress_usage": {
+                    "description": "Bytes servidos no mês (UTC)",
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "logical_storage_usage": {
+                    "type": "integer"
+                },
+                "members": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "plan": {
+                    "type": "string"
+                },
+                "projects": {
+                    "type": "integer"
+                },
+                "role": {
+                    "description": "Papel do usuário autenticado",
+                    "type": "string"
+                },
+                "slug": {
+                    "type": "string"
+                },
+                "storage_limit": {
+                    "type": "integer"
+                },
+                "storage_usage": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.OrganizationRequest": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "slug": {
+                    "description": "Gerado a partir do nome quando omitido",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.OrganizationsResponse": {
+            "type": "object",
+            "properties": {
+                "organizations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.OrganizationInfo"
+                    }
+                }
+            }
+        },
+        "handlers.PlanChangeInfo": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "applied_at": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "effective_at": {
+                    "type": "string"
+                },
+                "failure_reason": {
+                    "type": "string"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "payment_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.PlanChangeRequest": {
+            "type": "object",
+            "properties": {
+                "plan": {
+                    "description": "Nome ou ID do plano",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.PlanChangesResponse": {
+            "type": "object",
+            "properties": {
+                "changes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.PlanChangeInfo"
+                    }
+                }
+            }
+        },
+        "handlers.PlanInfo": {
+            "type": "object",
+            "properties": {
+                "allowed_mime_types": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "daily_upload_limit": {
+                    "type": "integer"
+                },
+                "egress_limit": {
+                    "type": "integer"
+                },
+                "egress_overage_rate": {
+                    "type": "number"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "included_egress": {
+                    "type": "integer"
+                },
+                "max_file_size": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "organizations": {
+                    "type": "integer"
+                },
+                "price": {
+                    "type": "number"
+                },
+                "rate_limit": {
+                    "type": "string"
+                },
+                "storage_limit": {
+                    "type": "integer"
+                },
+                "storage_overage_rate": {
+                    "type": "number"
+                },
+                "users": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.PlanRequest": {
+            "type": "object",
+            "properties": {
+                "allowed_mime_types": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "daily_upload_limit": {
+                    "description": "0 = ilimitado",
+                    "type": "integer"
+                },
+                "egress_limit": {
+                    "description": "Bytes servidos por mês antes de /files/ recusar; 0 = ilimitado",
+                    "type": "integer"
+                },
+                "egress_overage_rate": {
+                    "description": "Por GB além de included_egress",
+                    "type": "number"
+                },
+                "included_egress": {
+                    "description": "Bytes servidos por mês incluídos no preço",
+                    "type": "integer"
+                },
+                "max_file_size": {
+                    "description": "Em bytes",
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "number"
+                },
+                "rate_limit": {
+                    "description": "Ex.: \"600/1m\"; vazio usa RATE_LIMIT_API",
+                    "type": "string"
+                },
+                "storage_limit": {
+                    "description": "Em bytes",
+                    "type": "integer"
+                },
+                "storage_overage_rate": {
+                    "description": "Tarifas de excedente da fatura mensal",
+                    "type": "number"
+                }
+            }
+        },
+        "handlers.PlanStats": {
+            "type": "object",
+            "properties": {
+                "plan": {
+                    "type": "string"
+                },
+                "users": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.PlansResponse": {
+            "type": "object",
+            "properties": {
+                "plans": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.PlanInfo"
+                    }
+                }
+            }
+        },
+        "handlers.ProjectInfo": {
+            "type": "object",
+            "properties": {
+                "file_count": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "owner": {
+                    "description": "E-mail do dono, apenas nos projetos compartilhados com o usuário",
+                    "type": "string"
+                },
+                "role": {
+                    "description": "owner nos próprios projetos, o papel na organização ou a permissão do colaborador (read, upload ou manage)",
+                    "type": "string"
+                },
+                "total_size": {
+                    "type": "integer"
+                },
+                "visibility": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ProjectsResponse": {
+            "type": "object",
+            "properties": {
+                "page": {
+                    "type": "integer"
+                },
+                "per_page": {
+                    "type": "integer"
+                },
+                "projects": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.ProjectInfo"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "total_pages": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.RecoveryCodesResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "recovery_codes": {
+                    "description": "Exibidos apenas nesta resposta",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "handlers.RefreshRequest": {
+            "type": "object",
+            "properties": {
+                "refresh_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ResetPasswordRequest": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.SecondFactorRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                },
+                "recovery_code": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.SessionInfo": {
+            "type": "object",
+            "properties": {
+                "current": {
+                    "type": "boolean"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "ip_address": {
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "user_agent": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.SessionsResponse": {
+            "type": "object",
+            "properties": {
+                "sessions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.SessionInfo"
+                    }
+                }
+            }
+        },
+        "handlers.ShareRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "description": "Apenas ao convidar",
+                    "type": "string"
+                },
+                "permission": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.SignResponse": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "type": "string"
+                },
+                "file": {
+                    "type": "string"
+                },
+                "project": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.SubscriptionResponse": {
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "type": "string"
+                },
+                "grace_ends_at": {
+                    "description": "Depois dele os arquivos deixam de ser servidos",
+                    "type": "string"
+                },
+                "pending_change": {
+                    "$ref": "#/definitions/handlers.PlanChangeInfo"
+                },
+                "plan": {
+                    "$ref": "#/definitions/handlers.AvailablePlan"
+                },
+                "read_only": {
+                    "description": "Uso acima do limite: uploads são recusados",
+                    "type": "boolean"
+                },
+                "renews_at": {
+                    "description": "Nulo nos planos gratuitos",
+                    "type": "string"
+                },
+                "restricted": {
+                    "description": "Prazo esgotado: /files/ responde 402",
+                    "type": "boolean"
+                },
+                "storage_usage": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.SuspendRequest": {
+            "type": "object",
+            "properties": {
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.TokenRequest": {
+            "type": "object",
+            "properties": {
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.TokenResponse": {
+            "type": "object",
+            "properties": {
+                "expires_in": {
+                    "description": "Validade do access token, em segundos",
+                    "type": "integer"
+                },
+                "refresh_token": {
+                    "type": "string"
+                },
+                "refresh_token_expires_at": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.TwoFactorEnrollRequest": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.TwoFactorEnrollResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "otpauth_uri": {
+                    "description": "Para gerar o QR code",
+                    "type": "string"
+                },
+                "secret": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.TwoFactorLoginRequest": {
             "type": "object",
             "properties": {
-                "email": {
+                "challenge_token": {
                     "type": "string"
                 },
-                "name": {
+                "code": {
                     "type": "string"
                 },
-                "password": {
+                "recovery_code": {
                     "type": "string"
+                }
+            }
+        },
+        "handlers.TwoFactorStatusResponse": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
                 },
-                "whatsapp_number": {
+                "enabled_at": {
                     "type": "string"
+                },
+                "recovery_codes_remaining": {
+                    "type": "integer"
                 }
             }
         },
-        "handlers.AuthResponse": {
+        "handlers.UploadResponse": {
             "type": "object",
             "properties": {
-                "forge_api_key": {
+                "file": {
                     "type": "string"
                 },
                 "message": {
                     "type": "string"
                 },
-                "token": {
+                "project": {
                     "type": "string"
                 },
-                "user": {
-                    "$ref": "#/definitions/models.User"
+                "status": {
+                    "description": "\"pending\" até o processamento em segundo plano terminar",
+                    "type": "string"
+                },
+                "url": {
+                    "description": "Assinada e com validade limitada quando o projeto é privado",
+                    "type": "string"
                 }
             }
         },
-        "handlers.FileInfo": {
+        "handlers.UploadSessionResponse": {
             "type": "object",
             "properties": {
-                "name": {
+                "expires_at": {
                     "type": "string"
                 },
-                "size": {
+                "id": {
+                    "type": "string"
+                },
+                "length": {
                     "type": "integer"
                 },
-                "uploaded_at": {
+                "location": {
                     "type": "string"
                 },
-                "url": {
-                    "type": "string"
+                "offset": {
+                    "type": "integer"
                 }
             }
         },
-        "handlers.ListResponse": {
+        "handlers.UsageResponse": {
             "type": "object",
             "properties": {
-                "files": {
+                "average_storage": {
+                    "type": "integer"
+                },
+                "closed": {
+                    "description": "Mês fechado: os valores são os faturados",
+                    "type": "boolean"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "daily": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/handlers.FileInfo"
+                        "$ref": "#/definitions/handlers.DailyUsage"
                     }
                 },
-                "page": {
+                "days": {
+                    "description": "Dias com snapshot de armazenamento",
                     "type": "integer"
                 },
-                "per_page": {
+                "egress_bytes": {
                     "type": "integer"
                 },
-                "project": {
-                    "type": "string"
+                "egress_limit": {
+                    "description": "0 = ilimitado",
+                    "type": "integer"
                 },
-                "total": {
+                "included_egress": {
                     "type": "integer"
                 },
-                "total_pages": {
+                "month": {
+                    "description": "YYYY-MM",
+                    "type": "string"
+                },
+                "overage": {
+                    "description": "Estimado enquanto o mês está aberto",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/billing.Line"
+                    }
+                },
+                "overage_total": {
+                    "type": "number"
+                },
+                "peak_storage": {
                     "type": "integer"
+                },
+                "plan": {
+                    "type": "string"
+                },
+                "storage_gb_hours": {
+                    "type": "number"
                 }
             }
         },
-        "handlers.LoginRequest": {
+        "handlers.UserPlanRequest": {
+            "type": "object",
+            "properties": {
+                "plan": {
+                    "description": "Nome ou ID do plano",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.UserStatusResponse": {
             "type": "object",
             "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "daily_uploads_remaining": {
+                    "description": "-1 quando o plano não tem limite diário",
+                    "type": "integer"
+                },
                 "email": {
                     "type": "string"
                 },
+                "emailVerifiedAt": {
+                    "description": "Nil até o usuário confirmar o e-mail",
+                    "type": "string"
+                },
+                "failedLogins": {
+                    "description": "Falhas de login desde o último sucesso",
+                    "type": "integer"
+                },
+                "graceEndsAt": {
+                    "description": "Definido enquanto o uso passa do limite do plano: fim do prazo para voltar ao limite",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "isAdmin": {
+                    "type": "boolean"
+                },
+                "lockedUntil": {
+                    "description": "Bloqueio temporário do login",
+                    "type": "string"
+                },
+                "logicalStorageUsage": {
+                    "description": "Soma do tamanho de todos os arquivos, contando duplicatas",
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
                 "password": {
                     "type": "string"
+                },
+                "permanentlyLocked": {
+                    "description": "Só um administrador desbloqueia",
+                    "type": "boolean"
+                },
+                "plan": {
+                    "$ref": "#/definitions/models.Plan"
+                },
+                "planID": {
+                    "type": "string"
+                },
+                "planRenewsAt": {
+                    "description": "Fim do período pago; nil nos planos gratuitos",
+                    "type": "string"
+                },
+                "projects": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Project"
+                    }
+                },
+                "quotaRestrictedAt": {
+                    "description": "Quando o prazo acabou e os arquivos deixaram de ser servidos",
+                    "type": "string"
+                },
+                "storageUsage": {
+                    "description": "Bytes armazenados de fato (blobs deduplicados), usado no limite do plano",
+                    "type": "integer"
+                },
+                "suspendedAt": {
+                    "description": "Contas suspensas por um administrador não acessam a API",
+                    "type": "string"
+                },
+                "suspensionReason": {
+                    "type": "string"
+                },
+                "totpenabledAt": {
+                    "description": "Nil enquanto o 2FA não estiver ativo",
+                    "type": "string"
+                },
+                "uploads_today": {
+                    "type": "integer"
+                },
+                "whatsappNumber": {
+                    "type": "string"
                 }
             }
         },
-        "handlers.ProjectInfo": {
+        "handlers.VariantInfo": {
             "type": "object",
             "properties": {
-                "file_count": {
+                "fit": {
+                    "type": "string"
+                },
+                "height": {
                     "type": "integer"
                 },
-                "name": {
+                "size": {
+                    "type": "integer"
+                },
+                "url": {
                     "type": "string"
                 },
-                "total_size": {
+                "width": {
                     "type": "integer"
                 }
             }
         },
-        "handlers.ProjectsResponse": {
+        "handlers.VisibilityResponse": {
             "type": "object",
             "properties": {
-                "page": {
-                    "type": "integer"
+                "message": {
+                    "type": "string"
                 },
-                "per_page": {
-                    "type": "integer"
+                "project": {
+                    "type": "string"
                 },
-                "projects": {
+                "visibility": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.WebhookCreatedResponse": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "events": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/handlers.ProjectInfo"
+                        "type": "string"
                     }
                 },
-                "total": {
-                    "type": "integer"
+                "id": {
+                    "type": "string"
                 },
-                "total_pages": {
-                    "type": "integer"
+                "org": {
+                    "description": "Slug da organização dona do projeto",
+                    "type": "string"
+                },
+                "project": {
+                    "type": "string"
+                },
+                "secret": {
+                    "description": "Usado para conferir X-Forge-Signature; exibido apenas nesta resposta",
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
                 }
             }
         },
-        "handlers.UploadResponse": {
+        "handlers.WebhookInfo": {
             "type": "object",
             "properties": {
-                "file": {
+                "active": {
+                    "type": "boolean"
+                },
+                "created_at": {
                     "type": "string"
                 },
-                "message": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "org": {
+                    "description": "Slug da organização dona do projeto",
+                    "type": "string"
+                },
+                "project": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.WebhookRequest": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "org": {
+                    "description": "Organização do projeto; projetos pessoais quando omitido",
                     "type": "string"
                 },
                 "project": {
+                    "description": "Recebe eventos só deste projeto; todos quando omitido",
                     "type": "string"
                 },
                 "url": {
@@ -606,9 +7269,31 @@ const docTemplate = `{
                 }
             }
         },
+        "handlers.WebhooksResponse": {
+            "type": "object",
+            "properties": {
+                "webhooks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.WebhookInfo"
+                    }
+                }
+            }
+        },
         "models.File": {
             "type": "object",
             "properties": {
+                "blobID": {
+                    "description": "Conteúdo compartilhado; nulo em arquivos anteriores à deduplicação",
+                    "type": "string"
+                },
+                "bytesServed": {
+                    "type": "integer"
+                },
+                "downloads": {
+                    "description": "Acessos servidos em /files/, incluindo as variantes redimensionadas",
+                    "type": "integer"
+                },
                 "id": {
                     "type": "string"
                 },
@@ -624,9 +7309,17 @@ const docTemplate = `{
                 "projectID": {
                     "type": "string"
                 },
+                "scanResult": {
+                    "description": "Assinatura encontrada quando o arquivo está em quarentena",
+                    "type": "string"
+                },
                 "size": {
                     "type": "integer"
                 },
+                "status": {
+                    "description": "FileStatusPending, FileStatusReady, FileStatusFailed ou FileStatusQuarantined",
+                    "type": "string"
+                },
                 "uploadedAt": {
                     "type": "string"
                 }
@@ -635,21 +7328,53 @@ const docTemplate = `{
         "models.Plan": {
             "type": "object",
             "properties": {
+                "allowedMimeTypes": {
+                    "description": "Lista separada por vírgulas",
+                    "type": "string"
+                },
                 "createdAt": {
                     "type": "string"
                 },
+                "dailyUploadLimit": {
+                    "description": "Uploads por dia (UTC); 0 = ilimitado",
+                    "type": "integer"
+                },
+                "egressLimit": {
+                    "description": "Bytes servidos por mês antes de /files/ recusar; 0 = ilimitado",
+                    "type": "integer"
+                },
+                "egressOverageRate": {
+                    "description": "Por GB servido além de IncludedEgress",
+                    "type": "number"
+                },
                 "id": {
                     "type": "string"
                 },
+                "includedEgress": {
+                    "description": "Bytes servidos em /files/ por mês incluídos no preço",
+                    "type": "integer"
+                },
+                "maxFileSize": {
+                    "description": "Tamanho máximo por arquivo, em bytes",
+                    "type": "integer"
+                },
                 "name": {
                     "type": "string"
                 },
                 "price": {
                     "type": "number"
                 },
+                "rateLimit": {
+                    "description": "Requisições à API por usuário (ex.: \"600/1m\"); vazio usa RATE_LIMIT_API",
+                    "type": "string"
+                },
                 "storageLimit": {
                     "description": "Em bytes",
                     "type": "integer"
+                },
+                "storageOverageRate": {
+                    "description": "Tarifas de excedente cobradas na fatura mensal; 0 = não cobrado",
+                    "type": "number"
                 }
             }
         },
@@ -671,8 +7396,15 @@ const docTemplate = `{
                 "name": {
                     "type": "string"
                 },
+                "organizationID": {
+                    "type": "string"
+                },
                 "userID": {
                     "type": "string"
+                },
+                "visibility": {
+                    "description": "VisibilityPublic ou VisibilityPrivate",
+                    "type": "string"
                 }
             }
         },
@@ -685,33 +7417,77 @@ const docTemplate = `{
                 "email": {
                     "type": "string"
                 },
-                "forgeAPIKey": {
+                "emailVerifiedAt": {
+                    "description": "Nil até o usuário confirmar o e-mail",
+                    "type": "string"
+                },
+                "failedLogins": {
+                    "description": "Falhas de login desde o último sucesso",
+                    "type": "integer"
+                },
+                "graceEndsAt": {
+                    "description": "Definido enquanto o uso passa do limite do plano: fim do prazo para voltar ao limite",
                     "type": "string"
                 },
                 "id": {
                     "type": "string"
                 },
+                "isAdmin": {
+                    "type": "boolean"
+                },
+                "lockedUntil": {
+                    "description": "Bloqueio temporário do login",
+                    "type": "string"
+                },
+                "logicalStorageUsage": {
+                    "description": "Soma do tamanho de todos os arquivos, contando duplicatas",
+                    "type": "integer"
+                },
                 "name": {
                     "type": "string"
                 },
                 "password": {
                     "type": "string"
                 },
+                "permanentlyLocked": {
+                    "description": "Só um administrador desbloqueia",
+                    "type": "boolean"
+                },
                 "plan": {
                     "$ref": "#/definitions/models.Plan"
                 },
                 "planID": {
                     "type": "string"
                 },
+                "planRenewsAt": {
+                    "description": "Fim do período pago; nil nos planos gratuitos",
+                    "type": "string"
+                },
                 "projects": {
                     "type": "array",
                     "items": {
                         "$ref": "#/definitions/models.Project"
                     }
                 },
+                "quotaRestrictedAt": {
+                    "description": "Quando o prazo acabou e os arquivos deixaram de ser servidos",
+                    "type": "string"
+                },
                 "storageUsage": {
+                    "description": "Bytes armazenados de fato (blobs deduplicados), usado no limite do plano",
                     "type": "integer"
                 },
+                "suspendedAt": {
+                    "description": "Contas suspensas por um administrador não acessam a API",
+                    "type": "string"
+                },
+                "suspensionReason": {
+                    "type": "string"
+                },
+                "totpenabledAt": {
+                    "description": "Nil enquanto o 2FA não estiver ativo",
+                    "type": "string"
+                },
                 "whatsappNumber": {
                     "type": "string"
                 }
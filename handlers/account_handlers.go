@@ -0,0 +1,374 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/config"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/mailer"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/middleware"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/util"
+)
+
+// mailTimeout limita o envio de cada e-mail, que acontece fora da requisição
+const mailTimeout = 30 * time.Second
+
+var errUserTokenInvalid = errors.New("invalid or expired token")
+
+type TokenRequest struct {
+	Token string `json:"token"`
+}
+
+type EmailRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// issueUserToken cria um token de uso único para o usuário, invalidando os tokens
+// anteriores da mesma finalidade que ainda não foram usados
+func issueUserToken(db *gorm.DB, userID uuid.UUID, purpose string, ttl time.Duration) (string, error) {
+	token, hash, err := util.GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+			Delete(&models.UserToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.UserToken{
+			UserID:    userID,
+			Purpose:   purpose,
+			TokenHash: hash,
+			ExpiresAt: time.Now().Add(ttl),
+		}).Error
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// consumeUserToken marca o token como usado e o retorna. A atualização condicional
+// garante que duas requisições simultâneas não usem o mesmo token.
+func consumeUserToken(tx *gorm.DB, token, purpose string, now time.Time) (*models.UserToken, error) {
+	if token == "" {
+		return nil, errUserTokenInvalid
+	}
+	var t models.UserToken
+	if err := tx.First(&t, "token_hash = ? AND purpose = ?", util.HashToken(token), purpose).Error; err != nil {
+		return nil, errUserTokenInvalid
+	}
+	if t.UsedAt != nil || now.After(t.ExpiresAt) {
+		return nil, errUserTokenInvalid
+	}
+	result := tx.Model(&models.UserToken{}).Where("id = ? AND used_at IS NULL", t.ID).Update("used_at", now)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errUserTokenInvalid
+	}
+	t.UsedAt = &now
+	return &t, nil
+}
+
+// sendMail envia a mensagem em segundo plano: o tempo de resposta não depende do
+// servidor de e-mail nem revela se a conta existe. Falhas são apenas registradas.
+func sendMail(m mailer.Mailer, msg mailer.Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
+		if err := m.Send(ctx, msg); err != nil {
+			log.Printf("⚠️  Warning: Failed to send e-mail to %s: %v", msg.To, err)
+		}
+	}()
+}
+
+// sendVerificationEmail emite um token de verificação e envia o link para o usuário
+func sendVerificationEmail(db *gorm.DB, m mailer.Mailer, user *models.User) error {
+	token, err := issueUserToken(db, user.ID, models.TokenEmailVerification, config.AppConfig.EmailVerificationTTL)
+	if err != nil {
+		return err
+	}
+	link := fmt.Sprintf("%s/verify-email?token=%s", config.AppConfig.Domain, url.QueryEscape(token))
+	sendMail(m, mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your e-mail address",
+		Body: fmt.Sprintf("Hi %s,\n\nConfirm your e-mail address by opening the link below:\n\n%s\n\n"+
+			"The link expires in %s. If you did not create an account, ignore this message.\n",
+			user.Name, link, config.AppConfig.EmailVerificationTTL),
+	})
+	return nil
+}
+
+// requireVerifiedEmail recusa a requisição quando a conta ainda não confirmou o e-mail
+func requireVerifiedEmail(w http.ResponseWriter, user *models.User) bool {
+	if !config.AppConfig.RequireEmailVerification || user.EmailVerifiedAt != nil {
+		return true
+	}
+	http.Error(w, "Email address not verified. Open the link sent to your e-mail or request a new one at /api/user/resend-verification.", http.StatusForbidden)
+	return false
+}
+
+// VerifyEmailHandler godoc
+// @Summary Verify the e-mail address
+// @Description Confirms the account's e-mail address with the token sent by e-mail after registration. The token can be sent as the `token` query parameter (the link in the e-mail) or in a JSON body. Each token works once.
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param   token          query  string        false  "Verification token"
+// @Param   token_request  body   TokenRequest  false  "Verification token"
+// @Success 200 {object} map[string]string "message: Email verified successfully"
+// @Failure 400 {string} string "Invalid or expired token"
+// @Failure 500 {string} string "Could not verify email"
+// @Router /verify-email [get]
+// @Router /verify-email [post]
+func VerifyEmailHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var token string
+		switch r.Method {
+		case http.MethodGet:
+			token = r.URL.Query().Get("token")
+		case http.MethodPost:
+			var req TokenRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			token = req.Token
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		now := time.Now()
+		var user models.User
+		err := db.Transaction(func(tx *gorm.DB) error {
+			t, err := consumeUserToken(tx, token, models.TokenEmailVerification, now)
+			if err != nil {
+				return err
+			}
+			if err := tx.First(&user, "id = ?", t.UserID).Error; err != nil {
+				return err
+			}
+			if user.EmailVerifiedAt != nil {
+				return nil
+			}
+			return tx.Model(&user).Update("email_verified_at", now).Error
+		})
+		if errors.Is(err, errUserTokenInvalid) {
+			http.Error(w, "Invalid or expired token", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Could not verify email: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		middleware.RecordAudit(db, r, models.AuditEvent{
+			UserID: &user.ID, Actor: user.Email, Action: models.AuditEmailVerified, Target: user.Email, Outcome: models.AuditSuccess,
+		})
+		log.Printf("✅ E-mail verificado: %s", user.Email)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"message": "Email verified successfully",
+		})
+	}
+}
+
+// ResendVerificationHandler godoc
+// @Summary Resend the verification e-mail
+// @Description Sends a new verification link to the user's e-mail address. Links sent before stop working.
+// @Tags api
+// @Produce  json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 202 {object} map[string]string "message: Verification email sent"
+// @Failure 409 {string} string "Email address already verified"
+// @Failure 429 {string} string "Too many requests (see Retry-After)"
+// @Failure 500 {string} string "Could not send verification email"
+// @Router /api/user/resend-verification [post]
+func ResendVerificationHandler(db *gorm.DB, m mailer.Mailer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		userFromCtx, ok := r.Context().Value(middleware.UserContextKey).(*models.User)
+		if !ok {
+			http.Error(w, "Could not retrieve user from context", http.StatusInternalServerError)
+			return
+		}
+		var user models.User
+		if err := db.First(&user, "id = ?", userFromCtx.ID).Error; err != nil {
+			http.Error(w, "Could not retrieve user details", http.StatusInternalServerError)
+			return
+		}
+		if user.EmailVerifiedAt != nil {
+			http.Error(w, "Email address already verified", http.StatusConflict)
+			return
+		}
+
+		if err := sendVerificationEmail(db, m, &user); err != nil {
+			http.Error(w, "Could not send verification email: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]string{
+			"message": "Verification email sent",
+		})
+	}
+}
+
+// ForgotPasswordHandler godoc
+// @Summary Request a password reset
+// @Description Sends a password reset token to the e-mail address if it belongs to an account. The response is the same whether or not the account exists. Requesting a new token invalidates the previous one.
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param   email_request  body  EmailRequest  true  "E-mail address of the account"
+// @Success 202 {object} map[string]string "message: If the address belongs to an account, a reset token has been sent"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 429 {string} string "Too many requests (see Retry-After)"
+// @Router /password/forgot [post]
+func ForgotPasswordHandler(db *gorm.DB, m mailer.Mailer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req EmailRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		var user models.User
+		if err := db.First(&user, "email = ?", req.Email).Error; err == nil {
+			token, err := issueUserToken(db, user.ID, models.TokenPasswordReset, config.AppConfig.PasswordResetTTL)
+			if err != nil {
+				log.Printf("⚠️  Warning: Failed to create password reset token for %s: %v", user.Email, err)
+			} else {
+				// /password/reset só aceita POST, então o e-mail traz o token e a requisição
+				// a fazer, e não um link que o navegador abriria com GET
+				sendMail(m, mailer.Message{
+					To:      user.Email,
+					Subject: "Reset your password",
+					Body: fmt.Sprintf("Hi %s,\n\nA password reset was requested for your account. Your reset token is:\n\n%s\n\n"+
+						"To choose a new password, send it to the API:\n\n"+
+						"POST %s/password/reset\nContent-Type: application/json\n\n{\"token\": \"<token>\", \"password\": \"<new password>\"}\n\n"+
+						"The token expires in %s. If you did not request it, ignore this message; your password stays the same.\n",
+						user.Name, token, config.AppConfig.Domain, config.AppConfig.PasswordResetTTL),
+				})
+			}
+		}
+
+		// Mesma resposta para contas existentes ou não, para não revelar quais e-mails estão cadastrados
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]string{
+			"message": "If the address belongs to an account, a reset token has been sent",
+		})
+	}
+}
+
+// ResetPasswordHandler godoc
+// @Summary Reset the password
+// @Description Sets a new password with the token sent by /password/forgot. All of the user's sessions are ended, a temporary login lock is cleared and the e-mail address counts as verified. Permanent locks are kept. The token can also be sent as the `token` query parameter.
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param   token           query  string                false  "Password reset token"
+// @Param   reset_request   body   ResetPasswordRequest  true   "Token and new password"
+// @Success 200 {object} map[string]string "message: Password reset successfully"
+// @Failure 400 {string} string "Invalid request body, invalid password or invalid or expired token"
+// @Failure 429 {string} string "Too many requests (see Retry-After)"
+// @Failure 500 {string} string "Could not reset password"
+// @Router /password/reset [post]
+func ResetPasswordHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req ResetPasswordRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if req.Token == "" {
+			req.Token = r.URL.Query().Get("token")
+		}
+		if valid, message := isValidPassword(req.Password); !valid {
+			http.Error(w, message, http.StatusBadRequest)
+			return
+		}
+
+		now := time.Now()
+		var user models.User
+		err := db.Transaction(func(tx *gorm.DB) error {
+			t, err := consumeUserToken(tx, req.Token, models.TokenPasswordReset, now)
+			if err != nil {
+				return err
+			}
+			if err := tx.First(&user, "id = ?", t.UserID).Error; err != nil {
+				return err
+			}
+			if err := user.SetPassword(req.Password); err != nil {
+				return err
+			}
+			// Quem recebeu o token controla o e-mail: a conta passa a ser verificada
+			updates := map[string]interface{}{
+				"password":      user.Password,
+				"failed_logins": 0,
+				"locked_until":  nil,
+			}
+			if user.EmailVerifiedAt == nil {
+				updates["email_verified_at"] = now
+			}
+			return tx.Model(&user).Updates(updates).Error
+		})
+		if errors.Is(err, errUserTokenInvalid) {
+			http.Error(w, "Invalid or expired token", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Could not reset password: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// Encerra todas as sessões: quem conhecia a senha antiga perde o acesso
+		revoked := revokeAllSessions(db, user.ID)
+
+		middleware.RecordAudit(db, r, models.AuditEvent{
+			UserID: &user.ID, Actor: user.Email, Action: models.AuditPasswordReset, Target: user.Email, Outcome: models.AuditSuccess,
+		})
+		log.Printf("🔑 Senha redefinida: %s (%d sessão(ões) encerrada(s))", user.Email, revoked)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"message": "Password reset successfully",
+		})
+	}
+}
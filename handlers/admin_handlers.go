@@ -0,0 +1,936 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/mailer"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/middleware"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/ratelimit"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/util"
+)
+
+type LockedUserInfo struct {
+	ID           uuid.UUID  `json:"id"`
+	Email        string     `json:"email"`
+	FailedLogins int        `json:"failed_logins"`
+	LockedUntil  *time.Time `json:"locked_until"` // Nulo nos bloqueios permanentes
+	Permanent    bool       `json:"permanent"`
+}
+
+type LockedIPInfo struct {
+	IP          string    `json:"ip"`
+	Failures    int       `json:"failures"`
+	LockedUntil time.Time `json:"locked_until"`
+}
+
+type LockoutsResponse struct {
+	Users []LockedUserInfo `json:"users"`
+	IPs   []LockedIPInfo   `json:"ips"`
+}
+
+// LockoutsHandler godoc
+// @Summary List locked accounts and IPs
+// @Description Lists accounts whose login is locked (temporarily or permanently) after repeated failed logins, and IPs temporarily blocked for failing logins across accounts. Administrators only.
+// @Tags admin
+// @Produce  json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} LockoutsResponse
+// @Failure 403 {string} string "Administrator access required"
+// @Router /api/admin/lockouts [get]
+func LockoutsHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		now := time.Now()
+
+		var users []models.User
+		db.Where("permanently_locked = ? OR locked_until > ?", true, now).Order("email").Find(&users)
+		lockedUsers := make([]LockedUserInfo, 0, len(users))
+		for _, u := range users {
+			lockedUsers = append(lockedUsers, LockedUserInfo{
+				ID:           u.ID,
+				Email:        u.Email,
+				FailedLogins: u.FailedLogins,
+				LockedUntil:  u.LockedUntil,
+				Permanent:    u.PermanentlyLocked,
+			})
+		}
+
+		var entries []models.IPLoginFailure
+		db.Where("locked_until > ?", now).Order("locked_until DESC").Find(&entries)
+		lockedIPs := make([]LockedIPInfo, 0, len(entries))
+		for _, e := range entries {
+			lockedIPs = append(lockedIPs, LockedIPInfo{IP: e.IP, Failures: e.Failures, LockedUntil: *e.LockedUntil})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(LockoutsResponse{Users: lockedUsers, IPs: lockedIPs})
+	}
+}
+
+// UnlockUserHandler godoc
+// @Summary Unlock an account
+// @Description Removes a temporary or permanent login lock from an account and resets its failed login counter. The unlock is recorded in the account's audit log. Administrators only.
+// @Tags admin
+// @Produce  json
+// @Param   id  path  string  true  "User ID"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} map[string]string "message: Account unlocked"
+// @Failure 403 {string} string "Administrator access required"
+// @Failure 404 {string} string "User not found"
+// @Failure 500 {string} string "Could not unlock account"
+// @Router /api/admin/users/{id}/unlock [post]
+func UnlockUserHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		id, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		var user models.User
+		if err := db.First(&user, "id = ?", id).Error; err != nil {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+
+		if err := unlockUser(db, &user); err != nil {
+			http.Error(w, "Could not unlock account: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		// Registrado na conta desbloqueada; o ator é o administrador
+		middleware.RecordAudit(db, r, models.AuditEvent{
+			UserID:  &user.ID,
+			Action:  models.AuditAccountUnlock,
+			Target:  user.Email,
+			Outcome: models.AuditSuccess,
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"message": "Account unlocked",
+			"id":      user.ID.String(),
+			"email":   user.Email,
+		})
+	}
+}
+
+// UnlockIPHandler godoc
+// @Summary Unblock an IP
+// @Description Lifts the login block of an IP and resets its failure counter. Administrators only.
+// @Tags admin
+// @Produce  json
+// @Param   ip  path  string  true  "IP address"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} map[string]string "message: IP unblocked"
+// @Failure 403 {string} string "Administrator access required"
+// @Failure 404 {string} string "IP not found"
+// @Router /api/admin/ips/{ip}/unlock [post]
+func UnlockIPHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		ip := r.PathValue("ip")
+		result := db.Where("ip = ?", ip).Delete(&models.IPLoginFailure{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			http.Error(w, "IP not found", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"message": "IP unblocked",
+			"ip":      ip,
+		})
+	}
+}
+
+type PlanRequest struct {
+	Name             *string  `json:"name,omitempty"`
+	Price            *float64 `json:"price,omitempty"`
+	StorageLimit     *int64   `json:"storage_limit,omitempty"` // Em bytes
+	MaxFileSize      *int64   `json:"max_file_size,omitempty"` // Em bytes
+	AllowedMimeTypes []string `json:"allowed_mime_types,omitempty"`
+	DailyUploadLimit *int     `json:"daily_upload_limit,omitempty"` // 0 = ilimitado
+	RateLimit        *string  `json:"rate_limit,omitempty"`         // Ex.: "600/1m"; vazio usa RATE_LIMIT_API
+	// Tarifas de excedente da fatura mensal
+	StorageOverageRate *float64 `json:"storage_overage_rate,omitempty"` // Por GB-mês acima de storage_limit
+	IncludedEgress     *int64   `json:"included_egress,omitempty"`      // Bytes servidos por mês incluídos no preço
+	EgressOverageRate  *float64 `json:"egress_overage_rate,omitempty"`  // Por GB além de included_egress
+	EgressLimit        *int64   `json:"egress_limit,omitempty"`         // Bytes servidos por mês antes de /files/ recusar; 0 = ilimitado
+}
+
+type PlanInfo struct {
+	ID                 uuid.UUID `json:"id"`
+	Name               string    `json:"name"`
+	Price              float64   `json:"price"`
+	StorageLimit       int64     `json:"storage_limit"`
+	MaxFileSize        int64     `json:"max_file_size"`
+	AllowedMimeTypes   []string  `json:"allowed_mime_types"`
+	DailyUploadLimit   int       `json:"daily_upload_limit"`
+	RateLimit          string    `json:"rate_limit"`
+	StorageOverageRate float64   `json:"storage_overage_rate"`
+	IncludedEgress     int64     `json:"included_egress"`
+	EgressOverageRate  float64   `json:"egress_overage_rate"`
+	EgressLimit        int64     `json:"egress_limit"`
+	Users              int64     `json:"users"`
+	Organizations      int64     `json:"organizations"`
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+type PlansResponse struct {
+	Plans []PlanInfo `json:"plans"`
+}
+
+type AdminUserInfo struct {
+	ID                  uuid.UUID  `json:"id"`
+	Name                string     `json:"name"`
+	Email               string     `json:"email"`
+	Plan                string     `json:"plan"`
+	IsAdmin             bool       `json:"is_admin"`
+	EmailVerified       bool       `json:"email_verified"`
+	TwoFactorEnabled    bool       `json:"two_factor_enabled"`
+	StorageUsage        int64      `json:"storage_usage"`
+	LogicalStorageUsage int64      `json:"logical_storage_usage"`
+	StorageLimit        int64      `json:"storage_limit"`
+	PlanRenewsAt        *time.Time `json:"plan_renews_at,omitempty"`
+	GraceEndsAt         *time.Time `json:"grace_ends_at,omitempty"` // Definido enquanto o uso passa do limite
+	Locked              bool       `json:"locked"`
+	SuspendedAt         *time.Time `json:"suspended_at,omitempty"`
+	SuspensionReason    string     `json:"suspension_reason,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+}
+
+type AdminUsersResponse struct {
+	Users      []AdminUserInfo `json:"users"`
+	Total      int64           `json:"total"`
+	Page       int             `json:"page"`
+	PerPage    int             `json:"per_page"`
+	TotalPages int             `json:"total_pages"`
+}
+
+type UserPlanRequest struct {
+	Plan string `json:"plan"` // Nome ou ID do plano
+}
+
+type SuspendRequest struct {
+	Reason string `json:"reason"`
+}
+
+type MimeTypeStats struct {
+	MimeType string `json:"mime_type"`
+	Files    int64  `json:"files"`
+	Bytes    int64  `json:"bytes"`
+}
+
+type PlanStats struct {
+	Plan  string `json:"plan"`
+	Users int64  `json:"users"`
+}
+
+type DailyCount struct {
+	Day   string `json:"day"` // AAAA-MM-DD, em UTC
+	Count int64  `json:"count"`
+}
+
+type AdminStatsResponse struct {
+	Users           int64           `json:"users"`
+	VerifiedUsers   int64           `json:"verified_users"`
+	SuspendedUsers  int64           `json:"suspended_users"`
+	Admins          int64           `json:"admins"`
+	Organizations   int64           `json:"organizations"`
+	Projects        int64           `json:"projects"`
+	Files           int64           `json:"files"`
+	LogicalBytes    int64           `json:"logical_bytes"`  // Soma dos tamanhos dos arquivos, contando duplicatas
+	PhysicalBytes   int64           `json:"physical_bytes"` // Bytes de fato armazenados (blobs deduplicados)
+	VariantBytes    int64           `json:"variant_bytes"`  // Miniaturas e variantes no cache
+	FilesByMimeType []MimeTypeStats `json:"files_by_mime_type"`
+	UsersByPlan     []PlanStats     `json:"users_by_plan"`
+	SignupsPerDay   []DailyCount    `json:"signups_per_day"`
+}
+
+// maxStatsDays limita a janela de cadastros por dia de /api/admin/stats
+const maxStatsDays = 365
+
+// toPlanInfo converte o plano para a resposta da API, com quantos usuários e
+// organizações o usam
+func toPlanInfo(db *gorm.DB, plan *models.Plan) PlanInfo {
+	info := PlanInfo{
+		ID:                 plan.ID,
+		Name:               plan.Name,
+		Price:              plan.Price,
+		StorageLimit:       plan.StorageLimit,
+		MaxFileSize:        plan.MaxFileSize,
+		AllowedMimeTypes:   plan.MimeTypes(),
+		DailyUploadLimit:   plan.DailyUploadLimit,
+		RateLimit:          plan.RateLimit,
+		StorageOverageRate: plan.StorageOverageRate,
+		IncludedEgress:     plan.IncludedEgress,
+		EgressOverageRate:  plan.EgressOverageRate,
+		EgressLimit:        plan.EgressLimit,
+		CreatedAt:          plan.CreatedAt,
+	}
+	db.Model(&models.User{}).Where("plan_id = ?", plan.ID).Count(&info.Users)
+	db.Model(&models.Organization{}).Where("plan_id = ?", plan.ID).Count(&info.Organizations)
+	return info
+}
+
+// applyPlanRequest copia para o plano os campos informados e valida o resultado. O erro
+// retornado é a mensagem a ser respondida com 400.
+func applyPlanRequest(plan *models.Plan, req *PlanRequest) error {
+	if req.Name != nil {
+		plan.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Price != nil {
+		plan.Price = *req.Price
+	}
+	if req.StorageLimit != nil {
+		plan.StorageLimit = *req.StorageLimit
+	}
+	if req.MaxFileSize != nil {
+		plan.MaxFileSize = *req.MaxFileSize
+	}
+	if req.AllowedMimeTypes != nil {
+		plan.AllowedMimeTypes = strings.Join(req.AllowedMimeTypes, ",")
+	}
+	if req.DailyUploadLimit != nil {
+		plan.DailyUploadLimit = *req.DailyUploadLimit
+	}
+	if req.RateLimit != nil {
+		plan.RateLimit = strings.TrimSpace(*req.RateLimit)
+	}
+	if req.StorageOverageRate != nil {
+		plan.StorageOverageRate = *req.StorageOverageRate
+	}
+	if req.IncludedEgress != nil {
+		plan.IncludedEgress = *req.IncludedEgress
+	}
+	if req.EgressOverageRate != nil {
+		plan.EgressOverageRate = *req.EgressOverageRate
+	}
+	if req.EgressLimit != nil {
+		plan.EgressLimit = *req.EgressLimit
+	}
+
+	switch {
+	case plan.Name == "" || len(plan.Name) > 100:
+		return errors.New("Name must be between 1 and 100 characters.")
+	case plan.Price < 0:
+		return errors.New("Price cannot be negative.")
+	case plan.StorageLimit <= 0:
+		return errors.New("storage_limit must be a positive number of bytes.")
+	case plan.MaxFileSize <= 0 || plan.MaxFileSize > plan.StorageLimit:
+		return errors.New("max_file_size must be positive and not larger than storage_limit.")
+	case plan.DailyUploadLimit < 0:
+		return errors.New("daily_upload_limit cannot be negative (0 means unlimited).")
+	case plan.StorageOverageRate < 0 || plan.EgressOverageRate < 0:
+		return errors.New("Overage rates cannot be negative.")
+	case plan.IncludedEgress < 0:
+		return errors.New("included_egress cannot be negative.")
+	case plan.EgressLimit < 0:
+		return errors.New("egress_limit cannot be negative (0 means unlimited).")
+	}
+	if len(plan.MimeTypes()) == 0 {
+		return errors.New("allowed_mime_types must list at least one type.")
+	}
+	supported := util.SupportedMimeTypes()
+	for _, t := range plan.MimeTypes() {
+		if !slices.Contains(supported, t) {
+			return fmt.Errorf("Unsupported MIME type %q. Supported types are: %s.", t, strings.Join(supported, ", "))
+		}
+	}
+	if plan.RateLimit != "" {
+		if _, err := ratelimit.ParseLimit(plan.RateLimit); err != nil {
+			return fmt.Errorf("Invalid rate_limit: %v", err)
+		}
+	}
+	return nil
+}
+
+// planNameTaken indica se outro plano já usa o nome
+func planNameTaken(db *gorm.DB, name string, except uuid.UUID) bool {
+	var count int64
+	db.Model(&models.Plan{}).Where("name = ? AND id <> ?", name, except).Count(&count)
+	return count > 0
+}
+
+// findPlan busca um plano pelo ID ou pelo nome
+func findPlan(db *gorm.DB, ref string) (*models.Plan, error) {
+	var plan models.Plan
+	query := db.Where("name = ?", ref)
+	if id, err := uuid.Parse(ref); err == nil {
+		query = db.Where("id = ?", id)
+	}
+	if err := query.First(&plan).Error; err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// AdminPlansHandler godoc
+// @Summary List or create plans
+// @Description GET lists all plans with how many users and organizations use each. POST creates a plan; name, storage_limit, max_file_size and allowed_mime_types are required. Administrators only.
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Param   plan_request  body  PlanRequest  false  "Plan fields (POST only)"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} PlansResponse
+// @Success 201 {object} PlanInfo "Plan created"
+// @Failure 400 {string} string "Invalid request body or plan fields"
+// @Failure 403 {string} string "Administrator access required"
+// @Failure 409 {string} string "Plan name already taken"
+// @Router /api/admin/plans [get]
+// @Router /api/admin/plans [post]
+func AdminPlansHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			var plans []models.Plan
+			db.Order("price, name").Find(&plans)
+			infos := make([]PlanInfo, 0, len(plans))
+			for i := range plans {
+				infos = append(infos, toPlanInfo(db, &plans[i]))
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(PlansResponse{Plans: infos})
+
+		case http.MethodPost:
+			var req PlanRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			var plan models.Plan
+			if err := applyPlanRequest(&plan, &req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if planNameTaken(db, plan.Name, uuid.Nil) {
+				http.Error(w, "Plan name already taken", http.StatusConflict)
+				return
+			}
+			if err := db.Create(&plan).Error; err != nil {
+				recordAudit(db, r, models.AuditPlanCreate, plan.Name, models.AuditFailure, err.Error())
+				http.Error(w, "Could not create plan: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			recordAudit(db, r, models.AuditPlanCreate, plan.Name, models.AuditSuccess, "")
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(toPlanInfo(db, &plan))
+
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// AdminPlanHandler godoc
+// @Summary Get, update or delete a plan
+// @Description GET returns the plan. PATCH changes only the fields sent; the new limits apply immediately to everyone on the plan. DELETE removes a plan that no user or organization uses. The Free plan, assigned to new accounts and organizations, cannot be renamed or deleted. Administrators only.
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Param   id            path  string       true   "Plan ID"
+// @Param   plan_request  body  PlanRequest  false  "Fields to change (PATCH only)"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} PlanInfo
+// @Success 204 {string} string "Plan deleted"
+// @Failure 400 {string} string "Invalid request body or plan fields"
+// @Failure 403 {string} string "Administrator access required"
+// @Failure 404 {string} string "Plan not found"
+// @Failure 409 {string} string "Plan name already taken, plan still in use or the Free plan cannot be renamed or deleted"
+// @Router /api/admin/plans/{id} [get]
+// @Router /api/admin/plans/{id} [patch]
+// @Router /api/admin/plans/{id} [delete]
+func AdminPlanHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := uuid.Parse(r.PathValue("id"))
+		var plan models.Plan
+		if err == nil {
+			err = db.First(&plan, "id = ?", id).Error
+		}
+		if err != nil {
+			http.Error(w, "Plan not found", http.StatusNotFound)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(toPlanInfo(db, &plan))
+
+		case http.MethodPatch:
+			var req PlanRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			oldName := plan.Name
+			if err := applyPlanRequest(&plan, &req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if oldName == models.FreePlanName && plan.Name != oldName {
+				http.Error(w, "The Free plan cannot be renamed", http.StatusConflict)
+				return
+			}
+			if planNameTaken(db, plan.Name, plan.ID) {
+				http.Error(w, "Plan name already taken", http.StatusConflict)
+				return
+			}
+			if err := db.Save(&plan).Error; err != nil {
+				recordAudit(db, r, models.AuditPlanUpdate, oldName, models.AuditFailure, err.Error())
+				http.Error(w, "Could not update plan: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			recordAudit(db, r, models.AuditPlanUpdate, plan.Name, models.AuditSuccess, "")
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(toPlanInfo(db, &plan))
+
+		case http.MethodDelete:
+			if plan.Name == models.FreePlanName {
+				http.Error(w, "The Free plan cannot be deleted", http.StatusConflict)
+				return
+			}
+			info := toPlanInfo(db, &plan)
+			if info.Users > 0 || info.Organizations > 0 {
+				http.Error(w, fmt.Sprintf("Plan is still used by %d user(s) and %d organization(s). Move them to another plan first.", info.Users, info.Organizations), http.StatusConflict)
+				return
+			}
+			if err := db.Delete(&plan).Error; err != nil {
+				recordAudit(db, r, models.AuditPlanDelete, plan.Name, models.AuditFailure, err.Error())
+				http.Error(w, "Could not delete plan: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			recordAudit(db, r, models.AuditPlanDelete, plan.Name, models.AuditSuccess, "")
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// toAdminUserInfo converte o usuário, com o plano carregado, para a resposta da API
+func toAdminUserInfo(user *models.User, now time.Time) AdminUserInfo {
+	return AdminUserInfo{
+		ID:                  user.ID,
+		Name:                user.Name,
+		Email:               user.Email,
+		Plan:                user.Plan.Name,
+		IsAdmin:             user.IsAdmin,
+		EmailVerified:       user.EmailVerifiedAt != nil,
+		TwoFactorEnabled:    user.TOTPEnabledAt != nil,
+		StorageUsage:        user.StorageUsage,
+		LogicalStorageUsage: user.LogicalStorageUsage,
+		StorageLimit:        user.Plan.StorageLimit,
+		PlanRenewsAt:        user.PlanRenewsAt,
+		GraceEndsAt:         user.GraceEndsAt,
+		Locked:              userLocked(user, now),
+		SuspendedAt:         user.SuspendedAt,
+		SuspensionReason:    user.SuspensionReason,
+		CreatedAt:           user.CreatedAt,
+	}
+}
+
+// loadAdminTarget busca, com o plano, o usuário indicado no caminho
+func loadAdminTarget(w http.ResponseWriter, r *http.Request, db *gorm.DB) (*models.User, bool) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	var user models.User
+	if err == nil {
+		err = db.Preload("Plan").First(&user, "id = ?", id).Error
+	}
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return nil, false
+	}
+	return &user, true
+}
+
+// likePattern escapa os curingas do LIKE para buscar o texto literalmente
+func likePattern(q string) string {
+	q = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(q)
+	return "%" + q + "%"
+}
+
+// AdminUsersHandler godoc
+// @Summary List and search users
+// @Description Lists users, newest first. q searches name and e-mail (case-insensitive); plan filters by plan name; status filters by active, suspended, locked or admin. Administrators only.
+// @Tags admin
+// @Produce  json
+// @Param   q         query  string  false  "Text to search in name and e-mail"
+// @Param   plan      query  string  false  "Plan name"
+// @Param   status    query  string  false  "active, suspended, locked or admin"
+// @Param   page      query  int     false  "Page number for pagination"
+// @Param   per_page  query  int     false  "Number of items per page"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} AdminUsersResponse
+// @Failure 400 {string} string "Invalid status"
+// @Failure 403 {string} string "Administrator access required"
+// @Router /api/admin/users [get]
+func AdminUsersHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		now := time.Now()
+		query := r.URL.Query()
+
+		scope := db.Model(&models.User{})
+		if q := strings.TrimSpace(query.Get("q")); q != "" {
+			pattern := likePattern(q)
+			scope = scope.Where("email ILIKE ? OR name ILIKE ?", pattern, pattern)
+		}
+		if plan := query.Get("plan"); plan != "" {
+			scope = scope.Where("plan_id IN (?)", db.Model(&models.Plan{}).Select("id").Where("name = ?", plan))
+		}
+		switch query.Get("status") {
+		case "":
+		case "active":
+			scope = scope.Where("suspended_at IS NULL")
+		case "suspended":
+			scope = scope.Where("suspended_at IS NOT NULL")
+		case "locked":
+			scope = scope.Where("permanently_locked = ? OR locked_until > ?", true, now)
+		case "admin":
+			scope = scope.Where("is_admin = ?", true)
+		default:
+			http.Error(w, "status must be active, suspended, locked or admin", http.StatusBadRequest)
+			return
+		}
+
+		page, perPage := getPaginationParams(r)
+		var total int64
+		scope.Session(&gorm.Session{}).Count(&total)
+
+		var users []models.User
+		scope.Session(&gorm.Session{}).Preload("Plan").Order("created_at DESC").
+			Limit(perPage).Offset((page - 1) * perPage).Find(&users)
+
+		infos := make([]AdminUserInfo, 0, len(users))
+		for i := range users {
+			infos = append(infos, toAdminUserInfo(&users[i], now))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(AdminUsersResponse{
+			Users:      infos,
+			Total:      total,
+			Page:       page,
+			PerPage:    perPage,
+			TotalPages: calculateTotalPages(total, perPage),
+		})
+	}
+}
+
+// AdminUserHandler godoc
+// @Summary Get a user
+// @Description Returns a user's account details, plan and storage usage. Administrators only.
+// @Tags admin
+// @Produce  json
+// @Param   id  path  string  true  "User ID"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} AdminUserInfo
+// @Failure 403 {string} string "Administrator access required"
+// @Failure 404 {string} string "User not found"
+// @Router /api/admin/users/{id} [get]
+func AdminUserHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		user, ok := loadAdminTarget(w, r, db)
+		if !ok {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(toAdminUserInfo(user, time.Now()))
+	}
+}
+
+// AdminUserPlanHandler godoc
+// @Summary Change a user's plan
+// @Description Moves the user to another plan, identified by name or ID, without charging and cancelling any scheduled plan change. The new limits apply immediately; files above a smaller storage limit are kept, but uploads are refused until usage drops and the grace period starts. The user is notified and the change is recorded in the user's audit log. Administrators only.
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Param   id            path  string           true  "User ID"
+// @Param   plan_request  body  UserPlanRequest  true  "Plan name or ID"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} AdminUserInfo
+// @Failure 400 {string} string "Invalid request body or plan not found"
+// @Failure 403 {string} string "Administrator access required"
+// @Failure 404 {string} string "User not found"
+// @Router /api/admin/users/{id}/plan [post]
+func AdminUserPlanHandler(db *gorm.DB, m mailer.Mailer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost && r.Method != http.MethodPut {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		user, ok := loadAdminTarget(w, r, db)
+		if !ok {
+			return
+		}
+
+		var req UserPlanRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Plan == "" {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		plan, err := findPlan(db, req.Plan)
+		if err != nil {
+			http.Error(w, "Plan not found", http.StatusBadRequest)
+			return
+		}
+
+		detail := user.Plan.Name + " -> " + plan.Name
+		if err := setUserPlan(db, m, user, plan, "", time.Now()); err != nil {
+			http.Error(w, "Could not change plan: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		// Registrado na conta do usuário; o ator é o administrador
+		middleware.RecordAudit(db, r, models.AuditEvent{
+			UserID:  &user.ID,
+			Action:  models.AuditPlanChange,
+			Target:  user.Email,
+			Outcome: models.AuditSuccess,
+			Detail:  detail,
+		})
+		log.Printf("💳 Plano de %s alterado: %s", user.Email, detail)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(toAdminUserInfo(user, time.Now()))
+	}
+}
+
+// SuspendUserHandler godoc
+// @Summary Suspend an account
+// @Description Suspends the account: its sessions are ended and its tokens and API keys are refused with 403 until it is unsuspended. Files stay stored and public links keep working. Administrators cannot suspend themselves. Administrators only.
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Param   id               path  string          true   "User ID"
+// @Param   suspend_request  body  SuspendRequest  false  "Reason shown to administrators"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} AdminUserInfo
+// @Failure 400 {string} string "Invalid request body or cannot suspend your own account"
+// @Failure 403 {string} string "Administrator access required"
+// @Failure 404 {string} string "User not found"
+// @Router /api/admin/users/{id}/suspend [post]
+func SuspendUserHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		admin, _ := r.Context().Value(middleware.UserContextKey).(*models.User)
+		user, ok := loadAdminTarget(w, r, db)
+		if !ok {
+			return
+		}
+		if user.ID == admin.ID {
+			http.Error(w, "You cannot suspend your own account", http.StatusBadRequest)
+			return
+		}
+
+		var req SuspendRequest
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+		}
+
+		now := time.Now()
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(user).Updates(map[string]interface{}{
+				"suspended_at":      now,
+				"suspension_reason": strings.TrimSpace(req.Reason),
+			}).Error; err != nil {
+				return err
+			}
+			// Desafios de 2FA pendentes não podem mais virar sessões
+			return tx.Where("user_id = ?", user.ID).Delete(&models.LoginChallenge{}).Error
+		})
+		if err != nil {
+			http.Error(w, "Could not suspend account: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		revoked := revokeAllSessions(db, user.ID)
+		middleware.RecordAudit(db, r, models.AuditEvent{
+			UserID:  &user.ID,
+			Action:  models.AuditAccountSuspend,
+			Target:  user.Email,
+			Outcome: models.AuditSuccess,
+			Detail:  strings.TrimSpace(req.Reason),
+		})
+		log.Printf("⛔ Conta suspensa: %s (%d sessão(ões) encerrada(s))", user.Email, revoked)
+		user.SuspendedAt, user.SuspensionReason = &now, strings.TrimSpace(req.Reason)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(toAdminUserInfo(user, now))
+	}
+}
+
+// UnsuspendUserHandler godoc
+// @Summary Unsuspend an account
+// @Description Lifts the suspension of an account. The user has to log in again. Administrators only.
+// @Tags admin
+// @Produce  json
+// @Param   id  path  string  true  "User ID"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} AdminUserInfo
+// @Failure 403 {string} string "Administrator access required"
+// @Failure 404 {string} string "User not found"
+// @Router /api/admin/users/{id}/unsuspend [post]
+func UnsuspendUserHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		user, ok := loadAdminTarget(w, r, db)
+		if !ok {
+			return
+		}
+
+		if err := db.Model(user).Updates(map[string]interface{}{
+			"suspended_at":      nil,
+			"suspension_reason": "",
+		}).Error; err != nil {
+			http.Error(w, "Could not unsuspend account: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		middleware.RecordAudit(db, r, models.AuditEvent{
+			UserID:  &user.ID,
+			Action:  models.AuditAccountResume,
+			Target:  user.Email,
+			Outcome: models.AuditSuccess,
+		})
+		user.SuspendedAt, user.SuspensionReason = nil, ""
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(toAdminUserInfo(user, time.Now()))
+	}
+}
+
+// dailySeries completa com zeros os dias sem registros, de since até hoje (UTC)
+func dailySeries(counts map[string]int64, since, now time.Time) []DailyCount {
+	series := make([]DailyCount, 0)
+	for day := since.UTC().Truncate(24 * time.Hour); !day.After(now.UTC()); day = day.AddDate(0, 0, 1) {
+		key := day.Format("2006-01-02")
+		series = append(series, DailyCount{Day: key, Count: counts[key]})
+	}
+	return series
+}
+
+// AdminStatsHandler godoc
+// @Summary Global statistics
+// @Description Returns global counts: users (verified, suspended, administrators), organizations, projects, files, stored bytes (logical, physical after deduplication and variant cache), files and bytes per MIME type, users per plan and signups per day over the last days (UTC). Administrators only.
+// @Tags admin
+// @Produce  json
+// @Param   days  query  int  false  "Days of signups to return (default 30, max 365)"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} AdminStatsResponse
+// @Failure 400 {string} string "Invalid days"
+// @Failure 403 {string} string "Administrator access required"
+// @Router /api/admin/stats [get]
+func AdminStatsHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		days := 30
+		if v := r.URL.Query().Get("days"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 || n > maxStatsDays {
+				http.Error(w, fmt.Sprintf("days must be between 1 and %d", maxStatsDays), http.StatusBadRequest)
+				return
+			}
+			days = n
+		}
+
+		var stats AdminStatsResponse
+		db.Model(&models.User{}).Count(&stats.Users)
+		db.Model(&models.User{}).Where("email_verified_at IS NOT NULL").Count(&stats.VerifiedUsers)
+		db.Model(&models.User{}).Where("suspended_at IS NOT NULL").Count(&stats.SuspendedUsers)
+		db.Model(&models.User{}).Where("is_admin = ?", true).Count(&stats.Admins)
+		db.Model(&models.Organization{}).Count(&stats.Organizations)
+		db.Model(&models.Project{}).Count(&stats.Projects)
+		db.Model(&models.File{}).Count(&stats.Files)
+		db.Model(&models.File{}).Select("COALESCE(sum(size), 0)").Row().Scan(&stats.LogicalBytes)
+		db.Model(&models.Blob{}).Select("COALESCE(sum(size), 0)").Row().Scan(&stats.PhysicalBytes)
+		db.Model(&models.Variant{}).Select("COALESCE(sum(size), 0)").Row().Scan(&stats.VariantBytes)
+
+		stats.FilesByMimeType = make([]MimeTypeStats, 0)
+		db.Model(&models.File{}).
+			Select("mime_type, count(*) AS files, COALESCE(sum(size), 0) AS bytes").
+			Group("mime_type").Order("files DESC, mime_type").
+			Scan(&stats.FilesByMimeType)
+
+		stats.UsersByPlan = make([]PlanStats, 0)
+		db.Model(&models.Plan{}).
+			Select("plans.name AS plan, count(users.id) AS users").
+			Joins("LEFT JOIN users ON users.plan_id = plans.id").
+			Group("plans.id, plans.name").Order("users DESC, plans.name").
+			Scan(&stats.UsersByPlan)
+
+		now := time.Now()
+		since := now.UTC().Truncate(24*time.Hour).AddDate(0, 0, -(days - 1))
+		var signups []DailyCount
+		db.Model(&models.User{}).
+			Select("to_char(created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD') AS day, count(*) AS count").
+			Where("created_at >= ?", since).
+			Group("day").
+			Scan(&signups)
+		counts := make(map[string]int64, len(signups))
+		for _, s := range signups {
+			counts[s.Day] = s.Count
+		}
+		stats.SignupsPerDay = dailySeries(counts, since, now)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(stats)
+	}
+}
@@ -1,11 +1,13 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"os"
+	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -17,26 +19,36 @@ import (
 	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/config"
 	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/middleware"
 	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/storage"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/util"
 )
 
 // --- Structs para Respostas ---
 
 type UploadResponse struct {
 	Message string `json:"message"`
-	URL     string `json:"url"`
+	URL     string `json:"url"` // Assinada e com validade limitada quando o projeto é privado
 	Project string `json:"project"`
 	File    string `json:"file"`
+	Status  string `json:"status"` // "pending" até o processamento em segundo plano terminar
 }
 
 type FileInfo struct {
-	Name       string    `json:"name"`
-	URL        string    `json:"url"`
-	Size       int64     `json:"size"`
-	UploadedAt time.Time `json:"uploaded_at"`
+	Name         string        `json:"name"`
+	URL          string        `json:"url"`
+	URLExpiresAt *time.Time    `json:"url_expires_at,omitempty"` // Apenas para projetos privados
+	Size         int64         `json:"size"`
+	Status       string        `json:"status"`                // pending, ready, failed ou quarantined
+	ScanResult   string        `json:"scan_result,omitempty"` // Assinatura encontrada em arquivos em quarentena
+	Downloads    int64         `json:"downloads"`             // Acessos em /files/; continuações de downloads por partes não contam
+	BytesServed  int64         `json:"bytes_served"`
+	UploadedAt   time.Time     `json:"uploaded_at"`
+	Variants     []VariantInfo `json:"variants,omitempty"` // Miniaturas e variantes já geradas de imagens
 }
 
 type ListResponse struct {
 	Project    string     `json:"project"`
+	Visibility string     `json:"visibility"`
 	Files      []FileInfo `json:"files"`
 	Total      int64      `json:"total"`
 	Page       int        `json:"page"`
@@ -45,9 +57,25 @@ type ListResponse struct {
 }
 
 type ProjectInfo struct {
-	Name      string `json:"name"`
-	FileCount int64  `json:"file_count"`
-	TotalSize int64  `json:"total_size"`
+	Name       string `json:"name"`
+	Visibility string `json:"visibility"`
+	FileCount  int64  `json:"file_count"`
+	TotalSize  int64  `json:"total_size"`
+	Role       string `json:"role"`            // owner nos próprios projetos, o papel na organização ou a permissão do colaborador (read, upload ou manage)
+	Owner      string `json:"owner,omitempty"` // E-mail do dono, apenas nos projetos compartilhados com o usuário
+}
+
+type VisibilityResponse struct {
+	Message    string `json:"message"`
+	Project    string `json:"project"`
+	Visibility string `json:"visibility"`
+}
+
+// UserStatusResponse inclui os campos de models.User e o consumo da cota diária
+type UserStatusResponse struct {
+	*models.User
+	UploadsToday          int `json:"uploads_today"`
+	DailyUploadsRemaining int `json:"daily_uploads_remaining"` // -1 quando o plano não tem limite diário
 }
 
 type ProjectsResponse struct {
@@ -95,61 +123,142 @@ func calculateTotalPages(total int64, perPage int) int {
 	return pages
 }
 
-const (
-	MaxUploadSize = 10 * 1024 * 1024 // 10 MB
-)
+// multipartOverhead é a folga para cabeçalhos e campos do corpo multipart além do arquivo
+const multipartOverhead = 1 * 1024 * 1024 // 1 MB
 
-var (
-	AllowedMimeTypes = map[string]bool{
-		"image/jpeg":      true,
-		"image/png":       true,
-		"application/pdf": true,
+// multipartMemory é quanto do corpo multipart fica em memória; o restante vai para disco
+const multipartMemory = 10 * 1024 * 1024 // 10 MB
+
+// formatBytes formata um tamanho em bytes para mensagens de erro (ex.: "10.0 MB")
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
 	}
-)
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
 
-// updateUserStorage atualiza o uso de armazenamento do usuário de forma transacional
-func updateUserStorage(db *gorm.DB, userID uuid.UUID, delta int64) error {
-	return db.Transaction(func(tx *gorm.DB) error {
-		// Bloqueia a linha do usuário para evitar race conditions
-		var user models.User
-		if err := tx.Clauses().Where("id = ?", userID).First(&user).Error; err != nil {
-			return fmt.Errorf("failed to fetch user: %w", err)
-		}
+// validateFileContent detecta o tipo real do arquivo pelos primeiros bytes e confere se
+// ele é permitido pelo plano e compatível com a extensão do nome enviado. O erro
+// retornado é a mensagem a ser respondida com 415.
+func validateFileContent(head []byte, filename string, plan *models.Plan) (string, error) {
+	detected := util.DetectMimeType(head)
+	if !plan.AllowsMimeType(detected) {
+		return "", fmt.Errorf("Invalid file type. Allowed types for your plan are: %s.", strings.Join(plan.MimeTypes(), ", "))
+	}
+	if !util.ExtensionMatchesMimeType(filename, detected) {
+		return "", fmt.Errorf("File content (%s) does not match its extension (%q).", detected, filepath.Ext(filename))
+	}
+	return detected, nil
+}
 
-		newStorageUsage := user.StorageUsage + delta
-		if newStorageUsage < 0 {
-			newStorageUsage = 0
+// storeUpload grava o conteúdo como um blob deduplicado, registra o models.File como
+// pendente, enfileira as etapas de processamento e atualiza o uso de armazenamento do
+// dono do namespace. É o caminho comum ao upload multipart e à finalização de uploads
+// resumíveis. hash pode vir vazio para ser calculado aqui.
+func storeUpload(ctx context.Context, db *gorm.DB, store storage.Driver, ns *namespace, projectName, originalName string, content io.ReadSeeker, size int64, mimeType, hash string) (*models.File, *models.Project, error) {
+	project, created, err := ns.firstOrCreateProject(db, projectName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not find or create project: %w", err)
+	}
+	if created {
+		notifyProjectCreated(db, project)
+	}
+
+	if hash == "" {
+		var err error
+		if hash, err = hashContent(content); err != nil {
+			return nil, nil, fmt.Errorf("Could not read file: %w", err)
 		}
+	}
+
+	timestamp := time.Now().Format("20060102-150405")
+	ext := filepath.Ext(originalName)
+	name := strings.TrimSuffix(originalName, ext)
+	safeName := fmt.Sprintf("%s-%s%s", name, timestamp, ext)
 
-		// Atualiza o StorageUsage usando o ID do usuário
-		if err := tx.Model(&models.User{}).Where("id = ?", userID).Update("storage_usage", newStorageUsage).Error; err != nil {
-			return fmt.Errorf("failed to update storage usage: %w", err)
+	owner := ns.owner()
+	blobID, physical, err := acquireBlob(ctx, db, store, owner, hash, content, size, mimeType)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// O caminho lógico é o que aparece na URL; o conteúdo fica no blob
+	dbFile := models.File{
+		Name:      safeName,
+		Path:      path.Join(owner.prefix(), project.Name, safeName),
+		Size:      size,
+		MimeType:  mimeType,
+		ProjectID: project.ID,
+		BlobID:    &blobID,
+		Status:    models.FileStatusPending,
+	}
+	// O arquivo e a primeira etapa do pipeline são gravados juntos, para que nenhum
+	// arquivo fique pendente sem processamento agendado
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&dbFile).Error; err != nil {
+			return err
 		}
+		return enqueueUploadPipeline(tx, dbFile.ID)
+	})
+	if err != nil {
+		// Evita blobs órfãos no armazenamento
+		db.Transaction(func(tx *gorm.DB) error {
+			_, err := releaseBlob(ctx, tx, store, blobID)
+			return err
+		})
+		return nil, nil, fmt.Errorf("Could not save file metadata: %w", err)
+	}
+
+	// Atualiza o uso de armazenamento do dono de forma segura
+	if err := owner.updateStorage(db, physical, size); err != nil {
+		// Log mas não falha a requisição, pois o arquivo já foi salvo
+		fmt.Printf("⚠️  Warning: Failed to update storage usage for %s: %v\n", owner, err)
+	} else {
+		fmt.Printf("✅ Storage updated for %s: +%d bytes (+%d logical)\n", owner, physical, size)
+	}
 
-		return nil
+	dispatchProjectEvent(db, project, models.EventFileUploaded, map[string]any{
+		"project":   project.Name,
+		"file":      dbFile.Name,
+		"size":      dbFile.Size,
+		"mime_type": dbFile.MimeType,
+		"status":    dbFile.Status,
+		"url":       fileURL(project, &dbFile),
 	})
+
+	return &dbFile, project, nil
 }
 
 // --- Handlers ---
 
 // UploadHandler godoc
 // @Summary Upload a file to a project
-// @Description Uploads a file to a specified project. If the project doesn't exist, it will be created.
+// @Description Uploads a file to a specified project. If the project doesn't exist, it will be created. With org, the project belongs to that organization (member role or higher) and the organization's plan applies instead of the user's. With owner, the file goes to a project that user shared with the caller (upload permission or higher; the project must exist) and the owner's plan applies. The plan's max file size, allowed types and daily upload quota apply. The file type is detected from its content (magic bytes) and must match the file extension; the Content-Type sent by the client is ignored.
 // @Tags api
 // @Accept  multipart/form-data
 // @Produce  json
 // @Param   project  formData  string  true  "Project name"
 // @Param   file     formData  file    true  "File to upload"
+// @Param   org      query     string  false "Organization slug (personal projects when omitted)"
+// @Param   owner    query     string  false "E-mail of the user who shared the project"
 // @Security BearerAuth
 // @Security APIKeyAuth
 // @Success 201 {object} UploadResponse "File uploaded successfully"
-// @Failure 400 {string} string "Bad Request: Error reading file or file is too large"
-// @Failure 403 {string} string "Storage limit exceeded"
-// @Failure 413 {string} string "File is too large. Max size is 10MB."
-// @Failure 415 {string} string "Invalid file type. Allowed types are: jpeg, png, pdf."
+// @Failure 400 {string} string "Bad Request: Error reading file"
+// @Failure 403 {string} string "Email address not verified, storage limit exceeded, organization role below member, share permission below upload or API key restricted to another project"
+// @Failure 404 {string} string "Organization not found or shared project not found"
+// @Failure 413 {string} string "File is larger than the plan's max file size"
+// @Failure 415 {string} string "File type not allowed by the plan (detected from the file content) or content does not match the file extension"
+// @Failure 429 {string} string "Daily upload limit reached for the plan or too many requests (see Retry-After)"
 // @Failure 500 {string} string "Internal Server Error"
 // @Router /api/upload [post]
-func UploadHandler(db *gorm.DB) http.HandlerFunc {
+func UploadHandler(db *gorm.DB, store storage.Driver) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userFromCtx, ok := r.Context().Value(middleware.UserContextKey).(*models.User)
 		if !ok {
@@ -164,10 +273,29 @@ func UploadHandler(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 
-		// Limita o tamanho do corpo da requisição
-		r.Body = http.MaxBytesReader(w, r.Body, MaxUploadSize)
-		if err := r.ParseMultipartForm(MaxUploadSize); err != nil {
-			http.Error(w, "File is too large. Max size is 10MB.", http.StatusBadRequest)
+		if !requireVerifiedEmail(w, &user) {
+			return
+		}
+
+		// Projetos de organizações usam o plano da organização e os compartilhados, o do
+		// dono. Os parâmetros org e owner vêm da query string, pois o corpo multipart só é
+		// lido depois do limite de tamanho.
+		ns, ok := queryNamespace(w, db, &user, r.URL.Query(), models.RoleMember)
+		if !ok {
+			return
+		}
+		plan := ns.plan()
+
+		// Limita o tamanho do corpo da requisição ao máximo por arquivo do plano
+		tooLarge := fmt.Sprintf("File is too large. Max size for your plan is %s.", formatBytes(plan.MaxFileSize))
+		r.Body = http.MaxBytesReader(w, r.Body, plan.MaxFileSize+multipartOverhead)
+		if err := r.ParseMultipartForm(multipartMemory); err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				http.Error(w, tooLarge, http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "Error reading multipart form: "+err.Error(), http.StatusBadRequest)
 			return
 		}
 
@@ -178,80 +306,97 @@ func UploadHandler(db *gorm.DB) http.HandlerFunc {
 		}
 		defer file.Close()
 
-		// Verificar limite de armazenamento
-		if user.StorageUsage+header.Size > user.Plan.StorageLimit {
-			http.Error(w, "Storage limit exceeded", http.StatusForbidden)
+		if header.Size > plan.MaxFileSize {
+			http.Error(w, tooLarge, http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		// Conteúdo repetido não ocupa espaço novo, então o hash vem antes do limite
+		hash, err := hashContent(file)
+		if err != nil {
+			http.Error(w, "Error reading file: "+err.Error(), http.StatusBadRequest)
 			return
 		}
 
 		project_name := r.FormValue("project")
 		project_name = sanitizeProjectName(project_name)
-
-		// Valida o MIME type
-		mimeType := header.Header.Get("Content-Type")
-		if !AllowedMimeTypes[mimeType] {
-			http.Error(w, "Invalid file type. Allowed types are: jpeg, png, pdf.", http.StatusUnsupportedMediaType)
+		uploadTarget := ns.target(fileTarget(project_name, header.Filename))
+		if !projectAllowed(db, r, ns, project_name) {
+			recordAudit(db, r, models.AuditFileUpload, uploadTarget, models.AuditFailure, "API key is restricted to another project")
+			writeProjectForbidden(w)
 			return
 		}
+		// Colaboradores só enviam para projetos que já existem, com a permissão upload
+		if ns.SharedBy != nil {
+			if _, ok := ns.requireProject(w, db, project_name, models.RoleMember); !ok {
+				return
+			}
+		}
 
-		var project models.Project
-		if err := db.FirstOrCreate(&project, models.Project{Name: project_name, UserID: user.ID}).Error; err != nil {
-			http.Error(w, "Could not find or create project: "+err.Error(), http.StatusInternalServerError)
+		if ns.readOnly() {
+			notifyQuotaExceeded(db, ns, project_name, "storage")
+			recordAudit(db, r, models.AuditFileUpload, uploadTarget, models.AuditFailure, "Storage usage above plan limit")
+			writeReadOnly(w)
 			return
 		}
 
-		timestamp := time.Now().Format("20060102-150405")
-		ext := filepath.Ext(header.Filename)
-		name := strings.TrimSuffix(header.Filename, ext)
-		safeName := fmt.Sprintf("%s-%s%s", name, timestamp, ext)
-
-		userDir := filepath.Join("./uploads", fmt.Sprintf("user_%s", user.ID.String()))
-		projectDir := filepath.Join(userDir, project.Name)
-		if err := os.MkdirAll(projectDir, os.ModePerm); err != nil {
-			http.Error(w, "Could not create project directory: "+err.Error(), http.StatusInternalServerError)
+		// Verificar limite de armazenamento, incluindo o espaço reservado por uploads resumíveis
+		owner := ns.owner()
+		if ns.storageUsage()+owner.reserved(db)+storageCost(db, owner, hash, header.Size) > plan.StorageLimit {
+			notifyQuotaExceeded(db, ns, project_name, "storage")
+			recordAudit(db, r, models.AuditFileUpload, uploadTarget, models.AuditFailure, "Storage limit exceeded")
+			http.Error(w, "Storage limit exceeded", http.StatusForbidden)
 			return
 		}
 
-		filePath := filepath.Join(projectDir, safeName)
-		dst, err := os.Create(filePath)
-		if err != nil {
-			http.Error(w, "Could not save file: "+err.Error(), http.StatusInternalServerError)
+		// Valida o MIME type pelos primeiros bytes do arquivo, pois o Content-Type
+		// da parte multipart é controlado pelo cliente
+		head := make([]byte, util.SniffLen)
+		n, err := io.ReadFull(file, head)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			http.Error(w, "Error reading file: "+err.Error(), http.StatusBadRequest)
 			return
 		}
-		defer dst.Close()
-
-		size, err := io.Copy(dst, file)
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			http.Error(w, "Error reading file: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		mimeType, err := validateFileContent(head[:n], header.Filename, plan)
 		if err != nil {
-			http.Error(w, "Error saving file content: "+err.Error(), http.StatusInternalServerError)
+			recordAudit(db, r, models.AuditFileUpload, uploadTarget, models.AuditFailure, err.Error())
+			http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
 			return
 		}
 
-		dbFile := models.File{
-			Name:      safeName,
-			Path:      filePath,
-			Size:      size,
-			MimeType:  header.Header.Get("Content-Type"),
-			ProjectID: project.ID,
+		// Consome a cota diária do plano somente depois de todas as validações. A cota é
+		// de cada usuário, com o limite do plano do namespace.
+		allowed, err := consumeDailyUpload(db, user.ID, plan)
+		if err != nil {
+			http.Error(w, "Could not check daily upload quota: "+err.Error(), http.StatusInternalServerError)
+			return
 		}
-		if err := db.Create(&dbFile).Error; err != nil {
-			http.Error(w, "Could not save file metadata: "+err.Error(), http.StatusInternalServerError)
+		if !allowed {
+			notifyQuotaExceeded(db, ns, project_name, "daily_uploads")
+			recordAudit(db, r, models.AuditFileUpload, uploadTarget, models.AuditFailure, "Daily upload limit reached")
+			writeDailyQuotaExceeded(w, plan)
 			return
 		}
 
-		// Atualiza o uso de armazenamento do usuário de forma segura
-		if err := updateUserStorage(db, user.ID, size); err != nil {
-			// Log mas não falha a requisição, pois o arquivo já foi salvo
-			fmt.Printf("⚠️  Warning: Failed to update storage usage for user %s: %v\n", user.ID, err)
-		} else {
-			fmt.Printf("✅ Storage updated for user %s: +%d bytes\n", user.ID, size)
+		dbFile, project, err := storeUpload(r.Context(), db, store, ns, project_name, header.Filename, file, header.Size, mimeType, hash)
+		if err != nil {
+			refundDailyUpload(db, user.ID, today())
+			recordAudit(db, r, models.AuditFileUpload, uploadTarget, models.AuditFailure, err.Error())
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
+		recordAudit(db, r, models.AuditFileUpload, ns.target(fileTarget(project.Name, dbFile.Name)), models.AuditSuccess, "")
 
-		publicURL := fmt.Sprintf("%s/files/user_%s/%s/%s", config.AppConfig.Domain, user.ID.String(), project.Name, safeName)
 		resp := UploadResponse{
 			Message: "File uploaded successfully",
-			URL:     publicURL,
+			URL:     fileURL(project, dbFile),
 			Project: project.Name,
-			File:    safeName,
+			File:    dbFile.Name,
+			Status:  dbFile.Status,
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -262,23 +407,64 @@ func UploadHandler(db *gorm.DB) http.HandlerFunc {
 
 // ProjectsHandler godoc
 // @Summary List user's projects
-// @Description Retrieves a paginated list of projects for the authenticated user.
+// @Description Retrieves a paginated list of the authenticated user's personal projects together with the projects other users shared with them, each with the caller's role (owner, or the share permission: read, upload or manage) and, for shared projects, the owner's e-mail. With org, lists an organization's projects (viewer role or higher); with owner, only the projects that user shared with the caller.
 // @Tags api
 // @Produce  json
-// @Param   page      query  int  false  "Page number for pagination"
-// @Param   per_page  query  int  false  "Number of items per page"
+// @Param   org       query  string  false  "Organization slug (personal projects when omitted)"
+// @Param   owner     query  string  false  "E-mail of the user who shared the projects"
+// @Param   page      query  int     false  "Page number for pagination"
+// @Param   per_page  query  int     false  "Number of items per page"
 // @Security BearerAuth
 // @Security APIKeyAuth
 // @Success 200 {object} ProjectsResponse
+// @Failure 400 {string} string "Use either org or owner, not both"
+// @Failure 404 {string} string "Organization not found or no projects shared by owner"
 // @Router /api/projects [get]
 func ProjectsHandler(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		user, _ := r.Context().Value(middleware.UserContextKey).(*models.User)
+		ns, ok := queryNamespace(w, db, user, r.URL.Query(), models.RoleViewer)
+		if !ok {
+			return
+		}
 		page, perPage := getPaginationParams(r)
 		offset := (page - 1) * perPage
 
+		scope := ns.projects(db)
+		if ns.Org == nil && ns.SharedBy == nil {
+			// Os projetos compartilhados com o usuário aparecem junto com os próprios
+			scope = db.Where("(user_id = ? AND organization_id IS NULL) OR id IN (?)", user.ID, acceptedShares(db, user.ID))
+		}
+		// Chaves restritas a um projeto enxergam apenas esse projeto
+		if key := requestAPIKey(r); key != nil && key.ProjectID != nil {
+			scope = scope.Where("id = ?", *key.ProjectID)
+		}
+
 		var projects []models.Project
-		db.Where("user_id = ?", user.ID).Limit(perPage).Offset(offset).Find(&projects)
+		scope.Session(&gorm.Session{}).Order("created_at").Limit(perPage).Offset(offset).Find(&projects)
+
+		// Permissão e dono dos projetos compartilhados da página
+		var sharedIDs, ownerIDs []uuid.UUID
+		for _, p := range projects {
+			if ns.Org == nil && p.UserID != user.ID {
+				sharedIDs = append(sharedIDs, p.ID)
+				ownerIDs = append(ownerIDs, p.UserID)
+			}
+		}
+		permissions := make(map[uuid.UUID]string)
+		owners := make(map[uuid.UUID]string)
+		if len(sharedIDs) > 0 {
+			var shares []models.ProjectMember
+			db.Where("user_id = ? AND project_id IN ?", user.ID, sharedIDs).Find(&shares)
+			for _, share := range shares {
+				permissions[share.ProjectID] = share.Permission
+			}
+			var users []models.User
+			db.Select("id", "email").Where("id IN ?", ownerIDs).Find(&users)
+			for _, u := range users {
+				owners[u.ID] = u.Email
+			}
+		}
 
 		// Inicializa como slice vazio em vez de nil
 		projectInfos := make([]ProjectInfo, 0)
@@ -289,15 +475,22 @@ func ProjectsHandler(db *gorm.DB) http.HandlerFunc {
 			db.Model(&models.File{}).Where("project_id = ?", p.ID).Count(&fileCount)
 			db.Model(&models.File{}).Select("sum(size)").Where("project_id = ?", p.ID).Row().Scan(&totalSize)
 
-			projectInfos = append(projectInfos, ProjectInfo{
-				Name:      p.Name,
-				FileCount: fileCount,
-				TotalSize: totalSize,
-			})
+			info := ProjectInfo{
+				Name:       p.Name,
+				Visibility: projectVisibility(&p),
+				FileCount:  fileCount,
+				TotalSize:  totalSize,
+				Role:       ns.Role,
+			}
+			if ns.Org == nil && p.UserID != user.ID {
+				info.Role = permissions[p.ID]
+				info.Owner = owners[p.UserID]
+			}
+			projectInfos = append(projectInfos, info)
 		}
 
 		var totalProjects int64
-		db.Model(&models.Project{}).Where("user_id = ?", user.ID).Count(&totalProjects)
+		scope.Session(&gorm.Session{}).Model(&models.Project{}).Count(&totalProjects)
 
 		totalPages := calculateTotalPages(totalProjects, perPage)
 
@@ -314,17 +507,20 @@ func ProjectsHandler(db *gorm.DB) http.HandlerFunc {
 
 // ListHandler godoc
 // @Summary List files in a project
-// @Description Retrieves a paginated list of files within a specified project for the authenticated user.
+// @Description Retrieves a paginated list of files within a specified project for the authenticated user, within an organization's project with org (viewer role or higher) or within a project another user shared with the caller with owner (read permission or higher). Files in private projects are returned with signed URLs that expire after the default signed URL lifetime. Images include the URLs of their generated thumbnails and variants. Each file has a status: pending while post-upload processing runs, then ready, failed or quarantined (malware found; the file is no longer served). downloads and bytes_served count how often each file was served from /files/, variants included.
 // @Tags api
 // @Produce  json
 // @Param   project   query  string  true  "Project name"
+// @Param   org       query  string  false "Organization slug (personal projects when omitted)"
+// @Param   owner     query  string  false "E-mail of the user who shared the project"
 // @Param   page      query  int     false "Page number for pagination"
 // @Param   per_page  query  int     false "Number of items per page"
 // @Security BearerAuth
 // @Security APIKeyAuth
 // @Success 200 {object} ListResponse
 // @Failure 400 {string} string "Project name is required"
-// @Failure 404 {string} string "Project not found"
+// @Failure 403 {string} string "API key is restricted to another project"
+// @Failure 404 {string} string "Project or organization not found"
 // @Router /api/list [get]
 func ListHandler(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -335,29 +531,70 @@ func ListHandler(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 
+		ns, ok := queryNamespace(w, db, user, r.URL.Query(), models.RoleViewer)
+		if !ok {
+			return
+		}
+		if !projectAllowed(db, r, ns, projectName) {
+			writeProjectForbidden(w)
+			return
+		}
+
 		page, perPage := getPaginationParams(r)
 		offset := (page - 1) * perPage
 
-		var project models.Project
-		if err := db.First(&project, "name = ? AND user_id = ?", projectName, user.ID).Error; err != nil {
-			http.Error(w, "Project not found", http.StatusNotFound)
+		project, ok := ns.requireProject(w, db, projectName, models.RoleViewer)
+		if !ok {
 			return
 		}
 
 		var files []models.File
 		db.Where("project_id = ?", project.ID).Limit(perPage).Offset(offset).Find(&files)
 
+		// Carrega as variantes da página de uma vez
+		fileIDs := make([]uuid.UUID, 0, len(files))
+		for _, f := range files {
+			fileIDs = append(fileIDs, f.ID)
+		}
+		variantsByFile := make(map[uuid.UUID][]models.Variant)
+		if len(fileIDs) > 0 {
+			var variants []models.Variant
+			db.Where("file_id IN ?", fileIDs).Order("width, height").Find(&variants)
+			for _, v := range variants {
+				variantsByFile[v.FileID] = append(variantsByFile[v.FileID], v)
+			}
+		}
+
 		// Inicializa como slice vazio em vez de nil
 		fileInfos := make([]FileInfo, 0)
-		domain := config.AppConfig.Domain
 
+		// Em projetos privados as URLs são assinadas e expiram após SignedURLTTL
 		for _, f := range files {
-			fileInfos = append(fileInfos, FileInfo{
-				Name:       f.Name,
-				URL:        fmt.Sprintf("%s/files/user_%s/%s/%s", domain, user.ID.String(), projectName, f.Name),
-				Size:       f.Size,
-				UploadedAt: f.UploadedAt,
-			})
+			info := FileInfo{
+				Name:        f.Name,
+				Size:        f.Size,
+				Status:      f.Status,
+				ScanResult:  f.ScanResult,
+				Downloads:   f.Downloads,
+				BytesServed: f.BytesServed,
+				UploadedAt:  f.UploadedAt,
+			}
+			if project.IsPrivate() {
+				url, expiresAt := signedFileURL(f.Path, config.AppConfig.SignedURLTTL)
+				info.URL, info.URLExpiresAt = url, &expiresAt
+			} else {
+				info.URL = publicFileURL(f.Path)
+			}
+			for _, v := range variantsByFile[f.ID] {
+				info.Variants = append(info.Variants, VariantInfo{
+					Width:  v.Width,
+					Height: v.Height,
+					Fit:    v.Fit,
+					Size:   v.Size,
+					URL:    variantURL(info.URL, v),
+				})
+			}
+			fileInfos = append(fileInfos, info)
 		}
 
 		var totalFiles int64
@@ -368,6 +605,7 @@ func ListHandler(db *gorm.DB) http.HandlerFunc {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(ListResponse{
 			Project:    projectName,
+			Visibility: projectVisibility(project),
 			Files:      fileInfos,
 			Total:      totalFiles,
 			Page:       page,
@@ -379,33 +617,29 @@ func ListHandler(db *gorm.DB) http.HandlerFunc {
 
 // DeleteHandler godoc
 // @Summary Delete a file
-// @Description Deletes a specific file from a project.
+// @Description Deletes a specific file from a project, along with its resized image variants. In an organization's project (org) the member role or higher is required; in a project shared by another user (owner), the upload permission or higher.
 // @Tags api
 // @Produce  json
 // @Param   project  query  string  true  "Project name"
 // @Param   file     query  string  true  "File name"
+// @Param   org      query  string  false "Organization slug (personal projects when omitted)"
+// @Param   owner    query  string  false "E-mail of the user who shared the project"
 // @Security BearerAuth
 // @Security APIKeyAuth
 // @Success 200 {object} map[string]string "message: File deleted successfully"
 // @Failure 400 {string} string "'project' and 'file' parameters are required"
-// @Failure 404 {string} string "Project not found or File not found"
+// @Failure 403 {string} string "API key is restricted to another project, organization role below member or share permission below upload"
+// @Failure 404 {string} string "Organization not found, Project not found or File not found"
 // @Failure 500 {string} string "Could not delete file metadata"
 // @Router /api/delete [delete]
-func DeleteHandler(db *gorm.DB) http.HandlerFunc {
+func DeleteHandler(db *gorm.DB, store, cache storage.Driver) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		userFromCtx, ok := r.Context().Value(middleware.UserContextKey).(*models.User)
+		user, ok := r.Context().Value(middleware.UserContextKey).(*models.User)
 		if !ok {
 			http.Error(w, "Could not retrieve user from context", http.StatusInternalServerError)
 			return
 		}
 
-		// Carrega o usuário completo para obter o uso de armazenamento atual
-		var user models.User
-		if err := db.First(&user, userFromCtx.ID).Error; err != nil {
-			http.Error(w, "Could not retrieve user details", http.StatusInternalServerError)
-			return
-		}
-
 		projectName := r.URL.Query().Get("project")
 		fileName := r.URL.Query().Get("file")
 
@@ -414,9 +648,19 @@ func DeleteHandler(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 
-		var project models.Project
-		if err := db.First(&project, "name = ? AND user_id = ?", projectName, user.ID).Error; err != nil {
-			http.Error(w, "Project not found", http.StatusNotFound)
+		ns, ok := queryNamespace(w, db, user, r.URL.Query(), models.RoleMember)
+		if !ok {
+			return
+		}
+		target := ns.target(fileTarget(projectName, fileName))
+		if !projectAllowed(db, r, ns, projectName) {
+			recordAudit(db, r, models.AuditFileDelete, target, models.AuditFailure, "API key is restricted to another project")
+			writeProjectForbidden(w)
+			return
+		}
+
+		project, ok := ns.requireProject(w, db, projectName, models.RoleMember)
+		if !ok {
 			return
 		}
 
@@ -426,25 +670,26 @@ func DeleteHandler(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 
-		fileSize := file.Size
-
-		// Deleta o arquivo do filesystem
-		if err := os.Remove(file.Path); err != nil {
-			// Logar o erro, mas continuar para remover do DB
-			fmt.Printf("Could not delete file from filesystem: %s\n", err.Error())
-		}
-
-		// Deleta o registro do banco de dados
-		if err := db.Delete(&file).Error; err != nil {
+		// Remove o registro e as variantes e libera o blob quando esta for a última referência
+		freed, logical, err := deleteFile(r.Context(), db, store, cache, &file)
+		if err != nil {
+			recordAudit(db, r, models.AuditFileDelete, target, models.AuditFailure, err.Error())
 			http.Error(w, "Could not delete file metadata: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
+		recordAudit(db, r, models.AuditFileDelete, target, models.AuditSuccess, "")
+		dispatchProjectEvent(db, project, models.EventFileDeleted, map[string]any{
+			"project": project.Name,
+			"file":    file.Name,
+			"size":    file.Size,
+		})
 
-		// Atualiza o uso de armazenamento do usuário
-		if err := updateUserStorage(db, user.ID, -fileSize); err != nil {
-			fmt.Printf("⚠️  Warning: Failed to update storage usage for user %s: %v\n", user.ID, err)
+		// Atualiza o uso de armazenamento do dono do projeto
+		owner := projectOwner(project)
+		if err := owner.updateStorage(db, -freed, -logical); err != nil {
+			fmt.Printf("⚠️  Warning: Failed to update storage usage for %s: %v\n", owner, err)
 		} else {
-			fmt.Printf("✅ Storage updated for user %s: -%d bytes\n", user.ID, fileSize)
+			fmt.Printf("✅ Storage updated for %s: -%d bytes (-%d logical)\n", owner, freed, logical)
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -457,13 +702,14 @@ func DeleteHandler(db *gorm.DB) http.HandlerFunc {
 }
 
 // RotateAPIKeyHandler godoc
-// @Summary Rotate user's API key
-// @Description Generates a new API key for the authenticated user, invalidating the old one.
+// @Summary Rotate user's default API key
+// @Description Generates a new secret for the user's "default" API key (the one created at registration), invalidating the old one. The key is created if it no longer exists. The new key is returned only once. Use /api/keys/{id}/rotate for other keys. The default key has full access, so keys restricted to a project cannot rotate it.
 // @Tags api
 // @Produce  json
 // @Security BearerAuth
 // @Security APIKeyAuth
 // @Success 200 {object} map[string]string "message: API key rotated successfully, new_api_key: ..."
+// @Failure 403 {string} string "API key is restricted to another project"
 // @Failure 500 {string} string "Could not rotate API key"
 // @Router /api/user/rotate-api-key [post]
 func RotateAPIKeyHandler(db *gorm.DB) http.HandlerFunc {
@@ -473,12 +719,26 @@ func RotateAPIKeyHandler(db *gorm.DB) http.HandlerFunc {
 			http.Error(w, "Could not retrieve user from context", http.StatusInternalServerError)
 			return
 		}
+		// A chave padrão não tem restrição de projeto: uma chave restrita não pode obtê-la
+		if !requireUnrestrictedKey(w, r) {
+			return
+		}
 
-		newAPIKey := uuid.New().String()
-		if err := db.Model(&user).Update("forge_api_key", newAPIKey).Error; err != nil {
+		var newAPIKey string
+		var key models.APIKey
+		err := db.First(&key, "user_id = ? AND name = ?", user.ID, defaultAPIKeyName).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			key = models.APIKey{UserID: user.ID, Name: defaultAPIKeyName, Scopes: models.ScopeAdmin}
+			newAPIKey, err = createAPIKey(db, &key)
+		} else if err == nil {
+			newAPIKey, err = rotateAPIKey(db, &key)
+		}
+		if err != nil {
+			recordAudit(db, r, models.AuditAPIKeyRotate, defaultAPIKeyName, models.AuditFailure, err.Error())
 			http.Error(w, "Could not rotate API key: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
+		recordAudit(db, r, models.AuditAPIKeyRotate, defaultAPIKeyName+" ("+key.Prefix+")", models.AuditSuccess, "")
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{
@@ -490,15 +750,18 @@ func RotateAPIKeyHandler(db *gorm.DB) http.HandlerFunc {
 
 // DeleteProjectHandler godoc
 // @Summary Delete an empty project
-// @Description Deletes a project that has no files. Projects with files cannot be deleted.
+// @Description Deletes a project that has no files. Projects with files cannot be deleted. In an organization's project (org) the admin role or higher is required; in a project shared by another user (owner), the manage permission.
 // @Tags api
 // @Produce  json
 // @Param   project  query  string  true  "Project name"
+// @Param   org      query  string  false "Organization slug (personal projects when omitted)"
+// @Param   owner    query  string  false "E-mail of the user who shared the project"
 // @Security BearerAuth
 // @Security APIKeyAuth
 // @Success 200 {object} map[string]string "message: Project deleted successfully"
 // @Failure 400 {string} string "Project name is required or Project has files and cannot be deleted"
-// @Failure 404 {string} string "Project not found"
+// @Failure 403 {string} string "API key is restricted to another project, organization role below admin or share permission below manage"
+// @Failure 404 {string} string "Project or organization not found"
 // @Failure 500 {string} string "Could not delete project"
 // @Router /api/project/delete [delete]
 func DeleteProjectHandler(db *gorm.DB) http.HandlerFunc {
@@ -511,9 +774,19 @@ func DeleteProjectHandler(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 
-		var project models.Project
-		if err := db.First(&project, "name = ? AND user_id = ?", projectName, user.ID).Error; err != nil {
-			http.Error(w, "Project not found", http.StatusNotFound)
+		ns, ok := queryNamespace(w, db, user, r.URL.Query(), models.RoleAdmin)
+		if !ok {
+			return
+		}
+		target := ns.target(projectName)
+		if !projectAllowed(db, r, ns, projectName) {
+			recordAudit(db, r, models.AuditProjectDelete, target, models.AuditFailure, "API key is restricted to another project")
+			writeProjectForbidden(w)
+			return
+		}
+
+		project, ok := ns.requireProject(w, db, projectName, models.RoleAdmin)
+		if !ok {
 			return
 		}
 
@@ -527,14 +800,24 @@ func DeleteProjectHandler(db *gorm.DB) http.HandlerFunc {
 		}
 
 		// Deletar o projeto
-		if err := db.Delete(&project).Error; err != nil {
+		if err := db.Delete(project).Error; err != nil {
+			recordAudit(db, r, models.AuditProjectDelete, target, models.AuditFailure, err.Error())
 			http.Error(w, "Could not delete project: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
-
-		// Tentar remover o diretório vazio
-		projectDir := filepath.Join("./uploads", fmt.Sprintf("user_%s", user.ID.String()), projectName)
-		os.Remove(projectDir) // Ignora erro se não existir
+		recordAudit(db, r, models.AuditProjectDelete, target, models.AuditSuccess, "")
+		// Os webhooks restritos ao projeto foram apagados junto com ele; o evento chega
+		// apenas aos webhooks de todos os projetos de quem o apagou ou, nos projetos
+		// compartilhados, aos do dono
+		data := map[string]any{"project": project.Name}
+		recipient := user.ID
+		if ns.Org != nil {
+			data["org"] = ns.Org.Slug
+		}
+		if ns.SharedBy != nil {
+			recipient = ns.SharedBy.ID
+		}
+		dispatchEvent(db, recipient, nil, models.EventProjectDeleted, data)
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{
@@ -544,14 +827,82 @@ func DeleteProjectHandler(db *gorm.DB) http.HandlerFunc {
 	}
 }
 
+// ProjectVisibilityHandler godoc
+// @Summary Set a project's visibility
+// @Description Makes a project public (files served by direct URL) or private (files only reachable through signed, expiring URLs). The project is created if it does not exist yet, so it can be made private before the first upload. In an organization (org) the admin role or higher is required.
+// @Tags api
+// @Produce  json
+// @Param   project     query  string  true  "Project name"
+// @Param   visibility  query  string  true  "public or private"
+// @Param   org         query  string  false "Organization slug (personal projects when omitted)"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} VisibilityResponse
+// @Failure 400 {string} string "Project name is required or invalid visibility"
+// @Failure 403 {string} string "API key is restricted to another project or organization role below admin"
+// @Failure 404 {string} string "Organization not found"
+// @Failure 500 {string} string "Could not update project visibility"
+// @Router /api/project/visibility [post]
+func ProjectVisibilityHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost && r.Method != http.MethodPut {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		user, _ := r.Context().Value(middleware.UserContextKey).(*models.User)
+		projectName := r.URL.Query().Get("project")
+		visibility := r.URL.Query().Get("visibility")
+
+		if projectName == "" {
+			http.Error(w, "Project name is required", http.StatusBadRequest)
+			return
+		}
+		if visibility != models.VisibilityPublic && visibility != models.VisibilityPrivate {
+			http.Error(w, "Visibility must be 'public' or 'private'", http.StatusBadRequest)
+			return
+		}
+
+		projectName = sanitizeProjectName(projectName)
+		ns, ok := requestNamespace(w, db, user, r.URL.Query().Get("org"), models.RoleAdmin)
+		if !ok {
+			return
+		}
+		if !projectAllowed(db, r, ns, projectName) {
+			writeProjectForbidden(w)
+			return
+		}
+
+		project, created, err := ns.firstOrCreateProject(db, projectName)
+		if err != nil {
+			http.Error(w, "Could not find or create project: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if created {
+			notifyProjectCreated(db, project)
+		}
+		if err := db.Model(project).Update("visibility", visibility).Error; err != nil {
+			http.Error(w, "Could not update project visibility: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(VisibilityResponse{
+			Message:    "Project visibility updated",
+			Project:    project.Name,
+			Visibility: visibility,
+		})
+	}
+}
+
 // UserStatusHandler godoc
 // @Summary Get user status
-// @Description Retrieves the current user's information, including plan and storage usage.
+// @Description Retrieves the current user's information, including plan, storage usage and the remaining daily upload quota.
 // @Tags api
 // @Produce  json
 // @Security BearerAuth
 // @Security APIKeyAuth
-// @Success 200 {object} models.User "User status"
+// @Success 200 {object} UserStatusResponse "User status"
 // @Failure 500 {string} string "Could not retrieve user details"
 // @Router /api/user/status [get]
 func UserStatusHandler(db *gorm.DB) http.HandlerFunc {
@@ -568,7 +919,14 @@ func UserStatusHandler(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 
+		// Não retornar a senha
+		user.Password = ""
+
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(user)
+		json.NewEncoder(w).Encode(UserStatusResponse{
+			User:                  &user,
+			UploadsToday:          uploadsToday(db, user.ID),
+			DailyUploadsRemaining: remainingDailyUploads(db, user.ID, &user.Plan),
+		})
 	}
-}
\ No newline at end of file
+}
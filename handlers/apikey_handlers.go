@@ -0,0 +1,411 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/middleware"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/util"
+)
+
+type APIKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	Project   string     `json:"project,omitempty"`    // Restringe a chave a um projeto existente
+	Org       string     `json:"org,omitempty"`        // Organização do projeto; projetos pessoais quando omitido
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // Sem expiração quando omitido
+}
+
+type APIKeyInfo struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	Project    string     `json:"project,omitempty"`
+	Org        string     `json:"org,omitempty"` // Slug da organização dona do projeto
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type APIKeyCreatedResponse struct {
+	APIKeyInfo
+	Key string `json:"key"` // Exibida apenas nesta resposta
+}
+
+type APIKeysResponse struct {
+	Keys []APIKeyInfo `json:"keys"`
+}
+
+// requestAPIKey retorna a chave de API usada na requisição, ou nil para sessões JWT
+func requestAPIKey(r *http.Request) *models.APIKey {
+	key, _ := r.Context().Value(middleware.APIKeyContextKey).(*models.APIKey)
+	return key
+}
+
+// projectAllowed verifica se a chave de API da requisição, quando restrita a um
+// projeto, permite acessar o projeto informado pelo nome no namespace
+func projectAllowed(db *gorm.DB, r *http.Request, ns *namespace, projectName string) bool {
+	key := requestAPIKey(r)
+	if key == nil || key.ProjectID == nil {
+		return true
+	}
+	project, err := ns.findProject(db, projectName)
+	if err != nil {
+		return false
+	}
+	return project.ID == *key.ProjectID
+}
+
+// writeProjectForbidden responde 403 para chaves restritas a outro projeto
+func writeProjectForbidden(w http.ResponseWriter) {
+	http.Error(w, "API key is restricted to another project", http.StatusForbidden)
+}
+
+// validateScopes confere se todos os escopos são conhecidos e remove duplicados
+func validateScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("At least one scope is required. Valid scopes are: %s.", strings.Join(models.AllScopes, ", "))
+	}
+	valid := make([]string, 0, len(scopes))
+	for _, s := range scopes {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if !slices.Contains(models.AllScopes, s) {
+			return nil, fmt.Errorf("Unknown scope %q. Valid scopes are: %s.", s, strings.Join(models.AllScopes, ", "))
+		}
+		if !slices.Contains(valid, s) {
+			valid = append(valid, s)
+		}
+	}
+	return valid, nil
+}
+
+// defaultAPIKeyName é o nome da chave criada no registro e trocada por /api/user/rotate-api-key
+const defaultAPIKeyName = "default"
+
+// createAPIKey gera e grava uma nova chave; o valor completo só existe no retorno
+func createAPIKey(db *gorm.DB, key *models.APIKey) (string, error) {
+	plainKey, prefix, hash, err := util.GenerateAPIKey()
+	if err != nil {
+		return "", err
+	}
+	key.Prefix = prefix
+	key.KeyHash = hash
+	if err := db.Create(key).Error; err != nil {
+		return "", err
+	}
+	return plainKey, nil
+}
+
+// rotateAPIKey troca o segredo de uma chave mantendo nome, escopos e restrições.
+// A chave anterior deixa de funcionar imediatamente.
+func rotateAPIKey(db *gorm.DB, key *models.APIKey) (string, error) {
+	plainKey, prefix, hash, err := util.GenerateAPIKey()
+	if err != nil {
+		return "", err
+	}
+	if err := db.Model(key).Updates(map[string]interface{}{
+		"prefix":       prefix,
+		"key_hash":     hash,
+		"last_used_at": nil,
+	}).Error; err != nil {
+		return "", err
+	}
+	key.Prefix, key.KeyHash, key.LastUsedAt = prefix, hash, nil
+	return plainKey, nil
+}
+
+// toAPIKeyInfo converte o modelo para a resposta da API, sem o hash
+func toAPIKeyInfo(db *gorm.DB, key *models.APIKey) APIKeyInfo {
+	info := APIKeyInfo{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.ScopeList(),
+		LastUsedAt: key.LastUsedAt,
+		ExpiresAt:  key.ExpiresAt,
+		CreatedAt:  key.CreatedAt,
+	}
+	if key.ProjectID != nil {
+		info.Project, info.Org = projectLabel(db, *key.ProjectID)
+	}
+	return info
+}
+
+// APIKeysHandler godoc
+// @Summary List or create API keys
+// @Description GET lists the user's API keys (only the prefix of each key is shown). POST creates a named key with the given scopes (upload, read, delete, admin), optionally restricted to one project and with an expiry. Keys for organization projects (org) must be restricted to the project and act with the user's current role in the organization. The full key is returned only once, in the creation response.
+// @Tags keys
+// @Accept  json
+// @Produce  json
+// @Param   key_request  body  APIKeyRequest  false  "Key name, scopes, optional project, org and expiry (POST only)"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} APIKeysResponse "API keys"
+// @Success 201 {object} APIKeyCreatedResponse "API key created"
+// @Failure 400 {string} string "Invalid request body, name, scopes or expiry"
+// @Failure 403 {string} string "API key lacks the 'admin' scope"
+// @Failure 404 {string} string "Project or organization not found"
+// @Failure 500 {string} string "Could not create API key"
+// @Router /api/keys [get]
+// @Router /api/keys [post]
+func APIKeysHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, _ := r.Context().Value(middleware.UserContextKey).(*models.User)
+
+		switch r.Method {
+		case http.MethodGet:
+			var keys []models.APIKey
+			db.Where("user_id = ?", user.ID).Order("created_at").Find(&keys)
+
+			infos := make([]APIKeyInfo, 0, len(keys))
+			for i := range keys {
+				infos = append(infos, toAPIKeyInfo(db, &keys[i]))
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(APIKeysResponse{Keys: infos})
+
+		case http.MethodPost:
+			var req APIKeyRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+
+			req.Name = strings.TrimSpace(req.Name)
+			if req.Name == "" || len(req.Name) > 100 {
+				http.Error(w, "Name must be between 1 and 100 characters.", http.StatusBadRequest)
+				return
+			}
+			scopes, err := validateScopes(req.Scopes)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+				http.Error(w, "expires_at must be in the future", http.StatusBadRequest)
+				return
+			}
+
+			key := models.APIKey{
+				UserID:    user.ID,
+				Name:      req.Name,
+				Scopes:    strings.Join(scopes, ","),
+				ExpiresAt: req.ExpiresAt,
+			}
+
+			// O papel do usuário na organização é conferido a cada uso da chave, então
+			// qualquer membro pode criá-la
+			ns, ok := requestNamespace(w, db, user, req.Org, models.RoleViewer)
+			if !ok {
+				return
+			}
+			current := requestAPIKey(r)
+			restricted := current != nil && current.ProjectID != nil
+			if ns.Org != nil && req.Project == "" && !restricted {
+				http.Error(w, "Keys for an organization must be restricted to one of its projects", http.StatusBadRequest)
+				return
+			}
+
+			// Uma chave restrita a um projeto só pode criar chaves para o mesmo projeto
+			if restricted {
+				key.ProjectID = current.ProjectID
+				if !ns.contains(db, *current.ProjectID) || req.Project != "" && !projectAllowed(db, r, ns, req.Project) {
+					writeProjectForbidden(w)
+					return
+				}
+			} else if req.Project != "" {
+				project, err := ns.findProject(db, req.Project)
+				if err != nil {
+					http.Error(w, "Project not found", http.StatusNotFound)
+					return
+				}
+				key.ProjectID = &project.ID
+			}
+
+			plainKey, err := createAPIKey(db, &key)
+			if err != nil {
+				http.Error(w, "Could not create API key: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(APIKeyCreatedResponse{
+				APIKeyInfo: toAPIKeyInfo(db, &key),
+				Key:        plainKey,
+			})
+
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// APIKeyHandler godoc
+// @Summary Get, update or delete an API key
+// @Description GET returns one key. PATCH changes its name, scopes or expiry (the project restriction cannot be changed). DELETE revokes the key immediately.
+// @Tags keys
+// @Accept  json
+// @Produce  json
+// @Param   id           path  string         true   "API key ID"
+// @Param   key_request  body  APIKeyRequest  false  "Fields to change (PATCH only)"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} APIKeyInfo
+// @Failure 400 {string} string "Invalid request body, name, scopes or expiry"
+// @Failure 403 {string} string "API key lacks the 'admin' scope"
+// @Failure 404 {string} string "API key not found"
+// @Failure 500 {string} string "Could not update API key"
+// @Router /api/keys/{id} [get]
+// @Router /api/keys/{id} [patch]
+// @Router /api/keys/{id} [delete]
+func APIKeyHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, _ := r.Context().Value(middleware.UserContextKey).(*models.User)
+
+		id, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			http.Error(w, "API key not found", http.StatusNotFound)
+			return
+		}
+		var key models.APIKey
+		if err := db.First(&key, "id = ? AND user_id = ?", id, user.ID).Error; err != nil {
+			http.Error(w, "API key not found", http.StatusNotFound)
+			return
+		}
+
+		// Uma chave restrita a um projeto só gerencia chaves do mesmo projeto
+		if current := requestAPIKey(r); current != nil && current.ProjectID != nil &&
+			(key.ProjectID == nil || *key.ProjectID != *current.ProjectID) {
+			http.Error(w, "API key not found", http.StatusNotFound)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(toAPIKeyInfo(db, &key))
+
+		case http.MethodPatch:
+			var req APIKeyRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+
+			updates := map[string]interface{}{}
+			if name := strings.TrimSpace(req.Name); name != "" {
+				if len(name) > 100 {
+					http.Error(w, "Name must be between 1 and 100 characters.", http.StatusBadRequest)
+					return
+				}
+				updates["name"] = name
+			}
+			if req.Scopes != nil {
+				scopes, err := validateScopes(req.Scopes)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				updates["scopes"] = strings.Join(scopes, ",")
+			}
+			if req.ExpiresAt != nil {
+				if !req.ExpiresAt.After(time.Now()) {
+					http.Error(w, "expires_at must be in the future", http.StatusBadRequest)
+					return
+				}
+				updates["expires_at"] = *req.ExpiresAt
+			}
+
+			if len(updates) > 0 {
+				if err := db.Model(&key).Updates(updates).Error; err != nil {
+					http.Error(w, "Could not update API key: "+err.Error(), http.StatusInternalServerError)
+					return
+				}
+				db.First(&key, "id = ?", key.ID)
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(toAPIKeyInfo(db, &key))
+
+		case http.MethodDelete:
+			if err := db.Delete(&key).Error; err != nil {
+				http.Error(w, "Could not delete API key: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]string{
+				"message": "API key deleted successfully",
+				"id":      key.ID.String(),
+			})
+
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// RotateAPIKeyByIDHandler godoc
+// @Summary Rotate an API key
+// @Description Replaces the secret of an API key, keeping its name, scopes, project and expiry. The old secret stops working immediately. The new key is returned only once.
+// @Tags keys
+// @Produce  json
+// @Param   id  path  string  true  "API key ID"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} APIKeyCreatedResponse
+// @Failure 403 {string} string "API key lacks the 'admin' scope"
+// @Failure 404 {string} string "API key not found"
+// @Failure 500 {string} string "Could not rotate API key"
+// @Router /api/keys/{id}/rotate [post]
+func RotateAPIKeyByIDHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		user, _ := r.Context().Value(middleware.UserContextKey).(*models.User)
+
+		id, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			http.Error(w, "API key not found", http.StatusNotFound)
+			return
+		}
+		var key models.APIKey
+		if err := db.First(&key, "id = ? AND user_id = ?", id, user.ID).Error; err != nil {
+			http.Error(w, "API key not found", http.StatusNotFound)
+			return
+		}
+		if current := requestAPIKey(r); current != nil && current.ProjectID != nil &&
+			(key.ProjectID == nil || *key.ProjectID != *current.ProjectID) {
+			http.Error(w, "API key not found", http.StatusNotFound)
+			return
+		}
+
+		plainKey, err := rotateAPIKey(db, &key)
+		if err != nil {
+			recordAudit(db, r, models.AuditAPIKeyRotate, key.Name, models.AuditFailure, err.Error())
+			http.Error(w, "Could not rotate API key: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		recordAudit(db, r, models.AuditAPIKeyRotate, key.Name+" ("+key.Prefix+")", models.AuditSuccess, "")
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(APIKeyCreatedResponse{
+			APIKeyInfo: toAPIKeyInfo(db, &key),
+			Key:        plainKey,
+		})
+	}
+}
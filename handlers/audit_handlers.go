@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/middleware"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+type AuditEventInfo struct {
+	ID        uuid.UUID `json:"id"`
+	Action    string    `json:"action"`
+	Target    string    `json:"target,omitempty"`
+	Outcome   string    `json:"outcome"`
+	Detail    string    `json:"detail,omitempty"`
+	Actor     string    `json:"actor"`
+	APIKeyID  string    `json:"api_key_id,omitempty"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type AuditResponse struct {
+	Events     []AuditEventInfo `json:"events"`
+	Total      int64            `json:"total"`
+	Page       int              `json:"page"`
+	PerPage    int              `json:"per_page"`
+	TotalPages int              `json:"total_pages"`
+}
+
+// recordAudit registra uma ação do usuário da requisição no log de auditoria
+func recordAudit(db *gorm.DB, r *http.Request, action, target, outcome, detail string) {
+	middleware.RecordAudit(db, r, models.AuditEvent{
+		Action:  action,
+		Target:  target,
+		Outcome: outcome,
+		Detail:  detail,
+	})
+}
+
+// fileTarget identifica um arquivo no log de auditoria como "<projeto>/<arquivo>"
+func fileTarget(projectName, fileName string) string {
+	return path.Join(projectName, fileName)
+}
+
+func toAuditEventInfo(e *models.AuditEvent) AuditEventInfo {
+	info := AuditEventInfo{
+		ID:        e.ID,
+		Action:    e.Action,
+		Target:    e.Target,
+		Outcome:   e.Outcome,
+		Detail:    e.Detail,
+		Actor:     e.Actor,
+		IP:        e.IP,
+		UserAgent: e.UserAgent,
+		CreatedAt: e.CreatedAt,
+	}
+	if e.APIKeyID != nil {
+		info.APIKeyID = e.APIKeyID.String()
+	}
+	return info
+}
+
+// auditQuery monta a consulta dos eventos do usuário com os filtros action, outcome,
+// since e until (RFC 3339) da query string
+func auditQuery(db *gorm.DB, r *http.Request, userID uuid.UUID) (*gorm.DB, error) {
+	q := r.URL.Query()
+	query := db.Model(&models.AuditEvent{}).Where("user_id = ?", userID)
+	if action := q.Get("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if outcome := q.Get("outcome"); outcome != "" {
+		if outcome != models.AuditSuccess && outcome != models.AuditFailure {
+			return nil, fmt.Errorf("outcome must be '%s' or '%s'", models.AuditSuccess, models.AuditFailure)
+		}
+		query = query.Where("outcome = ?", outcome)
+	}
+	for _, bound := range []struct{ param, cond string }{{"since", "created_at >= ?"}, {"until", "created_at < ?"}} {
+		raw := q.Get(bound.param)
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be an RFC 3339 timestamp", bound.param)
+		}
+		query = query.Where(bound.cond, t)
+	}
+	return query, nil
+}
+
+// auditAllowed recusa chaves de API restritas a um projeto, já que o log cobre a conta toda
+func auditAllowed(w http.ResponseWriter, r *http.Request) bool {
+	if current := requestAPIKey(r); current != nil && current.ProjectID != nil {
+		http.Error(w, "API keys restricted to a project cannot read the audit log", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// AuditHandler godoc
+// @Summary List audit events
+// @Description Returns the audit log of the authenticated user's account, newest first: uploads, deletions, API key rotations, failed logins and rejected requests, with the actor, IP, user agent, target and outcome of each.
+// @Tags audit
+// @Produce  json
+// @Param   action    query  string  false  "Only events of this action (e.g. file.upload, auth.login)"
+// @Param   outcome   query  string  false  "success or failure"
+// @Param   since     query  string  false  "Only events at or after this RFC 3339 timestamp"
+// @Param   until     query  string  false  "Only events before this RFC 3339 timestamp"
+// @Param   page      query  int     false  "Page number for pagination"
+// @Param   per_page  query  int     false  "Number of items per page"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} AuditResponse
+// @Failure 400 {string} string "Invalid filter"
+// @Failure 403 {string} string "API key lacks the 'admin' scope or is restricted to a project"
+// @Router /api/audit [get]
+func AuditHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if !auditAllowed(w, r) {
+			return
+		}
+
+		user, _ := r.Context().Value(middleware.UserContextKey).(*models.User)
+		query, err := auditQuery(db, r, user.ID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var total int64
+		query.Session(&gorm.Session{}).Count(&total)
+
+		page, perPage := getPaginationParams(r)
+		var events []models.AuditEvent
+		query.Order("created_at DESC").Limit(perPage).Offset((page - 1) * perPage).Find(&events)
+
+		infos := make([]AuditEventInfo, 0, len(events))
+		for i := range events {
+			infos = append(infos, toAuditEventInfo(&events[i]))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(AuditResponse{
+			Events:     infos,
+			Total:      total,
+			Page:       page,
+			PerPage:    perPage,
+			TotalPages: calculateTotalPages(total, perPage),
+		})
+	}
+}
+
+// AuditExportHandler godoc
+// @Summary Export audit events as JSON Lines
+// @Description Streams every audit event matching the filters as JSON Lines (one JSON object per line), oldest first, for archiving or ingestion into a SIEM.
+// @Tags audit
+// @Produce  application/x-ndjson
+// @Param   action   query  string  false  "Only events of this action"
+// @Param   outcome  query  string  false  "success or failure"
+// @Param   since    query  string  false  "Only events at or after this RFC 3339 timestamp"
+// @Param   until    query  string  false  "Only events before this RFC 3339 timestamp"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {string} string "One AuditEventInfo per line"
+// @Failure 400 {string} string "Invalid filter"
+// @Failure 403 {string} string "API key lacks the 'admin' scope or is restricted to a project"
+// @Failure 500 {string} string "Could not export audit log"
+// @Router /api/audit/export [get]
+func AuditExportHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if !auditAllowed(w, r) {
+			return
+		}
+
+		user, _ := r.Context().Value(middleware.UserContextKey).(*models.User)
+		query, err := auditQuery(db, r, user.ID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Lê linha a linha para não carregar o log inteiro em memória
+		rows, err := query.Order("created_at").Rows()
+		if err != nil {
+			http.Error(w, "Could not export audit log: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="audit-%s.jsonl"`, time.Now().Format("20060102")))
+		enc := json.NewEncoder(w)
+		for rows.Next() {
+			var event models.AuditEvent
+			if err := db.ScanRows(rows, &event); err != nil {
+				// A resposta já começou; a exportação fica truncada
+				return
+			}
+			if err := enc.Encode(toAuditEventInfo(&event)); err != nil {
+				return
+			}
+		}
+	}
+}
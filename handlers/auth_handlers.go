@@ -2,13 +2,18 @@ package handlers
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
 	"net/mail"
 	"regexp"
+	"time"
 	"unicode"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/mailer"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/middleware"
 	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
 	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/util"
 )
@@ -26,10 +31,18 @@ type LoginRequest struct {
 }
 
 type AuthResponse struct {
-	Message     string       `json:"message"`
-	Token       string       `json:"token,omitempty"`
-	User        *models.User `json:"user,omitempty"`
-	ForgeAPIKey string       `json:"forge_api_key,omitempty"`
+	Message               string       `json:"message"`
+	Token                 string       `json:"token,omitempty"`
+	ExpiresIn             int          `json:"expires_in,omitempty"` // Validade do access token, em segundos
+	RefreshToken          string       `json:"refresh_token,omitempty"`
+	RefreshTokenExpiresAt *time.Time   `json:"refresh_token_expires_at,omitempty"`
+	User                  *models.User `json:"user,omitempty"`
+	ForgeAPIKey           string       `json:"forge_api_key,omitempty"` // Apenas no registro; não é recuperável depois
+
+	// Login com 2FA: em vez dos tokens, um desafio a ser trocado em /login/2fa
+	TwoFactorRequired  bool       `json:"two_factor_required,omitempty"`
+	ChallengeToken     string     `json:"challenge_token,omitempty"`
+	ChallengeExpiresAt *time.Time `json:"challenge_expires_at,omitempty"`
 }
 
 // isValidName checks if the name has between 3 and 100 characters.
@@ -87,16 +100,17 @@ func isValidPassword(password string) (bool, string) {
 
 // RegisterHandler godoc
 // @Summary Register a new user
-// @Description Creates a new user account and returns the user info along with an API key.
+// @Description Creates a new user account and returns the user info along with its initial API key. The key is shown only in this response; only its hash is stored. A verification link is sent to the e-mail address; uploads are blocked until it is confirmed at /verify-email.
 // @Tags auth
 // @Accept  json
 // @Produce  json
 // @Param   auth_request  body  AuthRequest  true  "User registration details (name, email, password, whatsapp_number)"
 // @Success 201 {object} AuthResponse "User created successfully"
 // @Failure 400 {string} string "Invalid request body or missing fields"
+// @Failure 429 {string} string "Too many requests (see Retry-After)"
 // @Failure 500 {string} string "Could not create user or find default plan"
 // @Router /register [post]
-func RegisterHandler(db *gorm.DB) http.HandlerFunc {
+func RegisterHandler(db *gorm.DB, m mailer.Mailer) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			w.WriteHeader(http.StatusMethodNotAllowed)
@@ -129,7 +143,7 @@ func RegisterHandler(db *gorm.DB) http.HandlerFunc {
 
 		// Encontrar o plano "Free"
 		var freePlan models.Plan
-		if err := db.Where("name = ?", "Free").First(&freePlan).Error; err != nil {
+		if err := db.Where("name = ?", models.FreePlanName).First(&freePlan).Error; err != nil {
 			http.Error(w, "Could not find default plan", http.StatusInternalServerError)
 			return
 		}
@@ -142,12 +156,27 @@ func RegisterHandler(db *gorm.DB) http.HandlerFunc {
 			PlanID:         freePlan.ID,
 		}
 
-		// O hook BeforeCreate irá gerar a API key e hashear a senha
-		if err := db.Create(user).Error; err != nil {
+		// O hook BeforeCreate irá hashear a senha; a chave de API inicial é criada na
+		// mesma transação e só é exibida nesta resposta
+		var apiKey string
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(user).Error; err != nil {
+				return err
+			}
+			var err error
+			apiKey, err = createAPIKey(tx, &models.APIKey{UserID: user.ID, Name: defaultAPIKeyName, Scopes: models.ScopeAdmin})
+			return err
+		})
+		if err != nil {
 			http.Error(w, "Could not create user: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
 
+		// A conta é criada mesmo se o e-mail falhar; o usuário pode pedir outro link
+		if err := sendVerificationEmail(db, m, user); err != nil {
+			log.Printf("⚠️  Warning: Failed to send verification email to %s: %v", user.Email, err)
+		}
+
 		// Carregar o usuário com o plano para a resposta
 		db.Preload("Plan").First(&user, "id = ?", user.ID)
 
@@ -162,21 +191,23 @@ func RegisterHandler(db *gorm.DB) http.HandlerFunc {
 		json.NewEncoder(w).Encode(AuthResponse{
 			Message:     "User created successfully",
 			User:        user,
-			ForgeAPIKey: user.ForgeAPIKey,
+			ForgeAPIKey: apiKey,
 		})
 	}
 }
 
 // LoginHandler godoc
 // @Summary Log in a user
-// @Description Authenticates a user using email and password, then returns a JWT token.
+// @Description Authenticates a user using email and password, then returns a short-lived JWT access token and a refresh token. Use /token/refresh to obtain new access tokens and /logout to end the session. Repeated failures lock the account: temporarily (the lock doubles each time) and, after more failures, permanently until an administrator unlocks it. A locked account answers 401 like wrong credentials, so the lock does not reveal which emails are registered. Failures from one IP across accounts also block that IP for a while. For accounts with two-factor authentication the response has no tokens: it sets two_factor_required and returns a challenge_token to be sent with a code to /login/2fa.
 // @Tags auth
 // @Accept  json
 // @Produce  json
 // @Param   auth_request  body  LoginRequest  true  "User login credentials (email and password)"
 // @Success 200 {object} AuthResponse "Logged in successfully"
 // @Failure 400 {string} string "Invalid request body"
-// @Failure 401 {string} string "Invalid credentials"
+// @Failure 401 {string} string "Invalid credentials (also returned while the account is locked)"
+// @Failure 403 {string} string "Account suspended"
+// @Failure 429 {string} string "Too many requests or too many failed logins from this address (see Retry-After)"
 // @Failure 500 {string} string "Could not generate token"
 // @Router /login [post]
 func LoginHandler(db *gorm.DB) http.HandlerFunc {
@@ -198,20 +229,76 @@ func LoginHandler(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 
+		// IPs com falhas demais em qualquer conta ficam temporariamente impedidos de tentar
+		now := time.Now()
+		ip := util.ClientIP(r)
+		if until := ipLockedUntil(db, ip, now); until != nil {
+			writeIPLocked(w, *until, now)
+			return
+		}
+
 		var user models.User
-		if err := db.Preload("Plan").Preload("Projects").First(&user, "email = ?", req.Email).Error; err != nil {
+		if err := db.Preload("Plan").Preload("Projects", "organization_id IS NULL").First(&user, "email = ?", req.Email).Error; err != nil {
+			middleware.RecordAudit(db, r, models.AuditEvent{
+				Actor: req.Email, Action: models.AuditLogin, Outcome: models.AuditFailure, Detail: "Unknown email",
+			})
+			recordLoginFailure(db, r, nil, ip, now)
+			http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+			return
+		}
+
+		// Contas bloqueadas não têm a senha conferida, para que o bloqueio interrompa a força
+		// bruta. A resposta é a mesma de um e-mail desconhecido, para que o bloqueio não
+		// revele quais e-mails estão cadastrados; o bloqueio fica só no log de auditoria.
+		if userLocked(&user, now) {
+			middleware.RecordAudit(db, r, models.AuditEvent{
+				UserID: &user.ID, Actor: req.Email, Action: models.AuditLogin, Outcome: models.AuditFailure, Detail: "Account locked",
+			})
+			recordLoginFailure(db, r, nil, ip, now)
 			http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 			return
 		}
 
 		// Verifica a senha
 		if !user.CheckPassword(req.Password) {
+			// Registrado na conta do usuário, que pode ver as tentativas em /api/audit
+			middleware.RecordAudit(db, r, models.AuditEvent{
+				UserID: &user.ID, Actor: req.Email, Action: models.AuditLogin, Outcome: models.AuditFailure, Detail: "Wrong password",
+			})
+			recordLoginFailure(db, r, &user, ip, now)
 			http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 			return
 		}
 
-		// Gera o token JWT
-		token, err := util.GenerateJWT(&user)
+		// A suspensão só é revelada a quem acertou a senha
+		if user.SuspendedAt != nil {
+			middleware.RecordAudit(db, r, models.AuditEvent{
+				UserID: &user.ID, Actor: req.Email, Action: models.AuditLogin, Outcome: models.AuditFailure, Detail: "Account suspended",
+			})
+			http.Error(w, "Account suspended", http.StatusForbidden)
+			return
+		}
+
+		// Com 2FA ativo a senha só libera o desafio; as falhas continuam contando até o código
+		if user.TOTPEnabledAt != nil {
+			challenge, expiresAt, err := startTwoFactorLogin(db, &user, now)
+			if err != nil {
+				http.Error(w, "Could not generate token", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(AuthResponse{
+				Message:            "Two-factor authentication required",
+				TwoFactorRequired:  true,
+				ChallengeToken:     challenge,
+				ChallengeExpiresAt: &expiresAt,
+			})
+			return
+		}
+		resetLoginFailures(db, &user)
+
+		// Gera o access token e o refresh token de uma nova sessão
+		tokens, err := issueTokens(db, r, &user, uuid.New(), time.Now())
 		if err != nil {
 			http.Error(w, "Could not generate token", http.StatusInternalServerError)
 			return
@@ -225,10 +312,12 @@ func LoginHandler(db *gorm.DB) http.HandlerFunc {
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(AuthResponse{
-			Message:     "Logged in successfully",
-			Token:       token,
-			User:        &user,
-			ForgeAPIKey: user.ForgeAPIKey,
+			Message:               "Logged in successfully",
+			Token:                 tokens.Token,
+			ExpiresIn:             tokens.ExpiresIn,
+			RefreshToken:          tokens.RefreshToken,
+			RefreshTokenExpiresAt: &tokens.RefreshTokenExpiresAt,
+			User:                  &user,
 		})
 	}
 }
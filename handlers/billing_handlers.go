@@ -0,0 +1,308 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/billing"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/config"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/middleware"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+type DailyUsage struct {
+	Day          string `json:"day"` // YYYY-MM-DD (UTC)
+	StorageBytes int64  `json:"storage_bytes"`
+	EgressBytes  int64  `json:"egress_bytes"`
+}
+
+type UsageResponse struct {
+	Month          string         `json:"month"`  // YYYY-MM
+	Closed         bool           `json:"closed"` // Mês fechado: os valores são os faturados
+	Plan           string         `json:"plan"`
+	Currency       string         `json:"currency"`
+	Days           int            `json:"days"` // Dias com snapshot de armazenamento
+	StorageGBHours float64        `json:"storage_gb_hours"`
+	AverageStorage int64          `json:"average_storage"`
+	PeakStorage    int64          `json:"peak_storage"`
+	EgressBytes    int64          `json:"egress_bytes"`
+	IncludedEgress int64          `json:"included_egress"`
+	EgressLimit    int64          `json:"egress_limit"` // 0 = ilimitado
+	Overage        []billing.Line `json:"overage"`      // Estimado enquanto o mês está aberto
+	OverageTotal   float64        `json:"overage_total"`
+	Daily          []DailyUsage   `json:"daily"`
+}
+
+type InvoiceInfo struct {
+	ID          uuid.UUID      `json:"id"`
+	Number      string         `json:"number"`
+	PeriodStart time.Time      `json:"period_start"`
+	PeriodEnd   time.Time      `json:"period_end"` // Exclusivo
+	Currency    string         `json:"currency"`
+	Lines       []billing.Line `json:"lines"`
+	Total       float64        `json:"total"`
+	AmountDue   float64        `json:"amount_due"` // Excedente cobrado com a fatura
+	Status      string         `json:"status"`     // paid ou open (cobrança recusada)
+	PDFURL      string         `json:"pdf_url"`
+	CreatedAt   time.Time      `json:"created_at"`
+}
+
+type InvoicesResponse struct {
+	Invoices   []InvoiceInfo `json:"invoices"`
+	Total      int64         `json:"total"`
+	Page       int           `json:"page"`
+	PerPage    int           `json:"per_page"`
+	TotalPages int           `json:"total_pages"`
+}
+
+func toInvoiceInfo(invoice *models.Invoice) InvoiceInfo {
+	info := InvoiceInfo{
+		ID:          invoice.ID,
+		Number:      invoice.Number,
+		PeriodStart: invoice.PeriodStart,
+		PeriodEnd:   invoice.PeriodEnd,
+		Currency:    invoice.Currency,
+		Lines:       []billing.Line{},
+		Total:       invoice.Total,
+		AmountDue:   invoice.AmountDue,
+		Status:      invoice.Status,
+		PDFURL:      fmt.Sprintf("%s/api/billing/invoices/%s/pdf", config.AppConfig.Domain, invoice.ID),
+		CreatedAt:   invoice.CreatedAt,
+	}
+	json.Unmarshal([]byte(invoice.Lines), &info.Lines)
+	return info
+}
+
+// dailyUsage lista, dia a dia, o armazenamento e o tráfego do dono no mês até hoje
+func dailyUsage(db *gorm.DB, owner storageOwner, month, now time.Time) []DailyUsage {
+	end := month.AddDate(0, 1, 0)
+	type dayBytes struct {
+		Day   time.Time
+		Bytes int64
+	}
+	var snapshots, egress []dayBytes
+	owner.storageSnapshots(db).Select("day, storage_bytes AS bytes").Where("day >= ? AND day < ?", month, end).Scan(&snapshots)
+	owner.dailyEgress(db).Select("day, bytes").Where("day >= ? AND day < ?", month, end).Scan(&egress)
+
+	storageByDay := make(map[string]int64, len(snapshots))
+	for _, s := range snapshots {
+		storageByDay[s.Day.Format("2006-01-02")] = s.Bytes
+	}
+	egressByDay := make(map[string]int64, len(egress))
+	for _, e := range egress {
+		egressByDay[e.Day.Format("2006-01-02")] = e.Bytes
+	}
+
+	days := []DailyUsage{}
+	for day := month; day.Before(end) && !day.After(now); day = day.AddDate(0, 0, 1) {
+		key := day.Format("2006-01-02")
+		days = append(days, DailyUsage{Day: key, StorageBytes: storageByDay[key], EgressBytes: egressByDay[key]})
+	}
+	return days
+}
+
+// UsageHandler godoc
+// @Summary Get monthly usage
+// @Description Returns the storage and data transfer of a month: daily storage snapshots, storage in GB-hours, bytes served from /files/ and the overage above the plan. Open months are estimated with the current plan; closed months show what was invoiced. With org, returns the usage of the organization's projects against its plan; organizations are not invoiced, so there is no overage.
+// @Tags billing
+// @Produce  json
+// @Param   month  query  string  false  "Month as YYYY-MM (defaults to the current month, UTC)"
+// @Param   org    query  string  false  "Organization slug (the user's own usage when omitted)"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} UsageResponse
+// @Failure 400 {string} string "month must be in the YYYY-MM format and not in the future"
+// @Failure 404 {string} string "Organization not found"
+// @Failure 500 {string} string "Could not compute usage"
+// @Router /api/billing/usage [get]
+func UsageHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		user, ok := currentUser(w, r, db)
+		if !ok {
+			return
+		}
+
+		now := time.Now().UTC()
+		month := monthStart(now)
+		if v := r.URL.Query().Get("month"); v != "" {
+			parsed, err := time.Parse("2006-01", v)
+			if err != nil || parsed.After(month) {
+				http.Error(w, "month must be in the YYYY-MM format and not in the future", http.StatusBadRequest)
+				return
+			}
+			month = parsed
+		}
+
+		ns, ok := requestNamespace(w, db, user, r.URL.Query().Get("org"), models.RoleViewer)
+		if !ok {
+			return
+		}
+		owner := ns.owner()
+
+		// Só os meses dos usuários são fechados; as organizações têm sempre o uso apurado
+		var record models.UsageRecord
+		err := gorm.ErrRecordNotFound
+		if ns.Org == nil {
+			err = db.Preload("Plan").First(&record, "user_id = ? AND month = ?", user.ID, month).Error
+		}
+		closed := err == nil
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			var live *models.UsageRecord
+			live, err = aggregateUsage(db, owner, ns.plan(), month)
+			if live != nil {
+				record = *live
+			}
+		}
+		if err != nil {
+			http.Error(w, "Could not compute usage", http.StatusInternalServerError)
+			return
+		}
+
+		// Organizações não são faturadas, então não têm excedente
+		var overage []billing.Line
+		if ns.Org == nil {
+			overage = overageLines(&record)
+		}
+		resp := UsageResponse{
+			Month:          month.Format("2006-01"),
+			Closed:         closed,
+			Plan:           record.Plan.Name,
+			Currency:       config.AppConfig.BillingCurrency,
+			Days:           record.Days,
+			StorageGBHours: float64(record.StorageByteHours) / billing.GB,
+			PeakStorage:    record.PeakStorage,
+			EgressBytes:    record.EgressBytes,
+			IncludedEgress: record.Plan.IncludedEgress,
+			EgressLimit:    record.Plan.EgressLimit,
+			Overage:        append([]billing.Line{}, overage...),
+			Daily:          dailyUsage(db, owner, month, now),
+		}
+		if record.Days > 0 {
+			resp.AverageStorage = record.StorageByteHours / 24 / int64(record.Days)
+		}
+		for _, l := range overage {
+			resp.OverageTotal += l.Amount
+		}
+		resp.OverageTotal = billing.RoundAmount(resp.OverageTotal)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// InvoicesHandler godoc
+// @Summary List invoices
+// @Description Lists the user's monthly invoices, newest first. An invoice is issued when a month closes and lists the plan payments made in the month plus the storage and data transfer overage, which is charged with the invoice.
+// @Tags billing
+// @Produce  json
+// @Param   page      query  int  false  "Page number"
+// @Param   per_page  query  int  false  "Number of items per page"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} InvoicesResponse
+// @Router /api/billing/invoices [get]
+func InvoicesHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		user, _ := r.Context().Value(middleware.UserContextKey).(*models.User)
+		page, perPage := getPaginationParams(r)
+
+		query := db.Model(&models.Invoice{}).Where("user_id = ?", user.ID)
+		var total int64
+		query.Count(&total)
+		var invoices []models.Invoice
+		query.Order("period_start DESC").Offset((page - 1) * perPage).Limit(perPage).Find(&invoices)
+
+		infos := make([]InvoiceInfo, 0, len(invoices))
+		for i := range invoices {
+			infos = append(infos, toInvoiceInfo(&invoices[i]))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(InvoicesResponse{
+			Invoices:   infos,
+			Total:      total,
+			Page:       page,
+			PerPage:    perPage,
+			TotalPages: calculateTotalPages(total, perPage),
+		})
+	}
+}
+
+// InvoiceHandler godoc
+// @Summary Get an invoice
+// @Description Returns one of the user's invoices with its lines.
+// @Tags billing
+// @Produce  json
+// @Param   id  path  string  true  "Invoice ID"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} InvoiceInfo
+// @Failure 404 {string} string "Invoice not found"
+// @Router /api/billing/invoices/{id} [get]
+func InvoiceHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		user, _ := r.Context().Value(middleware.UserContextKey).(*models.User)
+		invoice, err := findInvoice(db, user.ID, r.PathValue("id"))
+		if err != nil {
+			http.Error(w, "Invoice not found", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(toInvoiceInfo(invoice))
+	}
+}
+
+// InvoicePDFHandler godoc
+// @Summary Download an invoice as PDF
+// @Description Renders one of the user's invoices as a PDF document.
+// @Tags billing
+// @Produce  application/pdf
+// @Param   id  path  string  true  "Invoice ID"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {file} file "Invoice PDF"
+// @Failure 404 {string} string "Invoice not found"
+// @Failure 500 {string} string "Could not render invoice"
+// @Router /api/billing/invoices/{id}/pdf [get]
+func InvoicePDFHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		user, _ := r.Context().Value(middleware.UserContextKey).(*models.User)
+		invoice, err := findInvoice(db, user.ID, r.PathValue("id"))
+		if err != nil {
+			http.Error(w, "Invoice not found", http.StatusNotFound)
+			return
+		}
+		doc, err := invoiceDocument(user, invoice)
+		if err != nil {
+			http.Error(w, "Could not render invoice", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.pdf"`, invoice.Number))
+		doc.WritePDF(w)
+	}
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/storage"
+)
+
+// hashContent calcula o SHA-256 do conteúdo e volta ao início para que ele possa ser
+// gravado em seguida
+func hashContent(content io.ReadSeeker) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, content); err != nil {
+		return "", err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// blobKey monta a chave de um blob no armazenamento: <dono>/blobs/<2 primeiros>/<hash>,
+// onde o dono é user_<id> ou org_<id>
+func blobKey(owner storageOwner, hash string) string {
+	return path.Join(owner.prefix(), "blobs", hash[:2], hash)
+}
+
+// storageCost retorna quantos bytes físicos um conteúdo ocuparia: zero quando o dono
+// já tem um blob com o mesmo hash
+func storageCost(db *gorm.DB, owner storageOwner, hash string, size int64) int64 {
+	var count int64
+	owner.blobs(db).Where("hash = ?", hash).Count(&count)
+	if count > 0 {
+		return 0
+	}
+	return size
+}
+
+// acquireBlob garante que o conteúdo esteja no armazenamento e registra mais uma
+// referência ao blob. Retorna o blob e quantos bytes físicos foram acrescentados
+// (zero quando o conteúdo já existia).
+func acquireBlob(ctx context.Context, db *gorm.DB, store storage.Driver, owner storageOwner, hash string, content io.Reader, size int64, mimeType string) (uuid.UUID, int64, error) {
+	key := blobKey(owner, hash)
+
+	stored := false
+	if storageCost(db, owner, hash, size) > 0 {
+		if err := store.Put(ctx, key, content, size, mimeType); err != nil {
+			return uuid.Nil, 0, fmt.Errorf("Could not save file: %w", err)
+		}
+		stored = true
+	}
+
+	// Um único comando cria o blob ou incrementa a referência, então uploads simultâneos
+	// do mesmo conteúdo não criam linhas duplicadas. O alvo do conflito é o índice
+	// parcial do dono: por usuário ou por organização.
+	conflict := "(user_id, hash) WHERE organization_id IS NULL"
+	if owner.OrgID != nil {
+		conflict = "(organization_id, hash) WHERE organization_id IS NOT NULL"
+	}
+	var blobID uuid.UUID
+	var inserted bool
+	err := db.Raw(`INSERT INTO blobs (id, user_id, organization_id, hash, size, mime_type, storage_key, ref_count, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, 1, ?)
+		ON CONFLICT `+conflict+` DO UPDATE SET ref_count = blobs.ref_count + 1
+		RETURNING id, (xmax = 0) AS inserted`,
+		uuid.New(), owner.UserID, owner.OrgID, hash, size, mimeType, key, time.Now()).Row().Scan(&blobID, &inserted)
+	if err != nil {
+		return uuid.Nil, 0, fmt.Errorf("Could not save file metadata: %w", err)
+	}
+
+	if inserted && !stored {
+		// A última referência foi apagada entre a consulta e o upsert, levando o objeto junto
+		if err := store.Put(ctx, key, content, size, mimeType); err != nil {
+			db.Transaction(func(tx *gorm.DB) error {
+				_, err := releaseBlob(ctx, tx, store, blobID)
+				return err
+			})
+			return uuid.Nil, 0, fmt.Errorf("Could not save file: %w", err)
+		}
+	}
+
+	if inserted {
+		return blobID, size, nil
+	}
+	return blobID, 0, nil
+}
+
+// releaseBlob remove uma referência ao blob e, se era a última, apaga o objeto do
+// armazenamento. Deve ser chamada dentro de uma transação: a linha fica bloqueada até o
+// fim, de modo que um upload simultâneo do mesmo conteúdo espere e grave o objeto de
+// novo. Retorna quantos bytes físicos foram liberados.
+func releaseBlob(ctx context.Context, tx *gorm.DB, store storage.Driver, blobID uuid.UUID) (int64, error) {
+	var blob models.Blob
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&blob, "id = ?", blobID).Error; err != nil {
+		return 0, err
+	}
+
+	if blob.RefCount > 1 {
+		return 0, tx.Model(&blob).Update("ref_count", gorm.Expr("ref_count - 1")).Error
+	}
+
+	if err := store.Delete(ctx, blob.StorageKey); err != nil && !errors.Is(err, storage.ErrNotExist) {
+		return 0, err
+	}
+	if err := tx.Delete(&blob).Error; err != nil {
+		return 0, err
+	}
+	return blob.Size, nil
+}
+
+// deleteFile apaga o registro do arquivo, suas variantes e libera seu conteúdo. Retorna
+// quantos bytes físicos deixaram de ser ocupados (zero para o conteúdo enquanto outro
+// arquivo usar o mesmo blob) e quantos bytes saem do uso lógico.
+func deleteFile(ctx context.Context, db *gorm.DB, store, cache storage.Driver, file *models.File) (int64, int64, error) {
+	var freed int64
+	var variants []models.Variant
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("file_id = ?", file.ID).Find(&variants).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(file).Error; err != nil {
+			return err
+		}
+
+		if file.BlobID != nil {
+			var err error
+			freed, err = releaseBlob(ctx, tx, store, *file.BlobID)
+			return err
+		}
+
+		// Arquivos anteriores à deduplicação ficam no caminho do próprio arquivo
+		if err := store.Delete(ctx, file.Path); err != nil && !errors.Is(err, storage.ErrNotExist) {
+			// Logar o erro, mas continuar para remover do DB
+			log.Printf("Could not delete file from storage: %s", err.Error())
+		}
+		freed = file.Size
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	deleteVariants(ctx, cache, variants)
+	logical := file.Size
+	for _, v := range variants {
+		freed += v.Size
+		logical += v.Size
+	}
+	return freed, logical, nil
+}
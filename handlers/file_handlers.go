@@ -0,0 +1,340 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/config"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/middleware"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/storage"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/util"
+)
+
+type SignResponse struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Project   string    `json:"project"`
+	File      string    `json:"file"`
+}
+
+// publicFileURL monta a URL direta de um arquivo servido em /files/
+func publicFileURL(key string) string {
+	return fmt.Sprintf("%s/files/%s", config.AppConfig.Domain, key)
+}
+
+// signedFileURL monta a URL de um arquivo com assinatura HMAC válida por ttl
+func signedFileURL(key string, ttl time.Duration) (string, time.Time) {
+	expiresAt := time.Now().Add(ttl).UTC().Truncate(time.Second)
+	return publicFileURL(key) + "?" + util.SignURLPath(config.AppConfig.URLSigningSecret, key, expiresAt), expiresAt
+}
+
+// fileURL retorna a URL de acesso ao arquivo: direta em projetos públicos e assinada,
+// com a validade padrão, em projetos privados
+func fileURL(project *models.Project, file *models.File) string {
+	if project.IsPrivate() {
+		url, _ := signedFileURL(file.Path, config.AppConfig.SignedURLTTL)
+		return url
+	}
+	return publicFileURL(file.Path)
+}
+
+// projectVisibility retorna a visibilidade do projeto, tratando o valor vazio como público
+func projectVisibility(project *models.Project) string {
+	if project.Visibility == "" {
+		return models.VisibilityPublic
+	}
+	return project.Visibility
+}
+
+// fileForKey encontra o projeto e o arquivo correspondentes a um caminho
+// user_<id>/<projeto>/<arquivo> ou org_<id>/<projeto>/<arquivo> de /files/
+func fileForKey(db *gorm.DB, key string) (*models.Project, *models.File, error) {
+	parts := strings.SplitN(key, "/", 3)
+	if len(parts) != 3 {
+		return nil, nil, storage.ErrNotExist
+	}
+	var scope *gorm.DB
+	if id, ok := strings.CutPrefix(parts[0], "org_"); ok {
+		orgID, err := uuid.Parse(id)
+		if err != nil {
+			return nil, nil, storage.ErrNotExist
+		}
+		scope = db.Where("organization_id = ?", orgID)
+	} else if id, ok := strings.CutPrefix(parts[0], "user_"); ok {
+		userID, err := uuid.Parse(id)
+		if err != nil {
+			return nil, nil, storage.ErrNotExist
+		}
+		scope = db.Where("user_id = ? AND organization_id IS NULL", userID)
+	} else {
+		return nil, nil, storage.ErrNotExist
+	}
+
+	var project models.Project
+	if err := scope.First(&project, "name = ?", parts[1]).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, storage.ErrNotExist
+		}
+		return nil, nil, err
+	}
+
+	var file models.File
+	if err := db.First(&file, "name = ? AND project_id = ?", parts[2], project.ID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, storage.ErrNotExist
+		}
+		return nil, nil, err
+	}
+	return &project, &file, nil
+}
+
+// storageKeyForFile retorna a chave do conteúdo do arquivo no armazenamento: a do blob
+// ou, para arquivos anteriores à deduplicação, o próprio caminho
+func storageKeyForFile(db *gorm.DB, file *models.File) (string, error) {
+	if file.BlobID == nil {
+		return file.Path, nil
+	}
+	var blob models.Blob
+	if err := db.First(&blob, "id = ?", *file.BlobID).Error; err != nil {
+		return "", err
+	}
+	return blob.StorageKey, nil
+}
+
+// serveObject envia um objeto do driver de armazenamento. Retorna storage.ErrNotExist,
+// sem escrever a resposta, quando o objeto não existe.
+func serveObject(w http.ResponseWriter, r *http.Request, store storage.Driver, key, name string) error {
+	info, err := store.Stat(r.Context(), key)
+	if errors.Is(err, storage.ErrInvalidKey) {
+		return storage.ErrNotExist
+	}
+	if err != nil {
+		return err
+	}
+
+	rc, err := store.Get(r.Context(), key)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	if info.ContentType != "" && w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", info.ContentType)
+	}
+
+	// O driver local devolve um *os.File, que permite Range e cache condicional
+	if rs, ok := rc.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, name, info.ModTime, rs)
+		return nil
+	}
+
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+	if !info.ModTime.IsZero() {
+		w.Header().Set("Last-Modified", info.ModTime.UTC().Format(http.TimeFormat))
+	}
+	if r.Method == http.MethodHead {
+		return nil
+	}
+	io.Copy(w, rc)
+	return nil
+}
+
+// FileServerHandler serve os arquivos enviados a partir do driver de armazenamento.
+// Deve ser montado com http.StripPrefix("/files/", ...), de forma que o caminho
+// restante seja o caminho lógico do arquivo (user_<id>/<projeto>/<arquivo>, ou
+// org_<id>/... em projetos de organizações), que é resolvido para o blob
+// correspondente. Arquivos de projetos privados só são servidos com uma assinatura
+// válida (?expires=&signature=), conferida antes de tudo. Só arquivos prontos (ready)
+// são servidos: os pendentes respondem 409 e os em quarentena ou com falha, 404. Os de
+// quem passou do limite de armazenamento e esgotou o prazo de carência respondem 402.
+// Os bytes servidos contam nos downloads do arquivo e no tráfego do mês do dono;
+// esgotado o limite de tráfego do plano, responde 402 (planos gratuitos) ou 429 até o
+// mês seguinte. Imagens JPEG e PNG aceitam ?w=&h=&fit= para receber uma
+// variante redimensionada, guardada no cache de variantes.
+func FileServerHandler(db *gorm.DB, store, cache storage.Driver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		key := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+
+		project, file, err := fileForKey(db, key)
+		if errors.Is(err, storage.ErrNotExist) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Could not read file", http.StatusInternalServerError)
+			return
+		}
+		// A assinatura é conferida antes de qualquer outra resposta: sem uma URL assinada, o
+		// estado do arquivo e os limites do dono de um projeto privado não são revelados
+		if project.IsPrivate() {
+			switch err := util.VerifyURLSignature(config.AppConfig.URLSigningSecret, key, r.URL.Query(), time.Now()); {
+			case errors.Is(err, util.ErrSignatureMissing):
+				http.Error(w, "This file is private. Request a signed URL from /api/sign", http.StatusForbidden)
+				return
+			case errors.Is(err, util.ErrSignatureExpired):
+				http.Error(w, "Signed URL has expired", http.StatusForbidden)
+				return
+			case err != nil:
+				http.Error(w, "Invalid signature", http.StatusForbidden)
+				return
+			}
+			// URLs assinadas não devem ser guardadas por caches compartilhados
+			w.Header().Set("Cache-Control", "private, no-store")
+		}
+		// Só arquivos que passaram por todo o processamento (inclusive a verificação de
+		// malware) são servidos. Os em quarentena ou com falha não existem para quem acessa
+		// /files/; os pendentes respondem 409 até a verificação terminar.
+		switch file.Status {
+		case models.FileStatusReady:
+		case models.FileStatusPending:
+			w.Header().Set("Retry-After", "5")
+			http.Error(w, "File is still being processed", http.StatusConflict)
+			return
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		if quotaRestricted(db, project, time.Now()) {
+			http.Error(w, "The owner of this file is above the storage limit of their plan", http.StatusPaymentRequired)
+			return
+		}
+		// O limite de tráfego é zerado no início do mês (UTC). Planos gratuitos respondem 402,
+		// para o dono fazer upgrade; os pagos, 429.
+		if exceeded, free := egressExceeded(db, project, time.Now()); exceeded {
+			w.Header().Set("Retry-After", monthStart(time.Now()).AddDate(0, 1, 0).Format(http.TimeFormat))
+			if free {
+				http.Error(w, "The owner of this file has used the monthly bandwidth of their plan", http.StatusPaymentRequired)
+			} else {
+				http.Error(w, "The owner of this file has used the monthly bandwidth of their plan", http.StatusTooManyRequests)
+			}
+			return
+		}
+
+		size, resize, err := parseVariantSize(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// Os bytes enviados contam no arquivo e no tráfego do mês do dono (fatura, excedente e limite do plano)
+		counter := &countingWriter{ResponseWriter: w}
+		download := !isRangeContinuation(r.Header.Get("Range"))
+		defer func() { recordEgress(db, project, file, counter.n, download) }()
+		w = counter
+
+		if resize {
+			serveVariant(w, r, db, store, cache, project, file, size)
+			return
+		}
+
+		objectKey, err := storageKeyForFile(db, file)
+		if err != nil {
+			http.Error(w, "Could not read file", http.StatusInternalServerError)
+			return
+		}
+
+		err = serveObject(w, r, store, objectKey, path.Base(key))
+		if errors.Is(err, storage.ErrNotExist) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Could not read file", http.StatusInternalServerError)
+		}
+	}
+}
+
+// SignHandler godoc
+// @Summary Create a signed download URL
+// @Description Mints an HMAC-signed URL for a file that stays valid until it expires. Required to download files from private projects; also works for public ones. Collaborators sign URLs for a project shared with them by passing owner.
+// @Tags api
+// @Produce  json
+// @Param   project     query  string  true   "Project name"
+// @Param   file        query  string  true   "File name"
+// @Param   expires_in  query  int     false  "Lifetime of the URL in seconds (defaults to the server's signed URL lifetime)"
+// @Param   org         query  string  false  "Organization slug (personal projects when omitted)"
+// @Param   owner       query  string  false  "E-mail of the user who shared the project"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} SignResponse
+// @Failure 400 {string} string "'project' and 'file' parameters are required or invalid expires_in"
+// @Failure 403 {string} string "API key is restricted to another project"
+// @Failure 404 {string} string "Organization not found, Project not found or File not found"
+// @Router /api/sign [get]
+// @Router /api/sign [post]
+func SignHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		user, _ := r.Context().Value(middleware.UserContextKey).(*models.User)
+		projectName := r.URL.Query().Get("project")
+		fileName := r.URL.Query().Get("file")
+
+		if projectName == "" || fileName == "" {
+			http.Error(w, "'project' and 'file' parameters are required", http.StatusBadRequest)
+			return
+		}
+
+		ttl := config.AppConfig.SignedURLTTL
+		if v := r.URL.Query().Get("expires_in"); v != "" {
+			seconds, err := strconv.Atoi(v)
+			if err != nil || seconds <= 0 {
+				http.Error(w, "expires_in must be a positive number of seconds", http.StatusBadRequest)
+				return
+			}
+			ttl = time.Duration(seconds) * time.Second
+			if ttl > config.AppConfig.SignedURLMaxTTL {
+				http.Error(w, fmt.Sprintf("expires_in cannot exceed %d seconds", int(config.AppConfig.SignedURLMaxTTL.Seconds())), http.StatusBadRequest)
+				return
+			}
+		}
+
+		ns, ok := queryNamespace(w, db, user, r.URL.Query(), models.RoleViewer)
+		if !ok {
+			return
+		}
+		if !projectAllowed(db, r, ns, projectName) {
+			writeProjectForbidden(w)
+			return
+		}
+
+		project, ok := ns.requireProject(w, db, projectName, models.RoleViewer)
+		if !ok {
+			return
+		}
+
+		var file models.File
+		if err := db.First(&file, "name = ? AND project_id = ?", fileName, project.ID).Error; err != nil {
+			http.Error(w, "File not found", http.StatusNotFound)
+			return
+		}
+
+		url, expiresAt := signedFileURL(file.Path, ttl)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(SignResponse{
+			URL:       url,
+			ExpiresAt: expiresAt,
+			Project:   project.Name,
+			File:      file.Name,
+		})
+	}
+}
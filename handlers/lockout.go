@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/config"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/middleware"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/util"
+)
+
+// ipFailureWindow é o tempo sem falhas após o qual a contagem de um IP recomeça
+const ipFailureWindow = 24 * time.Hour
+
+// ipLockedUntil retorna até quando o IP está impedido de tentar logins, ou nil
+func ipLockedUntil(db *gorm.DB, ip string, now time.Time) *time.Time {
+	var entry models.IPLoginFailure
+	if err := db.First(&entry, "ip = ?", ip).Error; err != nil {
+		return nil
+	}
+	if entry.LockedUntil != nil && entry.LockedUntil.After(now) {
+		return entry.LockedUntil
+	}
+	return nil
+}
+
+// userLocked indica se o login do usuário está bloqueado
+func userLocked(user *models.User, now time.Time) bool {
+	return user.PermanentlyLocked || (user.LockedUntil != nil && user.LockedUntil.After(now))
+}
+
+// writeIPLocked responde 429 para IPs bloqueados
+func writeIPLocked(w http.ResponseWriter, until, now time.Time) {
+	retryAfter := int(math.Ceil(until.Sub(now).Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	http.Error(w, fmt.Sprintf("Too many failed login attempts from this address. Try again in %d minutes.", (retryAfter+59)/60), http.StatusTooManyRequests)
+}
+
+// recordLoginFailure conta uma falha de login para o IP e, quando o e-mail existe, para
+// o usuário, aplicando os bloqueios configurados
+func recordLoginFailure(db *gorm.DB, r *http.Request, user *models.User, ip string, now time.Time) {
+	cfg := config.AppConfig
+
+	var ipFailures int
+	err := db.Raw(`INSERT INTO ip_login_failures (ip, failures, updated_at) VALUES (?, 1, ?)
+		ON CONFLICT (ip) DO UPDATE SET
+			failures = CASE WHEN ip_login_failures.updated_at < ? THEN 1 ELSE ip_login_failures.failures + 1 END,
+			updated_at = EXCLUDED.updated_at
+		RETURNING failures`, ip, now, now.Add(-ipFailureWindow)).Scan(&ipFailures).Error
+	if err != nil {
+		log.Printf("⚠️  Warning: Could not record login failure for %s: %v", ip, err)
+	} else if d := lockoutFor(ipFailures, cfg.LockoutIPThreshold); d > 0 {
+		db.Model(&models.IPLoginFailure{}).Where("ip = ?", ip).Update("locked_until", now.Add(d))
+		log.Printf("🔒 Logins from %s blocked for %s after %d failures", ip, d, ipFailures)
+	}
+
+	if user == nil {
+		return
+	}
+	var failures int
+	if err := db.Raw("UPDATE users SET failed_logins = failed_logins + 1 WHERE id = ? RETURNING failed_logins", user.ID).Scan(&failures).Error; err != nil {
+		log.Printf("⚠️  Warning: Could not record login failure for user %s: %v", user.ID, err)
+		return
+	}
+
+	if cfg.LockoutPermanentThreshold > 0 && failures >= cfg.LockoutPermanentThreshold {
+		db.Model(user).Updates(map[string]interface{}{"permanently_locked": true, "locked_until": nil})
+		middleware.RecordAudit(db, r, models.AuditEvent{
+			UserID: &user.ID, Actor: user.Email, Action: models.AuditAccountLocked, Outcome: models.AuditSuccess,
+			Detail: fmt.Sprintf("Permanently locked after %d failed logins", failures),
+		})
+		return
+	}
+	if d := lockoutFor(failures, cfg.LockoutThreshold); d > 0 {
+		db.Model(user).Update("locked_until", now.Add(d))
+		middleware.RecordAudit(db, r, models.AuditEvent{
+			UserID: &user.ID, Actor: user.Email, Action: models.AuditAccountLocked, Outcome: models.AuditSuccess,
+			Detail: fmt.Sprintf("Locked for %s after %d failed logins", d, failures),
+		})
+	}
+}
+
+// lockoutFor aplica a política de bloqueio configurada à contagem de falhas
+func lockoutFor(failures, threshold int) time.Duration {
+	cfg := config.AppConfig
+	return util.LockoutDuration(failures, threshold, cfg.LockoutDuration, cfg.LockoutMaxDuration)
+}
+
+// resetLoginFailures zera a contagem do usuário depois de um login bem-sucedido
+func resetLoginFailures(db *gorm.DB, user *models.User) {
+	if user.FailedLogins == 0 && user.LockedUntil == nil {
+		return
+	}
+	db.Model(user).Updates(map[string]interface{}{"failed_logins": 0, "locked_until": nil})
+}
+
+// unlockUser remove os bloqueios temporário e permanente e zera a contagem de falhas
+func unlockUser(db *gorm.DB, user *models.User) error {
+	return db.Model(user).Updates(map[string]interface{}{
+		"failed_logins":      0,
+		"locked_until":       nil,
+		"permanently_locked": false,
+	}).Error
+}
+
+// cleanupLoginFailures remove as contagens de IPs sem falhas recentes e sem bloqueio ativo
+func cleanupLoginFailures(db *gorm.DB, now time.Time) error {
+	return db.Where("updated_at < ? AND (locked_until IS NULL OR locked_until < ?)", now.Add(-ipFailureWindow), now).
+		Delete(&models.IPLoginFailure{}).Error
+}
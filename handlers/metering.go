@@ -0,0 +1,379 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/billing"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/config"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/mailer"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// monthStart retorna o primeiro instante (UTC) do mês de t
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// countingWriter conta os bytes do corpo enviados ao cliente. ReadFrom repassa ao
+// ResponseWriter original, que mantém o sendfile do driver local.
+type countingWriter struct {
+	http.ResponseWriter
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.ResponseWriter.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingWriter) ReadFrom(r io.Reader) (int64, error) {
+	n, err := io.Copy(c.ResponseWriter, r)
+	c.n += n
+	return n, err
+}
+
+// recordEgress soma os bytes servidos aos contadores do arquivo e ao tráfego do dia do
+// dono do projeto (o usuário ou a organização). download é falso nas continuações de
+// downloads por partes (Range), que não contam como um novo acesso.
+func recordEgress(db *gorm.DB, project *models.Project, file *models.File, n int64, download bool) {
+	if n <= 0 {
+		return
+	}
+	counters := map[string]interface{}{"bytes_served": gorm.Expr("bytes_served + ?", n)}
+	if download {
+		counters["downloads"] = gorm.Expr("downloads + 1")
+	}
+	if err := db.Model(&models.File{}).Where("id = ?", file.ID).UpdateColumns(counters).Error; err != nil {
+		log.Printf("⚠️  Warning: Could not record downloads of %s: %v", file.Path, err)
+	}
+
+	owner := projectOwner(project)
+	if err := owner.addEgress(db, n); err != nil {
+		log.Printf("⚠️  Warning: Could not record egress of %s: %v", owner, err)
+	}
+}
+
+// isRangeContinuation informa se o header Range pede uma parte que não começa no primeiro
+// byte, como as retomadas de downloads e as requisições seguintes de players de vídeo
+func isRangeContinuation(header string) bool {
+	if header == "" {
+		return false
+	}
+	return !strings.HasPrefix(strings.TrimSpace(strings.TrimPrefix(header, "bytes=")), "0-")
+}
+
+// egressExceeded informa se o dono do projeto (o usuário ou a organização) já serviu no
+// mês o limite de tráfego do plano (EgressLimit) e se o plano é gratuito
+func egressExceeded(db *gorm.DB, project *models.Project, now time.Time) (exceeded, free bool) {
+	owner := projectOwner(project)
+	plan, _, err := owner.usage(db)
+	if err != nil || plan.EgressLimit <= 0 {
+		return false, false
+	}
+	return owner.egressSince(db, monthStart(now)) >= plan.EgressLimit, plan.Price == 0
+}
+
+// recordStorageSnapshots guarda o uso de armazenamento de hoje de cada usuário e de cada
+// organização. Só o primeiro registro do dia conta, então pode ser executada a qualquer hora.
+func recordStorageSnapshots(db *gorm.DB, now time.Time) error {
+	day := now.UTC().Format("2006-01-02")
+	if err := db.Exec(`INSERT INTO storage_snapshots (user_id, day, storage_bytes)
+		SELECT id, ?, storage_usage FROM users
+		ON CONFLICT (user_id, day) DO NOTHING`, day).Error; err != nil {
+		return err
+	}
+	return db.Exec(`INSERT INTO organization_storage_snapshots (organization_id, day, storage_bytes)
+		SELECT id, ?, storage_usage FROM organizations
+		ON CONFLICT (organization_id, day) DO NOTHING`, day).Error
+}
+
+// aggregateUsage consolida os snapshots e o tráfego do dono no mês que começa em month,
+// com o limite do plano informado. O registro retornado não é salvo; nas organizações,
+// que não são faturadas, ele fica sem UserID.
+func aggregateUsage(db *gorm.DB, owner storageOwner, plan *models.Plan, month time.Time) (*models.UsageRecord, error) {
+	end := month.AddDate(0, 1, 0)
+	record := models.UsageRecord{Month: month, PlanID: plan.ID, Plan: *plan}
+	if owner.OrgID == nil {
+		record.UserID = owner.UserID
+	}
+
+	var storage struct {
+		Days        int
+		ByteDays    int64
+		OverDays    int64
+		PeakStorage int64
+	}
+	err := owner.storageSnapshots(db).
+		Select("COUNT(*) AS days, COALESCE(SUM(storage_bytes), 0) AS byte_days, "+
+			"COALESCE(SUM(GREATEST(storage_bytes - ?, 0)), 0) AS over_days, COALESCE(MAX(storage_bytes), 0) AS peak_storage", plan.StorageLimit).
+		Where("day >= ? AND day < ?", month, end).
+		Scan(&storage).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := owner.dailyEgress(db).
+		Select("COALESCE(SUM(bytes), 0)").
+		Where("day >= ? AND day < ?", month, end).
+		Scan(&record.EgressBytes).Error; err != nil {
+		return nil, err
+	}
+
+	// Cada snapshot diário representa as 24 horas do dia
+	record.Days = storage.Days
+	record.StorageByteHours = storage.ByteDays * 24
+	record.OverageByteHours = storage.OverDays * 24
+	record.PeakStorage = storage.PeakStorage
+	return &record, nil
+}
+
+// overageLines calcula os itens de excedente de armazenamento e de tráfego do mês
+func overageLines(record *models.UsageRecord) []billing.Line {
+	var lines []billing.Line
+	plan := &record.Plan
+	if gbMonths, amount := billing.StorageOverage(record.OverageByteHours, plan.StorageOverageRate); amount > 0 {
+		lines = append(lines, billing.Line{
+			Description: "Storage above the " + formatBytes(plan.StorageLimit) + " plan limit",
+			Quantity:    gbMonths,
+			Unit:        "GB-month",
+			UnitPrice:   plan.StorageOverageRate,
+			Amount:      amount,
+		})
+	}
+	if gb, amount := billing.EgressOverage(record.EgressBytes, plan.IncludedEgress, plan.EgressOverageRate); amount > 0 {
+		lines = append(lines, billing.Line{
+			Description: "Data transfer above " + formatBytes(plan.IncludedEgress) + " included",
+			Quantity:    gb,
+			Unit:        "GB",
+			UnitPrice:   plan.EgressOverageRate,
+			Amount:      amount,
+		})
+	}
+	return lines
+}
+
+// subscriptionLines lista os pagamentos de planos feitos no mês, que entram na fatura já pagos
+func subscriptionLines(db *gorm.DB, userID uuid.UUID, month time.Time) []billing.Line {
+	var payments []models.Payment
+	db.Where("user_id = ? AND kind = ? AND created_at >= ? AND created_at < ?",
+		userID, models.PaymentSubscription, month, month.AddDate(0, 1, 0)).
+		Order("created_at").Find(&payments)
+	lines := make([]billing.Line, 0, len(payments))
+	for _, p := range payments {
+		lines = append(lines, billing.Line{
+			Description: p.Description + " (paid " + p.CreatedAt.UTC().Format("2006-01-02") + ")",
+			Quantity:    1,
+			Unit:        "payment",
+			UnitPrice:   p.Amount,
+			Amount:      p.Amount,
+		})
+	}
+	return lines
+}
+
+// invoiceNumber gera o número de uma fatura do mês, como INV-202405-1A2B3C4D
+func invoiceNumber(month time.Time) string {
+	return fmt.Sprintf("INV-%s-%s", month.Format("200601"), strings.ToUpper(uuid.NewString()[:8]))
+}
+
+// planAt retorna o plano em que o usuário estava em at, pelo histórico de mudanças
+// aplicadas: o destino da última mudança antes de at ou, se todas são posteriores, a
+// origem da primeira. Sem histórico (ou com o plano já removido), vale o plano atual.
+func planAt(db *gorm.DB, user *models.User, at time.Time) (*models.Plan, error) {
+	var before models.PlanChange
+	err := db.Preload("ToPlan").
+		Where("user_id = ? AND status = ? AND applied_at < ?", user.ID, models.PlanChangeApplied, at).
+		Order("applied_at DESC").First(&before).Error
+	if err == nil {
+		return knownPlan(&before.ToPlan, user), nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	var after models.PlanChange
+	err = db.Preload("FromPlan").
+		Where("user_id = ? AND status = ? AND applied_at >= ?", user.ID, models.PlanChangeApplied, at).
+		Order("applied_at").First(&after).Error
+	if err == nil {
+		return knownPlan(&after.FromPlan, user), nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return &user.Plan, nil
+}
+
+// knownPlan devolve o plano do histórico, ou o atual do usuário se ele já foi removido
+func knownPlan(plan *models.Plan, user *models.User) *models.Plan {
+	if plan.ID == uuid.Nil {
+		return &user.Plan
+	}
+	return plan
+}
+
+// closeUserMonth salva o uso do mês do usuário e emite a fatura, cobrando o excedente.
+// O mês é cobrado pelo plano em vigor no fim dele, não pelo atual, que pode ter mudado
+// depois. Meses sem nada a faturar ficam só com o registro de uso.
+func closeUserMonth(db *gorm.DB, provider billing.Provider, m mailer.Mailer, user *models.User, month time.Time) error {
+	plan, err := planAt(db, user, month.AddDate(0, 1, 0))
+	if err != nil {
+		return err
+	}
+	record, err := aggregateUsage(db, storageOwner{UserID: user.ID}, plan, month)
+	if err != nil {
+		return err
+	}
+	result := db.Omit("Plan").Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+	if result.Error != nil || result.RowsAffected == 0 {
+		// Outra réplica fechou o mês primeiro
+		return result.Error
+	}
+
+	overage := overageLines(record)
+	lines := append(subscriptionLines(db, user.ID, month), overage...)
+	if len(lines) == 0 {
+		return nil
+	}
+	var total, due float64
+	for _, l := range lines {
+		total += l.Amount
+	}
+	for _, l := range overage {
+		due += l.Amount
+	}
+	encoded, err := json.Marshal(lines)
+	if err != nil {
+		return err
+	}
+
+	invoice := models.Invoice{
+		UserID:        user.ID,
+		Number:        invoiceNumber(month),
+		UsageRecordID: record.ID,
+		PeriodStart:   month,
+		PeriodEnd:     month.AddDate(0, 1, 0),
+		Currency:      config.AppConfig.BillingCurrency,
+		Lines:         string(encoded),
+		Total:         billing.RoundAmount(total),
+		AmountDue:     billing.RoundAmount(due),
+		Status:        models.InvoicePaid,
+	}
+	// A fatura é salva antes da cobrança, para que nenhuma cobrança fique sem fatura
+	if invoice.AmountDue > 0 {
+		invoice.Status = models.InvoiceOpen
+	}
+	if err := db.Create(&invoice).Error; err != nil {
+		return err
+	}
+	if invoice.AmountDue > 0 {
+		payment, err := charge(db, provider, user, models.PaymentOverage, invoice.AmountDue,
+			"Overage "+month.Format("January 2006"), "invoice:"+invoice.ID.String())
+		if err != nil {
+			log.Printf("💳 Cobrança do excedente de %s recusada: %v", user.Email, err)
+		} else {
+			invoice.Status, invoice.PaymentID = models.InvoicePaid, &payment.ID
+			db.Model(&invoice).Updates(map[string]interface{}{"status": invoice.Status, "payment_id": payment.ID})
+		}
+	}
+	log.Printf("🧾 Fatura %s de %s: %.2f %s", invoice.Number, user.Email, invoice.Total, invoice.Currency)
+	notifyInvoice(m, user, &invoice)
+	return nil
+}
+
+// notifyInvoice envia a fatura do mês por e-mail
+func notifyInvoice(m mailer.Mailer, user *models.User, invoice *models.Invoice) {
+	body := fmt.Sprintf("Hi %s,\n\nYour invoice %s for %s is available: %.2f %s.\n",
+		user.Name, invoice.Number, invoice.PeriodStart.Format("January 2006"), invoice.Total, invoice.Currency)
+	if invoice.Status == models.InvoiceOpen {
+		body += fmt.Sprintf("\nWe could not charge the overage of %.2f %s. Please check your payment method.\n",
+			invoice.AmountDue, invoice.Currency)
+	}
+	body += fmt.Sprintf("\nDownload it at %s/api/billing/invoices/%s/pdf\n", config.AppConfig.Domain, invoice.ID)
+	sendMail(m, mailer.Message{To: user.Email, Subject: "Your invoice " + invoice.Number, Body: body})
+}
+
+// processMetering registra os snapshots de hoje e fecha o mês anterior dos usuários
+// que ainda não têm o registro de uso dele. Organizações não são faturadas: não têm forma
+// de pagamento nem assinatura, e o plano delas é definido por um administrador. O uso dos
+// projetos delas conta só para os limites do plano, não na fatura de quem enviou.
+func processMetering(db *gorm.DB, provider billing.Provider, m mailer.Mailer, now time.Time) error {
+	if err := recordStorageSnapshots(db, now); err != nil {
+		return err
+	}
+
+	previous := monthStart(now).AddDate(0, -1, 0)
+	var users []models.User
+	err := db.Preload("Plan").
+		Where("id IN (SELECT user_id FROM storage_snapshots WHERE day >= ? AND day < ?)", previous, monthStart(now)).
+		Where("NOT EXISTS (SELECT 1 FROM usage_records WHERE usage_records.user_id = users.id AND usage_records.month = ?)", previous).
+		Find(&users).Error
+	if err != nil {
+		return err
+	}
+	for i := range users {
+		if err := closeUserMonth(db, provider, m, &users[i], previous); err != nil {
+			log.Printf("⚠️  Warning: Could not close %s usage of %s: %v", previous.Format("2006-01"), users[i].Email, err)
+		}
+	}
+	return nil
+}
+
+// StartUsageMeter executa processMetering na inicialização e depois periodicamente. A
+// primeira execução é imediata para que reinícios frequentes não pulem o snapshot do dia.
+func StartUsageMeter(db *gorm.DB, provider billing.Provider, m mailer.Mailer, interval time.Duration) {
+	go func() {
+		for {
+			if err := processMetering(db, provider, m, time.Now()); err != nil {
+				log.Printf("⚠️  Warning: Failed to record usage: %v", err)
+			}
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// findInvoice busca uma fatura do usuário pelo ID do caminho
+func findInvoice(db *gorm.DB, userID uuid.UUID, rawID string) (*models.Invoice, error) {
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	var invoice models.Invoice
+	if err := db.First(&invoice, "id = ? AND user_id = ?", id, userID).Error; err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// invoiceDocument monta o documento da fatura para a resposta JSON ou o PDF
+func invoiceDocument(user *models.User, invoice *models.Invoice) (*billing.Invoice, error) {
+	var lines []billing.Line
+	if err := json.Unmarshal([]byte(invoice.Lines), &lines); err != nil {
+		return nil, errors.New("invalid invoice lines")
+	}
+	return &billing.Invoice{
+		Number:      invoice.Number,
+		IssuedAt:    invoice.CreatedAt,
+		PeriodStart: invoice.PeriodStart,
+		PeriodEnd:   invoice.PeriodEnd,
+		Customer:    user.Name,
+		Email:       user.Email,
+		Currency:    invoice.Currency,
+		Lines:       lines,
+		Total:       invoice.Total,
+		AmountDue:   invoice.AmountDue,
+		Status:      invoice.Status,
+	}, nil
+}
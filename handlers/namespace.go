@@ -0,0 +1,384 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// storageOwner identifica de quem é o armazenamento de um projeto: do usuário ou, nos
+// projetos de organizações, da organização, cujo plano e uso são compartilhados
+type storageOwner struct {
+	UserID uuid.UUID  // Dono do projeto pessoal ou, em organizações, quem enviou o conteúdo
+	OrgID  *uuid.UUID // nil para os projetos pessoais
+}
+
+// projectOwner retorna o dono do armazenamento de um projeto
+func projectOwner(project *models.Project) storageOwner {
+	return storageOwner{UserID: project.UserID, OrgID: project.OrganizationID}
+}
+
+// prefix é o primeiro segmento das chaves no armazenamento: user_<id> ou org_<id>
+func (o storageOwner) prefix() string {
+	if o.OrgID != nil {
+		return fmt.Sprintf("org_%s", o.OrgID.String())
+	}
+	return fmt.Sprintf("user_%s", o.UserID.String())
+}
+
+// blobs restringe a consulta aos blobs do dono, onde o conteúdo é deduplicado
+func (o storageOwner) blobs(db *gorm.DB) *gorm.DB {
+	if o.OrgID != nil {
+		return db.Model(&models.Blob{}).Where("organization_id = ?", *o.OrgID)
+	}
+	return db.Model(&models.Blob{}).Where("user_id = ? AND organization_id IS NULL", o.UserID)
+}
+
+// updateStorage ajusta o uso físico (limite do plano) e o uso lógico do dono em um
+// único comando, evitando perder atualizações concorrentes
+func (o storageOwner) updateStorage(db *gorm.DB, physicalDelta, logicalDelta int64) error {
+	query := db.Model(&models.User{}).Where("id = ?", o.UserID)
+	if o.OrgID != nil {
+		query = db.Model(&models.Organization{}).Where("id = ?", *o.OrgID)
+	}
+	err := query.Updates(map[string]interface{}{
+		"storage_usage":         gorm.Expr("GREATEST(storage_usage + ?, 0)", physicalDelta),
+		"logical_storage_usage": gorm.Expr("GREATEST(logical_storage_usage + ?, 0)", logicalDelta),
+	}).Error
+	if err != nil {
+		return fmt.Errorf("failed to update storage usage: %w", err)
+	}
+	return nil
+}
+
+// reserved soma os bytes reservados por sessões de upload resumível ainda ativas
+func (o storageOwner) reserved(db *gorm.DB) int64 {
+	query := db.Model(&models.UploadSession{}).Where("expires_at > ?", time.Now())
+	if o.OrgID != nil {
+		query = query.Where("organization_id = ?", *o.OrgID)
+	} else {
+		query = query.Where("user_id = ? AND organization_id IS NULL", o.UserID)
+	}
+	var reserved int64
+	query.Select("COALESCE(sum(upload_length), 0)").Row().Scan(&reserved)
+	return reserved
+}
+
+// addEgress soma os bytes servidos hoje ao tráfego do dono
+func (o storageOwner) addEgress(db *gorm.DB, n int64) error {
+	if o.OrgID != nil {
+		return db.Exec(`INSERT INTO organization_daily_egresses (organization_id, day, bytes) VALUES (?, ?, ?)
+			ON CONFLICT (organization_id, day) DO UPDATE SET bytes = organization_daily_egresses.bytes + EXCLUDED.bytes`,
+			*o.OrgID, today(), n).Error
+	}
+	return db.Exec(`INSERT INTO daily_egresses (user_id, day, bytes) VALUES (?, ?, ?)
+		ON CONFLICT (user_id, day) DO UPDATE SET bytes = daily_egresses.bytes + EXCLUDED.bytes`,
+		o.UserID, today(), n).Error
+}
+
+// dailyEgress restringe a consulta ao tráfego diário do dono
+func (o storageOwner) dailyEgress(db *gorm.DB) *gorm.DB {
+	if o.OrgID != nil {
+		return db.Model(&models.OrganizationDailyEgress{}).Where("organization_id = ?", *o.OrgID)
+	}
+	return db.Model(&models.DailyEgress{}).Where("user_id = ?", o.UserID)
+}
+
+// storageSnapshots restringe a consulta aos snapshots diários de armazenamento do dono
+func (o storageOwner) storageSnapshots(db *gorm.DB) *gorm.DB {
+	if o.OrgID != nil {
+		return db.Model(&models.OrganizationStorageSnapshot{}).Where("organization_id = ?", *o.OrgID)
+	}
+	return db.Model(&models.StorageSnapshot{}).Where("user_id = ?", o.UserID)
+}
+
+// egressSince soma os bytes servidos dos projetos do dono a partir do dia de since
+func (o storageOwner) egressSince(db *gorm.DB, since time.Time) int64 {
+	var served int64
+	o.dailyEgress(db).Where("day >= ?", since).Select("COALESCE(SUM(bytes), 0)").Scan(&served)
+	return served
+}
+
+// usage carrega o plano e o uso físico atual do dono
+func (o storageOwner) usage(db *gorm.DB) (*models.Plan, int64, error) {
+	if o.OrgID != nil {
+		var org models.Organization
+		if err := db.Preload("Plan").First(&org, "id = ?", *o.OrgID).Error; err != nil {
+			return nil, 0, err
+		}
+		return &org.Plan, org.StorageUsage, nil
+	}
+	var user models.User
+	if err := db.Preload("Plan").First(&user, "id = ?", o.UserID).Error; err != nil {
+		return nil, 0, err
+	}
+	return &user.Plan, user.StorageUsage, nil
+}
+
+// String identifica o dono nos logs
+func (o storageOwner) String() string {
+	if o.OrgID != nil {
+		return "organization " + o.OrgID.String()
+	}
+	return "user " + o.UserID.String()
+}
+
+// namespace é o conjunto de projetos em que uma requisição atua: os projetos pessoais do
+// usuário, os de uma organização da qual ele é membro ou os projetos pessoais que outro
+// usuário compartilhou com ele
+type namespace struct {
+	User     *models.User
+	Org      *models.Organization // nil fora das organizações
+	SharedBy *models.User         // Dono dos projetos compartilhados; nil fora deles
+	Role     string               // Papel do usuário; RoleOwner nos projetos pessoais e vazio nos compartilhados, em que depende do projeto
+}
+
+// queryNamespace resolve o namespace pela query string: owner (e-mail de quem
+// compartilhou projetos com o usuário) ou org. Nos projetos compartilhados minRole é
+// conferido por projeto, em requireProject.
+func queryNamespace(w http.ResponseWriter, db *gorm.DB, user *models.User, query url.Values, minRole string) (*namespace, bool) {
+	email := query.Get("owner")
+	if email == "" {
+		return requestNamespace(w, db, user, query.Get("org"), minRole)
+	}
+	if query.Get("org") != "" {
+		http.Error(w, "Use either org or owner, not both", http.StatusBadRequest)
+		return nil, false
+	}
+	var owner models.User
+	err := db.Preload("Plan").First(&owner, "email = ?", email).Error
+	if err == nil && owner.ID == user.ID {
+		return &namespace{User: user, Role: models.RoleOwner}, true
+	}
+	if err == nil {
+		var shared int64
+		db.Model(&models.ProjectMember{}).
+			Where("user_id = ? AND accepted_at IS NOT NULL AND project_id IN (?)", user.ID,
+				db.Model(&models.Project{}).Select("id").Where("user_id = ? AND organization_id IS NULL", owner.ID)).
+			Count(&shared)
+		if shared == 0 {
+			err = gorm.ErrRecordNotFound
+		}
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Quem não colabora com o dono não descobre se o e-mail tem conta
+		http.Error(w, "Project not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, "Could not load shared projects: "+err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	return &namespace{User: user, SharedBy: &owner}, true
+}
+
+// requestNamespace resolve o parâmetro org (slug da organização; vazio para os projetos
+// pessoais) e confere se o papel do usuário alcança minRole. Responde 404 quando o
+// usuário não é membro da organização e 403 quando o papel não basta.
+func requestNamespace(w http.ResponseWriter, db *gorm.DB, user *models.User, orgSlug, minRole string) (*namespace, bool) {
+	if orgSlug == "" {
+		return &namespace{User: user, Role: models.RoleOwner}, true
+	}
+	return loadNamespace(w, db, user, db.Where("slug = ?", orgSlug), minRole)
+}
+
+// organizationNamespace é como requestNamespace, mas a partir do ID da organização
+// guardado em uma sessão de upload
+func organizationNamespace(w http.ResponseWriter, db *gorm.DB, user *models.User, orgID *uuid.UUID, minRole string) (*namespace, bool) {
+	if orgID == nil {
+		return &namespace{User: user, Role: models.RoleOwner}, true
+	}
+	return loadNamespace(w, db, user, db.Where("id = ?", *orgID), minRole)
+}
+
+func loadNamespace(w http.ResponseWriter, db *gorm.DB, user *models.User, orgQuery *gorm.DB, minRole string) (*namespace, bool) {
+	var org models.Organization
+	err := orgQuery.Preload("Plan").First(&org).Error
+	var membership models.Membership
+	if err == nil {
+		err = db.First(&membership, "organization_id = ? AND user_id = ?", org.ID, user.ID).Error
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Quem não é membro não descobre se a organização existe
+		http.Error(w, "Organization not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, "Could not load organization: "+err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if !models.RoleAllows(membership.Role, minRole) {
+		writeRoleForbidden(w, minRole)
+		return nil, false
+	}
+	return &namespace{User: user, Org: &org, Role: membership.Role}, true
+}
+
+// writeRoleForbidden responde 403 para membros sem o papel necessário
+func writeRoleForbidden(w http.ResponseWriter, minRole string) {
+	http.Error(w, fmt.Sprintf("This action requires the %q role or higher in the organization", minRole), http.StatusForbidden)
+}
+
+// orgID retorna o ID da organização, ou nil para os projetos pessoais
+func (ns *namespace) orgID() *uuid.UUID {
+	if ns.Org == nil {
+		return nil
+	}
+	return &ns.Org.ID
+}
+
+// owner retorna o dono do armazenamento dos projetos do namespace
+func (ns *namespace) owner() storageOwner {
+	if ns.SharedBy != nil {
+		return storageOwner{UserID: ns.SharedBy.ID}
+	}
+	return storageOwner{UserID: ns.User.ID, OrgID: ns.orgID()}
+}
+
+// plan retorna o plano que limita os uploads: o da organização, o de quem compartilhou
+// o projeto ou o do usuário, que precisa ter sido carregado com Preload("Plan")
+func (ns *namespace) plan() *models.Plan {
+	if ns.Org != nil {
+		return &ns.Org.Plan
+	}
+	if ns.SharedBy != nil {
+		return &ns.SharedBy.Plan
+	}
+	return &ns.User.Plan
+}
+
+// storageUsage retorna o uso físico que conta contra plan()
+func (ns *namespace) storageUsage() int64 {
+	if ns.Org != nil {
+		return ns.Org.StorageUsage
+	}
+	if ns.SharedBy != nil {
+		return ns.SharedBy.StorageUsage
+	}
+	return ns.User.StorageUsage
+}
+
+// readOnly diz se o uso já passa do limite do plano, como depois de um downgrade. Nesse
+// caso nenhum upload é aceito, nem os deduplicados, até o uso voltar ao limite.
+func (ns *namespace) readOnly() bool {
+	return ns.storageUsage() > ns.plan().StorageLimit
+}
+
+// writeReadOnly responde 403 a uploads em namespaces acima do limite do plano
+func writeReadOnly(w http.ResponseWriter) {
+	http.Error(w, "Storage usage is above your plan's limit: uploads are disabled until you delete files or upgrade your plan", http.StatusForbidden)
+}
+
+// projects restringe a consulta aos projetos do namespace. Nos compartilhados, apenas
+// os projetos cujo convite o usuário aceitou.
+func (ns *namespace) projects(db *gorm.DB) *gorm.DB {
+	if ns.Org != nil {
+		return db.Where("organization_id = ?", ns.Org.ID)
+	}
+	if ns.SharedBy != nil {
+		return db.Where("user_id = ? AND organization_id IS NULL AND id IN (?)", ns.SharedBy.ID, acceptedShares(db, ns.User.ID))
+	}
+	return db.Where("user_id = ? AND organization_id IS NULL", ns.User.ID)
+}
+
+// acceptedShares é a subconsulta dos IDs dos projetos compartilhados com o usuário
+func acceptedShares(db *gorm.DB, userID uuid.UUID) *gorm.DB {
+	return db.Model(&models.ProjectMember{}).Select("project_id").Where("user_id = ? AND accepted_at IS NOT NULL", userID)
+}
+
+// projectRole retorna o papel do usuário no projeto: o do namespace ou, nos projetos
+// compartilhados, o equivalente à permissão do convite
+func (ns *namespace) projectRole(db *gorm.DB, project *models.Project) string {
+	if ns.SharedBy == nil {
+		return ns.Role
+	}
+	var share models.ProjectMember
+	if db.First(&share, "project_id = ? AND user_id = ? AND accepted_at IS NOT NULL", project.ID, ns.User.ID).Error != nil {
+		return ""
+	}
+	return models.PermissionRole(share.Permission)
+}
+
+// requireProject busca o projeto pelo nome e confere se o papel do usuário nele alcança
+// minRole. Responde 404 quando o projeto não existe no namespace e 403 quando a
+// permissão do colaborador não basta.
+func (ns *namespace) requireProject(w http.ResponseWriter, db *gorm.DB, name, minRole string) (*models.Project, bool) {
+	project, err := ns.findProject(db, name)
+	if err != nil {
+		http.Error(w, "Project not found", http.StatusNotFound)
+		return nil, false
+	}
+	// Fora dos projetos compartilhados o papel já foi conferido ao resolver o namespace
+	if ns.SharedBy != nil && !models.RoleAllows(ns.projectRole(db, project), minRole) {
+		writePermissionForbidden(w, minRole)
+		return nil, false
+	}
+	return project, true
+}
+
+// findProject busca um projeto do namespace pelo nome
+func (ns *namespace) findProject(db *gorm.DB, name string) (*models.Project, error) {
+	var project models.Project
+	if err := ns.projects(db).First(&project, "name = ?", name).Error; err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// contains indica se o projeto pertence ao namespace
+func (ns *namespace) contains(db *gorm.DB, projectID uuid.UUID) bool {
+	var count int64
+	ns.projects(db).Model(&models.Project{}).Where("id = ?", projectID).Count(&count)
+	return count > 0
+}
+
+// firstOrCreateProject busca o projeto pelo nome ou o cria no namespace. created indica
+// se ele acabou de ser criado. Colaboradores não criam projetos em namespaces alheios.
+func (ns *namespace) firstOrCreateProject(db *gorm.DB, name string) (*models.Project, bool, error) {
+	if ns.SharedBy != nil {
+		project, err := ns.findProject(db, name)
+		return project, false, err
+	}
+	var project models.Project
+	result := ns.projects(db).Where("name = ?", name).
+		Attrs(models.Project{Name: name, UserID: ns.User.ID, OrganizationID: ns.orgID()}).
+		FirstOrCreate(&project)
+	if result.Error != nil {
+		return nil, false, result.Error
+	}
+	return &project, result.RowsAffected > 0, nil
+}
+
+// projectLabel retorna o nome do projeto e o slug da organização dona dele (vazio nos
+// projetos pessoais), usados para descrever chaves e webhooks restritos ao projeto
+func projectLabel(db *gorm.DB, projectID uuid.UUID) (string, string) {
+	var project models.Project
+	if db.First(&project, "id = ?", projectID).Error != nil {
+		return "", ""
+	}
+	if project.OrganizationID == nil {
+		return project.Name, ""
+	}
+	var org models.Organization
+	db.Select("slug").First(&org, "id = ?", *project.OrganizationID)
+	return project.Name, org.Slug
+}
+
+// target prefixa o alvo de eventos de auditoria com a organização ou com o e-mail de
+// quem compartilhou o projeto, quando houver
+func (ns *namespace) target(target string) string {
+	if ns.Org != nil {
+		return ns.Org.Slug + ":" + target
+	}
+	if ns.SharedBy != nil {
+		return ns.SharedBy.Email + ":" + target
+	}
+	return target
+}
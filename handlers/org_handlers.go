@@ -0,0 +1,597 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/middleware"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/storage"
+)
+
+type OrganizationRequest struct {
+	Name string `json:"name"`
+	Slug string `json:"slug,omitempty"` // Gerado a partir do nome quando omitido
+}
+
+type OrganizationInfo struct {
+	ID                  uuid.UUID `json:"id"`
+	Name                string    `json:"name"`
+	Slug                string    `json:"slug"`
+	Role                string    `json:"role"` // Papel do usuário autenticado
+	Plan                string    `json:"plan"`
+	StorageUsage        int64     `json:"storage_usage"`
+	LogicalStorageUsage int64     `json:"logical_storage_usage"`
+	StorageLimit        int64     `json:"storage_limit"`
+	EgressUsage         int64     `json:"egress_usage"` // Bytes servidos no mês (UTC)
+	EgressLimit         int64     `json:"egress_limit"` // 0 = ilimitado
+	Members             int64     `json:"members"`
+	Projects            int64     `json:"projects"`
+	CreatedAt           time.Time `json:"created_at"`
+}
+
+type OrganizationsResponse struct {
+	Organizations []OrganizationInfo `json:"organizations"`
+}
+
+type MemberRequest struct {
+	Email string `json:"email,omitempty"` // Apenas ao adicionar
+	Role  string `json:"role"`
+}
+
+type MemberInfo struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type MembersResponse struct {
+	Members []MemberInfo `json:"members"`
+}
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,38}[a-z0-9]$`)
+
+// slugify gera um slug a partir do nome: minúsculas, dígitos e hífens
+func slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, c := range strings.ToLower(name) {
+		if c >= 'a' && c <= 'z' || c >= '0' && c <= '9' {
+			b.WriteRune(c)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	slug := strings.Trim(b.String(), "-")
+	if len(slug) > 40 {
+		slug = strings.Trim(slug[:40], "-")
+	}
+	return slug
+}
+
+// validateRole confere se o papel é conhecido
+func validateRole(role string) error {
+	if !slices.Contains(models.AllRoles, role) {
+		return errors.New("Unknown role. Valid roles are: " + strings.Join(models.AllRoles, ", ") + ".")
+	}
+	return nil
+}
+
+// requireUnrestrictedKey recusa chaves de API restritas a um projeto, que não podem
+// gerenciar organizações
+func requireUnrestrictedKey(w http.ResponseWriter, r *http.Request) bool {
+	if key := requestAPIKey(r); key != nil && key.ProjectID != nil {
+		writeProjectForbidden(w)
+		return false
+	}
+	return true
+}
+
+// toOrganizationInfo converte a organização para a resposta da API
+func toOrganizationInfo(db *gorm.DB, org *models.Organization, role string) OrganizationInfo {
+	info := OrganizationInfo{
+		ID:                  org.ID,
+		Name:                org.Name,
+		Slug:                org.Slug,
+		Role:                role,
+		Plan:                org.Plan.Name,
+		StorageUsage:        org.StorageUsage,
+		LogicalStorageUsage: org.LogicalStorageUsage,
+		StorageLimit:        org.Plan.StorageLimit,
+		EgressLimit:         org.Plan.EgressLimit,
+		CreatedAt:           org.CreatedAt,
+	}
+	info.EgressUsage = storageOwner{OrgID: &org.ID}.egressSince(db, monthStart(time.Now()))
+	db.Model(&models.Membership{}).Where("organization_id = ?", org.ID).Count(&info.Members)
+	db.Model(&models.Project{}).Where("organization_id = ?", org.ID).Count(&info.Projects)
+	return info
+}
+
+// lockOwners bloqueia, até o fim da transação, as associações de donos da organização e
+// retorna quantos donos ela tem. Serializa as alterações que poderiam deixá-la sem dono.
+func lockOwners(tx *gorm.DB, orgID uuid.UUID) (int, error) {
+	var owners []models.Membership
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("organization_id = ? AND role = ?", orgID, models.RoleOwner).
+		Find(&owners).Error
+	return len(owners), err
+}
+
+// revokeOrganizationAccess apaga as chaves de API e os webhooks do usuário restritos a
+// projetos da organização. Com keepKeys, apenas os webhooks são apagados (usado quando o
+// membro perde o papel admin, exigido para webhooks).
+func revokeOrganizationAccess(tx *gorm.DB, orgID, userID uuid.UUID, keepKeys bool) error {
+	projectIDs := tx.Model(&models.Project{}).Select("id").Where("organization_id = ?", orgID)
+	if err := tx.Where("user_id = ? AND project_id IN (?)", userID, projectIDs).Delete(&models.Webhook{}).Error; err != nil {
+		return err
+	}
+	if keepKeys {
+		return nil
+	}
+	return tx.Where("user_id = ? AND project_id IN (?)", userID, projectIDs).Delete(&models.APIKey{}).Error
+}
+
+// OrganizationsHandler godoc
+// @Summary List or create organizations
+// @Description GET lists the organizations the user belongs to, with their role. POST creates an organization on the Free plan with the user as its owner. Organization projects are managed with the org parameter of the project endpoints and share the organization's plan and storage quota.
+// @Tags orgs
+// @Accept  json
+// @Produce  json
+// @Param   org_request  body  OrganizationRequest  false  "Name and optional slug (POST only)"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} OrganizationsResponse "Organizations"
+// @Success 201 {object} OrganizationInfo "Organization created"
+// @Failure 400 {string} string "Invalid request body, name or slug"
+// @Failure 403 {string} string "API key lacks the 'admin' scope or is restricted to a project"
+// @Failure 409 {string} string "Slug already taken"
+// @Failure 500 {string} string "Could not create organization"
+// @Router /api/orgs [get]
+// @Router /api/orgs [post]
+func OrganizationsHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, _ := r.Context().Value(middleware.UserContextKey).(*models.User)
+		if !requireUnrestrictedKey(w, r) {
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			var memberships []models.Membership
+			db.Where("user_id = ?", user.ID).Order("created_at").Find(&memberships)
+
+			infos := make([]OrganizationInfo, 0, len(memberships))
+			for _, m := range memberships {
+				var org models.Organization
+				if db.Preload("Plan").First(&org, "id = ?", m.OrganizationID).Error == nil {
+					infos = append(infos, toOrganizationInfo(db, &org, m.Role))
+				}
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(OrganizationsResponse{Organizations: infos})
+
+		case http.MethodPost:
+			var req OrganizationRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			req.Name = strings.TrimSpace(req.Name)
+			if req.Name == "" || len(req.Name) > 100 {
+				http.Error(w, "Name must be between 1 and 100 characters.", http.StatusBadRequest)
+				return
+			}
+			if req.Slug == "" {
+				req.Slug = slugify(req.Name)
+			}
+			if !slugPattern.MatchString(req.Slug) {
+				http.Error(w, "Slug must be 3 to 40 lowercase letters, digits or hyphens, starting and ending with a letter or digit.", http.StatusBadRequest)
+				return
+			}
+
+			var freePlan models.Plan
+			if err := db.Where("name = ?", models.FreePlanName).First(&freePlan).Error; err != nil {
+				http.Error(w, "Could not find default plan", http.StatusInternalServerError)
+				return
+			}
+
+			org := models.Organization{Name: req.Name, Slug: req.Slug, PlanID: freePlan.ID}
+			err := db.Transaction(func(tx *gorm.DB) error {
+				var taken int64
+				tx.Model(&models.Organization{}).Where("slug = ?", org.Slug).Count(&taken)
+				if taken > 0 {
+					return gorm.ErrDuplicatedKey
+				}
+				if err := tx.Create(&org).Error; err != nil {
+					return err
+				}
+				return tx.Create(&models.Membership{OrganizationID: org.ID, UserID: user.ID, Role: models.RoleOwner}).Error
+			})
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				http.Error(w, "Slug already taken", http.StatusConflict)
+				return
+			}
+			if err != nil {
+				recordAudit(db, r, models.AuditOrgCreate, org.Slug, models.AuditFailure, err.Error())
+				http.Error(w, "Could not create organization: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			recordAudit(db, r, models.AuditOrgCreate, org.Slug, models.AuditSuccess, "")
+			org.Plan = freePlan
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(toOrganizationInfo(db, &org, models.RoleOwner))
+
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// OrganizationHandler godoc
+// @Summary Get, update or delete an organization
+// @Description GET returns the organization and its storage usage (any member). PATCH renames it or changes its slug (admin or owner). DELETE removes it (owner only); it must have no projects left.
+// @Tags orgs
+// @Accept  json
+// @Produce  json
+// @Param   org          path  string               true   "Organization slug"
+// @Param   org_request  body  OrganizationRequest  false  "Fields to change (PATCH only)"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} OrganizationInfo
+// @Success 204 {string} string "Organization deleted"
+// @Failure 400 {string} string "Invalid request body, name or slug"
+// @Failure 403 {string} string "Role does not allow this action"
+// @Failure 404 {string} string "Organization not found"
+// @Failure 409 {string} string "Slug already taken or organization still has projects"
+// @Router /api/orgs/{org} [get]
+// @Router /api/orgs/{org} [patch]
+// @Router /api/orgs/{org} [delete]
+func OrganizationHandler(db *gorm.DB, store storage.Driver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, _ := r.Context().Value(middleware.UserContextKey).(*models.User)
+		if !requireUnrestrictedKey(w, r) {
+			return
+		}
+
+		minRole := models.RoleViewer
+		switch r.Method {
+		case http.MethodPatch:
+			minRole = models.RoleAdmin
+		case http.MethodDelete:
+			minRole = models.RoleOwner
+		}
+		ns, ok := requestNamespace(w, db, user, r.PathValue("org"), minRole)
+		if !ok {
+			return
+		}
+		org := ns.Org
+
+		switch r.Method {
+		case http.MethodGet:
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(toOrganizationInfo(db, org, ns.Role))
+
+		case http.MethodPatch:
+			var req OrganizationRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			updates := map[string]interface{}{}
+			if name := strings.TrimSpace(req.Name); name != "" {
+				if len(name) > 100 {
+					http.Error(w, "Name must be between 1 and 100 characters.", http.StatusBadRequest)
+					return
+				}
+				updates["name"] = name
+			}
+			if req.Slug != "" && req.Slug != org.Slug {
+				if !slugPattern.MatchString(req.Slug) {
+					http.Error(w, "Slug must be 3 to 40 lowercase letters, digits or hyphens, starting and ending with a letter or digit.", http.StatusBadRequest)
+					return
+				}
+				var taken int64
+				db.Model(&models.Organization{}).Where("slug = ?", req.Slug).Count(&taken)
+				if taken > 0 {
+					http.Error(w, "Slug already taken", http.StatusConflict)
+					return
+				}
+				updates["slug"] = req.Slug
+			}
+			if len(updates) > 0 {
+				if err := db.Model(org).Updates(updates).Error; err != nil {
+					http.Error(w, "Could not update organization: "+err.Error(), http.StatusInternalServerError)
+					return
+				}
+				db.Preload("Plan").First(org, "id = ?", org.ID)
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(toOrganizationInfo(db, org, ns.Role))
+
+		case http.MethodDelete:
+			var projects int64
+			db.Model(&models.Project{}).Where("organization_id = ?", org.ID).Count(&projects)
+			if projects > 0 {
+				http.Error(w, "Organization still has projects. Delete them first.", http.StatusConflict)
+				return
+			}
+			var sessions []models.UploadSession
+			db.Where("organization_id = ?", org.ID).Find(&sessions)
+			for i := range sessions {
+				removeUploadSession(db, store, &sessions[i])
+			}
+			// As associações são apagadas em cascata
+			if err := db.Delete(org).Error; err != nil {
+				recordAudit(db, r, models.AuditOrgDelete, org.Slug, models.AuditFailure, err.Error())
+				http.Error(w, "Could not delete organization: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			recordAudit(db, r, models.AuditOrgDelete, org.Slug, models.AuditSuccess, "")
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// MembersHandler godoc
+// @Summary List or add organization members
+// @Description GET lists the members and their roles (any member). POST adds an existing user by email with a role (owner, admin, member or viewer; member by default). Admins can add members and viewers; only owners can add admins and owners.
+// @Tags orgs
+// @Accept  json
+// @Produce  json
+// @Param   org             path  string         true   "Organization slug"
+// @Param   member_request  body  MemberRequest  false  "Email and role (POST only)"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} MembersResponse "Members"
+// @Success 201 {object} MemberInfo "Member added"
+// @Failure 400 {string} string "Invalid request body or role"
+// @Failure 403 {string} string "Role does not allow this action"
+// @Failure 404 {string} string "Organization or user not found"
+// @Failure 409 {string} string "User is already a member"
+// @Router /api/orgs/{org}/members [get]
+// @Router /api/orgs/{org}/members [post]
+func MembersHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, _ := r.Context().Value(middleware.UserContextKey).(*models.User)
+		if !requireUnrestrictedKey(w, r) {
+			return
+		}
+
+		minRole := models.RoleViewer
+		if r.Method == http.MethodPost {
+			minRole = models.RoleAdmin
+		}
+		ns, ok := requestNamespace(w, db, user, r.PathValue("org"), minRole)
+		if !ok {
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			var memberships []models.Membership
+			db.Preload("User").Where("organization_id = ?", ns.Org.ID).Order("created_at").Find(&memberships)
+
+			infos := make([]MemberInfo, 0, len(memberships))
+			for _, m := range memberships {
+				infos = append(infos, toMemberInfo(&m))
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(MembersResponse{Members: infos})
+
+		case http.MethodPost:
+			var req MemberRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			if req.Role == "" {
+				req.Role = models.RoleMember
+			}
+			if err := validateRole(req.Role); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if models.RoleAllows(req.Role, models.RoleAdmin) && ns.Role != models.RoleOwner {
+				writeRoleForbidden(w, models.RoleOwner)
+				return
+			}
+
+			target := ns.Org.Slug + ":" + req.Email
+			var member models.User
+			if err := db.First(&member, "email = ?", strings.TrimSpace(req.Email)).Error; err != nil {
+				http.Error(w, "User not found", http.StatusNotFound)
+				return
+			}
+			membership := models.Membership{OrganizationID: ns.Org.ID, UserID: member.ID, Role: req.Role}
+			result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&membership)
+			if result.Error != nil {
+				recordAudit(db, r, models.AuditMemberAdd, target, models.AuditFailure, result.Error.Error())
+				http.Error(w, "Could not add member: "+result.Error.Error(), http.StatusInternalServerError)
+				return
+			}
+			if result.RowsAffected == 0 {
+				http.Error(w, "User is already a member", http.StatusConflict)
+				return
+			}
+			recordAudit(db, r, models.AuditMemberAdd, target, models.AuditSuccess, req.Role)
+			membership.User = member
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(toMemberInfo(&membership))
+
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// MemberHandler godoc
+// @Summary Change a member's role or remove a member
+// @Description PATCH changes the member's role. DELETE removes the member; any member can remove themselves to leave the organization. Admins manage members and viewers; only owners can change or remove admins and owners, and the last owner can be neither demoted nor removed. Removed members lose their API keys and webhooks for the organization's projects.
+// @Tags orgs
+// @Accept  json
+// @Produce  json
+// @Param   org             path  string         true   "Organization slug"
+// @Param   user_id         path  string         true   "Member user ID"
+// @Param   member_request  body  MemberRequest  false  "New role (PATCH only)"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} MemberInfo
+// @Success 204 {string} string "Member removed"
+// @Failure 400 {string} string "Invalid request body or role"
+// @Failure 403 {string} string "Role does not allow this action"
+// @Failure 404 {string} string "Organization or member not found"
+// @Failure 409 {string} string "The organization must keep at least one owner"
+// @Router /api/orgs/{org}/members/{user_id} [patch]
+// @Router /api/orgs/{org}/members/{user_id} [delete]
+func MemberHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, _ := r.Context().Value(middleware.UserContextKey).(*models.User)
+		if !requireUnrestrictedKey(w, r) {
+			return
+		}
+		ns, ok := requestNamespace(w, db, user, r.PathValue("org"), models.RoleViewer)
+		if !ok {
+			return
+		}
+
+		memberID, err := uuid.Parse(r.PathValue("user_id"))
+		if err != nil {
+			http.Error(w, "Member not found", http.StatusNotFound)
+			return
+		}
+		var membership models.Membership
+		if err := db.Preload("User").First(&membership, "organization_id = ? AND user_id = ?", ns.Org.ID, memberID).Error; err != nil {
+			http.Error(w, "Member not found", http.StatusNotFound)
+			return
+		}
+		target := ns.Org.Slug + ":" + membership.User.Email
+		leaving := r.Method == http.MethodDelete && memberID == user.ID
+
+		// Admins gerenciam membros e leitores; donos e admins só são gerenciados por donos
+		if !leaving {
+			required := models.RoleAdmin
+			if models.RoleAllows(membership.Role, models.RoleAdmin) {
+				required = models.RoleOwner
+			}
+			if !models.RoleAllows(ns.Role, required) {
+				writeRoleForbidden(w, required)
+				return
+			}
+		}
+
+		switch r.Method {
+		case http.MethodPatch:
+			var req MemberRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			if err := validateRole(req.Role); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if models.RoleAllows(req.Role, models.RoleAdmin) && ns.Role != models.RoleOwner {
+				writeRoleForbidden(w, models.RoleOwner)
+				return
+			}
+
+			err := db.Transaction(func(tx *gorm.DB) error {
+				owners, err := lockOwners(tx, ns.Org.ID)
+				if err != nil {
+					return err
+				}
+				if membership.Role == models.RoleOwner && req.Role != models.RoleOwner && owners <= 1 {
+					return errLastOwner
+				}
+				if err := tx.Model(&membership).Update("role", req.Role).Error; err != nil {
+					return err
+				}
+				// Webhooks de projetos da organização exigem o papel admin
+				if !models.RoleAllows(req.Role, models.RoleAdmin) {
+					return revokeOrganizationAccess(tx, ns.Org.ID, memberID, true)
+				}
+				return nil
+			})
+			if errors.Is(err, errLastOwner) {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+			if err != nil {
+				recordAudit(db, r, models.AuditMemberRole, target, models.AuditFailure, err.Error())
+				http.Error(w, "Could not update member: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			recordAudit(db, r, models.AuditMemberRole, target, models.AuditSuccess, req.Role)
+			membership.Role = req.Role
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(toMemberInfo(&membership))
+
+		case http.MethodDelete:
+			err := db.Transaction(func(tx *gorm.DB) error {
+				owners, err := lockOwners(tx, ns.Org.ID)
+				if err != nil {
+					return err
+				}
+				if membership.Role == models.RoleOwner && owners <= 1 {
+					return errLastOwner
+				}
+				if err := tx.Delete(&membership).Error; err != nil {
+					return err
+				}
+				return revokeOrganizationAccess(tx, ns.Org.ID, memberID, false)
+			})
+			if errors.Is(err, errLastOwner) {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+			if err != nil {
+				recordAudit(db, r, models.AuditMemberRemove, target, models.AuditFailure, err.Error())
+				http.Error(w, "Could not remove member: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			recordAudit(db, r, models.AuditMemberRemove, target, models.AuditSuccess, "")
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// errLastOwner impede que a organização fique sem dono
+var errLastOwner = errors.New("The organization must keep at least one owner")
+
+// toMemberInfo converte a associação, com o usuário carregado, para a resposta da API
+func toMemberInfo(m *models.Membership) MemberInfo {
+	return MemberInfo{
+		UserID:    m.UserID,
+		Name:      m.User.Name,
+		Email:     m.User.Email,
+		Role:      m.Role,
+		CreatedAt: m.CreatedAt,
+	}
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/imaging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/jobs"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/scanner"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/storage"
+)
+
+// Etapas do processamento pós-upload, executadas como jobs
+const (
+	JobVerifyChecksum = "verify_checksum"
+	JobScan           = "scan"
+	JobThumbnails     = "thumbnails"
+)
+
+// uploadPipeline é a ordem das etapas executadas após cada upload. Cada etapa enfileira
+// a seguinte ao terminar; depois da última o arquivo fica pronto.
+var uploadPipeline = []string{JobVerifyChecksum, JobScan, JobThumbnails}
+
+// errPipelineHalted encerra o pipeline sem erro, sem executar as etapas seguintes
+var errPipelineHalted = errors.New("pipeline halted")
+
+// fileJob é o payload das etapas do pipeline
+type fileJob struct {
+	FileID uuid.UUID `json:"file_id"`
+}
+
+// fileStep processa um arquivo em uma etapa do pipeline
+type fileStep func(ctx context.Context, file *models.File) error
+
+// enqueueUploadPipeline agenda a primeira etapa do pipeline de um arquivo recém-criado
+func enqueueUploadPipeline(db *gorm.DB, fileID uuid.UUID) error {
+	return jobs.Enqueue(db, uploadPipeline[0], fileJob{FileID: fileID}, nil)
+}
+
+// RegisterUploadJobs registra as etapas do pipeline pós-upload no runner. scan pode ser
+// nil para desativar a verificação de malware.
+func RegisterUploadJobs(runner *jobs.Runner, db *gorm.DB, store, cache storage.Driver, scan scanner.Scanner) {
+	steps := map[string]fileStep{
+		JobVerifyChecksum: func(ctx context.Context, file *models.File) error {
+			return verifyChecksum(ctx, db, store, file)
+		},
+		JobScan: func(ctx context.Context, file *models.File) error {
+			return scanFile(ctx, db, store, scan, file)
+		},
+		JobThumbnails: func(ctx context.Context, file *models.File) error {
+			return createThumbnails(ctx, db, store, cache, file)
+		},
+	}
+	for _, jobType := range uploadPipeline {
+		runner.Register(jobType, pipelineStep(db, jobType, steps[jobType]), failUpload(db))
+	}
+}
+
+// pipelineStep carrega o arquivo do job, executa a etapa e avança o pipeline
+func pipelineStep(db *gorm.DB, jobType string, step fileStep) jobs.HandlerFunc {
+	return func(ctx context.Context, job *models.Job) error {
+		var payload fileJob
+		if err := jobs.Decode(job, &payload); err != nil {
+			return err
+		}
+
+		var file models.File
+		if err := db.First(&file, "id = ?", payload.FileID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				// O arquivo foi apagado antes do processamento terminar
+				return nil
+			}
+			return err
+		}
+
+		// Arquivos em quarentena não seguem no pipeline
+		if file.Status == models.FileStatusQuarantined {
+			return nil
+		}
+
+		if err := step(ctx, &file); err != nil {
+			if errors.Is(err, errPipelineHalted) {
+				return nil
+			}
+			return err
+		}
+		return advanceUploadPipeline(db, &file, jobType)
+	}
+}
+
+// advanceUploadPipeline enfileira a etapa seguinte a current ou, se ela era a última,
+// marca o arquivo como pronto
+func advanceUploadPipeline(db *gorm.DB, file *models.File, current string) error {
+	for i, jobType := range uploadPipeline {
+		if jobType == current && i+1 < len(uploadPipeline) {
+			return jobs.Enqueue(db, uploadPipeline[i+1], fileJob{FileID: file.ID}, nil)
+		}
+	}
+	return db.Model(&models.File{}).
+		Where("id = ? AND status = ?", file.ID, models.FileStatusPending).
+		Update("status", models.FileStatusReady).Error
+}
+
+// failUpload marca o arquivo como falho quando uma etapa esgota as tentativas
+func failUpload(db *gorm.DB) jobs.DeadFunc {
+	return func(ctx context.Context, job *models.Job, err error) {
+		var payload fileJob
+		if jobs.Decode(job, &payload) != nil {
+			return
+		}
+		if err := db.Model(&models.File{}).Where("id = ?", payload.FileID).
+			Update("status", models.FileStatusFailed).Error; err != nil {
+			log.Printf("⚠️  Warning: Could not mark file %s as failed: %v", payload.FileID, err)
+		}
+	}
+}
+
+// verifyChecksum relê o conteúdo do armazenamento e confere o SHA-256 do blob,
+// detectando gravações corrompidas
+func verifyChecksum(ctx context.Context, db *gorm.DB, store storage.Driver, file *models.File) error {
+	// Arquivos anteriores à deduplicação não têm hash registrado
+	if file.BlobID == nil {
+		return nil
+	}
+	var blob models.Blob
+	if err := db.First(&blob, "id = ?", *file.BlobID).Error; err != nil {
+		return err
+	}
+
+	rc, err := store.Get(ctx, blob.StorageKey)
+	if errors.Is(err, storage.ErrNotExist) {
+		return jobs.Permanent(fmt.Errorf("content %s is missing from storage", blob.StorageKey))
+	}
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, rc); err != nil {
+		return err
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != blob.Hash {
+		return jobs.Permanent(fmt.Errorf("checksum mismatch: expected %s, got %s", blob.Hash, sum))
+	}
+	return nil
+}
+
+// scanFile verifica o conteúdo com o scanner configurado. Conteúdo infectado coloca o
+// arquivo em quarentena e encerra o pipeline.
+func scanFile(ctx context.Context, db *gorm.DB, store storage.Driver, scan scanner.Scanner, file *models.File) error {
+	if scan == nil {
+		return nil
+	}
+	key, err := storageKeyForFile(db, file)
+	if err != nil {
+		return err
+	}
+	rc, err := store.Get(ctx, key)
+	if errors.Is(err, storage.ErrNotExist) {
+		return jobs.Permanent(fmt.Errorf("content %s is missing from storage", key))
+	}
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	result, err := scan.Scan(ctx, rc)
+	if err != nil {
+		return fmt.Errorf("%s scan failed: %w", scan.Name(), err)
+	}
+	if !result.Infected {
+		return nil
+	}
+
+	log.Printf("☣️  File %s quarantined by %s scanner: %s", file.ID, scan.Name(), result.Signature)
+	if err := db.Model(file).Updates(map[string]interface{}{
+		"status":      models.FileStatusQuarantined,
+		"scan_result": result.Signature,
+	}).Error; err != nil {
+		return err
+	}
+	return errPipelineHalted
+}
+
+// createThumbnails gera as miniaturas de imagens JPEG e PNG. Imagens que não podem ser
+// decodificadas continuam disponíveis, apenas sem miniaturas.
+func createThumbnails(ctx context.Context, db *gorm.DB, store, cache storage.Driver, file *models.File) error {
+	if !imaging.Supported(file.MimeType) {
+		return nil
+	}
+
+	var project models.Project
+	if err := db.First(&project, "id = ?", file.ProjectID).Error; err != nil {
+		return err
+	}
+
+	img, err := loadImage(ctx, db, store, file)
+	if errors.Is(err, imaging.ErrTooManyPixels) || errors.Is(err, imaging.ErrUnsupportedType) {
+		log.Printf("⚠️  Skipping thumbnails for file %s: %v", file.ID, err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return generateThumbnails(ctx, db, cache, projectOwner(&project), file, img)
+}
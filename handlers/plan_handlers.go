@@ -0,0 +1,310 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/billing"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/config"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/mailer"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/middleware"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+type AvailablePlan struct {
+	ID               uuid.UUID `json:"id"`
+	Name             string    `json:"name"`
+	Price            float64   `json:"price"` // Por período, em BILLING_CURRENCY
+	StorageLimit     int64     `json:"storage_limit"`
+	MaxFileSize      int64     `json:"max_file_size"`
+	AllowedMimeTypes []string  `json:"allowed_mime_types"`
+	DailyUploadLimit int       `json:"daily_upload_limit"`
+	// Excedentes cobrados na fatura mensal
+	StorageOverageRate float64 `json:"storage_overage_rate"` // Por GB-mês acima de storage_limit
+	IncludedEgress     int64   `json:"included_egress"`      // Bytes servidos por mês incluídos no preço
+	EgressOverageRate  float64 `json:"egress_overage_rate"`  // Por GB além de included_egress
+	EgressLimit        int64   `json:"egress_limit"`         // Bytes servidos por mês antes de /files/ recusar; 0 = ilimitado
+}
+
+type AvailablePlansResponse struct {
+	Plans    []AvailablePlan `json:"plans"`
+	Currency string          `json:"currency"`
+}
+
+type PlanChangeRequest struct {
+	Plan string `json:"plan"` // Nome ou ID do plano
+}
+
+type PlanChangeInfo struct {
+	ID            uuid.UUID  `json:"id"`
+	From          string     `json:"from"`
+	To            string     `json:"to"`
+	Status        string     `json:"status"`
+	EffectiveAt   time.Time  `json:"effective_at"`
+	AppliedAt     *time.Time `json:"applied_at,omitempty"`
+	Amount        float64    `json:"amount"`
+	PaymentID     string     `json:"payment_id,omitempty"`
+	FailureReason string     `json:"failure_reason,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+type PlanChangesResponse struct {
+	Changes []PlanChangeInfo `json:"changes"`
+}
+
+type SubscriptionResponse struct {
+	Plan          AvailablePlan   `json:"plan"`
+	Currency      string          `json:"currency"`
+	RenewsAt      *time.Time      `json:"renews_at,omitempty"` // Nulo nos planos gratuitos
+	StorageUsage  int64           `json:"storage_usage"`
+	ReadOnly      bool            `json:"read_only"`               // Uso acima do limite: uploads são recusados
+	GraceEndsAt   *time.Time      `json:"grace_ends_at,omitempty"` // Depois dele os arquivos deixam de ser servidos
+	Restricted    bool            `json:"restricted"`              // Prazo esgotado: /files/ responde 402
+	PendingChange *PlanChangeInfo `json:"pending_change,omitempty"`
+}
+
+// maxPlanChanges limita o histórico retornado por /api/plan/changes
+const maxPlanChanges = 50
+
+func toAvailablePlan(plan *models.Plan) AvailablePlan {
+	return AvailablePlan{
+		ID:                 plan.ID,
+		Name:               plan.Name,
+		Price:              plan.Price,
+		StorageLimit:       plan.StorageLimit,
+		MaxFileSize:        plan.MaxFileSize,
+		AllowedMimeTypes:   plan.MimeTypes(),
+		DailyUploadLimit:   plan.DailyUploadLimit,
+		StorageOverageRate: plan.StorageOverageRate,
+		IncludedEgress:     plan.IncludedEgress,
+		EgressOverageRate:  plan.EgressOverageRate,
+		EgressLimit:        plan.EgressLimit,
+	}
+}
+
+func toPlanChangeInfo(change *models.PlanChange) PlanChangeInfo {
+	return PlanChangeInfo{
+		ID:            change.ID,
+		From:          change.FromPlan.Name,
+		To:            change.ToPlan.Name,
+		Status:        change.Status,
+		EffectiveAt:   change.EffectiveAt,
+		AppliedAt:     change.AppliedAt,
+		Amount:        change.Amount,
+		PaymentID:     change.PaymentID,
+		FailureReason: change.FailureReason,
+		CreatedAt:     change.CreatedAt,
+	}
+}
+
+// currentUser recarrega o usuário autenticado com o plano
+func currentUser(w http.ResponseWriter, r *http.Request, db *gorm.DB) (*models.User, bool) {
+	userFromCtx, _ := r.Context().Value(middleware.UserContextKey).(*models.User)
+	var user models.User
+	if err := db.Preload("Plan").First(&user, "id = ?", userFromCtx.ID).Error; err != nil {
+		http.Error(w, "Could not retrieve user details", http.StatusInternalServerError)
+		return nil, false
+	}
+	return &user, true
+}
+
+// AvailablePlansHandler godoc
+// @Summary List plans
+// @Description Lists the plans a user can subscribe to, from the cheapest. Prices are per billing period.
+// @Tags billing
+// @Produce  json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} AvailablePlansResponse
+// @Router /api/plans [get]
+func AvailablePlansHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		var plans []models.Plan
+		db.Order("price, name").Find(&plans)
+		infos := make([]AvailablePlan, 0, len(plans))
+		for i := range plans {
+			infos = append(infos, toAvailablePlan(&plans[i]))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(AvailablePlansResponse{Plans: infos, Currency: config.AppConfig.BillingCurrency})
+	}
+}
+
+// SubscriptionHandler godoc
+// @Summary Get the current plan
+// @Description Returns the user's plan, when the paid period renews and the scheduled plan change, if any. read_only is true while storage usage is above the plan's limit (after a downgrade): uploads are refused, and once grace_ends_at passes the user's files are no longer served (restricted).
+// @Tags billing
+// @Produce  json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} SubscriptionResponse
+// @Failure 500 {string} string "Could not retrieve user details"
+// @Router /api/plan [get]
+func SubscriptionHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		user, ok := currentUser(w, r, db)
+		if !ok {
+			return
+		}
+
+		now := time.Now()
+		resp := SubscriptionResponse{
+			Plan:         toAvailablePlan(&user.Plan),
+			Currency:     config.AppConfig.BillingCurrency,
+			RenewsAt:     user.PlanRenewsAt,
+			StorageUsage: user.StorageUsage,
+			ReadOnly:     overQuota(user),
+		}
+		if resp.ReadOnly {
+			resp.GraceEndsAt = user.GraceEndsAt
+			resp.Restricted = user.GraceEndsAt != nil && !now.Before(*user.GraceEndsAt)
+		}
+		if change, err := pendingPlanChange(db, user.ID); err == nil {
+			info := toPlanChangeInfo(change)
+			resp.PendingChange = &info
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// PlanChangeHandler godoc
+// @Summary Change or cancel a plan change
+// @Description POST changes the user's plan, identified by name or ID. Upgrades to a more expensive plan are charged and applied immediately, starting a new billing period (200). Other changes take effect at the end of the current paid period (202) or immediately when there is none. A new request replaces a scheduled one. If storage usage is above the new plan's limit, uploads are disabled and the files stop being served when the grace period ends, unless usage drops. DELETE cancels the scheduled change.
+// @Tags billing
+// @Accept  json
+// @Produce  json
+// @Param   plan_request  body  PlanChangeRequest  false  "Plan name or ID (POST only)"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} PlanChangeInfo "Plan changed, or scheduled change cancelled"
+// @Success 202 {object} PlanChangeInfo "Change scheduled for the end of the billing period"
+// @Failure 400 {string} string "Invalid request body, plan not found or already on this plan"
+// @Failure 402 {string} string "Payment declined"
+// @Failure 404 {string} string "No scheduled plan change"
+// @Failure 409 {string} string "Another plan change is in progress"
+// @Failure 502 {string} string "Could not charge the payment"
+// @Router /api/plan/change [post]
+// @Router /api/plan/change [delete]
+func PlanChangeHandler(db *gorm.DB, provider billing.Provider, m mailer.Mailer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		user, ok := currentUser(w, r, db)
+		if !ok {
+			return
+		}
+
+		if r.Method == http.MethodDelete {
+			change, err := pendingPlanChange(db, user.ID)
+			if err != nil {
+				http.Error(w, "No scheduled plan change", http.StatusNotFound)
+				return
+			}
+			if err := db.Model(change).Update("status", models.PlanChangeCanceled).Error; err != nil {
+				http.Error(w, "Could not cancel plan change: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			recordAudit(db, r, models.AuditPlanCancel, change.ToPlan.Name, models.AuditSuccess, "")
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(toPlanChangeInfo(change))
+			return
+		}
+
+		var req PlanChangeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Plan == "" {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		plan, err := findPlan(db, req.Plan)
+		if err != nil {
+			http.Error(w, "Plan not found", http.StatusBadRequest)
+			return
+		}
+		if plan.ID == user.PlanID {
+			http.Error(w, "You are already on this plan; cancel the scheduled change instead", http.StatusBadRequest)
+			return
+		}
+
+		detail := user.Plan.Name + " -> " + plan.Name
+		change, err := requestPlanChange(db, provider, m, user, plan, time.Now())
+		switch {
+		case errors.Is(err, errPlanChangeInProgress):
+			http.Error(w, "Another plan change is in progress", http.StatusConflict)
+			return
+		case errors.Is(err, billing.ErrDeclined):
+			recordAudit(db, r, models.AuditPlanRequest, plan.Name, models.AuditFailure, "Payment declined")
+			http.Error(w, "Payment declined", http.StatusPaymentRequired)
+			return
+		case err != nil && change != nil:
+			log.Printf("💳 Cobrança do plano %s para %s falhou: %v", plan.Name, user.Email, err)
+			recordAudit(db, r, models.AuditPlanRequest, plan.Name, models.AuditFailure, err.Error())
+			http.Error(w, "Could not charge the payment", http.StatusBadGateway)
+			return
+		case err != nil:
+			http.Error(w, "Could not change plan: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		status := http.StatusOK
+		if change.Status == models.PlanChangePending {
+			status = http.StatusAccepted
+			detail += " on " + change.EffectiveAt.UTC().Format(time.RFC3339)
+		}
+		recordAudit(db, r, models.AuditPlanRequest, plan.Name, models.AuditSuccess, detail)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(toPlanChangeInfo(change))
+	}
+}
+
+// PlanChangesHandler godoc
+// @Summary List plan changes
+// @Description Lists the user's most recent plan changes, including scheduled, cancelled and failed ones and changes made by administrators, newest first.
+// @Tags billing
+// @Produce  json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} PlanChangesResponse
+// @Router /api/plan/changes [get]
+func PlanChangesHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		user, _ := r.Context().Value(middleware.UserContextKey).(*models.User)
+
+		var changes []models.PlanChange
+		db.Preload("FromPlan").Preload("ToPlan").
+			Where("user_id = ?", user.ID).
+			Order("created_at DESC").Limit(maxPlanChanges).Find(&changes)
+		infos := make([]PlanChangeInfo, 0, len(changes))
+		for i := range changes {
+			infos = append(infos, toPlanChangeInfo(&changes[i]))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(PlanChangesResponse{Changes: infos})
+	}
+}
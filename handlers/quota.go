@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// today retorna a data corrente (UTC) usada como chave da cota diária
+func today() string {
+	return time.Now().UTC().Format("2006-01-02")
+}
+
+// uploadsToday retorna quantos uploads o usuário já fez hoje
+func uploadsToday(db *gorm.DB, userID uuid.UUID) int {
+	var count models.DailyUploadCount
+	if err := db.Where("user_id = ? AND day = ?", userID, today()).First(&count).Error; err != nil {
+		return 0
+	}
+	return count.Uploads
+}
+
+// remainingDailyUploads retorna quantos uploads ainda cabem na cota de hoje, ou -1 se o plano não tem limite
+func remainingDailyUploads(db *gorm.DB, userID uuid.UUID, plan *models.Plan) int {
+	if plan.DailyUploadLimit <= 0 {
+		return -1
+	}
+	return max(plan.DailyUploadLimit-uploadsToday(db, userID), 0)
+}
+
+// consumeDailyUpload registra um upload na cota diária do usuário e retorna false se
+// o limite do plano já foi atingido. O incremento é feito em um único comando, então
+// uploads simultâneos não conseguem ultrapassar o limite.
+func consumeDailyUpload(db *gorm.DB, userID uuid.UUID, plan *models.Plan) (bool, error) {
+	if plan.DailyUploadLimit <= 0 {
+		err := db.Exec(`INSERT INTO daily_upload_counts (user_id, day, uploads) VALUES (?, ?, 1)
+			ON CONFLICT (user_id, day) DO UPDATE SET uploads = daily_upload_counts.uploads + 1`,
+			userID, today()).Error
+		return err == nil, err
+	}
+
+	result := db.Exec(`INSERT INTO daily_upload_counts (user_id, day, uploads) VALUES (?, ?, 1)
+		ON CONFLICT (user_id, day) DO UPDATE SET uploads = daily_upload_counts.uploads + 1
+		WHERE daily_upload_counts.uploads < ?`,
+		userID, today(), plan.DailyUploadLimit)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// refundDailyUpload devolve à cota do dia (no formato de today) um upload cujo arquivo
+// não chegou a ser salvo
+func refundDailyUpload(db *gorm.DB, userID uuid.UUID, day string) {
+	db.Exec(`UPDATE daily_upload_counts SET uploads = uploads - 1 WHERE user_id = ? AND day = ? AND uploads > 0`,
+		userID, day)
+}
+
+// writeDailyQuotaExceeded responde 429 com Retry-After até a virada do dia (UTC)
+func writeDailyQuotaExceeded(w http.ResponseWriter, plan *models.Plan) {
+	now := time.Now().UTC()
+	midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	w.Header().Set("Retry-After", strconv.Itoa(int(midnight.Sub(now).Seconds())+1))
+	http.Error(w, "Daily upload limit of "+strconv.Itoa(plan.DailyUploadLimit)+" uploads reached for your plan", http.StatusTooManyRequests)
+}
@@ -0,0 +1,402 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/config"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/middleware"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/util"
+)
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type TokenResponse struct {
+	Token                 string    `json:"token"`
+	ExpiresIn             int       `json:"expires_in"` // Validade do access token, em segundos
+	RefreshToken          string    `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"`
+}
+
+type SessionInfo struct {
+	ID         uuid.UUID `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	StartedAt  time.Time `json:"started_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+type SessionsResponse struct {
+	Sessions []SessionInfo `json:"sessions"`
+}
+
+// errRefreshTokenInvalid cobre tokens inexistentes, expirados, revogados ou reutilizados
+var errRefreshTokenInvalid = errors.New("Invalid or expired refresh token")
+
+// issueTokens emite um access token e um refresh token pertencentes à família (sessão)
+// informada. Um login novo deve passar uuid.New() como família.
+func issueTokens(db *gorm.DB, r *http.Request, user *models.User, familyID uuid.UUID, startedAt time.Time) (*TokenResponse, error) {
+	accessToken, claims, err := util.GenerateJWT(user)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, refreshHash, err := util.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	record := models.RefreshToken{
+		UserID:           user.ID,
+		FamilyID:         familyID,
+		TokenHash:        refreshHash,
+		AccessJTI:        claims.ID,
+		UserAgent:        r.UserAgent(),
+		IPAddress:        util.ClientIP(r),
+		SessionStartedAt: startedAt,
+		ExpiresAt:        time.Now().Add(config.AppConfig.RefreshTokenTTL),
+	}
+	if err := db.Create(&record).Error; err != nil {
+		return nil, err
+	}
+
+	return &TokenResponse{
+		Token:                 accessToken,
+		ExpiresIn:             int(config.AppConfig.AccessTokenTTL.Seconds()),
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: record.ExpiresAt,
+	}, nil
+}
+
+// revokeAccessToken coloca o jti na lista de revogação até a expiração do token
+func revokeAccessToken(db *gorm.DB, userID uuid.UUID, jti string, expiresAt time.Time) error {
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.RevokedToken{
+		JTI:       jti,
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+	}).Error
+}
+
+// revokeSession revoga todos os refresh tokens da família e os access tokens emitidos
+// com eles que ainda podem estar válidos
+func revokeSession(db *gorm.DB, userID, familyID uuid.UUID) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.RefreshToken{}).
+			Where("user_id = ? AND family_id = ? AND revoked_at IS NULL", userID, familyID).
+			Update("revoked_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		var tokens []models.RefreshToken
+		if err := tx.Where("user_id = ? AND family_id = ? AND created_at > ?", userID, familyID, time.Now().Add(-config.AppConfig.AccessTokenTTL)).
+			Find(&tokens).Error; err != nil {
+			return err
+		}
+		for _, t := range tokens {
+			if err := revokeAccessToken(tx, userID, t.AccessJTI, t.CreatedAt.Add(config.AppConfig.AccessTokenTTL)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// revokeAllSessions encerra todas as sessões ativas do usuário e retorna quantas eram
+func revokeAllSessions(db *gorm.DB, userID uuid.UUID) int {
+	var families []uuid.UUID
+	db.Model(&models.RefreshToken{}).Where("user_id = ? AND revoked_at IS NULL", userID).Distinct().Pluck("family_id", &families)
+	for _, familyID := range families {
+		if err := revokeSession(db, userID, familyID); err != nil {
+			log.Printf("⚠️  Warning: Failed to revoke session %s: %v", familyID, err)
+		}
+	}
+	return len(families)
+}
+
+// rotateRefreshToken troca um refresh token válido por um novo par de tokens. Se o
+// token apresentado já tiver sido trocado, assume-se que vazou: a sessão inteira é revogada.
+func rotateRefreshToken(db *gorm.DB, r *http.Request, presented string) (*TokenResponse, error) {
+	var current models.RefreshToken
+	if err := db.First(&current, "token_hash = ?", util.HashToken(presented)).Error; err != nil {
+		return nil, errRefreshTokenInvalid
+	}
+
+	if current.RevokedAt != nil {
+		log.Printf("🚨 Refresh token reutilizado para o usuário %s; revogando a sessão %s", current.UserID, current.FamilyID)
+		if err := revokeSession(db, current.UserID, current.FamilyID); err != nil {
+			log.Printf("⚠️  Warning: Failed to revoke session %s: %v", current.FamilyID, err)
+		}
+		return nil, errRefreshTokenInvalid
+	}
+	if time.Now().After(current.ExpiresAt) {
+		return nil, errRefreshTokenInvalid
+	}
+
+	var user models.User
+	if err := db.First(&user, current.UserID).Error; err != nil {
+		return nil, errRefreshTokenInvalid
+	}
+
+	var resp *TokenResponse
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// A condição em revoked_at garante que duas trocas simultâneas do mesmo token
+		// não gerem dois pares válidos
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", current.ID).
+			Update("revoked_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errRefreshTokenInvalid
+		}
+
+		var err error
+		resp, err = issueTokens(tx, r, &user, current.FamilyID, current.SessionStartedAt)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// CleanupExpiredTokens remove refresh tokens expirados, entradas da lista de revogação
+// cujos access tokens já expiraram, tokens de e-mail e desafios de 2FA vencidos
+func CleanupExpiredTokens(db *gorm.DB) (int64, error) {
+	now := time.Now()
+	refresh := db.Where("expires_at <= ?", now).Delete(&models.RefreshToken{})
+	if refresh.Error != nil {
+		return 0, refresh.Error
+	}
+	revoked := db.Where("expires_at <= ?", now).Delete(&models.RevokedToken{})
+	if revoked.Error != nil {
+		return refresh.RowsAffected, revoked.Error
+	}
+	userTokens := db.Where("expires_at <= ?", now).Delete(&models.UserToken{})
+	if userTokens.Error != nil {
+		return refresh.RowsAffected + revoked.RowsAffected, userTokens.Error
+	}
+	challenges := db.Where("expires_at <= ?", now).Delete(&models.LoginChallenge{})
+	if challenges.Error != nil {
+		return refresh.RowsAffected + revoked.RowsAffected + userTokens.RowsAffected, challenges.Error
+	}
+	return refresh.RowsAffected + revoked.RowsAffected + userTokens.RowsAffected + challenges.RowsAffected, nil
+}
+
+// StartTokenJanitor executa CleanupExpiredTokens e cleanupLoginFailures periodicamente em segundo plano
+func StartTokenJanitor(db *gorm.DB, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			removed, err := CleanupExpiredTokens(db)
+			if err != nil {
+				log.Printf("⚠️  Warning: Failed to clean up expired tokens: %v", err)
+			} else if removed > 0 {
+				log.Printf("🧹 Removed %d expired token(s)", removed)
+			}
+			if err := cleanupLoginFailures(db, time.Now()); err != nil {
+				log.Printf("⚠️  Warning: Failed to clean up login failures: %v", err)
+			}
+		}
+	}()
+}
+
+// RefreshTokenHandler godoc
+// @Summary Refresh the access token
+// @Description Exchanges a refresh token for a new access token and a new refresh token. Each refresh token can be used only once; reusing an already exchanged token revokes the whole session.
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param   refresh_request  body  RefreshRequest  true  "Refresh token returned by /login or by a previous refresh"
+// @Success 200 {object} TokenResponse
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Invalid or expired refresh token"
+// @Failure 429 {string} string "Too many requests (see Retry-After)"
+// @Failure 500 {string} string "Could not generate token"
+// @Router /token/refresh [post]
+func RefreshTokenHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req RefreshRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		resp, err := rotateRefreshToken(db, r, req.RefreshToken)
+		if errors.Is(err, errRefreshTokenInvalid) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Could not generate token", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// LogoutHandler godoc
+// @Summary Log out
+// @Description Ends the current session: the access token used in the request is revoked along with its refresh token. A refresh token may also be sent in the body to end that session instead (e.g. when authenticating with an API key).
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param   refresh_request  body  RefreshRequest  false  "Refresh token of the session to end"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string "message: Logged out successfully"
+// @Failure 400 {string} string "No session to end"
+// @Failure 500 {string} string "Could not end session"
+// @Router /logout [post]
+func LogoutHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		user, _ := r.Context().Value(middleware.UserContextKey).(*models.User)
+		claims, _ := r.Context().Value(middleware.ClaimsContextKey).(*util.Claims)
+
+		var req RefreshRequest
+		json.NewDecoder(r.Body).Decode(&req) // O corpo é opcional
+
+		var session models.RefreshToken
+		var found bool
+		if req.RefreshToken != "" {
+			found = db.First(&session, "token_hash = ? AND user_id = ?", util.HashToken(req.RefreshToken), user.ID).Error == nil
+		} else if claims != nil {
+			found = db.First(&session, "access_jti = ? AND user_id = ?", claims.ID, user.ID).Error == nil
+		}
+
+		if !found && claims == nil {
+			http.Error(w, "No session to end", http.StatusBadRequest)
+			return
+		}
+
+		if found {
+			if err := revokeSession(db, user.ID, session.FamilyID); err != nil {
+				http.Error(w, "Could not end session: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		if claims != nil {
+			if err := revokeAccessToken(db, user.ID, claims.ID, claims.ExpiresAt.Time); err != nil {
+				http.Error(w, "Could not end session: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"message": "Logged out successfully",
+		})
+	}
+}
+
+// SessionsHandler godoc
+// @Summary List active sessions
+// @Description Lists the user's active login sessions (one per device/login), with the most recent activity first.
+// @Tags api
+// @Produce  json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} SessionsResponse
+// @Router /api/sessions [get]
+func SessionsHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		user, _ := r.Context().Value(middleware.UserContextKey).(*models.User)
+		claims, _ := r.Context().Value(middleware.ClaimsContextKey).(*util.Claims)
+
+		// O refresh token ainda não usado de cada família representa a sessão
+		var tokens []models.RefreshToken
+		db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", user.ID, time.Now()).
+			Order("created_at DESC").Find(&tokens)
+
+		sessions := make([]SessionInfo, 0, len(tokens))
+		for _, t := range tokens {
+			sessions = append(sessions, SessionInfo{
+				ID:         t.FamilyID,
+				UserAgent:  t.UserAgent,
+				IPAddress:  t.IPAddress,
+				StartedAt:  t.SessionStartedAt,
+				LastUsedAt: t.CreatedAt,
+				ExpiresAt:  t.ExpiresAt,
+				Current:    claims != nil && claims.ID == t.AccessJTI,
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(SessionsResponse{Sessions: sessions})
+	}
+}
+
+// RevokeSessionHandler godoc
+// @Summary Revoke a session
+// @Description Ends one of the user's sessions, e.g. on a lost or stolen device. Its refresh token stops working immediately and its access token is rejected.
+// @Tags api
+// @Produce  json
+// @Param   id  path  string  true  "Session ID"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} map[string]string "message: Session revoked"
+// @Failure 404 {string} string "Session not found"
+// @Failure 500 {string} string "Could not revoke session"
+// @Router /api/sessions/{id} [delete]
+func RevokeSessionHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		user, _ := r.Context().Value(middleware.UserContextKey).(*models.User)
+		familyID, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			http.Error(w, "Session not found", http.StatusNotFound)
+			return
+		}
+
+		var count int64
+		db.Model(&models.RefreshToken{}).Where("user_id = ? AND family_id = ?", user.ID, familyID).Count(&count)
+		if count == 0 {
+			http.Error(w, "Session not found", http.StatusNotFound)
+			return
+		}
+
+		if err := revokeSession(db, user.ID, familyID); err != nil {
+			http.Error(w, "Could not revoke session: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"message": "Session revoked",
+			"session": familyID.String(),
+		})
+	}
+}
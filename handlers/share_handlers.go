@@ -0,0 +1,486 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/config"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/mailer"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/middleware"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+type ShareRequest struct {
+	Email      string `json:"email,omitempty"` // Apenas ao convidar
+	Permission string `json:"permission"`
+}
+
+type CollaboratorInfo struct {
+	UserID     uuid.UUID  `json:"user_id"`
+	Name       string     `json:"name"`
+	Email      string     `json:"email"`
+	Permission string     `json:"permission"`
+	Status     string     `json:"status"` // pending até o convite ser aceito, depois accepted
+	CreatedAt  time.Time  `json:"created_at"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+}
+
+type CollaboratorsResponse struct {
+	Project       string             `json:"project"`
+	Collaborators []CollaboratorInfo `json:"collaborators"`
+}
+
+type InvitationInfo struct {
+	ID         uuid.UUID `json:"id"`
+	Project    string    `json:"project"`
+	Owner      string    `json:"owner"` // E-mail do dono, usado no parâmetro owner depois de aceitar
+	Permission string    `json:"permission"`
+	InvitedBy  string    `json:"invited_by,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type InvitationsResponse struct {
+	Invitations []InvitationInfo `json:"invitations"`
+}
+
+// validatePermission confere se a permissão é conhecida
+func validatePermission(permission string) error {
+	if !slices.Contains(models.AllPermissions, permission) {
+		return errors.New("Unknown permission. Valid permissions are: " + strings.Join(models.AllPermissions, ", ") + ".")
+	}
+	return nil
+}
+
+// writePermissionForbidden responde 403 para colaboradores sem a permissão necessária.
+// minRole é o papel equivalente à permissão (ver models.PermissionRole).
+func writePermissionForbidden(w http.ResponseWriter, minRole string) {
+	for _, permission := range models.AllPermissions {
+		if models.PermissionRole(permission) == minRole {
+			http.Error(w, fmt.Sprintf("This action requires the %q permission on the project", permission), http.StatusForbidden)
+			return
+		}
+	}
+	http.Error(w, "Only the project owner can do this", http.StatusForbidden)
+}
+
+// shareableProject resolve o projeto pessoal indicado em ?project= (e ?owner= quando o
+// usuário é colaborador) e o papel do usuário nele. Projetos de organizações são
+// compartilhados pelas associações, não por convites.
+func shareableProject(w http.ResponseWriter, r *http.Request, db *gorm.DB, user *models.User, minRole string) (*namespace, *models.Project, string, bool) {
+	query := r.URL.Query()
+	if query.Get("org") != "" {
+		http.Error(w, "Organization projects are shared through organization memberships", http.StatusBadRequest)
+		return nil, nil, "", false
+	}
+	projectName := query.Get("project")
+	if projectName == "" {
+		http.Error(w, "Project name is required", http.StatusBadRequest)
+		return nil, nil, "", false
+	}
+	ns, ok := queryNamespace(w, db, user, query, minRole)
+	if !ok {
+		return nil, nil, "", false
+	}
+	project, ok := ns.requireProject(w, db, projectName, minRole)
+	if !ok {
+		return nil, nil, "", false
+	}
+	return ns, project, ns.projectRole(db, project), true
+}
+
+// toCollaboratorInfo converte o convite, com o usuário carregado, para a resposta da API
+func toCollaboratorInfo(share *models.ProjectMember) CollaboratorInfo {
+	status := "pending"
+	if share.AcceptedAt != nil {
+		status = "accepted"
+	}
+	return CollaboratorInfo{
+		UserID:     share.UserID,
+		Name:       share.User.Name,
+		Email:      share.User.Email,
+		Permission: share.Permission,
+		Status:     status,
+		CreatedAt:  share.CreatedAt,
+		AcceptedAt: share.AcceptedAt,
+	}
+}
+
+// sendInvitationEmail avisa o convidado sobre o convite
+func sendInvitationEmail(m mailer.Mailer, inviter, invitee *models.User, project *models.Project, permission string) {
+	sendMail(m, mailer.Message{
+		To:      invitee.Email,
+		Subject: fmt.Sprintf("%s shared the project %q with you", inviter.Name, project.Name),
+		Body: fmt.Sprintf("Hi %s,\n\n%s (%s) invited you to the project %q with the %q permission.\n\n"+
+			"List your pending invitations with GET %s/api/invitations and accept this one with "+
+			"POST %s/api/invitations/{id}/accept. If you do not want access, decline it with DELETE %s/api/invitations/{id}.\n",
+			invitee.Name, inviter.Name, inviter.Email, project.Name, permission,
+			config.AppConfig.Domain, config.AppConfig.Domain, config.AppConfig.Domain),
+	})
+}
+
+// ProjectMembersHandler godoc
+// @Summary List or invite project collaborators
+// @Description Shares a single personal project with another user. GET lists the collaborators and pending invitations (any collaborator). POST invites an existing user by email with a permission: read (list files and sign URLs), upload (also upload and delete files) or manage (also delete the project and manage collaborators); read by default. The invitee is notified by e-mail and gets access after accepting. Collaborators with the manage permission can invite read and upload collaborators; only the owner grants manage. Collaborators pass owner with the owner's e-mail.
+// @Tags sharing
+// @Accept  json
+// @Produce  json
+// @Param   project        query  string        true   "Project name"
+// @Param   owner          query  string        false  "E-mail of the user who shared the project"
+// @Param   share_request  body   ShareRequest  false  "Email and permission (POST only)"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} CollaboratorsResponse "Collaborators"
+// @Success 201 {object} CollaboratorInfo "Invitation sent"
+// @Failure 400 {string} string "Project name is required, invalid request body or permission, organization project or the owner was invited"
+// @Failure 403 {string} string "Permission does not allow this action or API key is restricted to a project"
+// @Failure 404 {string} string "Project or user not found"
+// @Failure 409 {string} string "User is already a collaborator or has a pending invitation"
+// @Router /api/project/members [get]
+// @Router /api/project/members [post]
+func ProjectMembersHandler(db *gorm.DB, m mailer.Mailer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, _ := r.Context().Value(middleware.UserContextKey).(*models.User)
+		if !requireUnrestrictedKey(w, r) {
+			return
+		}
+
+		minRole := models.RoleViewer
+		if r.Method == http.MethodPost {
+			minRole = models.RoleAdmin
+		}
+		ns, project, role, ok := shareableProject(w, r, db, user, minRole)
+		if !ok {
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			var shares []models.ProjectMember
+			db.Preload("User").Where("project_id = ?", project.ID).Order("created_at").Find(&shares)
+
+			infos := make([]CollaboratorInfo, 0, len(shares))
+			for _, share := range shares {
+				infos = append(infos, toCollaboratorInfo(&share))
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(CollaboratorsResponse{Project: project.Name, Collaborators: infos})
+
+		case http.MethodPost:
+			var req ShareRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			if req.Permission == "" {
+				req.Permission = models.PermissionRead
+			}
+			if err := validatePermission(req.Permission); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if req.Permission == models.PermissionManage && role != models.RoleOwner {
+				writePermissionForbidden(w, models.RoleOwner)
+				return
+			}
+
+			target := ns.target(project.Name + ":" + req.Email)
+			var invitee models.User
+			if err := db.First(&invitee, "email = ?", strings.TrimSpace(req.Email)).Error; err != nil {
+				http.Error(w, "User not found", http.StatusNotFound)
+				return
+			}
+			if invitee.ID == project.UserID {
+				http.Error(w, "The project owner already has access", http.StatusBadRequest)
+				return
+			}
+			share := models.ProjectMember{ProjectID: project.ID, UserID: invitee.ID, Permission: req.Permission, InvitedByID: &user.ID}
+			result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&share)
+			if result.Error != nil {
+				recordAudit(db, r, models.AuditShareInvite, target, models.AuditFailure, result.Error.Error())
+				http.Error(w, "Could not invite collaborator: "+result.Error.Error(), http.StatusInternalServerError)
+				return
+			}
+			if result.RowsAffected == 0 {
+				http.Error(w, "User is already a collaborator or has a pending invitation", http.StatusConflict)
+				return
+			}
+			recordAudit(db, r, models.AuditShareInvite, target, models.AuditSuccess, req.Permission)
+			sendInvitationEmail(m, user, &invitee, project, req.Permission)
+			share.User = invitee
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(toCollaboratorInfo(&share))
+
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// ProjectMemberHandler godoc
+// @Summary Change a collaborator's permission or revoke access
+// @Description PATCH changes the collaborator's permission. DELETE revokes the access or cancels a pending invitation; collaborators can remove themselves to leave the project. Collaborators with the manage permission manage read and upload collaborators; only the owner changes or removes manage collaborators.
+// @Tags sharing
+// @Accept  json
+// @Produce  json
+// @Param   project        query  string        true   "Project name"
+// @Param   owner          query  string        false  "E-mail of the user who shared the project"
+// @Param   user_id        path   string        true   "Collaborator user ID"
+// @Param   share_request  body   ShareRequest  false  "New permission (PATCH only)"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} CollaboratorInfo
+// @Success 204 {string} string "Access revoked"
+// @Failure 400 {string} string "Project name is required, invalid request body or permission"
+// @Failure 403 {string} string "Permission does not allow this action or API key is restricted to a project"
+// @Failure 404 {string} string "Project or collaborator not found"
+// @Router /api/project/members/{user_id} [patch]
+// @Router /api/project/members/{user_id} [delete]
+func ProjectMemberHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, _ := r.Context().Value(middleware.UserContextKey).(*models.User)
+		if !requireUnrestrictedKey(w, r) {
+			return
+		}
+		ns, project, role, ok := shareableProject(w, r, db, user, models.RoleViewer)
+		if !ok {
+			return
+		}
+
+		collaboratorID, err := uuid.Parse(r.PathValue("user_id"))
+		if err != nil {
+			http.Error(w, "Collaborator not found", http.StatusNotFound)
+			return
+		}
+		var share models.ProjectMember
+		if err := db.Preload("User").First(&share, "project_id = ? AND user_id = ?", project.ID, collaboratorID).Error; err != nil {
+			http.Error(w, "Collaborator not found", http.StatusNotFound)
+			return
+		}
+		target := ns.target(project.Name + ":" + share.User.Email)
+		leaving := r.Method == http.MethodDelete && collaboratorID == user.ID
+
+		// Colaboradores com manage gerenciam os demais; os com manage só são gerenciados pelo dono
+		if !leaving {
+			required := models.RoleAdmin
+			if share.Permission == models.PermissionManage {
+				required = models.RoleOwner
+			}
+			if !models.RoleAllows(role, required) {
+				writePermissionForbidden(w, required)
+				return
+			}
+		}
+
+		switch r.Method {
+		case http.MethodPatch:
+			var req ShareRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			if err := validatePermission(req.Permission); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if req.Permission == models.PermissionManage && role != models.RoleOwner {
+				writePermissionForbidden(w, models.RoleOwner)
+				return
+			}
+			if err := db.Model(&share).Update("permission", req.Permission).Error; err != nil {
+				recordAudit(db, r, models.AuditSharePermission, target, models.AuditFailure, err.Error())
+				http.Error(w, "Could not update collaborator: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			recordAudit(db, r, models.AuditSharePermission, target, models.AuditSuccess, req.Permission)
+			share.Permission = req.Permission
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(toCollaboratorInfo(&share))
+
+		case http.MethodDelete:
+			if err := db.Delete(&share).Error; err != nil {
+				recordAudit(db, r, models.AuditShareRevoke, target, models.AuditFailure, err.Error())
+				http.Error(w, "Could not revoke access: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			recordAudit(db, r, models.AuditShareRevoke, target, models.AuditSuccess, "")
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// loadInvitation busca um convite pendente do usuário pelo ID do caminho
+func loadInvitation(w http.ResponseWriter, r *http.Request, db *gorm.DB, user *models.User) (*models.ProjectMember, bool) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	var share models.ProjectMember
+	if err == nil {
+		err = db.Preload("Project").First(&share, "id = ? AND user_id = ? AND accepted_at IS NULL", id, user.ID).Error
+	}
+	if err != nil {
+		http.Error(w, "Invitation not found", http.StatusNotFound)
+		return nil, false
+	}
+	return &share, true
+}
+
+// invitationOwner retorna o e-mail do dono do projeto do convite
+func invitationOwner(db *gorm.DB, share *models.ProjectMember) string {
+	var owner models.User
+	db.Select("email").First(&owner, "id = ?", share.Project.UserID)
+	return owner.Email
+}
+
+// InvitationsHandler godoc
+// @Summary List pending project invitations
+// @Description Lists the invitations to other users' projects that the authenticated user has not accepted yet.
+// @Tags sharing
+// @Produce  json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} InvitationsResponse
+// @Failure 403 {string} string "API key is restricted to a project"
+// @Router /api/invitations [get]
+func InvitationsHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		user, _ := r.Context().Value(middleware.UserContextKey).(*models.User)
+		if !requireUnrestrictedKey(w, r) {
+			return
+		}
+
+		var shares []models.ProjectMember
+		db.Preload("Project").Where("user_id = ? AND accepted_at IS NULL", user.ID).Order("created_at").Find(&shares)
+
+		// E-mails dos donos e de quem convidou
+		emails := make(map[uuid.UUID]string)
+		var userIDs []uuid.UUID
+		for _, share := range shares {
+			userIDs = append(userIDs, share.Project.UserID)
+			if share.InvitedByID != nil {
+				userIDs = append(userIDs, *share.InvitedByID)
+			}
+		}
+		if len(userIDs) > 0 {
+			var users []models.User
+			db.Select("id", "email").Where("id IN ?", userIDs).Find(&users)
+			for _, u := range users {
+				emails[u.ID] = u.Email
+			}
+		}
+
+		infos := make([]InvitationInfo, 0, len(shares))
+		for _, share := range shares {
+			info := InvitationInfo{
+				ID:         share.ID,
+				Project:    share.Project.Name,
+				Owner:      emails[share.Project.UserID],
+				Permission: share.Permission,
+				CreatedAt:  share.CreatedAt,
+			}
+			if share.InvitedByID != nil {
+				info.InvitedBy = emails[*share.InvitedByID]
+			}
+			infos = append(infos, info)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(InvitationsResponse{Invitations: infos})
+	}
+}
+
+// AcceptInvitationHandler godoc
+// @Summary Accept a project invitation
+// @Description Accepts a pending invitation. The project then shows up in /api/projects and is reachable by the project endpoints with owner set to the owner's e-mail.
+// @Tags sharing
+// @Produce  json
+// @Param   id  path  string  true  "Invitation ID"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} map[string]string "message: Invitation accepted, project, owner, permission"
+// @Failure 403 {string} string "API key is restricted to a project"
+// @Failure 404 {string} string "Invitation not found"
+// @Router /api/invitations/{id}/accept [post]
+func AcceptInvitationHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		user, _ := r.Context().Value(middleware.UserContextKey).(*models.User)
+		if !requireUnrestrictedKey(w, r) {
+			return
+		}
+		share, ok := loadInvitation(w, r, db, user)
+		if !ok {
+			return
+		}
+		owner := invitationOwner(db, share)
+		target := owner + ":" + share.Project.Name
+
+		if err := db.Model(share).Update("accepted_at", time.Now()).Error; err != nil {
+			recordAudit(db, r, models.AuditShareAccept, target, models.AuditFailure, err.Error())
+			http.Error(w, "Could not accept invitation: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		recordAudit(db, r, models.AuditShareAccept, target, models.AuditSuccess, share.Permission)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"message":    "Invitation accepted",
+			"project":    share.Project.Name,
+			"owner":      owner,
+			"permission": share.Permission,
+		})
+	}
+}
+
+// InvitationHandler godoc
+// @Summary Decline a project invitation
+// @Description Declines and removes a pending invitation. To leave a project after accepting, use DELETE /api/project/members/{user_id} with your own user ID.
+// @Tags sharing
+// @Param   id  path  string  true  "Invitation ID"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 204 {string} string "Invitation declined"
+// @Failure 403 {string} string "API key is restricted to a project"
+// @Failure 404 {string} string "Invitation not found"
+// @Router /api/invitations/{id} [delete]
+func InvitationHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		user, _ := r.Context().Value(middleware.UserContextKey).(*models.User)
+		if !requireUnrestrictedKey(w, r) {
+			return
+		}
+		share, ok := loadInvitation(w, r, db, user)
+		if !ok {
+			return
+		}
+		if err := db.Delete(share).Error; err != nil {
+			http.Error(w, "Could not decline invitation: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,470 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/billing"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/config"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/mailer"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// billingTimeout limita cada chamada ao provedor de pagamentos
+const billingTimeout = 30 * time.Second
+
+// errPlanChangeInProgress indica que outra mudança de plano do usuário está pendente
+var errPlanChangeInProgress = errors.New("another plan change is in progress")
+
+// errPlanChangeClaimed indica que outra réplica já encerrou a mudança de plano
+var errPlanChangeClaimed = errors.New("plan change was already processed")
+
+// isUpgrade diz se a mudança é para um plano mais caro, cobrado e aplicado na hora
+func isUpgrade(from, to *models.Plan) bool {
+	return to.Price > from.Price
+}
+
+// planChangeEffectiveAt retorna quando a mudança entra em vigor: upgrades imediatamente;
+// os demais no fim do período pago, ou imediatamente se não houver um em andamento
+func planChangeEffectiveAt(user *models.User, to *models.Plan, now time.Time) time.Time {
+	if isUpgrade(&user.Plan, to) || user.PlanRenewsAt == nil || !user.PlanRenewsAt.After(now) {
+		return now
+	}
+	return *user.PlanRenewsAt
+}
+
+// chargePlan cobra um período do plano. Planos gratuitos não são cobrados e retornam nil.
+func chargePlan(db *gorm.DB, provider billing.Provider, user *models.User, plan *models.Plan, description, key string) (*models.Payment, error) {
+	if plan.Price <= 0 {
+		return nil, nil
+	}
+	return charge(db, provider, user, models.PaymentSubscription, plan.Price, description, key)
+}
+
+// charge cobra o valor pelo provedor e registra o pagamento, que aparece na fatura do
+// mês. Repetir a chave devolve a cobrança já feita sem registrá-la de novo.
+func charge(db *gorm.DB, provider billing.Provider, user *models.User, kind string, amount float64, description, key string) (*models.Payment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), billingTimeout)
+	defer cancel()
+	payment, err := provider.Charge(ctx, billing.ChargeRequest{
+		CustomerID:     user.ID.String(),
+		Email:          user.Email,
+		Amount:         billing.Cents(amount),
+		Currency:       config.AppConfig.BillingCurrency,
+		Description:    description,
+		IdempotencyKey: key,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	record := models.Payment{
+		UserID:      user.ID,
+		Kind:        kind,
+		Amount:      amount,
+		Currency:    config.AppConfig.BillingCurrency,
+		Description: description,
+		ProviderID:  payment.ID,
+	}
+	if err := db.Where(models.Payment{ProviderID: payment.ID}).FirstOrCreate(&record).Error; err != nil {
+		log.Printf("⚠️  Warning: Could not record payment %s of %s: %v", payment.ID, user.Email, err)
+	}
+	return &record, nil
+}
+
+// nextRenewal retorna o fim do período que começa em now, ou nil nos planos gratuitos
+func nextRenewal(plan *models.Plan, now time.Time) *time.Time {
+	if plan.Price <= 0 {
+		return nil
+	}
+	renewsAt := now.Add(config.AppConfig.BillingPeriod)
+	return &renewsAt
+}
+
+// requestPlanChange registra o pedido do usuário, substituindo outro que estivesse
+// pendente, e o aplica se ele já estiver em vigor
+func requestPlanChange(db *gorm.DB, provider billing.Provider, m mailer.Mailer, user *models.User, to *models.Plan, now time.Time) (*models.PlanChange, error) {
+	change := models.PlanChange{
+		UserID:      user.ID,
+		FromPlanID:  user.PlanID,
+		ToPlanID:    to.ID,
+		Status:      models.PlanChangePending,
+		EffectiveAt: planChangeEffectiveAt(user, to, now),
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.PlanChange{}).
+			Where("user_id = ? AND status = ?", user.ID, models.PlanChangePending).
+			Update("status", models.PlanChangeCanceled).Error; err != nil {
+			return err
+		}
+		return tx.Create(&change).Error
+	})
+	if err != nil {
+		// O índice único de pedidos pendentes recusa pedidos simultâneos
+		var count int64
+		db.Model(&models.PlanChange{}).Where("user_id = ? AND status = ?", user.ID, models.PlanChangePending).Count(&count)
+		if count > 0 {
+			return nil, errPlanChangeInProgress
+		}
+		return nil, err
+	}
+	change.FromPlan, change.ToPlan = user.Plan, *to
+
+	if change.EffectiveAt.After(now) {
+		notifyPlanChangeScheduled(m, user, &change)
+		return &change, nil
+	}
+	if err := applyPlanChange(db, provider, m, user, &change, now); err != nil {
+		// O usuário vê o erro e pode pedir de novo; o pedido não fica para o agendador
+		if change.Status == models.PlanChangePending {
+			failPlanChange(db, &change, err)
+		}
+		return &change, err
+	}
+	return &change, nil
+}
+
+// failPlanChange encerra a mudança como failed, guardando o motivo. Só mudanças ainda
+// pendentes são alteradas.
+func failPlanChange(db *gorm.DB, change *models.PlanChange, err error) {
+	db.Model(change).Where("status = ?", models.PlanChangePending).Updates(map[string]interface{}{
+		"status":         models.PlanChangeFailed,
+		"failure_reason": err.Error(),
+	})
+	change.Status, change.FailureReason = models.PlanChangeFailed, err.Error()
+}
+
+// applyPlanChange cobra o primeiro período do novo plano e o coloca em vigor. Se a
+// cobrança for recusada, a mudança fica como failed e o usuário continua no plano
+// atual; outros erros do provedor deixam a mudança pendente, para nova tentativa. O
+// agendador roda em todas as réplicas: só quem tira a mudança de pending altera o plano
+// do usuário e envia as notificações.
+func applyPlanChange(db *gorm.DB, provider billing.Provider, m mailer.Mailer, user *models.User, change *models.PlanChange, now time.Time) error {
+	payment, err := chargePlan(db, provider, user, &change.ToPlan, change.ToPlan.Name+" plan", "plan_change:"+change.ID.String())
+	if err != nil {
+		if errors.Is(err, billing.ErrDeclined) {
+			failPlanChange(db, change, err)
+		}
+		return err
+	}
+
+	updates := map[string]interface{}{
+		"status":     models.PlanChangeApplied,
+		"applied_at": now,
+	}
+	if payment != nil {
+		updates["amount"] = change.ToPlan.Price
+		updates["payment_id"] = payment.ProviderID
+		change.Amount, change.PaymentID = change.ToPlan.Price, payment.ProviderID
+	}
+	renewsAt := nextRenewal(&change.ToPlan, now)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		claimed := tx.Model(change).Where("status = ?", models.PlanChangePending).Updates(updates)
+		if claimed.Error != nil {
+			return claimed.Error
+		}
+		if claimed.RowsAffected != 1 {
+			return errPlanChangeClaimed
+		}
+		return tx.Model(user).Updates(map[string]interface{}{
+			"plan_id":        change.ToPlanID,
+			"plan_renews_at": renewsAt,
+		}).Error
+	})
+	if errors.Is(err, errPlanChangeClaimed) {
+		// A mesma cobrança (pela chave de idempotência) já foi aplicada por outra réplica
+		db.First(change, "id = ?", change.ID)
+		db.Preload("Plan").First(user, "id = ?", user.ID)
+		return nil
+	}
+	if err != nil {
+		// A cobrança já foi feita; a chave de idempotência evita cobrar de novo na próxima tentativa
+		return fmt.Errorf("charged but could not apply plan change: %w", err)
+	}
+	change.Status, change.AppliedAt = models.PlanChangeApplied, &now
+	user.PlanID, user.Plan, user.PlanRenewsAt = change.ToPlanID, change.ToPlan, renewsAt
+
+	log.Printf("💳 Plano de %s alterado: %s -> %s", user.Email, change.FromPlan.Name, change.ToPlan.Name)
+	notifyPlanChanged(db, m, user, change)
+	refreshQuotaState(db, m, user, now)
+	return nil
+}
+
+// setUserPlan troca o plano sem cobrança (mudanças feitas por um administrador ou
+// quando a renovação é recusada), cancelando pedidos pendentes. reason, vazio nas
+// mudanças do administrador, explica a troca no histórico e no e-mail ao usuário.
+func setUserPlan(db *gorm.DB, m mailer.Mailer, user *models.User, plan *models.Plan, reason string, now time.Time) error {
+	change := models.PlanChange{
+		UserID:        user.ID,
+		FromPlanID:    user.PlanID,
+		ToPlanID:      plan.ID,
+		Status:        models.PlanChangeApplied,
+		EffectiveAt:   now,
+		AppliedAt:     &now,
+		FailureReason: reason,
+	}
+	updates := map[string]interface{}{"plan_id": plan.ID}
+	if plan.Price <= 0 {
+		updates["plan_renews_at"] = nil
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.PlanChange{}).
+			Where("user_id = ? AND status = ?", user.ID, models.PlanChangePending).
+			Update("status", models.PlanChangeCanceled).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&change).Error; err != nil {
+			return err
+		}
+		return tx.Model(user).Updates(updates).Error
+	})
+	if err != nil {
+		return err
+	}
+	change.FromPlan, change.ToPlan = user.Plan, *plan
+	user.PlanID, user.Plan = plan.ID, *plan
+	if plan.Price <= 0 {
+		user.PlanRenewsAt = nil
+	}
+
+	notifyPlanChanged(db, m, user, &change)
+	refreshQuotaState(db, m, user, now)
+	return nil
+}
+
+// renewPlan cobra o próximo período do plano atual. Se a cobrança for recusada, o
+// usuário volta para o plano gratuito; outros erros são tentados de novo no próximo ciclo.
+func renewPlan(db *gorm.DB, provider billing.Provider, m mailer.Mailer, user *models.User, now time.Time) error {
+	if user.Plan.Price <= 0 {
+		return db.Model(user).Update("plan_renews_at", nil).Error
+	}
+	key := fmt.Sprintf("renewal:%s:%d", user.ID, user.PlanRenewsAt.Unix())
+	if _, err := chargePlan(db, provider, user, &user.Plan, user.Plan.Name+" plan renewal", key); err != nil {
+		if !errors.Is(err, billing.ErrDeclined) {
+			return err
+		}
+		log.Printf("💳 Renovação do plano de %s recusada: %v", user.Email, err)
+		return downgradeToFree(db, m, user, now)
+	}
+
+	// Períodos perdidos (servidor parado) não são cobrados retroativamente
+	renewsAt := user.PlanRenewsAt.Add(config.AppConfig.BillingPeriod)
+	if !renewsAt.After(now) {
+		renewsAt = now.Add(config.AppConfig.BillingPeriod)
+	}
+	if err := db.Model(user).Update("plan_renews_at", renewsAt).Error; err != nil {
+		return err
+	}
+	log.Printf("💳 Plano %s de %s renovado até %s", user.Plan.Name, user.Email, renewsAt.Format(time.RFC3339))
+	return nil
+}
+
+// downgradeToFree move para o plano gratuito quem teve a renovação recusada
+func downgradeToFree(db *gorm.DB, m mailer.Mailer, user *models.User, now time.Time) error {
+	var free models.Plan
+	if err := db.Where("name = ?", models.FreePlanName).First(&free).Error; err != nil {
+		return err
+	}
+	return setUserPlan(db, m, user, &free, "renewal payment declined", now)
+}
+
+// overQuota diz se o uso passa do limite do plano do usuário (Plan carregado)
+func overQuota(user *models.User) bool {
+	return user.StorageUsage > user.Plan.StorageLimit
+}
+
+// refreshQuotaState acompanha o uso acima do limite depois de um downgrade: abre o
+// prazo de carência, registra o fim dele e o encerra quando o uso volta ao limite,
+// avisando o usuário em cada etapa. O bloqueio em si é calculado na hora (uploads e
+// /files/), então isto só mantém as datas e as notificações.
+func refreshQuotaState(db *gorm.DB, m mailer.Mailer, user *models.User, now time.Time) {
+	var updates map[string]interface{}
+	switch {
+	case overQuota(user) && user.GraceEndsAt == nil:
+		graceEndsAt := now.Add(config.AppConfig.OverQuotaGracePeriod)
+		updates = map[string]interface{}{"grace_ends_at": graceEndsAt, "quota_restricted_at": nil}
+		user.GraceEndsAt, user.QuotaRestrictedAt = &graceEndsAt, nil
+		notifyOverQuota(db, m, user, "grace")
+	case overQuota(user) && user.QuotaRestrictedAt == nil && !now.Before(*user.GraceEndsAt):
+		updates = map[string]interface{}{"quota_restricted_at": now}
+		user.QuotaRestrictedAt = &now
+		notifyOverQuota(db, m, user, "restricted")
+	case !overQuota(user) && user.GraceEndsAt != nil:
+		updates = map[string]interface{}{"grace_ends_at": nil, "quota_restricted_at": nil}
+		user.GraceEndsAt, user.QuotaRestrictedAt = nil, nil
+		sendMail(m, mailer.Message{
+			To:      user.Email,
+			Subject: "Your storage is back within your plan's limit",
+			Body: fmt.Sprintf("Hi %s,\n\nYour storage usage (%s) is within the %s limit of the %s plan again. "+
+				"Uploads are enabled and all your files are being served.\n",
+				user.Name, formatBytes(user.StorageUsage), formatBytes(user.Plan.StorageLimit), user.Plan.Name),
+		})
+	default:
+		return
+	}
+	if err := db.Model(user).Updates(updates).Error; err != nil {
+		log.Printf("⚠️  Warning: Could not update quota state of %s: %v", user.Email, err)
+	}
+}
+
+// quotaRestricted diz se os arquivos do projeto deixaram de ser servidos porque o dono
+// passou do limite do plano e o prazo de carência acabou. Projetos de organizações não
+// passam por downgrades e nunca são bloqueados.
+func quotaRestricted(db *gorm.DB, project *models.Project, now time.Time) bool {
+	if project.OrganizationID != nil {
+		return false
+	}
+	var count int64
+	db.Model(&models.User{}).
+		Joins("JOIN plans ON plans.id = users.plan_id").
+		Where("users.id = ? AND users.grace_ends_at <= ? AND users.storage_usage > plans.storage_limit", project.UserID, now).
+		Count(&count)
+	return count > 0
+}
+
+// notifyPlanChangeScheduled avisa que um downgrade foi agendado para o fim do período
+func notifyPlanChangeScheduled(m mailer.Mailer, user *models.User, change *models.PlanChange) {
+	body := fmt.Sprintf("Hi %s,\n\nYour plan will change from %s to %s on %s, at the end of the current billing period.\n",
+		user.Name, change.FromPlan.Name, change.ToPlan.Name, change.EffectiveAt.UTC().Format("January 2, 2006"))
+	if user.StorageUsage > change.ToPlan.StorageLimit {
+		body += fmt.Sprintf("\nYou are storing %s, above the %s limit of the %s plan. After the change uploads will be "+
+			"disabled, and if usage is still above the limit %s later your files will no longer be served.\n",
+			formatBytes(user.StorageUsage), formatBytes(change.ToPlan.StorageLimit), change.ToPlan.Name, config.AppConfig.OverQuotaGracePeriod)
+	}
+	sendMail(m, mailer.Message{To: user.Email, Subject: "Your plan change is scheduled", Body: body})
+}
+
+// notifyPlanChanged avisa por e-mail e pelos webhooks que um plano entrou em vigor
+func notifyPlanChanged(db *gorm.DB, m mailer.Mailer, user *models.User, change *models.PlanChange) {
+	body := fmt.Sprintf("Hi %s,\n\nYour plan changed from %s to %s.\n", user.Name, change.FromPlan.Name, change.ToPlan.Name)
+	if change.FailureReason != "" {
+		body += fmt.Sprintf("\nWe could not charge the renewal of your plan (%s), so your account was moved to the %s plan.\n",
+			change.FailureReason, change.ToPlan.Name)
+	}
+	if change.PaymentID != "" {
+		body += fmt.Sprintf("\nYou were charged %.2f %s. ", change.Amount, config.AppConfig.BillingCurrency)
+	}
+	if user.PlanRenewsAt != nil {
+		body += fmt.Sprintf("The plan renews on %s.\n", user.PlanRenewsAt.UTC().Format("January 2, 2006"))
+	}
+	sendMail(m, mailer.Message{To: user.Email, Subject: "Your plan is now " + change.ToPlan.Name, Body: body})
+
+	dispatchEvent(db, user.ID, nil, models.EventPlanChanged, map[string]any{
+		"from":      change.FromPlan.Name,
+		"to":        change.ToPlan.Name,
+		"renews_at": user.PlanRenewsAt,
+	})
+}
+
+// notifyOverQuota avisa que o uso passou do limite (state "grace") ou que o prazo de
+// carência acabou e os arquivos deixaram de ser servidos (state "restricted")
+func notifyOverQuota(db *gorm.DB, m mailer.Mailer, user *models.User, state string) {
+	usage := fmt.Sprintf("You are storing %s, above the %s limit of the %s plan.",
+		formatBytes(user.StorageUsage), formatBytes(user.Plan.StorageLimit), user.Plan.Name)
+	msg := mailer.Message{To: user.Email}
+	if state == "grace" {
+		msg.Subject = "Your storage is above your plan's limit"
+		msg.Body = fmt.Sprintf("Hi %s,\n\n%s Uploads are disabled until usage is back within the limit.\n\n"+
+			"Delete files or upgrade your plan before %s. After that date your files will no longer be served.\n",
+			user.Name, usage, user.GraceEndsAt.UTC().Format(time.RFC1123))
+	} else {
+		msg.Subject = "Your files are no longer being served"
+		msg.Body = fmt.Sprintf("Hi %s,\n\n%s The grace period has ended, so your files are no longer being served. "+
+			"They are still stored: delete files or upgrade your plan to restore access.\n", user.Name, usage)
+	}
+	sendMail(m, msg)
+
+	dispatchEvent(db, user.ID, nil, models.EventQuotaOverLimit, map[string]any{
+		"state":         state,
+		"plan":          user.Plan.Name,
+		"storage_usage": user.StorageUsage,
+		"storage_limit": user.Plan.StorageLimit,
+		"grace_ends_at": user.GraceEndsAt,
+	})
+}
+
+// processPlanLifecycle aplica as mudanças de plano que venceram, renova os períodos
+// pagos e atualiza o estado de quem está acima do limite
+func processPlanLifecycle(db *gorm.DB, provider billing.Provider, m mailer.Mailer, now time.Time) error {
+	var due []models.PlanChange
+	if err := db.Preload("FromPlan").Preload("ToPlan").
+		Where("status = ? AND effective_at <= ?", models.PlanChangePending, now).
+		Order("effective_at").Find(&due).Error; err != nil {
+		return err
+	}
+	for i := range due {
+		var user models.User
+		if err := db.Preload("Plan").First(&user, "id = ?", due[i].UserID).Error; err != nil {
+			continue
+		}
+		// No fim do período a cobrança do novo plano é a renovação: se ela for recusada,
+		// o usuário vai para o plano gratuito, como em renewPlan
+		if err := applyPlanChange(db, provider, m, &user, &due[i], now); err != nil {
+			log.Printf("💳 Mudança de plano de %s falhou: %v", user.Email, err)
+			if errors.Is(err, billing.ErrDeclined) {
+				if err := downgradeToFree(db, m, &user, now); err != nil {
+					log.Printf("⚠️  Warning: Could not move %s to the free plan: %v", user.Email, err)
+				}
+			}
+		}
+	}
+
+	// Quem ainda tem uma mudança pendente (erro temporário do provedor) espera por ela
+	var renewals []models.User
+	if err := db.Preload("Plan").
+		Where("plan_renews_at <= ? AND suspended_at IS NULL", now).
+		Where("NOT EXISTS (SELECT 1 FROM plan_changes WHERE plan_changes.user_id = users.id AND plan_changes.status = ?)", models.PlanChangePending).
+		Find(&renewals).Error; err != nil {
+		return err
+	}
+	for i := range renewals {
+		if err := renewPlan(db, provider, m, &renewals[i], now); err != nil {
+			log.Printf("⚠️  Warning: Could not renew plan of %s: %v", renewals[i].Email, err)
+		}
+	}
+
+	// Quem passou do limite sem mudar de plano (limite reduzido pelo administrador) e
+	// quem está no prazo de carência
+	var tracked []models.User
+	if err := db.Preload("Plan").
+		Joins("JOIN plans ON plans.id = users.plan_id").
+		Where("users.grace_ends_at IS NOT NULL OR users.storage_usage > plans.storage_limit").
+		Find(&tracked).Error; err != nil {
+		return err
+	}
+	for i := range tracked {
+		refreshQuotaState(db, m, &tracked[i], now)
+	}
+	return nil
+}
+
+// StartPlanScheduler executa processPlanLifecycle periodicamente em segundo plano
+func StartPlanScheduler(db *gorm.DB, provider billing.Provider, m mailer.Mailer, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := processPlanLifecycle(db, provider, m, time.Now()); err != nil {
+				log.Printf("⚠️  Warning: Failed to process plan changes: %v", err)
+			}
+		}
+	}()
+}
+
+// pendingPlanChange retorna a mudança agendada do usuário, se houver
+func pendingPlanChange(db *gorm.DB, userID uuid.UUID) (*models.PlanChange, error) {
+	var change models.PlanChange
+	err := db.Preload("FromPlan").Preload("ToPlan").
+		Where("user_id = ? AND status = ?", userID, models.PlanChangePending).
+		First(&change).Error
+	if err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
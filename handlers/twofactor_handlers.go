@@ -0,0 +1,578 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/config"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/middleware"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/util"
+)
+
+const (
+	// recoveryCodeCount é quantos códigos de recuperação são gerados de cada vez
+	recoveryCodeCount = 10
+	// totpSkew aceita o código do intervalo anterior e do seguinte (relógios fora de sincronia)
+	totpSkew = 1
+)
+
+var errSecondFactorInvalid = errors.New("invalid two-factor code")
+
+type TwoFactorStatusResponse struct {
+	Enabled                bool       `json:"enabled"`
+	EnabledAt              *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesRemaining int64      `json:"recovery_codes_remaining"`
+}
+
+type TwoFactorEnrollRequest struct {
+	Password string `json:"password"`
+}
+
+type TwoFactorEnrollResponse struct {
+	Message    string `json:"message"`
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"` // Para gerar o QR code
+}
+
+// SecondFactorRequest leva um código do aplicativo autenticador ou um código de recuperação
+type SecondFactorRequest struct {
+	Password     string `json:"password,omitempty"`
+	Code         string `json:"code,omitempty"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+}
+
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code,omitempty"`
+	RecoveryCode   string `json:"recovery_code,omitempty"`
+}
+
+type RecoveryCodesResponse struct {
+	Message       string   `json:"message"`
+	RecoveryCodes []string `json:"recovery_codes"` // Exibidos apenas nesta resposta
+}
+
+// requireSession recusa alterações do 2FA feitas com chave de API: quem tem apenas uma
+// chave não deve conseguir ativar, desativar ou trocar o segundo fator da conta
+func requireSession(w http.ResponseWriter, r *http.Request) bool {
+	if requestAPIKey(r) == nil {
+		return true
+	}
+	http.Error(w, "Two-factor settings can only be changed from a logged-in session, not with an API key", http.StatusForbidden)
+	return false
+}
+
+// loadContextUser recarrega do banco o usuário autenticado
+func loadContextUser(db *gorm.DB, w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	userFromCtx, ok := r.Context().Value(middleware.UserContextKey).(*models.User)
+	if !ok {
+		http.Error(w, "Could not retrieve user from context", http.StatusInternalServerError)
+		return nil, false
+	}
+	var user models.User
+	if err := db.First(&user, "id = ?", userFromCtx.ID).Error; err != nil {
+		http.Error(w, "Could not retrieve user details", http.StatusInternalServerError)
+		return nil, false
+	}
+	return &user, true
+}
+
+// verifySecondFactor confere um código TOTP ou um código de recuperação e o consome.
+// As atualizações condicionais impedem que o mesmo código seja aceito duas vezes, mesmo
+// em requisições simultâneas.
+func verifySecondFactor(db *gorm.DB, user *models.User, code, recoveryCode string, now time.Time) (string, error) {
+	if recoveryCode != "" {
+		result := db.Model(&models.RecoveryCode{}).
+			Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, util.HashToken(util.NormalizeRecoveryCode(recoveryCode))).
+			Update("used_at", now)
+		if result.Error != nil {
+			return "", result.Error
+		}
+		if result.RowsAffected == 0 {
+			return "", errSecondFactorInvalid
+		}
+		return "recovery_code", nil
+	}
+
+	step, ok := util.ValidateTOTP(user.TOTPSecret, code, now, totpSkew, user.TOTPLastStep)
+	if !ok {
+		return "", errSecondFactorInvalid
+	}
+	result := db.Model(&models.User{}).Where("id = ? AND totp_last_step < ?", user.ID, step).Update("totp_last_step", step)
+	if result.Error != nil {
+		return "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		return "", errSecondFactorInvalid
+	}
+	user.TOTPLastStep = step
+	return "totp", nil
+}
+
+// replaceRecoveryCodes apaga os códigos de recuperação do usuário e gera novos
+func replaceRecoveryCodes(tx *gorm.DB, userID uuid.UUID) ([]string, error) {
+	codes, err := util.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+	rows := make([]models.RecoveryCode, len(codes))
+	for i, code := range codes {
+		rows[i] = models.RecoveryCode{UserID: userID, CodeHash: util.HashToken(util.NormalizeRecoveryCode(code))}
+	}
+	if err := tx.Create(&rows).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// startTwoFactorLogin emite o desafio do segundo passo do login
+func startTwoFactorLogin(db *gorm.DB, user *models.User, now time.Time) (string, time.Time, error) {
+	token, hash, err := util.GenerateOpaqueToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	expiresAt := now.Add(config.AppConfig.TwoFactorChallengeTTL)
+	challenge := models.LoginChallenge{UserID: user.ID, TokenHash: hash, ExpiresAt: expiresAt}
+	if err := db.Create(&challenge).Error; err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// TwoFactorLoginHandler godoc
+// @Summary Complete a login with two-factor authentication
+// @Description Second step of /login for accounts with two-factor authentication. Exchanges the challenge token returned by /login and a code from the authenticator app (or an unused recovery code) for the session tokens. A challenge expires after a few minutes and accepts a limited number of wrong codes; wrong codes also count toward the account lock.
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param   two_factor_request  body  TwoFactorLoginRequest  true  "Challenge token and code or recovery code"
+// @Success 200 {object} AuthResponse "Logged in successfully"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Invalid or expired challenge, or invalid code"
+// @Failure 429 {string} string "Too many requests or too many failed logins from this address (see Retry-After)"
+// @Failure 500 {string} string "Could not generate token"
+// @Router /login/2fa [post]
+func TwoFactorLoginHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req TwoFactorLoginRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ChallengeToken == "" {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		now := time.Now()
+		ip := util.ClientIP(r)
+		if until := ipLockedUntil(db, ip, now); until != nil {
+			writeIPLocked(w, *until, now)
+			return
+		}
+
+		var challenge models.LoginChallenge
+		if err := db.First(&challenge, "token_hash = ?", util.HashToken(req.ChallengeToken)).Error; err != nil || now.After(challenge.ExpiresAt) {
+			http.Error(w, "Invalid or expired challenge. Log in again.", http.StatusUnauthorized)
+			return
+		}
+		var user models.User
+		if err := db.Preload("Plan").Preload("Projects", "organization_id IS NULL").First(&user, "id = ?", challenge.UserID).Error; err != nil {
+			http.Error(w, "Invalid or expired challenge. Log in again.", http.StatusUnauthorized)
+			return
+		}
+		// Como em /login, o bloqueio fica só no log de auditoria: a resposta é a de um
+		// desafio inválido, sem revelar o bloqueio nem quanto falta para ele acabar
+		if userLocked(&user, now) {
+			db.Delete(&challenge)
+			middleware.RecordAudit(db, r, models.AuditEvent{
+				UserID: &user.ID, Actor: user.Email, Action: models.AuditLogin, Outcome: models.AuditFailure, Detail: "Account locked",
+			})
+			http.Error(w, "Invalid or expired challenge. Log in again.", http.StatusUnauthorized)
+			return
+		}
+
+		// A tentativa é reservada de forma atômica antes de conferir o código, para que
+		// requisições simultâneas não testem mais do que TwoFactorChallengeAttempts códigos
+		var attempts int
+		reserved := db.Raw("UPDATE login_challenges SET attempts = attempts + 1 WHERE id = ? AND attempts < ? RETURNING attempts",
+			challenge.ID, config.AppConfig.TwoFactorChallengeAttempts).Scan(&attempts)
+		if reserved.Error != nil {
+			http.Error(w, "Could not verify two-factor code: "+reserved.Error.Error(), http.StatusInternalServerError)
+			return
+		}
+		if reserved.RowsAffected == 0 {
+			db.Delete(&challenge)
+			http.Error(w, "Invalid or expired challenge. Log in again.", http.StatusUnauthorized)
+			return
+		}
+
+		method, err := verifySecondFactor(db, &user, req.Code, req.RecoveryCode, now)
+		if errors.Is(err, errSecondFactorInvalid) {
+			// O desafio é descartado depois de TwoFactorChallengeAttempts códigos errados
+			if attempts >= config.AppConfig.TwoFactorChallengeAttempts {
+				db.Delete(&challenge)
+			}
+			middleware.RecordAudit(db, r, models.AuditEvent{
+				UserID: &user.ID, Actor: user.Email, Action: models.AuditLogin, Outcome: models.AuditFailure, Detail: "Wrong two-factor code",
+			})
+			recordLoginFailure(db, r, &user, ip, now)
+			http.Error(w, "Invalid two-factor code", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Could not verify two-factor code: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// Cada desafio gera uma única sessão
+		if result := db.Delete(&challenge); result.Error != nil || result.RowsAffected == 0 {
+			http.Error(w, "Invalid or expired challenge. Log in again.", http.StatusUnauthorized)
+			return
+		}
+		resetLoginFailures(db, &user)
+		if method == "recovery_code" {
+			log.Printf("🔑 Login de %s com código de recuperação", user.Email)
+		}
+
+		tokens, err := issueTokens(db, r, &user, uuid.New(), now)
+		if err != nil {
+			http.Error(w, "Could not generate token", http.StatusInternalServerError)
+			return
+		}
+
+		user.Password = ""
+		if user.Projects == nil {
+			user.Projects = make([]models.Project, 0)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(AuthResponse{
+			Message:               "Logged in successfully",
+			Token:                 tokens.Token,
+			ExpiresIn:             tokens.ExpiresIn,
+			RefreshToken:          tokens.RefreshToken,
+			RefreshTokenExpiresAt: &tokens.RefreshTokenExpiresAt,
+			User:                  &user,
+		})
+	}
+}
+
+// TwoFactorStatusHandler godoc
+// @Summary Two-factor authentication status
+// @Description Shows whether two-factor authentication is enabled and how many unused recovery codes remain.
+// @Tags api
+// @Produce  json
+// @Security BearerAuth
+// @Success 200 {object} TwoFactorStatusResponse
+// @Failure 500 {string} string "Could not retrieve user details"
+// @Router /api/user/2fa [get]
+func TwoFactorStatusHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		user, ok := loadContextUser(db, w, r)
+		if !ok {
+			return
+		}
+
+		resp := TwoFactorStatusResponse{Enabled: user.TOTPEnabledAt != nil, EnabledAt: user.TOTPEnabledAt}
+		db.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", user.ID).Count(&resp.RecoveryCodesRemaining)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// TwoFactorEnrollHandler godoc
+// @Summary Start two-factor enrollment
+// @Description Generates a new TOTP secret and its otpauth:// URI (to be shown as a QR code in an authenticator app). Two-factor authentication is only enabled after a code is confirmed at /api/user/2fa/confirm. Requires the current password and a logged-in session; API keys are refused.
+// @Tags api
+// @Accept  json
+// @Produce  json
+// @Param   enroll_request  body  TwoFactorEnrollRequest  true  "Current password"
+// @Security BearerAuth
+// @Success 200 {object} TwoFactorEnrollResponse
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 401 {string} string "Wrong password"
+// @Failure 403 {string} string "Not allowed with an API key"
+// @Failure 409 {string} string "Two-factor authentication already enabled"
+// @Failure 500 {string} string "Could not start enrollment"
+// @Router /api/user/2fa/enroll [post]
+func TwoFactorEnrollHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if !requireSession(w, r) {
+			return
+		}
+
+		var req TwoFactorEnrollRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		user, ok := loadContextUser(db, w, r)
+		if !ok {
+			return
+		}
+		if !user.CheckPassword(req.Password) {
+			http.Error(w, "Wrong password", http.StatusUnauthorized)
+			return
+		}
+		if user.TOTPEnabledAt != nil {
+			http.Error(w, "Two-factor authentication is already enabled. Disable it first to enroll a new device.", http.StatusConflict)
+			return
+		}
+
+		secret, err := util.GenerateTOTPSecret()
+		if err != nil {
+			http.Error(w, "Could not start enrollment: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := db.Model(user).Updates(map[string]interface{}{"totp_secret": secret, "totp_last_step": 0}).Error; err != nil {
+			http.Error(w, "Could not start enrollment: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(TwoFactorEnrollResponse{
+			Message:    "Add the secret to your authenticator app and confirm a code at /api/user/2fa/confirm",
+			Secret:     secret,
+			OTPAuthURI: util.TOTPURI(config.AppConfig.TOTPIssuer, user.Email, secret),
+		})
+	}
+}
+
+// TwoFactorConfirmHandler godoc
+// @Summary Confirm two-factor enrollment
+// @Description Enables two-factor authentication after checking a code generated from the secret returned by /api/user/2fa/enroll, and returns the recovery codes. The recovery codes are shown only in this response; each one works once.
+// @Tags api
+// @Accept  json
+// @Produce  json
+// @Param   confirm_request  body  SecondFactorRequest  true  "Code from the authenticator app"
+// @Security BearerAuth
+// @Success 200 {object} RecoveryCodesResponse
+// @Failure 400 {string} string "Invalid request body, invalid code or enrollment not started"
+// @Failure 403 {string} string "Not allowed with an API key"
+// @Failure 409 {string} string "Two-factor authentication already enabled"
+// @Failure 500 {string} string "Could not enable two-factor authentication"
+// @Router /api/user/2fa/confirm [post]
+func TwoFactorConfirmHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if !requireSession(w, r) {
+			return
+		}
+
+		var req SecondFactorRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		user, ok := loadContextUser(db, w, r)
+		if !ok {
+			return
+		}
+		if user.TOTPEnabledAt != nil {
+			http.Error(w, "Two-factor authentication is already enabled", http.StatusConflict)
+			return
+		}
+		if user.TOTPSecret == "" {
+			http.Error(w, "Start the enrollment at /api/user/2fa/enroll first", http.StatusBadRequest)
+			return
+		}
+
+		now := time.Now()
+		step, valid := util.ValidateTOTP(user.TOTPSecret, req.Code, now, totpSkew, user.TOTPLastStep)
+		if !valid {
+			http.Error(w, "Invalid two-factor code", http.StatusBadRequest)
+			return
+		}
+
+		var codes []string
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(user).Updates(map[string]interface{}{"totp_enabled_at": now, "totp_last_step": step}).Error; err != nil {
+				return err
+			}
+			var err error
+			codes, err = replaceRecoveryCodes(tx, user.ID)
+			return err
+		})
+		if err != nil {
+			http.Error(w, "Could not enable two-factor authentication: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		middleware.RecordAudit(db, r, models.AuditEvent{
+			Action: models.AuditTwoFactorOn, Target: user.Email, Outcome: models.AuditSuccess,
+		})
+		log.Printf("🔐 2FA ativado para %s", user.Email)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(RecoveryCodesResponse{
+			Message:       "Two-factor authentication enabled. Store the recovery codes in a safe place.",
+			RecoveryCodes: codes,
+		})
+	}
+}
+
+// TwoFactorDisableHandler godoc
+// @Summary Disable two-factor authentication
+// @Description Disables two-factor authentication and deletes the recovery codes. Requires the current password and a code from the authenticator app or a recovery code.
+// @Tags api
+// @Accept  json
+// @Produce  json
+// @Param   disable_request  body  SecondFactorRequest  true  "Current password and code or recovery code"
+// @Security BearerAuth
+// @Success 200 {object} map[string]string "message: Two-factor authentication disabled"
+// @Failure 400 {string} string "Invalid request body or two-factor authentication not enabled"
+// @Failure 401 {string} string "Wrong password or invalid code"
+// @Failure 403 {string} string "Not allowed with an API key"
+// @Failure 500 {string} string "Could not disable two-factor authentication"
+// @Router /api/user/2fa/disable [post]
+func TwoFactorDisableHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if !requireSession(w, r) {
+			return
+		}
+
+		var req SecondFactorRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		user, ok := loadContextUser(db, w, r)
+		if !ok {
+			return
+		}
+		if user.TOTPEnabledAt == nil {
+			http.Error(w, "Two-factor authentication is not enabled", http.StatusBadRequest)
+			return
+		}
+		if !user.CheckPassword(req.Password) {
+			http.Error(w, "Wrong password", http.StatusUnauthorized)
+			return
+		}
+		if _, err := verifySecondFactor(db, user, req.Code, req.RecoveryCode, time.Now()); err != nil {
+			http.Error(w, "Invalid two-factor code", http.StatusUnauthorized)
+			return
+		}
+
+		if err := disableTwoFactor(db, user.ID); err != nil {
+			http.Error(w, "Could not disable two-factor authentication: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		middleware.RecordAudit(db, r, models.AuditEvent{
+			Action: models.AuditTwoFactorOff, Target: user.Email, Outcome: models.AuditSuccess,
+		})
+		log.Printf("🔓 2FA desativado para %s", user.Email)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"message": "Two-factor authentication disabled",
+		})
+	}
+}
+
+// disableTwoFactor remove o segredo, os códigos de recuperação e os desafios pendentes
+func disableTwoFactor(db *gorm.DB, userID uuid.UUID) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"totp_secret":     "",
+			"totp_enabled_at": nil,
+			"totp_last_step":  0,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.LoginChallenge{}).Error
+	})
+}
+
+// RecoveryCodesHandler godoc
+// @Summary Regenerate recovery codes
+// @Description Replaces all recovery codes with new ones, which are shown only in this response. Requires a code from the authenticator app or an unused recovery code.
+// @Tags api
+// @Accept  json
+// @Produce  json
+// @Param   codes_request  body  SecondFactorRequest  true  "Code or recovery code"
+// @Security BearerAuth
+// @Success 200 {object} RecoveryCodesResponse
+// @Failure 400 {string} string "Invalid request body or two-factor authentication not enabled"
+// @Failure 401 {string} string "Invalid code"
+// @Failure 403 {string} string "Not allowed with an API key"
+// @Failure 500 {string} string "Could not generate recovery codes"
+// @Router /api/user/2fa/recovery-codes [post]
+func RecoveryCodesHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if !requireSession(w, r) {
+			return
+		}
+
+		var req SecondFactorRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		user, ok := loadContextUser(db, w, r)
+		if !ok {
+			return
+		}
+		if user.TOTPEnabledAt == nil {
+			http.Error(w, "Two-factor authentication is not enabled", http.StatusBadRequest)
+			return
+		}
+		if _, err := verifySecondFactor(db, user, req.Code, req.RecoveryCode, time.Now()); err != nil {
+			http.Error(w, "Invalid two-factor code", http.StatusUnauthorized)
+			return
+		}
+
+		var codes []string
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			codes, err = replaceRecoveryCodes(tx, user.ID)
+			return err
+		})
+		if err != nil {
+			http.Error(w, "Could not generate recovery codes: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(RecoveryCodesResponse{
+			Message:       "New recovery codes generated. The previous ones no longer work.",
+			RecoveryCodes: codes,
+		})
+	}
+}
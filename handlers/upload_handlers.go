@@ -0,0 +1,627 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/config"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/middleware"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/storage"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/util"
+)
+
+// Versão do protocolo tus implementada pelos endpoints de upload resumível
+const tusVersion = "1.0.0"
+
+// uploadSessionLockClass separa os advisory locks das sessões de upload dos demais
+// locks do Postgres (como o das migrações)
+const uploadSessionLockClass = 7301
+
+type UploadSessionResponse struct {
+	ID        string    `json:"id"`
+	Location  string    `json:"location"`
+	Offset    int64     `json:"offset"`
+	Length    int64     `json:"length"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// sessionPrefix retorna o prefixo, no driver de armazenamento, das partes recebidas pela
+// sessão. Cada PATCH grava uma parte em <prefixo><offset>, de modo que qualquer réplica
+// consegue retomar ou finalizar o upload.
+func sessionPrefix(session *models.UploadSession) string {
+	owner := storageOwner{UserID: session.UserID, OrgID: session.OrganizationID}
+	return path.Join(owner.prefix(), "uploads", session.ID.String()) + "/"
+}
+
+// sessionPartKey retorna a chave da parte que começa em offset. O offset tem largura
+// fixa para que a ordem das chaves seja a ordem dos bytes.
+func sessionPartKey(session *models.UploadSession, offset int64) string {
+	return fmt.Sprintf("%s%020d", sessionPrefix(session), offset)
+}
+
+// sessionParts retorna as chaves das partes que cobrem os bytes já contabilizados da
+// sessão, em ordem. Partes de um PATCH interrompido antes de atualizar o offset são
+// ignoradas (ou sobrescritas pelo PATCH seguinte, que começa no mesmo offset).
+func sessionParts(ctx context.Context, store storage.Driver, session *models.UploadSession) ([]string, error) {
+	objects, err := store.List(ctx, sessionPrefix(session))
+	if err != nil {
+		return nil, err
+	}
+	sizes := make(map[string]int64, len(objects))
+	for _, obj := range objects {
+		sizes[obj.Key] = obj.Size
+	}
+
+	var keys []string
+	for offset := int64(0); offset < session.UploadOffset; {
+		key := sessionPartKey(session, offset)
+		size, ok := sizes[key]
+		if !ok || size <= 0 {
+			return nil, fmt.Errorf("upload session %s is missing the bytes from offset %d", session.ID, offset)
+		}
+		keys = append(keys, key)
+		offset += size
+	}
+	return keys, nil
+}
+
+// partsReader lê em sequência as partes de uma sessão, abrindo uma de cada vez
+type partsReader struct {
+	ctx   context.Context
+	store storage.Driver
+	keys  []string
+	cur   io.ReadCloser
+}
+
+func (p *partsReader) Read(b []byte) (int, error) {
+	for {
+		if p.cur == nil {
+			if len(p.keys) == 0 {
+				return 0, io.EOF
+			}
+			rc, err := p.store.Get(p.ctx, p.keys[0])
+			if err != nil {
+				return 0, err
+			}
+			p.cur, p.keys = rc, p.keys[1:]
+		}
+		n, err := p.cur.Read(b)
+		if err == io.EOF {
+			p.cur.Close()
+			p.cur = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (p *partsReader) Close() error {
+	if p.cur != nil {
+		return p.cur.Close()
+	}
+	return nil
+}
+
+// openSessionContent abre os bytes já recebidos pela sessão
+func openSessionContent(ctx context.Context, store storage.Driver, session *models.UploadSession) (io.ReadCloser, error) {
+	keys, err := sessionParts(ctx, store, session)
+	if err != nil {
+		return nil, err
+	}
+	return &partsReader{ctx: ctx, store: store, keys: keys}, nil
+}
+
+// withUploadSessionLock executa fn com o advisory lock da sessão, que vale entre todas
+// as réplicas. Retorna false, sem executar fn, se outro PATCH já estiver com o lock. O
+// lock fica preso à conexão e é liberado mesmo se o processo cair.
+func withUploadSessionLock(db *gorm.DB, id uuid.UUID, fn func()) (bool, error) {
+	var locked bool
+	err := db.Connection(func(conn *gorm.DB) error {
+		if err := conn.Raw("SELECT pg_try_advisory_lock(?, hashtext(?))", uploadSessionLockClass, id.String()).Scan(&locked).Error; err != nil {
+			return err
+		}
+		if !locked {
+			return nil
+		}
+		defer conn.Exec("SELECT pg_advisory_unlock(?, hashtext(?))", uploadSessionLockClass, id.String())
+		fn()
+		return nil
+	})
+	return locked, err
+}
+
+// parseUploadMetadata decodifica o cabeçalho Upload-Metadata do tus:
+// pares "chave valorBase64" separados por vírgula.
+func parseUploadMetadata(header string) (map[string]string, error) {
+	meta := make(map[string]string)
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, encoded, _ := strings.Cut(pair, " ")
+		value, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("invalid Upload-Metadata value for %q", key)
+		}
+		meta[key] = string(value)
+	}
+	return meta, nil
+}
+
+// setUploadHeaders escreve os cabeçalhos tus que descrevem o estado da sessão
+func setUploadHeaders(w http.ResponseWriter, session *models.UploadSession) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.Header().Set("Upload-Offset", strconv.FormatInt(session.UploadOffset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(session.UploadLength, 10))
+	w.Header().Set("Upload-Expires", session.ExpiresAt.UTC().Format(http.TimeFormat))
+	w.Header().Set("Cache-Control", "no-store")
+}
+
+// removeUploadSession apaga o registro e as partes da sessão
+func removeUploadSession(db *gorm.DB, store storage.Driver, session *models.UploadSession) error {
+	ctx := context.Background()
+	parts, err := store.List(ctx, sessionPrefix(session))
+	if err != nil {
+		log.Printf("Não foi possível listar as partes da sessão %s: %v", session.ID, err)
+	}
+	for _, part := range parts {
+		if err := store.Delete(ctx, part.Key); err != nil && !errors.Is(err, storage.ErrNotExist) {
+			log.Printf("Não foi possível remover a parte %s da sessão %s: %v", part.Key, session.ID, err)
+		}
+	}
+	return db.Delete(session).Error
+}
+
+// readSessionHead lê os primeiros bytes já recebidos pela sessão
+func readSessionHead(ctx context.Context, store storage.Driver, session *models.UploadSession) ([]byte, error) {
+	content, err := openSessionContent(ctx, store, session)
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+
+	head := make([]byte, util.SniffLen)
+	n, err := io.ReadFull(content, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	return head[:n], nil
+}
+
+// CleanupExpiredUploadSessions remove as sessões cujo prazo expirou, liberando o
+// espaço reservado no plano do usuário. Retorna quantas sessões foram removidas.
+func CleanupExpiredUploadSessions(db *gorm.DB, store storage.Driver) (int, error) {
+	var expired []models.UploadSession
+	if err := db.Where("expires_at <= ?", time.Now()).Find(&expired).Error; err != nil {
+		return 0, err
+	}
+	for i := range expired {
+		if err := removeUploadSession(db, store, &expired[i]); err != nil {
+			return i, err
+		}
+	}
+	return len(expired), nil
+}
+
+// StartUploadSessionJanitor executa CleanupExpiredUploadSessions periodicamente
+func StartUploadSessionJanitor(db *gorm.DB, store storage.Driver, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			removed, err := CleanupExpiredUploadSessions(db, store)
+			if err != nil {
+				log.Printf("⚠️  Warning: Failed to clean up expired upload sessions: %v", err)
+			} else if removed > 0 {
+				log.Printf("🧹 Removed %d expired upload session(s)", removed)
+			}
+		}
+	}()
+}
+
+// CreateUploadSessionHandler godoc
+// @Summary Create a resumable upload session
+// @Description Starts a tus-style resumable upload. Send the total size in Upload-Length and the file name and project in Upload-Metadata (base64 values); add org to upload to an organization's project (member role or higher), which uses the organization's plan. The file type is detected from the first bytes received and must match the file extension. The declared size is reserved against the plan's storage limit until the session completes or expires, must not exceed the plan's max file size, and each session counts toward the daily upload quota.
+// @Tags uploads
+// @Produce  json
+// @Param   Upload-Length    header  int     true   "Total file size in bytes"
+// @Param   Upload-Metadata  header  string  true   "tus metadata: filename, project and optional org (base64 encoded)"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 201 {object} UploadSessionResponse "Upload session created"
+// @Failure 400 {string} string "Invalid Upload-Length or Upload-Metadata"
+// @Failure 403 {string} string "Email address not verified, storage limit exceeded, organization role below member or API key restricted to another project"
+// @Failure 404 {string} string "Organization not found"
+// @Failure 413 {string} string "File is too large"
+// @Failure 415 {string} string "File type not allowed by the plan"
+// @Failure 429 {string} string "Daily upload limit reached for the plan or too many requests (see Retry-After)"
+// @Router /api/uploads [post]
+func CreateUploadSessionHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		userFromCtx, ok := r.Context().Value(middleware.UserContextKey).(*models.User)
+		if !ok {
+			http.Error(w, "Could not retrieve user from context", http.StatusInternalServerError)
+			return
+		}
+
+		var user models.User
+		if err := db.Preload("Plan").First(&user, userFromCtx.ID).Error; err != nil {
+			http.Error(w, "Could not retrieve user details", http.StatusInternalServerError)
+			return
+		}
+
+		if !requireVerifiedEmail(w, &user) {
+			return
+		}
+
+		length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+		if err != nil || length <= 0 {
+			http.Error(w, "Upload-Length header must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		meta, err := parseUploadMetadata(r.Header.Get("Upload-Metadata"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Projetos de organizações usam o plano da organização
+		ns, ok := requestNamespace(w, db, &user, meta["org"], models.RoleMember)
+		if !ok {
+			return
+		}
+		plan := ns.plan()
+
+		// Vale o menor entre o limite por arquivo do plano e o teto do servidor
+		maxSize := min(plan.MaxFileSize, config.AppConfig.MaxResumableUploadSize)
+		if length > maxSize {
+			http.Error(w, fmt.Sprintf("File is too large. Max size for your plan is %s.", formatBytes(maxSize)), http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		fileName := filepath.Base(strings.TrimSpace(meta["filename"]))
+		if fileName == "" || fileName == "." || fileName == "/" {
+			http.Error(w, "Upload-Metadata must include a filename", http.StatusBadRequest)
+			return
+		}
+		projectName := sanitizeProjectName(meta["project"])
+		if !projectAllowed(db, r, ns, projectName) {
+			writeProjectForbidden(w)
+			return
+		}
+
+		// O tipo provisório vem da extensão; quando os primeiros bytes chegarem, o conteúdo
+		// é conferido e o tipo detectado passa a valer. O filetype declarado pelo cliente
+		// não é considerado
+		mimeType := util.MimeTypeForExtension(fileName)
+		if !plan.AllowsMimeType(mimeType) {
+			http.Error(w, fmt.Sprintf("Invalid file type. Allowed types for your plan are: %s.", strings.Join(plan.MimeTypes(), ", ")), http.StatusUnsupportedMediaType)
+			return
+		}
+
+		if ns.readOnly() {
+			notifyQuotaExceeded(db, ns, projectName, "storage")
+			writeReadOnly(w)
+			return
+		}
+
+		// Sessões parciais contam contra o limite do plano desde a criação
+		if ns.storageUsage()+ns.owner().reserved(db)+length > plan.StorageLimit {
+			notifyQuotaExceeded(db, ns, projectName, "storage")
+			http.Error(w, "Storage limit exceeded", http.StatusForbidden)
+			return
+		}
+
+		// Cada sessão criada conta como um upload na cota diária
+		allowed, err := consumeDailyUpload(db, user.ID, plan)
+		if err != nil {
+			http.Error(w, "Could not check daily upload quota: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !allowed {
+			notifyQuotaExceeded(db, ns, projectName, "daily_uploads")
+			writeDailyQuotaExceeded(w, plan)
+			return
+		}
+
+		session := models.UploadSession{
+			UserID:         user.ID,
+			OrganizationID: ns.orgID(),
+			ProjectName:    projectName,
+			FileName:       fileName,
+			MimeType:       mimeType,
+			UploadLength:   length,
+			ExpiresAt:      time.Now().Add(config.AppConfig.UploadSessionTTL),
+		}
+		if err := db.Create(&session).Error; err != nil {
+			refundDailyUpload(db, user.ID, today())
+			http.Error(w, "Could not create upload session: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		location := fmt.Sprintf("%s/api/uploads/%s", config.AppConfig.Domain, session.ID)
+		setUploadHeaders(w, &session)
+		w.Header().Set("Location", location)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(UploadSessionResponse{
+			ID:        session.ID.String(),
+			Location:  location,
+			Offset:    session.UploadOffset,
+			Length:    session.UploadLength,
+			ExpiresAt: session.ExpiresAt,
+		})
+	}
+}
+
+// UploadSessionHandler godoc
+// @Summary Query, append to or cancel a resumable upload
+// @Description HEAD returns the current Upload-Offset. PATCH appends a chunk (Content-Type application/offset+octet-stream) at the given Upload-Offset; when the last byte arrives the file is stored and the upload response is returned. DELETE cancels the session.
+// @Tags uploads
+// @Produce  json
+// @Param   id             path    string  true   "Upload session ID"
+// @Param   Upload-Offset  header  int     false  "Offset of the chunk being sent (PATCH only)"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} UploadResponse "Upload completed"
+// @Success 204 {string} string "Chunk accepted or session cancelled"
+// @Failure 400 {string} string "Invalid chunk"
+// @Failure 403 {string} string "API key is restricted to another project"
+// @Failure 404 {string} string "Upload session not found"
+// @Failure 409 {string} string "Upload-Offset does not match the current offset"
+// @Failure 410 {string} string "Upload session expired"
+// @Failure 415 {string} string "Content-Type must be application/offset+octet-stream, or the file content does not match its extension"
+// @Failure 423 {string} string "Upload session is busy"
+// @Router /api/uploads/{id} [head]
+// @Router /api/uploads/{id} [patch]
+// @Router /api/uploads/{id} [delete]
+func UploadSessionHandler(db *gorm.DB, store storage.Driver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := r.Context().Value(middleware.UserContextKey).(*models.User)
+		if !ok {
+			http.Error(w, "Could not retrieve user from context", http.StatusInternalServerError)
+			return
+		}
+
+		id, err := uuid.Parse(r.PathValue("id"))
+		if err != nil {
+			http.Error(w, "Upload session not found", http.StatusNotFound)
+			return
+		}
+
+		var session models.UploadSession
+		if err := db.First(&session, "id = ? AND user_id = ?", id, user.ID).Error; err != nil {
+			http.Error(w, "Upload session not found", http.StatusNotFound)
+			return
+		}
+		// O papel na organização é conferido de novo, pois o membro pode ter sido removido
+		ns, ok := organizationNamespace(w, db, user, session.OrganizationID, models.RoleMember)
+		if !ok {
+			return
+		}
+		if !projectAllowed(db, r, ns, session.ProjectName) {
+			writeProjectForbidden(w)
+			return
+		}
+		if time.Now().After(session.ExpiresAt) {
+			removeUploadSession(db, store, &session)
+			http.Error(w, "Upload session expired", http.StatusGone)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodHead:
+			setUploadHeaders(w, &session)
+			w.WriteHeader(http.StatusOK)
+		case http.MethodPatch:
+			appendUploadChunk(w, r, db, store, ns, &session)
+		case http.MethodDelete:
+			if err := removeUploadSession(db, store, &session); err != nil {
+				http.Error(w, "Could not delete upload session: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Tus-Resumable", tusVersion)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// appendUploadChunk grava o corpo do PATCH como uma nova parte da sessão e finaliza a
+// sessão quando todos os bytes declarados tiverem chegado. O advisory lock impede que
+// dois PATCH, mesmo em réplicas diferentes, escrevam na mesma sessão ao mesmo tempo.
+func appendUploadChunk(w http.ResponseWriter, r *http.Request, db *gorm.DB, store storage.Driver, ns *namespace, session *models.UploadSession) {
+	if r.Header.Get("Content-Type") != "application/offset+octet-stream" {
+		http.Error(w, "Content-Type must be application/offset+octet-stream", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	locked, err := withUploadSessionLock(db, session.ID, func() {
+		writeUploadChunk(w, r, db, store, ns, session)
+	})
+	if err != nil {
+		http.Error(w, "Could not lock upload session: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !locked {
+		http.Error(w, "Upload session is busy", http.StatusLocked)
+	}
+}
+
+// writeUploadChunk é o corpo de appendUploadChunk, executado com o lock da sessão
+func writeUploadChunk(w http.ResponseWriter, r *http.Request, db *gorm.DB, store storage.Driver, ns *namespace, session *models.UploadSession) {
+	// Recarrega a sessão já com o lock, pois outro PATCH pode ter avançado o offset
+	if err := db.First(session, "id = ?", session.ID).Error; err != nil {
+		http.Error(w, "Upload session not found", http.StatusNotFound)
+		return
+	}
+
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset != session.UploadOffset {
+		setUploadHeaders(w, session)
+		http.Error(w, "Upload-Offset does not match the current offset", http.StatusConflict)
+		return
+	}
+
+	remaining := session.UploadLength - session.UploadOffset
+	if r.ContentLength > remaining {
+		http.Error(w, "Chunk exceeds the declared Upload-Length", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// O pedaço é recebido em um arquivo temporário local e só então gravado como parte no
+	// driver, com o tamanho que de fato chegou. Mesmo que a conexão caia no meio, os bytes
+	// recebidos são mantidos para o cliente retomar, inclusive em outra réplica.
+	if err := os.MkdirAll(config.AppConfig.UploadSessionDir, os.ModePerm); err != nil {
+		http.Error(w, "Could not write chunk: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	chunk, err := os.CreateTemp(config.AppConfig.UploadSessionDir, session.ID.String()+"-*")
+	if err != nil {
+		http.Error(w, "Could not write chunk: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer os.Remove(chunk.Name())
+	defer chunk.Close()
+
+	written, copyErr := io.Copy(chunk, io.LimitReader(r.Body, remaining))
+	if written > 0 {
+		if _, err := chunk.Seek(0, io.SeekStart); err != nil {
+			http.Error(w, "Could not write chunk: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		// A requisição pode ter sido cancelada pela queda da conexão; a parte é gravada mesmo assim
+		ctx := context.WithoutCancel(r.Context())
+		if err := store.Put(ctx, sessionPartKey(session, session.UploadOffset), io.LimitReader(chunk, written), written, "application/octet-stream"); err != nil {
+			http.Error(w, "Could not write chunk: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	previousOffset := session.UploadOffset
+	session.UploadOffset += written
+	if err := db.Model(session).Update("upload_offset", session.UploadOffset).Error; err != nil {
+		http.Error(w, "Could not update upload session: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Assim que os primeiros bytes chegam, confere o tipo real do conteúdo e
+	// descarta a sessão se ele não bater com a extensão
+	sniffEnd := min(int64(util.SniffLen), session.UploadLength)
+	if previousOffset < sniffEnd && session.UploadOffset >= sniffEnd {
+		plan, _, err := storageOwner{UserID: session.UserID, OrgID: session.OrganizationID}.usage(db)
+		if err != nil {
+			http.Error(w, "Could not retrieve plan details", http.StatusInternalServerError)
+			return
+		}
+		head, err := readSessionHead(r.Context(), store, session)
+		if err != nil {
+			http.Error(w, "Could not read upload session: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		detected, err := validateFileContent(head, session.FileName, plan)
+		if err != nil {
+			// O servidor recusou o arquivo: o upload volta para a cota do dia em que a
+			// sessão foi criada, e a reserva de armazenamento sai junto com a sessão
+			removeUploadSession(db, store, session)
+			refundDailyUpload(db, session.UserID, session.CreatedAt.UTC().Format("2006-01-02"))
+			http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+			return
+		}
+		// O tipo detectado substitui o provisório, vindo da extensão, e é o gravado no arquivo
+		session.MimeType = detected
+		if err := db.Model(session).Update("mime_type", detected).Error; err != nil {
+			http.Error(w, "Could not update upload session: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if copyErr != nil {
+		setUploadHeaders(w, session)
+		http.Error(w, "Error reading chunk: "+copyErr.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if session.UploadOffset < session.UploadLength {
+		setUploadHeaders(w, session)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	finalizeUploadSession(w, r, db, store, ns, session)
+}
+
+// finalizeUploadSession junta as partes em um arquivo temporário local (o blob precisa
+// ser lido duas vezes: para o hash e para a gravação), grava o conteúdo no driver de
+// armazenamento e cria o models.File correspondente
+func finalizeUploadSession(w http.ResponseWriter, r *http.Request, db *gorm.DB, store storage.Driver, ns *namespace, session *models.UploadSession) {
+	parts, err := openSessionContent(r.Context(), store, session)
+	if err != nil {
+		http.Error(w, "Could not open upload session: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer parts.Close()
+
+	content, err := os.CreateTemp(config.AppConfig.UploadSessionDir, session.ID.String()+"-*")
+	if err != nil {
+		http.Error(w, "Could not open upload session: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer os.Remove(content.Name())
+	defer content.Close()
+	if _, err := io.Copy(content, parts); err != nil {
+		http.Error(w, "Could not read upload session: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		http.Error(w, "Could not read upload session: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	dbFile, project, err := storeUpload(r.Context(), db, store, ns, session.ProjectName, session.FileName, content, session.UploadLength, session.MimeType, "")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := removeUploadSession(db, store, session); err != nil {
+		log.Printf("⚠️  Warning: Failed to remove completed upload session %s: %v", session.ID, err)
+	}
+
+	setUploadHeaders(w, session)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(UploadResponse{
+		Message: "File uploaded successfully",
+		URL:     fileURL(project, dbFile),
+		Project: project.Name,
+		File:    dbFile.Name,
+		Status:  dbFile.Status,
+	})
+}
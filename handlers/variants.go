@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/config"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/imaging"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/storage"
+)
+
+// VariantInfo descreve uma variante já gerada de uma imagem
+type VariantInfo struct {
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Fit    string `json:"fit"`
+	Size   int64  `json:"size"`
+	URL    string `json:"url"`
+}
+
+// variantKey monta a chave de uma variante no cache: <dono>/<arquivo>/<W>x<H>-<fit>.<ext>,
+// onde o dono é user_<id> ou org_<id>
+func variantKey(owner storageOwner, fileID uuid.UUID, size imaging.Size, mimeType string) string {
+	ext := ".jpg"
+	if mimeType == "image/png" {
+		ext = ".png"
+	}
+	return path.Join(owner.prefix(), fileID.String(), size.String()+ext)
+}
+
+// variantURL acrescenta os parâmetros da variante à URL do arquivo, que pode já estar assinada
+func variantURL(fileURL string, v models.Variant) string {
+	params := url.Values{}
+	params.Set("w", strconv.Itoa(v.Width))
+	params.Set("h", strconv.Itoa(v.Height))
+	params.Set("fit", v.Fit)
+	sep := "?"
+	if strings.Contains(fileURL, "?") {
+		sep = "&"
+	}
+	return fileURL + sep + params.Encode()
+}
+
+// parseVariantSize lê ?w=&h=&fit= da requisição. ok é falso quando nenhum deles foi
+// informado, ou seja, quando o original foi pedido.
+func parseVariantSize(query url.Values) (size imaging.Size, ok bool, err error) {
+	w, h, fit := query.Get("w"), query.Get("h"), query.Get("fit")
+	if w == "" && h == "" && fit == "" {
+		return imaging.Size{}, false, nil
+	}
+	width, height := 0, 0
+	if w != "" {
+		if width, err = strconv.Atoi(w); err != nil {
+			return imaging.Size{}, true, errors.New("w must be a number of pixels")
+		}
+	}
+	if h != "" {
+		if height, err = strconv.Atoi(h); err != nil {
+			return imaging.Size{}, true, errors.New("h must be a number of pixels")
+		}
+	}
+	size, err = imaging.NewSize(width, height, fit)
+	return size, true, err
+}
+
+// loadImage lê o conteúdo original do arquivo e o decodifica
+func loadImage(ctx context.Context, db *gorm.DB, store storage.Driver, file *models.File) (image.Image, error) {
+	key, err := storageKeyForFile(db, file)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	// O driver S3 devolve um stream sem Seek, que a decodificação precisa
+	rs, ok := rc.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(rc)
+		if err != nil {
+			return nil, err
+		}
+		rs = bytes.NewReader(data)
+	}
+	return imaging.Decode(rs, config.AppConfig.MaxImagePixels)
+}
+
+// renderVariant redimensiona a imagem e a codifica no formato do original
+func renderVariant(img image.Image, size imaging.Size, mimeType string) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := imaging.Encode(&buf, imaging.Resize(img, size), mimeType); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// saveVariant grava a variante no cache e a registra, somando seu tamanho ao uso de
+// armazenamento do dono do projeto. Retorna false, sem gravar, quando ela não cabe no
+// plano ou o arquivo já tem o máximo de variantes; nesse caso ela é servida sem ficar no
+// cache.
+func saveVariant(ctx context.Context, db *gorm.DB, cache storage.Driver, owner storageOwner, file *models.File, size imaging.Size, data []byte) (bool, error) {
+	plan, usage, err := owner.usage(db)
+	if err != nil {
+		return false, err
+	}
+	if usage+int64(len(data)) > plan.StorageLimit {
+		return false, nil
+	}
+	var count int64
+	db.Model(&models.Variant{}).Where("file_id = ?", file.ID).Count(&count)
+	if count >= config.AppConfig.MaxVariantsPerFile {
+		return false, nil
+	}
+
+	key := variantKey(owner, file.ID, size, file.MimeType)
+	if err := cache.Put(ctx, key, bytes.NewReader(data), int64(len(data)), file.MimeType); err != nil {
+		return false, err
+	}
+
+	// Pedidos simultâneos da mesma variante gravam o mesmo conteúdo; só o primeiro registro conta
+	variant := models.Variant{
+		FileID:     file.ID,
+		Width:      size.Width,
+		Height:     size.Height,
+		Fit:        size.Fit,
+		StorageKey: key,
+		Size:       int64(len(data)),
+		MimeType:   file.MimeType,
+	}
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&variant)
+	if result.Error != nil {
+		// O arquivo pode ter sido apagado enquanto a variante era gerada
+		cache.Delete(ctx, key)
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	if err := owner.updateStorage(db, variant.Size, variant.Size); err != nil {
+		log.Printf("⚠️  Warning: Failed to update storage usage for %s: %v", owner, err)
+	}
+	return true, nil
+}
+
+// generateThumbnails cria as miniaturas configuradas em THUMBNAIL_SIZES para uma imagem
+// já decodificada. Miniaturas existentes são mantidas, então pode ser repetida.
+func generateThumbnails(ctx context.Context, db *gorm.DB, cache storage.Driver, owner storageOwner, file *models.File, img image.Image) error {
+	for _, size := range config.AppConfig.ThumbnailSizes {
+		data, err := renderVariant(img, size, file.MimeType)
+		if err != nil {
+			return fmt.Errorf("could not render %s thumbnail: %w", size, err)
+		}
+		if _, err := saveVariant(ctx, db, cache, owner, file, size, data); err != nil {
+			return fmt.Errorf("could not save %s thumbnail: %w", size, err)
+		}
+	}
+	return nil
+}
+
+// serveVariant serve a variante pedida do cache ou, se ela ainda não existir, a gera a
+// partir do original e a guarda para os próximos pedidos
+func serveVariant(w http.ResponseWriter, r *http.Request, db *gorm.DB, store, cache storage.Driver, project *models.Project, file *models.File, size imaging.Size) {
+	if !imaging.Supported(file.MimeType) {
+		http.Error(w, "Resizing is only supported for JPEG and PNG images", http.StatusBadRequest)
+		return
+	}
+
+	var variant models.Variant
+	err := db.First(&variant, "file_id = ? AND width = ? AND height = ? AND fit = ?", file.ID, size.Width, size.Height, size.Fit).Error
+	cached := err == nil
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, "Could not read file", http.StatusInternalServerError)
+		return
+	}
+	if cached {
+		w.Header().Set("Content-Type", variant.MimeType)
+		err := serveObject(w, r, cache, variant.StorageKey, file.Name)
+		if err == nil {
+			return
+		}
+		if !errors.Is(err, storage.ErrNotExist) {
+			http.Error(w, "Could not read file", http.StatusInternalServerError)
+			return
+		}
+		// O cache foi limpo: a variante é gerada de novo abaixo
+	}
+
+	img, err := loadImage(r.Context(), db, store, file)
+	if errors.Is(err, imaging.ErrTooManyPixels) {
+		http.Error(w, "Image is too large to be resized", http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Could not resize image", http.StatusInternalServerError)
+		return
+	}
+	data, err := renderVariant(img, size, file.MimeType)
+	if err != nil {
+		http.Error(w, "Could not resize image", http.StatusInternalServerError)
+		return
+	}
+
+	if cached {
+		if err := cache.Put(r.Context(), variant.StorageKey, bytes.NewReader(data), int64(len(data)), file.MimeType); err != nil {
+			log.Printf("⚠️  Warning: Could not restore variant %s: %v", variant.StorageKey, err)
+		}
+	} else if _, err := saveVariant(r.Context(), db, cache, projectOwner(project), file, size, data); err != nil {
+		log.Printf("⚠️  Warning: Could not cache %s variant of file %s: %v", size, file.ID, err)
+	}
+
+	w.Header().Set("Content-Type", file.MimeType)
+	http.ServeContent(w, r, file.Name, time.Now(), bytes.NewReader(data))
+}
+
+// deleteVariants remove do cache os objetos das variantes de um arquivo já apagado.
+// Os registros somem junto com o arquivo (ON DELETE CASCADE).
+func deleteVariants(ctx context.Context, cache storage.Driver, variants []models.Variant) {
+	for _, v := range variants {
+		if err := cache.Delete(ctx, v.StorageKey); err != nil && !errors.Is(err, storage.ErrNotExist) {
+			log.Printf("Could not delete variant from cache: %s", err.Error())
+		}
+	}
+}
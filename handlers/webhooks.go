@@ -0,0 +1,746 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/config"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/jobs"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/middleware"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/util"
+)
+
+// JobWebhookDelivery envia uma entrega de webhook
+const JobWebhookDelivery = "webhook_delivery"
+
+// webhookResponseLimit é quanto do corpo da resposta fica registrado na entrega
+const webhookResponseLimit = 1024
+
+type WebhookRequest struct {
+	URL     string   `json:"url"`
+	Events  []string `json:"events"`
+	Project string   `json:"project,omitempty"` // Recebe eventos só deste projeto; todos quando omitido
+	Org     string   `json:"org,omitempty"`     // Organização do projeto; projetos pessoais quando omitido
+	Active  *bool    `json:"active,omitempty"`
+}
+
+type WebhookInfo struct {
+	ID        uuid.UUID `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Project   string    `json:"project,omitempty"`
+	Org       string    `json:"org,omitempty"` // Slug da organização dona do projeto
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type WebhookCreatedResponse struct {
+	WebhookInfo
+	Secret string `json:"secret"` // Usado para conferir X-Forge-Signature; exibido apenas nesta resposta
+}
+
+type WebhooksResponse struct {
+	Webhooks []WebhookInfo `json:"webhooks"`
+}
+
+type DeliveryInfo struct {
+	ID             uuid.UUID       `json:"id"`
+	Event          string          `json:"event"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	ResponseStatus int             `json:"response_status,omitempty"`
+	ResponseBody   string          `json:"response_body,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	Payload        json.RawMessage `json:"payload"`
+	DeliveredAt    *time.Time      `json:"delivered_at"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+type DeliveriesResponse struct {
+	Deliveries []DeliveryInfo `json:"deliveries"`
+	Total      int64          `json:"total"`
+	Page       int            `json:"page"`
+	PerPage    int            `json:"per_page"`
+	TotalPages int            `json:"total_pages"`
+}
+
+// WebhookEvent é o corpo enviado em cada entrega
+type WebhookEvent struct {
+	Event     string    `json:"event"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// deliveryJob é o payload do job JobWebhookDelivery
+type deliveryJob struct {
+	DeliveryID uuid.UUID `json:"delivery_id"`
+}
+
+// errPrivateAddress impede que webhooks sejam usados para alcançar a rede interna
+var errPrivateAddress = errors.New("webhook URL resolves to a private or loopback address")
+
+// newWebhookClient cria o cliente HTTP das entregas. O endereço é conferido depois da
+// resolução de DNS, de modo que um domínio apontando para a rede interna também é
+// recusado, e redirecionamentos não são seguidos.
+func newWebhookClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, c syscall.RawConn) error {
+			if config.AppConfig.WebhookAllowPrivate {
+				return nil
+			}
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+				ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+				return errPrivateAddress
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout:   config.AppConfig.WebhookTimeout,
+		Transport: &http.Transport{DialContext: dialer.DialContext},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// validateWebhookURL aceita apenas URLs absolutas http(s)
+func validateWebhookURL(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", errors.New("url must be an absolute http or https URL")
+	}
+	return u.String(), nil
+}
+
+// validateEvents confere se todos os eventos são conhecidos e remove duplicados
+func validateEvents(events []string) ([]string, error) {
+	if len(events) == 0 {
+		return nil, fmt.Errorf("At least one event is required. Valid events are: %s.", strings.Join(models.AllEvents, ", "))
+	}
+	valid := make([]string, 0, len(events))
+	for _, e := range events {
+		e = strings.ToLower(strings.TrimSpace(e))
+		if !slices.Contains(models.AllEvents, e) {
+			return nil, fmt.Errorf("Unknown event %q. Valid events are: %s.", e, strings.Join(models.AllEvents, ", "))
+		}
+		if !slices.Contains(valid, e) {
+			valid = append(valid, e)
+		}
+	}
+	return valid, nil
+}
+
+// toWebhookInfo converte o modelo para a resposta da API, sem o segredo
+func toWebhookInfo(db *gorm.DB, wh *models.Webhook) WebhookInfo {
+	info := WebhookInfo{
+		ID:        wh.ID,
+		URL:       wh.URL,
+		Events:    wh.EventList(),
+		Active:    wh.Active,
+		CreatedAt: wh.CreatedAt,
+	}
+	if wh.ProjectID != nil {
+		info.Project, info.Org = projectLabel(db, *wh.ProjectID)
+	}
+	return info
+}
+
+// toDeliveryInfo converte o registro de entrega para a resposta da API
+func toDeliveryInfo(d *models.WebhookDelivery) DeliveryInfo {
+	return DeliveryInfo{
+		ID:             d.ID,
+		Event:          d.Event,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		ResponseStatus: d.ResponseStatus,
+		ResponseBody:   d.ResponseBody,
+		LastError:      d.LastError,
+		Payload:        json.RawMessage(d.Payload),
+		DeliveredAt:    d.DeliveredAt,
+		CreatedAt:      d.CreatedAt,
+	}
+}
+
+// createDelivery grava uma entrega pendente e agenda seu envio
+func createDelivery(db *gorm.DB, webhookID uuid.UUID, event, payload string) (*models.WebhookDelivery, error) {
+	delivery := models.WebhookDelivery{
+		WebhookID: webhookID,
+		Event:     event,
+		Payload:   payload,
+		Status:    models.DeliveryPending,
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&delivery).Error; err != nil {
+			return err
+		}
+		return jobs.Enqueue(tx, JobWebhookDelivery, deliveryJob{DeliveryID: delivery.ID},
+			&jobs.EnqueueOptions{MaxAttempts: config.AppConfig.WebhookMaxAttempts})
+	})
+	return &delivery, err
+}
+
+// dispatchEvent agenda a entrega do evento a todos os webhooks ativos do usuário que o
+// assinam: os de todos os projetos e, quando projectID não é nil, os daquele projeto.
+// Falhas são apenas registradas para não afetar a operação que gerou o evento.
+func dispatchEvent(db *gorm.DB, userID uuid.UUID, projectID *uuid.UUID, event string, data any) {
+	query := db.Where("user_id = ? AND active = ?", userID, true)
+	if projectID != nil {
+		query = query.Where("project_id IS NULL OR project_id = ?", *projectID)
+	} else {
+		query = query.Where("project_id IS NULL")
+	}
+	deliverEvent(db, query, event, data)
+}
+
+// dispatchProjectEvent agenda a entrega de um evento de projeto. Em projetos pessoais
+// equivale a dispatchEvent; em projetos de organizações o evento chega aos webhooks
+// restritos ao projeto, de qualquer membro.
+func dispatchProjectEvent(db *gorm.DB, project *models.Project, event string, data any) {
+	if project.OrganizationID == nil {
+		dispatchEvent(db, project.UserID, &project.ID, event, data)
+		return
+	}
+	deliverEvent(db, db.Where("project_id = ? AND active = ?", project.ID, true), event, data)
+}
+
+// deliverEvent agenda a entrega do evento aos webhooks da consulta que o assinam
+func deliverEvent(db *gorm.DB, query *gorm.DB, event string, data any) {
+	var webhooks []models.Webhook
+	if err := query.Find(&webhooks).Error; err != nil {
+		log.Printf("⚠️  Warning: Could not load webhooks for %s event: %v", event, err)
+		return
+	}
+
+	var payload []byte
+	for _, wh := range webhooks {
+		if !wh.Subscribes(event) {
+			continue
+		}
+		if payload == nil {
+			var err error
+			if payload, err = json.Marshal(WebhookEvent{Event: event, CreatedAt: time.Now().UTC(), Data: data}); err != nil {
+				log.Printf("⚠️  Warning: Could not encode %s event: %v", event, err)
+				return
+			}
+		}
+		if _, err := createDelivery(db, wh.ID, event, string(payload)); err != nil {
+			log.Printf("⚠️  Warning: Could not schedule %s delivery to webhook %s: %v", event, wh.ID, err)
+		}
+	}
+}
+
+// notifyProjectCreated dispara project.created para um projeto recém-criado
+func notifyProjectCreated(db *gorm.DB, project *models.Project) {
+	dispatchProjectEvent(db, project, models.EventProjectCreated, map[string]any{
+		"project":    project.Name,
+		"visibility": project.Visibility,
+	})
+}
+
+// notifyQuotaExceeded dispara quota.exceeded quando um upload é recusado por limite do
+// plano do namespace (o do usuário ou o da organização)
+func notifyQuotaExceeded(db *gorm.DB, ns *namespace, projectName, limit string) {
+	data := map[string]any{
+		"limit":         limit, // "storage" ou "daily_uploads"
+		"project":       projectName,
+		"plan":          ns.plan().Name,
+		"storage_usage": ns.storageUsage(),
+		"storage_limit": ns.plan().StorageLimit,
+	}
+	if ns.Org != nil {
+		data["org"] = ns.Org.Slug
+	}
+	project, err := ns.findProject(db, projectName)
+	if err != nil {
+		// Projeto ainda não criado: apenas os webhooks de todos os projetos do usuário
+		dispatchEvent(db, ns.User.ID, nil, models.EventQuotaExceeded, data)
+		return
+	}
+	dispatchProjectEvent(db, project, models.EventQuotaExceeded, data)
+}
+
+// sendDelivery faz uma tentativa de entrega e registra o resultado. Retorna erro quando
+// o destino não respondeu 2xx.
+func sendDelivery(ctx context.Context, db *gorm.DB, client *http.Client, delivery *models.WebhookDelivery, wh *models.Webhook) error {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wh.URL, bytes.NewReader(body))
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Forge-Webhooks/1.0")
+	req.Header.Set("X-Forge-Event", delivery.Event)
+	req.Header.Set("X-Forge-Delivery", delivery.ID.String())
+	req.Header.Set("X-Forge-Signature", util.SignWebhookPayload(wh.Secret, body, time.Now()))
+
+	updates := map[string]interface{}{
+		"attempts":        gorm.Expr("attempts + 1"),
+		"response_status": 0,
+		"response_body":   "",
+		"last_error":      "",
+	}
+	resp, sendErr := client.Do(req)
+	if sendErr == nil {
+		defer resp.Body.Close()
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseLimit))
+		updates["response_status"] = resp.StatusCode
+		updates["response_body"] = string(snippet)
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			sendErr = fmt.Errorf("webhook responded %d", resp.StatusCode)
+		}
+	}
+	if sendErr == nil {
+		now := time.Now()
+		updates["status"] = models.DeliverySucceeded
+		updates["delivered_at"] = now
+	} else {
+		updates["last_error"] = sendErr.Error()
+	}
+
+	if err := db.Model(delivery).Updates(updates).Error; err != nil {
+		log.Printf("⚠️  Warning: Could not record delivery %s: %v", delivery.ID, err)
+	}
+	db.First(delivery, "id = ?", delivery.ID)
+	return sendErr
+}
+
+// RegisterWebhookJobs registra o envio de entregas de webhook no runner. Cada falha é
+// tentada de novo com espera exponencial até WEBHOOK_MAX_ATTEMPTS.
+func RegisterWebhookJobs(runner *jobs.Runner, db *gorm.DB) {
+	client := newWebhookClient()
+
+	deliver := func(ctx context.Context, job *models.Job) error {
+		var payload deliveryJob
+		if err := jobs.Decode(job, &payload); err != nil {
+			return err
+		}
+		var delivery models.WebhookDelivery
+		if err := db.First(&delivery, "id = ?", payload.DeliveryID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				// O webhook foi removido junto com suas entregas
+				return nil
+			}
+			return err
+		}
+		var wh models.Webhook
+		if err := db.First(&wh, "id = ?", delivery.WebhookID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+		return sendDelivery(ctx, db, client, &delivery, &wh)
+	}
+
+	markFailed := func(ctx context.Context, job *models.Job, err error) {
+		var payload deliveryJob
+		if jobs.Decode(job, &payload) != nil {
+			return
+		}
+		db.Model(&models.WebhookDelivery{}).
+			Where("id = ? AND status = ?", payload.DeliveryID, models.DeliveryPending).
+			Update("status", models.DeliveryFailed)
+	}
+
+	runner.Register(JobWebhookDelivery, deliver, markFailed)
+}
+
+// findWebhook carrega o webhook {id} do usuário, respeitando a restrição de projeto da
+// chave de API. Responde 404 e retorna nil quando não encontrado.
+func findWebhook(w http.ResponseWriter, r *http.Request, db *gorm.DB, userID uuid.UUID) *models.Webhook {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return nil
+	}
+	var wh models.Webhook
+	if err := db.First(&wh, "id = ? AND user_id = ?", id, userID).Error; err != nil {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return nil
+	}
+	if current := requestAPIKey(r); current != nil && current.ProjectID != nil &&
+		(wh.ProjectID == nil || *wh.ProjectID != *current.ProjectID) {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return nil
+	}
+	return &wh
+}
+
+// WebhooksHandler godoc
+// @Summary List or create webhooks
+// @Description GET lists the user's webhooks. POST subscribes a URL to events (file.uploaded, file.deleted, project.created, project.deleted, quota.exceeded), optionally for a single project. Webhooks for organization projects (org) require the admin role and must be restricted to the project; they receive the events of every member. Each delivery is a JSON POST signed in X-Forge-Signature ("t=<unix>,v1=<hex HMAC-SHA256 of "<t>.<body>">"). The signing secret is returned only once, in the creation response.
+// @Tags webhooks
+// @Accept  json
+// @Produce  json
+// @Param   webhook_request  body  WebhookRequest  false  "URL, events and optional project and org (POST only)"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} WebhooksResponse "Webhooks"
+// @Success 201 {object} WebhookCreatedResponse "Webhook created"
+// @Failure 400 {string} string "Invalid request body, URL or events"
+// @Failure 403 {string} string "API key lacks the 'admin' scope or is restricted to another project, or the organization role is below admin"
+// @Failure 404 {string} string "Project or organization not found"
+// @Failure 500 {string} string "Could not create webhook"
+// @Router /api/webhooks [get]
+// @Router /api/webhooks [post]
+func WebhooksHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, _ := r.Context().Value(middleware.UserContextKey).(*models.User)
+
+		switch r.Method {
+		case http.MethodGet:
+			query := db.Where("user_id = ?", user.ID)
+			if current := requestAPIKey(r); current != nil && current.ProjectID != nil {
+				query = query.Where("project_id = ?", *current.ProjectID)
+			}
+			var webhooks []models.Webhook
+			query.Order("created_at").Find(&webhooks)
+
+			infos := make([]WebhookInfo, 0, len(webhooks))
+			for i := range webhooks {
+				infos = append(infos, toWebhookInfo(db, &webhooks[i]))
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(WebhooksResponse{Webhooks: infos})
+
+		case http.MethodPost:
+			var req WebhookRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			target, err := validateWebhookURL(req.URL)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			events, err := validateEvents(req.Events)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			secret, _, err := util.GenerateOpaqueToken()
+			if err != nil {
+				http.Error(w, "Could not create webhook: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			wh := models.Webhook{
+				UserID: user.ID,
+				URL:    target,
+				Secret: "whsec_" + secret,
+				Events: strings.Join(events, ","),
+				Active: req.Active == nil || *req.Active,
+			}
+
+			// Webhooks recebem eventos de todos os membros, então em organizações exigem
+			// o papel admin e ficam restritos a um projeto
+			ns, ok := requestNamespace(w, db, user, req.Org, models.RoleAdmin)
+			if !ok {
+				return
+			}
+			current := requestAPIKey(r)
+			restricted := current != nil && current.ProjectID != nil
+			if ns.Org != nil && req.Project == "" && !restricted {
+				http.Error(w, "Webhooks for an organization must be restricted to one of its projects", http.StatusBadRequest)
+				return
+			}
+
+			// Uma chave restrita a um projeto só cria webhooks para o mesmo projeto
+			if restricted {
+				wh.ProjectID = current.ProjectID
+				if !ns.contains(db, *current.ProjectID) || req.Project != "" && !projectAllowed(db, r, ns, req.Project) {
+					writeProjectForbidden(w)
+					return
+				}
+			} else if req.Project != "" {
+				project, err := ns.findProject(db, req.Project)
+				if err != nil {
+					http.Error(w, "Project not found", http.StatusNotFound)
+					return
+				}
+				wh.ProjectID = &project.ID
+			}
+
+			if err := db.Create(&wh).Error; err != nil {
+				http.Error(w, "Could not create webhook: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(WebhookCreatedResponse{
+				WebhookInfo: toWebhookInfo(db, &wh),
+				Secret:      wh.Secret,
+			})
+
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// WebhookHandler godoc
+// @Summary Get, update or delete a webhook
+// @Description GET returns one webhook. PATCH changes its URL, events or active flag (the project cannot be changed). DELETE removes the webhook and its delivery log.
+// @Tags webhooks
+// @Accept  json
+// @Produce  json
+// @Param   id               path  string          true   "Webhook ID"
+// @Param   webhook_request  body  WebhookRequest  false  "Fields to change (PATCH only)"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} WebhookInfo
+// @Failure 400 {string} string "Invalid request body, URL or events"
+// @Failure 403 {string} string "API key lacks the 'admin' scope"
+// @Failure 404 {string} string "Webhook not found"
+// @Failure 500 {string} string "Could not update webhook"
+// @Router /api/webhooks/{id} [get]
+// @Router /api/webhooks/{id} [patch]
+// @Router /api/webhooks/{id} [delete]
+func WebhookHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, _ := r.Context().Value(middleware.UserContextKey).(*models.User)
+		wh := findWebhook(w, r, db, user.ID)
+		if wh == nil {
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(toWebhookInfo(db, wh))
+
+		case http.MethodPatch:
+			var req WebhookRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+
+			updates := map[string]interface{}{}
+			if req.URL != "" {
+				target, err := validateWebhookURL(req.URL)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				updates["url"] = target
+			}
+			if req.Events != nil {
+				events, err := validateEvents(req.Events)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				updates["events"] = strings.Join(events, ",")
+			}
+			if req.Active != nil {
+				updates["active"] = *req.Active
+			}
+
+			if len(updates) > 0 {
+				if err := db.Model(wh).Updates(updates).Error; err != nil {
+					http.Error(w, "Could not update webhook: "+err.Error(), http.StatusInternalServerError)
+					return
+				}
+				db.First(wh, "id = ?", wh.ID)
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(toWebhookInfo(db, wh))
+
+		case http.MethodDelete:
+			if err := db.Delete(wh).Error; err != nil {
+				http.Error(w, "Could not delete webhook: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]string{
+				"message": "Webhook deleted successfully",
+				"id":      wh.ID.String(),
+			})
+
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// WebhookTestHandler godoc
+// @Summary Send a test event to a webhook
+// @Description Sends a signed webhook.test event to the webhook right away and returns the recorded delivery, including the response status and body. Test deliveries are not retried.
+// @Tags webhooks
+// @Produce  json
+// @Param   id  path  string  true  "Webhook ID"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} DeliveryInfo
+// @Failure 403 {string} string "API key lacks the 'admin' scope"
+// @Failure 404 {string} string "Webhook not found"
+// @Failure 500 {string} string "Could not record delivery"
+// @Router /api/webhooks/{id}/test [post]
+func WebhookTestHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		user, _ := r.Context().Value(middleware.UserContextKey).(*models.User)
+		wh := findWebhook(w, r, db, user.ID)
+		if wh == nil {
+			return
+		}
+
+		payload, _ := json.Marshal(WebhookEvent{
+			Event:     models.EventWebhookTest,
+			CreatedAt: time.Now().UTC(),
+			Data:      map[string]string{"webhook_id": wh.ID.String()},
+		})
+		delivery := models.WebhookDelivery{
+			WebhookID: wh.ID,
+			Event:     models.EventWebhookTest,
+			Payload:   string(payload),
+			Status:    models.DeliveryPending,
+		}
+		if err := db.Create(&delivery).Error; err != nil {
+			http.Error(w, "Could not record delivery: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if err := sendDelivery(r.Context(), db, newWebhookClient(), &delivery, wh); err != nil {
+			db.Model(&delivery).Update("status", models.DeliveryFailed)
+			delivery.Status = models.DeliveryFailed
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(toDeliveryInfo(&delivery))
+	}
+}
+
+// WebhookDeliveriesHandler godoc
+// @Summary List a webhook's deliveries
+// @Description Returns the delivery log of a webhook, newest first, with the status, number of attempts and the last response of each delivery.
+// @Tags webhooks
+// @Produce  json
+// @Param   id        path   string  true   "Webhook ID"
+// @Param   page      query  int     false  "Page number for pagination"
+// @Param   per_page  query  int     false  "Number of items per page"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} DeliveriesResponse
+// @Failure 403 {string} string "API key lacks the 'admin' scope"
+// @Failure 404 {string} string "Webhook not found"
+// @Router /api/webhooks/{id}/deliveries [get]
+func WebhookDeliveriesHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		user, _ := r.Context().Value(middleware.UserContextKey).(*models.User)
+		wh := findWebhook(w, r, db, user.ID)
+		if wh == nil {
+			return
+		}
+
+		page, perPage := getPaginationParams(r)
+		var deliveries []models.WebhookDelivery
+		db.Where("webhook_id = ?", wh.ID).Order("created_at DESC").
+			Limit(perPage).Offset((page - 1) * perPage).Find(&deliveries)
+
+		var total int64
+		db.Model(&models.WebhookDelivery{}).Where("webhook_id = ?", wh.ID).Count(&total)
+
+		infos := make([]DeliveryInfo, 0, len(deliveries))
+		for i := range deliveries {
+			infos = append(infos, toDeliveryInfo(&deliveries[i]))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(DeliveriesResponse{
+			Deliveries: infos,
+			Total:      total,
+			Page:       page,
+			PerPage:    perPage,
+			TotalPages: calculateTotalPages(total, perPage),
+		})
+	}
+}
+
+// RedeliverHandler godoc
+// @Summary Redeliver a webhook event
+// @Description Schedules a new delivery of the same payload as an earlier delivery, with a fresh set of retries. The original delivery is kept in the log.
+// @Tags webhooks
+// @Produce  json
+// @Param   id           path  string  true  "Webhook ID"
+// @Param   delivery_id  path  string  true  "Delivery ID"
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 202 {object} DeliveryInfo
+// @Failure 403 {string} string "API key lacks the 'admin' scope"
+// @Failure 404 {string} string "Webhook not found or Delivery not found"
+// @Failure 500 {string} string "Could not schedule delivery"
+// @Router /api/webhooks/{id}/deliveries/{delivery_id}/redeliver [post]
+func RedeliverHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		user, _ := r.Context().Value(middleware.UserContextKey).(*models.User)
+		wh := findWebhook(w, r, db, user.ID)
+		if wh == nil {
+			return
+		}
+
+		deliveryID, err := uuid.Parse(r.PathValue("delivery_id"))
+		if err != nil {
+			http.Error(w, "Delivery not found", http.StatusNotFound)
+			return
+		}
+		var original models.WebhookDelivery
+		if err := db.First(&original, "id = ? AND webhook_id = ?", deliveryID, wh.ID).Error; err != nil {
+			http.Error(w, "Delivery not found", http.StatusNotFound)
+			return
+		}
+
+		delivery, err := createDelivery(db, wh.ID, original.Event, original.Payload)
+		if err != nil {
+			http.Error(w, "Could not schedule delivery: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(toDeliveryInfo(delivery))
+	}
+}
@@ -0,0 +1,181 @@
+// Package imaging gera variantes redimensionadas de imagens JPEG e PNG usando apenas
+// codecs em Go puro, sem bibliotecas nativas.
+package imaging
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+// Modos de ajuste da imagem à caixa WxH pedida
+const (
+	FitContain = "contain" // Cabe inteira na caixa, mantendo a proporção
+	FitCover   = "cover"   // Preenche a caixa, mantendo a proporção e cortando o excesso
+	FitFill    = "fill"    // Estica para o tamanho exato, sem manter a proporção
+)
+
+// MaxDimension é o maior lado aceito para uma variante
+const MaxDimension = 4096
+
+var (
+	// ErrUnsupportedType indica um tipo de arquivo que não pode ser redimensionado
+	ErrUnsupportedType = errors.New("unsupported image type")
+	// ErrTooManyPixels indica uma imagem grande demais para ser decodificada com segurança
+	ErrTooManyPixels = errors.New("image has too many pixels")
+)
+
+// Size é uma caixa de redimensionamento. Width ou Height zero significa "proporcional".
+type Size struct {
+	Width  int
+	Height int
+	Fit    string
+}
+
+// String retorna a forma usada em configuração e nas chaves de armazenamento (ex.: "150x150-cover")
+func (s Size) String() string {
+	return fmt.Sprintf("%dx%d-%s", s.Width, s.Height, s.Fit)
+}
+
+// Supported indica se o tipo de arquivo pode ter variantes
+func Supported(mimeType string) bool {
+	return mimeType == "image/jpeg" || mimeType == "image/png"
+}
+
+// ParseFit valida o modo de ajuste; vazio vira FitContain
+func ParseFit(fit string) (string, error) {
+	switch fit {
+	case "":
+		return FitContain, nil
+	case FitContain, FitCover, FitFill:
+		return fit, nil
+	}
+	return "", fmt.Errorf("fit must be one of %s, %s or %s", FitContain, FitCover, FitFill)
+}
+
+// NewSize valida as dimensões pedidas e o modo de ajuste
+func NewSize(width, height int, fit string) (Size, error) {
+	if width < 0 || height < 0 || (width == 0 && height == 0) {
+		return Size{}, errors.New("width and/or height must be positive")
+	}
+	if width > MaxDimension || height > MaxDimension {
+		return Size{}, fmt.Errorf("width and height cannot exceed %d", MaxDimension)
+	}
+	fit, err := ParseFit(fit)
+	if err != nil {
+		return Size{}, err
+	}
+	// Sem uma das dimensões, cover e fill não fazem sentido: a outra é proporcional
+	if width == 0 || height == 0 {
+		fit = FitContain
+	}
+	return Size{Width: width, Height: height, Fit: fit}, nil
+}
+
+// ParseSizes lê uma lista como "150x150:cover,600x600" (o modo padrão é contain)
+func ParseSizes(list string) ([]Size, error) {
+	sizes := make([]Size, 0)
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		dims, fit, _ := strings.Cut(item, ":")
+		w, h, ok := strings.Cut(strings.ToLower(dims), "x")
+		if !ok {
+			return nil, fmt.Errorf("invalid size %q: expected WxH", item)
+		}
+		width, err1 := strconv.Atoi(w)
+		height, err2 := strconv.Atoi(h)
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("invalid size %q: expected WxH", item)
+		}
+		size, err := NewSize(width, height, fit)
+		if err != nil {
+			return nil, fmt.Errorf("invalid size %q: %w", item, err)
+		}
+		sizes = append(sizes, size)
+	}
+	return sizes, nil
+}
+
+// Decode lê uma imagem JPEG ou PNG, recusando imagens com mais de maxPixels pixels
+// antes de alocar memória para elas
+func Decode(r io.ReadSeeker, maxPixels int64) (image.Image, error) {
+	cfg, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > maxPixels {
+		return nil, ErrTooManyPixels
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(r)
+	return img, err
+}
+
+// Encode grava a imagem no mesmo formato do original
+func Encode(w io.Writer, img image.Image, mimeType string) error {
+	switch mimeType {
+	case "image/jpeg":
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: 85})
+	case "image/png":
+		return png.Encode(w, img)
+	}
+	return ErrUnsupportedType
+}
+
+// Resize redimensiona a imagem para a caixa pedida. Imagens nunca são ampliadas: se a
+// caixa for maior que o original, ela é reduzida mantendo sua proporção.
+func Resize(src image.Image, size Size) image.Image {
+	sb := src.Bounds()
+	sw, sh := sb.Dx(), sb.Dy()
+	if sw == 0 || sh == 0 {
+		return src
+	}
+
+	tw, th := size.Width, size.Height
+	switch {
+	case tw == 0:
+		tw = max(1, sw*th/sh)
+	case th == 0:
+		th = max(1, sh*tw/sw)
+	}
+
+	// Reduz a caixa, mantendo a proporção, até caber no original
+	if tw > sw || th > sh {
+		scale := min(float64(sw)/float64(tw), float64(sh)/float64(th))
+		tw, th = max(1, int(float64(tw)*scale)), max(1, int(float64(th)*scale))
+	}
+
+	srcRect := sb
+	switch size.Fit {
+	case FitContain:
+		scale := min(float64(tw)/float64(sw), float64(th)/float64(sh))
+		tw, th = max(1, int(float64(sw)*scale+0.5)), max(1, int(float64(sh)*scale+0.5))
+	case FitCover:
+		// Recorta do centro do original a região com a proporção da caixa
+		cw, ch := sw, sh
+		if sw*th > sh*tw {
+			cw = max(1, sh*tw/th)
+		} else {
+			ch = max(1, sw*th/tw)
+		}
+		x0 := sb.Min.X + (sw-cw)/2
+		y0 := sb.Min.Y + (sh-ch)/2
+		srcRect = image.Rect(x0, y0, x0+cw, y0+ch)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, srcRect, draw.Src, nil)
+	return dst
+}
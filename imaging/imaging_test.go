@@ -0,0 +1,63 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResize(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 400, 200))
+
+	tests := []struct {
+		name  string
+		size  Size
+		wantW int
+		wantH int
+	}{
+		{"contain", Size{100, 100, FitContain}, 100, 50},
+		{"cover", Size{100, 100, FitCover}, 100, 100},
+		{"fill", Size{100, 100, FitFill}, 100, 100},
+		{"width only", Size{200, 0, FitContain}, 200, 100},
+		{"height only", Size{0, 50, FitContain}, 100, 50},
+		{"never upscales", Size{800, 800, FitCover}, 200, 200},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := Resize(src, tt.size).Bounds()
+			assert.Equal(t, tt.wantW, b.Dx())
+			assert.Equal(t, tt.wantH, b.Dy())
+		})
+	}
+}
+
+func TestParseSizes(t *testing.T) {
+	sizes, err := ParseSizes("150x150:cover, 600x600,320x0")
+	assert.NoError(t, err)
+	assert.Equal(t, []Size{{150, 150, FitCover}, {600, 600, FitContain}, {320, 0, FitContain}}, sizes)
+
+	for _, invalid := range []string{"150", "0x0", "axb", "100x100:stretch", "5000x10"} {
+		_, err := ParseSizes(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestDecodeRejectsLargeImages(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	img.Set(0, 0, color.White)
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, img))
+
+	_, err := Decode(bytes.NewReader(buf.Bytes()), 100*100)
+	assert.NoError(t, err)
+
+	_, err = Decode(bytes.NewReader(buf.Bytes()), 100*100-1)
+	assert.ErrorIs(t, err, ErrTooManyPixels)
+
+	_, err = Decode(bytes.NewReader([]byte("%PDF-1.7")), 100*100)
+	assert.ErrorIs(t, err, ErrUnsupportedType)
+}
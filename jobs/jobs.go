@@ -0,0 +1,299 @@
+// Package jobs executa tarefas em segundo plano a partir de uma fila guardada na tabela
+// jobs do Postgres. Vários workers, inclusive em instâncias diferentes, disputam os jobs
+// com SELECT ... FOR UPDATE SKIP LOCKED, de modo que cada job roda em um só worker.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/config"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+// HandlerFunc executa um job. Um erro faz o job ser tentado de novo mais tarde, a não
+// ser que seja marcado com Permanent.
+type HandlerFunc func(ctx context.Context, job *models.Job) error
+
+// DeadFunc é chamada quando um job esgota as tentativas ou falha de forma permanente
+type DeadFunc func(ctx context.Context, job *models.Job, err error)
+
+// Options configura o Runner
+type Options struct {
+	Workers      int           // Quantidade de goroutines executando jobs
+	PollInterval time.Duration // Espera entre consultas quando a fila está vazia
+	Timeout      time.Duration // Jobs em execução há mais tempo voltam para a fila (worker caiu)
+	Retention    time.Duration // Por quanto tempo jobs concluídos são mantidos
+}
+
+// EnqueueOptions altera o agendamento de um job
+type EnqueueOptions struct {
+	MaxAttempts int           // Zero usa JOB_MAX_ATTEMPTS
+	Delay       time.Duration // Atraso antes da primeira execução
+}
+
+// permanentError marca um erro que não adianta tentar de novo
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marca err como definitivo: o job vai direto para JobDead, sem novas tentativas
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// IsPermanent indica se o erro foi marcado com Permanent
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// Backoff retorna a espera antes da próxima tentativa: 10s, 20s, 40s... até 1 hora
+func Backoff(attempt int) time.Duration {
+	const base, ceiling = 10 * time.Second, time.Hour
+	if attempt < 1 {
+		attempt = 1
+	}
+	if attempt > 16 {
+		return ceiling
+	}
+	return min(base<<(attempt-1), ceiling)
+}
+
+// Runner mantém os handlers registrados e os workers que executam a fila
+type Runner struct {
+	db       *gorm.DB
+	opts     Options
+	mu       sync.RWMutex
+	handlers map[string]HandlerFunc
+	onDead   map[string]DeadFunc
+}
+
+// NewRunner cria um Runner sem handlers; registre-os com Register antes de Start
+func NewRunner(db *gorm.DB, opts Options) *Runner {
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 15 * time.Minute
+	}
+	if opts.Retention <= 0 {
+		opts.Retention = 7 * 24 * time.Hour
+	}
+	return &Runner{
+		db:       db,
+		opts:     opts,
+		handlers: make(map[string]HandlerFunc),
+		onDead:   make(map[string]DeadFunc),
+	}
+}
+
+// Register associa um tipo de job ao seu handler. onDead pode ser nil.
+func (r *Runner) Register(jobType string, handler HandlerFunc, onDead DeadFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[jobType] = handler
+	if onDead != nil {
+		r.onDead[jobType] = onDead
+	}
+}
+
+// Enqueue grava um job para execução. db pode ser uma transação, de modo que o job só
+// exista se o restante da transação for confirmado.
+func Enqueue(db *gorm.DB, jobType string, payload any, opts *EnqueueOptions) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("jobs: invalid payload: %w", err)
+	}
+	job := models.Job{
+		Type:        jobType,
+		Payload:     string(data),
+		Status:      models.JobQueued,
+		MaxAttempts: config.AppConfig.JobMaxAttempts,
+		RunAt:       time.Now(),
+	}
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = 1
+	}
+	if opts != nil {
+		if opts.MaxAttempts > 0 {
+			job.MaxAttempts = opts.MaxAttempts
+		}
+		job.RunAt = job.RunAt.Add(opts.Delay)
+	}
+	if err := db.Create(&job).Error; err != nil {
+		return fmt.Errorf("jobs: could not enqueue %s: %w", jobType, err)
+	}
+	return nil
+}
+
+// Start inicia os workers e a manutenção da fila. Eles param quando ctx é cancelado.
+func (r *Runner) Start(ctx context.Context) {
+	for i := 0; i < r.opts.Workers; i++ {
+		go r.work(ctx)
+	}
+	go r.maintain(ctx)
+	log.Printf("⚙️  %d worker(s) de jobs iniciados", r.opts.Workers)
+}
+
+// work executa jobs enquanto houver algum disponível e espera PollInterval quando a fila esvazia
+func (r *Runner) work(ctx context.Context) {
+	for {
+		ran, err := r.RunNext(ctx)
+		if err != nil {
+			log.Printf("⚠️  Warning: Failed to fetch next job: %v", err)
+		}
+		if ran && err == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(r.opts.PollInterval):
+		}
+	}
+}
+
+// RunNext reserva e executa um job pronto. Retorna false quando não havia nenhum.
+func (r *Runner) RunNext(ctx context.Context) (bool, error) {
+	job, err := r.claim()
+	if err != nil || job == nil {
+		return false, err
+	}
+
+	r.mu.RLock()
+	handler, ok := r.handlers[job.Type]
+	r.mu.RUnlock()
+
+	if !ok {
+		err = Permanent(fmt.Errorf("no handler registered for job type %q", job.Type))
+	} else {
+		err = runHandler(ctx, handler, job)
+	}
+	return true, r.finish(ctx, job, err)
+}
+
+// runHandler executa o handler convertendo panics em erros, para não derrubar o worker
+func runHandler(ctx context.Context, handler HandlerFunc, job *models.Job) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// claim marca o próximo job pronto como em execução. SKIP LOCKED faz com que workers
+// simultâneos peguem jobs diferentes em vez de esperarem uns pelos outros.
+func (r *Runner) claim() (*models.Job, error) {
+	r.mu.RLock()
+	types := make([]string, 0, len(r.handlers))
+	for t := range r.handlers {
+		types = append(types, t)
+	}
+	r.mu.RUnlock()
+	if len(types) == 0 {
+		return nil, nil
+	}
+
+	var jobs []models.Job
+	err := r.db.Raw(`UPDATE jobs SET status = ?, attempts = attempts + 1, locked_at = now(), updated_at = now()
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE status = ? AND run_at <= now() AND type IN ?
+			ORDER BY run_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, models.JobRunning, models.JobQueued, types).Scan(&jobs).Error
+	if err != nil || len(jobs) == 0 {
+		return nil, err
+	}
+	return &jobs[0], nil
+}
+
+// finish grava o resultado da execução: concluído, reagendado com backoff ou morto
+func (r *Runner) finish(ctx context.Context, job *models.Job, runErr error) error {
+	if runErr == nil {
+		return r.db.Model(job).Updates(map[string]interface{}{
+			"status":     models.JobDone,
+			"locked_at":  nil,
+			"last_error": "",
+		}).Error
+	}
+
+	if !IsPermanent(runErr) && job.Attempts < job.MaxAttempts {
+		delay := Backoff(job.Attempts)
+		log.Printf("🔁 Job %s (%s) falhou na tentativa %d/%d, nova tentativa em %s: %v", job.ID, job.Type, job.Attempts, job.MaxAttempts, delay, runErr)
+		return r.db.Model(job).Updates(map[string]interface{}{
+			"status":     models.JobQueued,
+			"run_at":     time.Now().Add(delay),
+			"locked_at":  nil,
+			"last_error": runErr.Error(),
+		}).Error
+	}
+
+	log.Printf("💀 Job %s (%s) desistiu após %d tentativa(s): %v", job.ID, job.Type, job.Attempts, runErr)
+	err := r.db.Model(job).Updates(map[string]interface{}{
+		"status":     models.JobDead,
+		"locked_at":  nil,
+		"last_error": runErr.Error(),
+	}).Error
+
+	r.mu.RLock()
+	onDead := r.onDead[job.Type]
+	r.mu.RUnlock()
+	if onDead != nil {
+		onDead(ctx, job, runErr)
+	}
+	return err
+}
+
+// maintain devolve à fila jobs presos em execução e remove jobs concluídos antigos
+func (r *Runner) maintain(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// O worker que reservou o job caiu ou travou: a tentativa já foi contada
+		result := r.db.Model(&models.Job{}).
+			Where("status = ? AND locked_at < ?", models.JobRunning, time.Now().Add(-r.opts.Timeout)).
+			Updates(map[string]interface{}{"status": models.JobQueued, "locked_at": nil, "run_at": time.Now()})
+		if result.Error != nil {
+			log.Printf("⚠️  Warning: Failed to requeue stalled jobs: %v", result.Error)
+		} else if result.RowsAffected > 0 {
+			log.Printf("🔁 Requeued %d stalled job(s)", result.RowsAffected)
+		}
+
+		result = r.db.Where("status = ? AND updated_at < ?", models.JobDone, time.Now().Add(-r.opts.Retention)).Delete(&models.Job{})
+		if result.Error != nil {
+			log.Printf("⚠️  Warning: Failed to clean up finished jobs: %v", result.Error)
+		} else if result.RowsAffected > 0 {
+			log.Printf("🧹 Removed %d finished job(s)", result.RowsAffected)
+		}
+	}
+}
+
+// Decode lê o payload do job em v
+func Decode(job *models.Job, v any) error {
+	if err := json.Unmarshal([]byte(job.Payload), v); err != nil {
+		return Permanent(fmt.Errorf("invalid payload: %w", err))
+	}
+	return nil
+}
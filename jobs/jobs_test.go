@@ -0,0 +1,46 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 10*time.Second, Backoff(0))
+	assert.Equal(t, 10*time.Second, Backoff(1))
+	assert.Equal(t, 20*time.Second, Backoff(2))
+	assert.Equal(t, 80*time.Second, Backoff(4))
+	assert.Equal(t, time.Hour, Backoff(10))
+	assert.Equal(t, time.Hour, Backoff(100))
+}
+
+func TestPermanent(t *testing.T) {
+	base := errors.New("checksum mismatch")
+	err := fmt.Errorf("verify: %w", Permanent(base))
+	assert.True(t, IsPermanent(err))
+	assert.ErrorIs(t, err, base)
+	assert.False(t, IsPermanent(base))
+}
+
+func TestRunHandlerRecoversPanics(t *testing.T) {
+	err := runHandler(context.Background(), func(ctx context.Context, job *models.Job) error {
+		panic("boom")
+	}, &models.Job{})
+	assert.EqualError(t, err, "panic: boom")
+}
+
+func TestDecode(t *testing.T) {
+	var payload struct {
+		FileID string `json:"file_id"`
+	}
+	assert.NoError(t, Decode(&models.Job{Payload: `{"file_id":"abc"}`}, &payload))
+	assert.Equal(t, "abc", payload.FileID)
+	assert.True(t, IsPermanent(Decode(&models.Job{Payload: `{`}, &payload)))
+}
@@ -0,0 +1,40 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LogMailer não envia nada: registra cada mensagem no log e, se dir estiver definido,
+// grava uma cópia em <dir>/<data>-<destinatário>.eml para testes locais
+type LogMailer struct {
+	dir  string
+	from string
+	now  func() time.Time
+}
+
+func NewLogMailer(dir, from string) *LogMailer {
+	return &LogMailer{dir: dir, from: from, now: time.Now}
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	if err := validAddress(msg.To); err != nil {
+		return err
+	}
+	now := m.now()
+	log.Printf("📧 E-mail para %s: %s", msg.To, msg.Subject)
+	if m.dir == "" {
+		log.Printf("📧 %s", msg.Body)
+		return nil
+	}
+
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", now.Format("20060102-150405.000000000"), filepath.Base(msg.To))
+	return os.WriteFile(filepath.Join(m.dir, name), buildMessage(m.from, msg, now), 0o644)
+}
@@ -0,0 +1,68 @@
+// Package mailer envia os e-mails transacionais (verificação de conta, recuperação de
+// senha). O backend é escolhido em MAILER: "smtp" ou "log", que grava as mensagens em
+// arquivos para testes locais.
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/config"
+)
+
+// Message é um e-mail em texto puro
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer é a interface comum aos backends de envio
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New cria o mailer configurado em cfg.Mailer
+func New(cfg *config.Config) (Mailer, error) {
+	switch strings.ToLower(cfg.Mailer) {
+	case "", "log":
+		return NewLogMailer(cfg.MailLogDir, cfg.MailFrom), nil
+	case "smtp":
+		if cfg.SMTPHost == "" {
+			return nil, fmt.Errorf("mailer: SMTP_HOST is required when MAILER=smtp")
+		}
+		return NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom), nil
+	default:
+		return nil, fmt.Errorf("mailer: unknown backend %q", cfg.Mailer)
+	}
+}
+
+// buildMessage monta a mensagem no formato RFC 5322, com o corpo em UTF-8
+func buildMessage(from string, msg Message, now time.Time) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "Message-ID: <%s@forge-uploader>\r\n", uuid.New())
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return b.Bytes()
+}
+
+// validAddress impede quebras de linha nos cabeçalhos (header injection)
+func validAddress(addr string) error {
+	if addr == "" || strings.ContainsAny(addr, "\r\n") {
+		return fmt.Errorf("mailer: invalid address %q", addr)
+	}
+	return nil
+}
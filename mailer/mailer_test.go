@@ -0,0 +1,67 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/config"
+)
+
+func TestLogMailerWritesEML(t *testing.T) {
+	dir := t.TempDir()
+	m := NewLogMailer(dir, "Forge <no-reply@example.com>")
+	m.now = func() time.Time { return time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC) }
+
+	err := m.Send(context.Background(), Message{
+		To:      "ana@example.com",
+		Subject: "Redefinição de senha",
+		Body:    "Olá!\nAbra o link: https://example.com/verify-email?token=abc",
+	})
+	require.NoError(t, err)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.True(t, strings.HasSuffix(entries[0].Name(), "-ana@example.com.eml"))
+
+	raw, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	require.NoError(t, err)
+	content := string(raw)
+	assert.Contains(t, content, "From: Forge <no-reply@example.com>\r\n")
+	assert.Contains(t, content, "To: ana@example.com\r\n")
+	assert.Contains(t, content, "Subject: =?utf-8?q?Redefini=C3=A7=C3=A3o_de_senha?=\r\n")
+	assert.Contains(t, content, "\r\n\r\nOlá!\r\nAbra o link: https://example.com/verify-email?token=abc")
+}
+
+func TestRejectsHeaderInjection(t *testing.T) {
+	m := NewLogMailer(t.TempDir(), "no-reply@example.com")
+	err := m.Send(context.Background(), Message{To: "ana@example.com\r\nBcc: eve@example.com", Subject: "x"})
+	assert.Error(t, err)
+
+	s := NewSMTPMailer("localhost", 25, "", "", "no-reply@example.com")
+	err = s.Send(context.Background(), Message{To: "", Subject: "x"})
+	assert.Error(t, err)
+}
+
+func TestNew(t *testing.T) {
+	m, err := New(&config.Config{Mailer: "log", MailLogDir: t.TempDir()})
+	require.NoError(t, err)
+	assert.IsType(t, &LogMailer{}, m)
+
+	m, err = New(&config.Config{Mailer: "smtp", SMTPHost: "smtp.example.com", SMTPPort: 587})
+	require.NoError(t, err)
+	assert.IsType(t, &SMTPMailer{}, m)
+
+	_, err = New(&config.Config{Mailer: "smtp"})
+	assert.Error(t, err)
+
+	_, err = New(&config.Config{Mailer: "pigeon"})
+	assert.Error(t, err)
+}
@@ -26,18 +26,26 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
-	"os"
+	"time"
 
 	"gorm.io/gorm"
 
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/billing"
 	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/config"
 	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/database"
 	_ "github.com/GoogleCloudPlatform/golang-samples/run/helloworld/docs"
 	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/handlers"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/jobs"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/mailer"
 	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/middleware"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/ratelimit"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/scanner"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/storage"
 	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/util"
 	swagger "github.com/swaggo/http-swagger"
 )
@@ -58,8 +66,71 @@ func main() {
 	}
 	log.Println("Conexão com o banco de dados estabelecida.")
 
-	// Cria pasta uploads se não existir
-	os.MkdirAll("./uploads", os.ModePerm)
+	// Inicializa o driver de armazenamento (local ou S3/MinIO)
+	store, err := storage.New(config.AppConfig)
+	if err != nil {
+		log.Fatal("Falha ao inicializar o armazenamento:", err)
+	}
+	log.Printf("Armazenamento inicializado com o driver %q.", config.AppConfig.StorageDriver)
+
+	// Cache local das variantes redimensionadas de imagens
+	variantCache, err := storage.NewLocalDriver(config.AppConfig.VariantCacheDir)
+	if err != nil {
+		log.Fatal("Falha ao inicializar o cache de variantes:", err)
+	}
+
+	// Remove periodicamente sessões de upload resumível expiradas
+	handlers.StartUploadSessionJanitor(DB, store, 10*time.Minute)
+
+	// Remove periodicamente refresh tokens expirados e entradas vencidas da lista de revogação
+	handlers.StartTokenJanitor(DB, 1*time.Hour)
+
+	// Verificação de malware dos uploads (builtin, clamd ou none)
+	scan, err := scanner.New(config.AppConfig)
+	if err != nil {
+		log.Fatal("Falha ao inicializar o scanner:", err)
+	}
+
+	// E-mails transacionais (smtp, ou log para testes locais)
+	mail, err := mailer.New(config.AppConfig)
+	if err != nil {
+		log.Fatal("Falha ao inicializar o envio de e-mails:", err)
+	}
+
+	// Cobrança dos planos pagos (fake, para testes locais)
+	payments, err := billing.New(config.AppConfig)
+	if err != nil {
+		log.Fatal("Falha ao inicializar o provedor de pagamentos:", err)
+	}
+
+	// Aplica mudanças de plano agendadas, renova os períodos pagos e acompanha o prazo de
+	// carência de quem está acima do limite
+	handlers.StartPlanScheduler(DB, payments, mail, 15*time.Minute)
+
+	// Snapshots diários do armazenamento e fechamento mensal do uso, com as faturas
+	handlers.StartUsageMeter(DB, payments, mail, 1*time.Hour)
+
+	// Fila de jobs em segundo plano: processamento pós-upload (checksum, malware, miniaturas)
+	// e entregas de webhooks
+	runner := jobs.NewRunner(DB, jobs.Options{
+		Workers:      config.AppConfig.JobWorkers,
+		PollInterval: config.AppConfig.JobPollInterval,
+		Timeout:      config.AppConfig.JobTimeout,
+		Retention:    config.AppConfig.JobRetention,
+	})
+	handlers.RegisterUploadJobs(runner, DB, store, variantCache, scan)
+	handlers.RegisterWebhookJobs(runner, DB)
+	runner.Start(context.Background())
+
+	// Limite de requisições, em memória ou compartilhado entre réplicas pelo Postgres
+	limiter, err := ratelimit.NewBackend(config.AppConfig.RateLimitBackend, DB)
+	if err != nil {
+		log.Fatal("Falha ao inicializar o limite de requisições:", err)
+	}
+	ratelimit.StartJanitor(limiter, 1*time.Hour)
+	limit := func(name string, l ratelimit.Limit, key middleware.KeyFunc, h http.Handler) http.Handler {
+		return middleware.RateLimit(limiter, name, middleware.StaticLimit(l), key, h)
+	}
 
 	mux := http.NewServeMux()
 
@@ -67,25 +138,92 @@ func main() {
 	mux.HandleFunc("/swagger/", swagger.WrapHandler)
 
 	// Endpoints de autenticação (públicos)
-	mux.HandleFunc("/register", handlers.RegisterHandler(DB))
-	mux.HandleFunc("/login", handlers.LoginHandler(DB))
+	mux.Handle("/register", limit("register:ip", config.AppConfig.RateLimitRegister, middleware.ByIP, handlers.RegisterHandler(DB, mail)))
+	mux.Handle("/login", limit("login:ip", config.AppConfig.RateLimitLoginIP, middleware.ByIP,
+		limit("login:email", config.AppConfig.RateLimitLoginEmail, middleware.ByEmail, handlers.LoginHandler(DB))))
+	mux.Handle("/login/2fa", limit("login:ip", config.AppConfig.RateLimitLoginIP, middleware.ByIP, handlers.TwoFactorLoginHandler(DB)))
+	mux.Handle("/token/refresh", limit("refresh:ip", config.AppConfig.RateLimitLoginIP, middleware.ByIP, handlers.RefreshTokenHandler(DB)))
+	mux.Handle("/verify-email", limit("verify:ip", config.AppConfig.RateLimitLoginIP, middleware.ByIP, handlers.VerifyEmailHandler(DB)))
+	mux.Handle("/password/forgot", limit("forgot:ip", config.AppConfig.RateLimitLoginIP, middleware.ByIP,
+		limit("forgot:email", config.AppConfig.RateLimitLoginEmail, middleware.ByEmail, handlers.ForgotPasswordHandler(DB, mail))))
+	mux.Handle("/password/reset", limit("reset:ip", config.AppConfig.RateLimitLoginIP, middleware.ByIP, handlers.ResetPasswordHandler(DB)))
+	mux.Handle("/logout", middleware.AuthMiddleware(DB, handlers.LogoutHandler(DB)))
 	// API Endpoints (protegidos)
 	api := http.NewServeMux()
-	api.HandleFunc("/upload", handlers.UploadHandler(DB))
-	api.HandleFunc("/projects", handlers.ProjectsHandler(DB))
-	api.HandleFunc("/list", handlers.ListHandler(DB))
-	api.HandleFunc("/delete", handlers.DeleteHandler(DB))
-	api.HandleFunc("/project/delete", handlers.DeleteProjectHandler(DB))
-	api.HandleFunc("/user/rotate-api-key", handlers.RotateAPIKeyHandler(DB))
-	api.HandleFunc("/user/status", handlers.UserStatusHandler(DB))
-
-	// Aplica middleware de autenticação à API
-	protectedAPI := middleware.AuthMiddleware(DB, api)
+	// Chaves de API só acessam as rotas dos escopos concedidos (sessões JWT têm acesso total)
+	scoped := func(scope string, h http.HandlerFunc) http.Handler { return middleware.RequireScope(scope, h) }
+	// Uploads e novas sessões de upload têm um limite próprio, além do limite geral da API
+	uploadLimit := func(h http.Handler) http.Handler {
+		return limit("upload:user", config.AppConfig.RateLimitUpload, middleware.ByUser, h)
+	}
+	api.Handle("/upload", uploadLimit(scoped(models.ScopeUpload, handlers.UploadHandler(DB, store))))
+	api.Handle("/uploads", uploadLimit(scoped(models.ScopeUpload, handlers.CreateUploadSessionHandler(DB))))
+	api.Handle("/uploads/{id}", scoped(models.ScopeUpload, handlers.UploadSessionHandler(DB, store)))
+	api.Handle("/projects", scoped(models.ScopeRead, handlers.ProjectsHandler(DB)))
+	api.Handle("/list", scoped(models.ScopeRead, handlers.ListHandler(DB)))
+	api.Handle("/sign", scoped(models.ScopeRead, handlers.SignHandler(DB)))
+	api.Handle("/delete", scoped(models.ScopeDelete, handlers.DeleteHandler(DB, store, variantCache)))
+	api.Handle("/project/delete", scoped(models.ScopeDelete, handlers.DeleteProjectHandler(DB)))
+	api.Handle("/project/visibility", scoped(models.ScopeAdmin, handlers.ProjectVisibilityHandler(DB)))
+	api.Handle("/project/members", scoped(models.ScopeAdmin, handlers.ProjectMembersHandler(DB, mail)))
+	api.Handle("/project/members/{user_id}", scoped(models.ScopeAdmin, handlers.ProjectMemberHandler(DB)))
+	api.Handle("/invitations", scoped(models.ScopeAdmin, handlers.InvitationsHandler(DB)))
+	api.Handle("/invitations/{id}", scoped(models.ScopeAdmin, handlers.InvitationHandler(DB)))
+	api.Handle("/invitations/{id}/accept", scoped(models.ScopeAdmin, handlers.AcceptInvitationHandler(DB)))
+	api.Handle("/user/rotate-api-key", scoped(models.ScopeAdmin, handlers.RotateAPIKeyHandler(DB)))
+	api.Handle("/user/resend-verification", limit("verify:user", config.AppConfig.RateLimitLoginEmail, middleware.ByUser, scoped(models.ScopeAdmin, handlers.ResendVerificationHandler(DB, mail))))
+	api.Handle("/user/2fa", scoped(models.ScopeAdmin, handlers.TwoFactorStatusHandler(DB)))
+	api.Handle("/user/2fa/enroll", scoped(models.ScopeAdmin, handlers.TwoFactorEnrollHandler(DB)))
+	api.Handle("/user/2fa/confirm", scoped(models.ScopeAdmin, handlers.TwoFactorConfirmHandler(DB)))
+	api.Handle("/user/2fa/disable", scoped(models.ScopeAdmin, handlers.TwoFactorDisableHandler(DB)))
+	api.Handle("/user/2fa/recovery-codes", scoped(models.ScopeAdmin, handlers.RecoveryCodesHandler(DB)))
+	api.Handle("/user/status", scoped(models.ScopeRead, handlers.UserStatusHandler(DB)))
+	api.Handle("/plans", scoped(models.ScopeRead, handlers.AvailablePlansHandler(DB)))
+	api.Handle("/plan", scoped(models.ScopeRead, handlers.SubscriptionHandler(DB)))
+	api.Handle("/plan/change", scoped(models.ScopeAdmin, handlers.PlanChangeHandler(DB, payments, mail)))
+	api.Handle("/plan/changes", scoped(models.ScopeRead, handlers.PlanChangesHandler(DB)))
+	api.Handle("/billing/usage", scoped(models.ScopeRead, handlers.UsageHandler(DB)))
+	api.Handle("/billing/invoices", scoped(models.ScopeRead, handlers.InvoicesHandler(DB)))
+	api.Handle("/billing/invoices/{id}", scoped(models.ScopeRead, handlers.InvoiceHandler(DB)))
+	api.Handle("/billing/invoices/{id}/pdf", scoped(models.ScopeRead, handlers.InvoicePDFHandler(DB)))
+	api.Handle("/sessions", scoped(models.ScopeAdmin, handlers.SessionsHandler(DB)))
+	api.Handle("/sessions/{id}", scoped(models.ScopeAdmin, handlers.RevokeSessionHandler(DB)))
+	api.Handle("/keys", scoped(models.ScopeAdmin, handlers.APIKeysHandler(DB)))
+	api.Handle("/keys/{id}", scoped(models.ScopeAdmin, handlers.APIKeyHandler(DB)))
+	api.Handle("/keys/{id}/rotate", scoped(models.ScopeAdmin, handlers.RotateAPIKeyByIDHandler(DB)))
+	api.Handle("/orgs", scoped(models.ScopeAdmin, handlers.OrganizationsHandler(DB)))
+	api.Handle("/orgs/{org}", scoped(models.ScopeAdmin, handlers.OrganizationHandler(DB, store)))
+	api.Handle("/orgs/{org}/members", scoped(models.ScopeAdmin, handlers.MembersHandler(DB)))
+	api.Handle("/orgs/{org}/members/{user_id}", scoped(models.ScopeAdmin, handlers.MemberHandler(DB)))
+	// Rotas de administração: exigem um usuário administrador (IsAdmin)
+	admin := func(h http.HandlerFunc) http.Handler { return middleware.RequireAdmin(scoped(models.ScopeAdmin, h)) }
+	api.Handle("/admin/lockouts", admin(handlers.LockoutsHandler(DB)))
+	api.Handle("/admin/users/{id}/unlock", admin(handlers.UnlockUserHandler(DB)))
+	api.Handle("/admin/ips/{ip}/unlock", admin(handlers.UnlockIPHandler(DB)))
+	api.Handle("/admin/plans", admin(handlers.AdminPlansHandler(DB)))
+	api.Handle("/admin/plans/{id}", admin(handlers.AdminPlanHandler(DB)))
+	api.Handle("/admin/users", admin(handlers.AdminUsersHandler(DB)))
+	api.Handle("/admin/users/{id}", admin(handlers.AdminUserHandler(DB)))
+	api.Handle("/admin/users/{id}/plan", admin(handlers.AdminUserPlanHandler(DB, mail)))
+	api.Handle("/admin/users/{id}/suspend", admin(handlers.SuspendUserHandler(DB)))
+	api.Handle("/admin/users/{id}/unsuspend", admin(handlers.UnsuspendUserHandler(DB)))
+	api.Handle("/admin/stats", admin(handlers.AdminStatsHandler(DB)))
+	api.Handle("/audit", scoped(models.ScopeAdmin, handlers.AuditHandler(DB)))
+	api.Handle("/audit/export", scoped(models.ScopeAdmin, handlers.AuditExportHandler(DB)))
+	api.Handle("/webhooks", scoped(models.ScopeAdmin, handlers.WebhooksHandler(DB)))
+	api.Handle("/webhooks/{id}", scoped(models.ScopeAdmin, handlers.WebhookHandler(DB)))
+	api.Handle("/webhooks/{id}/test", scoped(models.ScopeAdmin, handlers.WebhookTestHandler(DB)))
+	api.Handle("/webhooks/{id}/deliveries", scoped(models.ScopeAdmin, handlers.WebhookDeliveriesHandler(DB)))
+	api.Handle("/webhooks/{id}/deliveries/{delivery_id}/redeliver", scoped(models.ScopeAdmin, handlers.RedeliverHandler(DB)))
+
+	// Aplica middleware de autenticação à API, com limite por IP antes da autenticação e
+	// por usuário (conforme o plano) depois dela
+	userLimitedAPI := middleware.RateLimit(limiter, "api:user", middleware.PlanLimit(DB, config.AppConfig.RateLimitAPI), middleware.ByUser, api)
+	protectedAPI := limit("api:ip", config.AppConfig.RateLimitAPIIP, middleware.ByIP, middleware.AuthMiddleware(DB, userLimitedAPI))
 	mux.Handle("/api/", http.StripPrefix("/api", protectedAPI))
 
-	// Servidor de arquivos estáticos
-	fs := http.FileServer(http.Dir("./uploads"))
-	mux.Handle("/files/", http.StripPrefix("/files/", fs))
+	// Servidor de arquivos a partir do driver de armazenamento (projetos privados exigem URL assinada)
+	mux.Handle("/files/", http.StripPrefix("/files/", handlers.FileServerHandler(DB, store, variantCache)))
 
 	// Aplica o middleware de logging a todas as rotas
 	loggedMux := middleware.LoggingMiddleware(mux)
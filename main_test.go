@@ -10,6 +10,7 @@ import (
 	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/config"
 	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/database"
 	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/handlers"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/mailer"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -35,7 +36,7 @@ func TestRegisterHandler(t *testing.T) {
 	}
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(handlers.RegisterHandler(db))
+	handler := http.HandlerFunc(handlers.RegisterHandler(db, mailer.NewLogMailer("", config.AppConfig.MailFrom)))
 	handler.ServeHTTP(rr, req)
 
 	assert.Equal(t, http.StatusCreated, rr.Code, "O código de status esperado era 201")
@@ -67,7 +68,7 @@ func TestLoginHandler(t *testing.T) {
 	jsonRegisterBody, _ := json.Marshal(registerData)
 	registerReq, _ := http.NewRequest("POST", "/register", bytes.NewBuffer(jsonRegisterBody))
 	registerRR := httptest.NewRecorder()
-	handlers.RegisterHandler(db)(registerRR, registerReq)
+	handlers.RegisterHandler(db, mailer.NewLogMailer("", config.AppConfig.MailFrom))(registerRR, registerReq)
 	assert.Equal(t, http.StatusCreated, registerRR.Code)
 
 	// Testar login com email
@@ -85,4 +86,5 @@ func TestLoginHandler(t *testing.T) {
 	json.Unmarshal(loginRREmail.Body.Bytes(), &responseBodyEmail)
 	assert.Equal(t, "Logged in successfully", responseBodyEmail["message"])
 	assert.NotNil(t, responseBodyEmail["token"])
+	assert.NotNil(t, responseBodyEmail["refresh_token"])
 }
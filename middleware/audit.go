@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+
+	"gorm.io/gorm"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/util"
+)
+
+// RecordAudit grava um evento no log de auditoria. Usuário, chave de API e ator vêm do
+// contexto da requisição quando não forem informados, e IP e user agent da própria
+// requisição. Falhas são apenas registradas para não afetar a operação auditada.
+func RecordAudit(db *gorm.DB, r *http.Request, event models.AuditEvent) {
+	user, _ := r.Context().Value(UserContextKey).(*models.User)
+	key, _ := r.Context().Value(APIKeyContextKey).(*models.APIKey)
+
+	if event.UserID == nil && user != nil {
+		event.UserID = &user.ID
+	}
+	if event.APIKeyID == nil && key != nil {
+		event.APIKeyID = &key.ID
+	}
+	if event.Actor == "" {
+		switch {
+		case key != nil:
+			event.Actor = "api_key:" + key.Prefix
+		case user != nil:
+			event.Actor = user.Email
+		default:
+			event.Actor = "anonymous"
+		}
+	}
+	event.IP = util.ClientIP(r)
+	event.UserAgent = r.UserAgent()
+
+	if err := db.Create(&event).Error; err != nil {
+		log.Printf("⚠️  Warning: Could not record audit event %s: %v", event.Action, err)
+	}
+}
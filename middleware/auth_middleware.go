@@ -2,13 +2,15 @@ package middleware
 
 import (
 	"context"
+	"crypto/subtle"
+	"errors"
 	"net/http"
+	"net/url"
 	"strings"
+	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"gorm.io/gorm"
 
-	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/config"
 	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
 	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/util"
 )
@@ -17,56 +19,178 @@ type contextKey string
 
 const UserContextKey = contextKey("user")
 
+// ClaimsContextKey guarda as *util.Claims quando a requisição foi autenticada por JWT
+const ClaimsContextKey = contextKey("claims")
+
+// APIKeyContextKey guarda a *models.APIKey quando a requisição foi autenticada por uma chave de API
+const APIKeyContextKey = contextKey("api_key")
+
+// lastUsedResolution evita uma escrita no banco a cada requisição feita com a mesma chave
+const lastUsedResolution = time.Minute
+
+var errAPIKeyExpired = errors.New("API key has expired")
+
+// lookupAPIKey autentica uma chave de API. A chave é localizada pelo prefixo público e
+// conferida pelo hash, já que o banco não guarda o valor em texto puro.
+func lookupAPIKey(db *gorm.DB, token string) (*models.User, *models.APIKey, error) {
+	var candidates []models.APIKey
+	if err := db.Where("prefix = ?", util.APIKeyLookupPrefix(token)).Find(&candidates).Error; err != nil {
+		return nil, nil, err
+	}
+
+	hash := util.HashToken(token)
+	var key *models.APIKey
+	for i := range candidates {
+		if subtle.ConstantTimeCompare([]byte(candidates[i].KeyHash), []byte(hash)) == 1 {
+			key = &candidates[i]
+			break
+		}
+	}
+	if key == nil {
+		return nil, nil, gorm.ErrRecordNotFound
+	}
+
+	now := time.Now()
+	if key.IsExpired(now) {
+		// A chave é devolvida para que a recusa seja atribuída ao dono no log de auditoria
+		return nil, key, errAPIKeyExpired
+	}
+
+	var u models.User
+	if err := db.First(&u, key.UserID).Error; err != nil {
+		return nil, nil, err
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > lastUsedResolution {
+		db.Model(key).UpdateColumn("last_used_at", now)
+	}
+	return &u, key, nil
+}
+
+// RequireScope recusa com 403 requisições feitas com uma chave de API sem o escopo
+// informado. Sessões JWT têm acesso total.
+func RequireScope(scope string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key, ok := r.Context().Value(APIKeyContextKey).(*models.APIKey); ok && !key.HasScope(scope) {
+			http.Error(w, "API key lacks the '"+scope+"' scope", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireAdmin recusa com 403 requisições de usuários que não são administradores. Deve
+// ficar depois do AuthMiddleware.
+func RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, ok := r.Context().Value(UserContextKey).(*models.User); !ok || !user.IsAdmin {
+			http.Error(w, "Administrator access required", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rejectRequest recusa a requisição com 401 e registra a recusa no log de auditoria
+func rejectRequest(db *gorm.DB, w http.ResponseWriter, r *http.Request, event models.AuditEvent, message string) {
+	rejectWithStatus(db, w, r, event, message, http.StatusUnauthorized)
+}
+
+// rejectWithStatus é como rejectRequest, com outro status HTTP
+func rejectWithStatus(db *gorm.DB, w http.ResponseWriter, r *http.Request, event models.AuditEvent, message string, status int) {
+	event.Action = models.AuditAuthRejected
+	event.Target = r.Method + " " + requestPath(r)
+	event.Outcome = models.AuditFailure
+	event.Detail = message
+	RecordAudit(db, r, event)
+	http.Error(w, message, status)
+}
+
+// requestPath retorna o caminho original da requisição, antes de http.StripPrefix,
+// sem a query string (que pode conter assinaturas)
+func requestPath(r *http.Request) string {
+	if u, err := url.ParseRequestURI(r.RequestURI); err == nil {
+		return u.Path
+	}
+	return r.URL.Path
+}
+
 // AuthMiddleware protects routes that require authentication
 func AuthMiddleware(db *gorm.DB, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			http.Error(w, "Authorization header required", http.StatusUnauthorized)
+			rejectRequest(db, w, r, models.AuditEvent{}, "Authorization header required")
 			return
 		}
 
 		var tokenString string
 		var user *models.User
+		var claims *util.Claims
+		var apiKey *models.APIKey
+		var keyErr error
 
 		// Check for Bearer token format
 		if strings.HasPrefix(authHeader, "Bearer ") {
 			tokenString = strings.TrimPrefix(authHeader, "Bearer ")
 			// Try to parse as JWT
-			claims := &util.Claims{}
-			token, jwtErr := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-				return []byte(config.AppConfig.JWTSecret), nil
-			})
+			parsed, jwtErr := util.ParseJWT(tokenString)
+
+			if jwtErr == nil {
+				// Tokens revogados (logout ou sessão encerrada) são recusados até expirarem
+				var revoked int64
+				db.Model(&models.RevokedToken{}).Where("jti = ?", parsed.ID).Count(&revoked)
+				if revoked > 0 {
+					rejectRequest(db, w, r, models.AuditEvent{UserID: &parsed.UserID, Actor: parsed.Email}, "Token has been revoked")
+					return
+				}
 
-			if jwtErr == nil && token.Valid {
 				// JWT is valid, find user by ID
 				var u models.User
-				if err := db.First(&u, claims.UserID).Error; err == nil {
+				if err := db.First(&u, parsed.UserID).Error; err == nil {
 					user = &u
+					claims = parsed
 				}
 			} else {
 				// If JWT is invalid, try to use the tokenString as API key
-				var u models.User
-				if err := db.First(&u, "forge_api_key = ?", tokenString).Error; err == nil {
-					user = &u
-				}
+				user, apiKey, keyErr = lookupAPIKey(db, tokenString)
 			}
 		} else {
 			// If not "Bearer", assume it might be a raw API key
 			tokenString = authHeader
-			var u models.User
-			if err := db.First(&u, "forge_api_key = ?", tokenString).Error; err == nil {
-				user = &u
-			}
+			user, apiKey, keyErr = lookupAPIKey(db, tokenString)
 		}
 
+		if errors.Is(keyErr, errAPIKeyExpired) {
+			rejectRequest(db, w, r, models.AuditEvent{
+				UserID:   &apiKey.UserID,
+				APIKeyID: &apiKey.ID,
+				Actor:    "api_key:" + apiKey.Prefix,
+			}, keyErr.Error())
+			return
+		}
 		if user == nil {
-			http.Error(w, "Invalid token or API key", http.StatusUnauthorized)
+			rejectRequest(db, w, r, models.AuditEvent{}, "Invalid token or API key")
+			return
+		}
+		// Credenciais válidas de contas suspensas são recusadas com 403
+		if user.SuspendedAt != nil {
+			event := models.AuditEvent{UserID: &user.ID, Actor: user.Email}
+			if apiKey != nil {
+				event.APIKeyID, event.Actor = &apiKey.ID, "api_key:"+apiKey.Prefix
+			}
+			rejectWithStatus(db, w, r, event, "Account suspended", http.StatusForbidden)
 			return
 		}
 
 		// Add user to context
 		ctx := context.WithValue(r.Context(), UserContextKey, user)
+		if claims != nil {
+			ctx = context.WithValue(ctx, ClaimsContextKey, claims)
+		}
+		if apiKey != nil {
+			ctx = context.WithValue(ctx, APIKeyContextKey, apiKey)
+		}
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -12,10 +12,13 @@ func CORSMiddleware(next http.Handler) http.Handler {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 
 		// Define os métodos HTTP permitidos.
-		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
+		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE, PATCH, HEAD")
 
-		// Define os cabeçalhos permitidos.
-		w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
+		// Define os cabeçalhos permitidos, incluindo os do protocolo tus de upload resumível.
+		w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, Tus-Resumable, Upload-Length, Upload-Offset, Upload-Metadata")
+
+		// Expõe ao navegador os cabeçalhos que o cliente tus precisa ler e os do limite de requisições.
+		w.Header().Set("Access-Control-Expose-Headers", "Location, Tus-Resumable, Upload-Offset, Upload-Length, Upload-Expires, Retry-After, X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset")
 
 		// Se a requisição for um 'OPTIONS' (preflight request), apenas retorne os cabeçalhos.
 		if r.Method == "OPTIONS" {
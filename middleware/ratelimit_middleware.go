@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/ratelimit"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/util"
+)
+
+// KeyFunc identifica quem está sendo limitado. Vazio deixa a requisição passar sem limite.
+type KeyFunc func(r *http.Request) string
+
+// LimitFunc retorna o limite que vale para a requisição
+type LimitFunc func(r *http.Request) ratelimit.Limit
+
+// planLimitCacheTTL evita uma consulta ao plano a cada requisição
+const planLimitCacheTTL = time.Minute
+
+// loginBodyLimit é o maior corpo lido por ByEmail
+const loginBodyLimit = 64 * 1024
+
+// RateLimit consome um token do bucket "<name>:<chave>" a cada requisição e responde 429,
+// com Retry-After, quando ele está vazio. Todas as respostas trazem os cabeçalhos
+// X-RateLimit-Limit, X-RateLimit-Remaining e X-RateLimit-Reset (segundos até o bucket
+// encher de novo). Se o backend falhar, a requisição passa.
+func RateLimit(backend ratelimit.Backend, name string, limit LimitFunc, key KeyFunc, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l := limit(r)
+		k := key(r)
+		if l.Disabled() || k == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		res, err := backend.Take(r.Context(), name+":"+k, l)
+		if err != nil {
+			log.Printf("⚠️  Warning: Rate limit check failed for %s: %v", name, err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		w.Header().Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+		if !res.Allowed {
+			retryAfter := max(ceilSeconds(res.RetryAfter), 1)
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			http.Error(w, fmt.Sprintf("Too many requests. Try again in %d seconds.", retryAfter), http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// StaticLimit aplica o mesmo limite a todas as requisições
+func StaticLimit(l ratelimit.Limit) LimitFunc {
+	return func(r *http.Request) ratelimit.Limit { return l }
+}
+
+// PlanLimit aplica o limite do plano do usuário autenticado (Plan.RateLimit), ou fallback
+// quando o plano não define um. Deve ficar depois do AuthMiddleware.
+func PlanLimit(db *gorm.DB, fallback ratelimit.Limit) LimitFunc {
+	type cached struct {
+		limit   ratelimit.Limit
+		expires time.Time
+	}
+	var mu sync.Mutex
+	cache := make(map[uuid.UUID]cached)
+
+	return func(r *http.Request) ratelimit.Limit {
+		user, ok := r.Context().Value(UserContextKey).(*models.User)
+		if !ok {
+			return fallback
+		}
+
+		mu.Lock()
+		entry, found := cache[user.PlanID]
+		mu.Unlock()
+		if found && time.Now().Before(entry.expires) {
+			return entry.limit
+		}
+
+		limit := fallback
+		var plan models.Plan
+		if err := db.Select("rate_limit").First(&plan, "id = ?", user.PlanID).Error; err == nil && plan.RateLimit != "" {
+			if parsed, err := ratelimit.ParseLimit(plan.RateLimit); err == nil {
+				limit = parsed
+			} else {
+				log.Printf("⚠️  Warning: Invalid rate limit for plan %s: %v", user.PlanID, err)
+			}
+		}
+
+		mu.Lock()
+		cache[user.PlanID] = cached{limit: limit, expires: time.Now().Add(planLimitCacheTTL)}
+		mu.Unlock()
+		return limit
+	}
+}
+
+// ByIP limita pelo IP do cliente. X-Forwarded-For só conta vindo de TRUSTED_PROXIES;
+// caso contrário, um cliente ganharia um balde novo a cada valor inventado do header.
+func ByIP(r *http.Request) string {
+	return util.ClientIP(r)
+}
+
+// ByUser limita pelo usuário autenticado. Deve ficar depois do AuthMiddleware.
+func ByUser(r *http.Request) string {
+	if user, ok := r.Context().Value(UserContextKey).(*models.User); ok {
+		return user.ID.String()
+	}
+	return ""
+}
+
+// ByEmail limita pelo campo "email" do corpo JSON, para que tentativas contra a mesma
+// conta sejam contadas juntas mesmo vindo de IPs diferentes. O corpo é devolvido intacto
+// para o handler.
+func ByEmail(r *http.Request) string {
+	if r.Body == nil {
+		return ""
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, loginBodyLimit))
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+	var req struct {
+		Email string `json:"email"`
+	}
+	if json.Unmarshal(body, &req) != nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(req.Email))
+}
@@ -28,20 +28,41 @@ func RecalculateStorageUsage(db *gorm.DB) {
 
 	fmt.Printf("Found %d users to process.\n", len(users))
 
+	// Recalculate blob reference counts from the files that point to them
+	if err := db.Exec(`UPDATE blobs SET ref_count = (SELECT count(*) FROM files WHERE files.blob_id = blobs.id)`).Error; err != nil {
+		log.Fatalf("Error recalculating blob references: %v", err)
+	}
+
 	for _, user := range users {
-		var totalSize int64
+		var totalSize, physicalSize int64
 		// Subquery to get all project IDs for the user
 		projectIDs := db.Model(&models.Project{}).Select("id").Where("user_id = ?", user.ID)
 
-		// Sum the size of all files belonging to those projects
-		if err := db.Model(&models.File{}).Select("sum(size)").Where("project_id IN (?)", projectIDs).Row().Scan(&totalSize); err != nil {
+		// Resized variants of the files in those projects
+		variantSize := db.Model(&models.Variant{}).Select("COALESCE(sum(size), 0)").Where("file_id IN (?)", db.Model(&models.File{}).Select("id").Where("project_id IN (?)", projectIDs))
+
+		// Logical usage: sum the size of all files belonging to those projects and of their variants
+		if err := db.Model(&models.File{}).Select("COALESCE(sum(size), 0) + (?)", variantSize).Where("project_id IN (?)", projectIDs).Row().Scan(&totalSize); err != nil {
+			log.Printf("Could not calculate storage for user %s: %v", user.Email, err)
+			continue
+		}
+
+		// Physical usage: each referenced blob once, plus files stored before deduplication and variants
+		if err := db.Raw(`SELECT
+				COALESCE((SELECT sum(size) FROM blobs WHERE user_id = ? AND ref_count > 0), 0) +
+				COALESCE((SELECT sum(size) FROM files WHERE blob_id IS NULL AND project_id IN (?)), 0) +
+				(?)`,
+			user.ID, projectIDs, variantSize).Row().Scan(&physicalSize); err != nil {
 			log.Printf("Could not calculate storage for user %s: %v", user.Email, err)
 			continue
 		}
 
-		if user.StorageUsage != totalSize {
-			fmt.Printf("Updating user %s: Old storage %d, New storage %d\n", user.Email, user.StorageUsage, totalSize)
-			if err := db.Model(&user).Update("storage_usage", totalSize).Error; err != nil {
+		if user.StorageUsage != physicalSize || user.LogicalStorageUsage != totalSize {
+			fmt.Printf("Updating user %s: Old storage %d (logical %d), New storage %d (logical %d)\n", user.Email, user.StorageUsage, user.LogicalStorageUsage, physicalSize, totalSize)
+			if err := db.Model(&user).Updates(map[string]interface{}{
+				"storage_usage":         physicalSize,
+				"logical_storage_usage": totalSize,
+			}).Error; err != nil {
 				log.Printf("Failed to update storage for user %s: %v", user.Email, err)
 			}
 		} else {
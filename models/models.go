@@ -1,6 +1,8 @@
 package models
 
 import (
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -9,51 +11,580 @@ import (
 )
 
 const (
-	FreePlanStorageLimit = 1 * 1024 * 1024 * 1024 // 1 GB
+	FreePlanName             = "Free"                 // Plano padrão de novas contas e organizações
+	FreePlanStorageLimit     = 1 * 1024 * 1024 * 1024 // 1 GB
+	FreePlanMaxFileSize      = 10 * 1024 * 1024       // 10 MB
+	FreePlanAllowedMimeTypes = "image/jpeg,image/png,application/pdf"
+	FreePlanDailyUploadLimit = 100
 )
 
+// Visibilidade dos projetos
+const (
+	VisibilityPublic  = "public"  // Arquivos acessíveis por URL direta em /files/
+	VisibilityPrivate = "private" // Arquivos acessíveis apenas por URL assinada
+)
+
+// Estados de um arquivo no pipeline de processamento pós-upload
+const (
+	FileStatusPending = "pending" // Aguardando as etapas em segundo plano
+	FileStatusReady   = "ready"   // Todas as etapas concluídas
+	FileStatusFailed  = "failed"  // Alguma etapa falhou em todas as tentativas
+	// Conteúdo suspeito: o arquivo não é servido em /files/ e não avança no pipeline
+	FileStatusQuarantined = "quarantined"
+)
+
+// Estados de um job em segundo plano
+const (
+	JobQueued  = "queued"  // Aguardando RunAt para ser executado
+	JobRunning = "running" // Em execução por um worker
+	JobDone    = "done"    // Concluído com sucesso
+	JobDead    = "dead"    // Esgotou as tentativas ou falhou de forma permanente
+)
+
+// Eventos enviados aos webhooks
+const (
+	EventFileUploaded   = "file.uploaded"
+	EventFileDeleted    = "file.deleted"
+	EventProjectCreated = "project.created"
+	EventProjectDeleted = "project.deleted"
+	EventQuotaExceeded  = "quota.exceeded"
+	EventQuotaOverLimit = "quota.over_limit" // O uso passou do limite do plano (downgrade); começa o prazo de carência
+	EventPlanChanged    = "plan.changed"
+	EventWebhookTest    = "webhook.test" // Enviado apenas por /api/webhooks/{id}/test
+)
+
+// AllEvents lista os eventos que podem ser assinados
+var AllEvents = []string{EventFileUploaded, EventFileDeleted, EventProjectCreated, EventProjectDeleted, EventQuotaExceeded, EventQuotaOverLimit, EventPlanChanged}
+
+// Estados de uma entrega de webhook
+const (
+	DeliveryPending   = "pending"   // Aguardando a primeira tentativa ou uma nova tentativa
+	DeliverySucceeded = "succeeded" // O destino respondeu 2xx
+	DeliveryFailed    = "failed"    // Esgotou as tentativas
+)
+
+// Ações registradas no log de auditoria
+const (
+	AuditFileUpload      = "file.upload"
+	AuditFileDelete      = "file.delete"
+	AuditProjectDelete   = "project.delete"
+	AuditAPIKeyRotate    = "api_key.rotate"
+	AuditLogin           = "auth.login"    // Tentativas de login que falharam
+	AuditAuthRejected    = "auth.rejected" // Requisições recusadas pelo AuthMiddleware
+	AuditAccountLocked   = "auth.locked"   // Bloqueio do login por excesso de falhas
+	AuditAccountUnlock   = "auth.unlocked" // Desbloqueio feito por um administrador
+	AuditPasswordReset   = "auth.password_reset"
+	AuditEmailVerified   = "auth.email_verified"
+	AuditTwoFactorOn     = "auth.2fa_enabled"
+	AuditTwoFactorOff    = "auth.2fa_disabled"
+	AuditOrgCreate       = "org.create"
+	AuditOrgDelete       = "org.delete"
+	AuditMemberAdd       = "org.member_add"
+	AuditMemberRole      = "org.member_role"
+	AuditMemberRemove    = "org.member_remove"
+	AuditShareInvite     = "project.share_invite"
+	AuditShareAccept     = "project.share_accept"
+	AuditSharePermission = "project.share_permission"
+	AuditShareRevoke     = "project.share_revoke"
+	AuditAccountSuspend  = "account.suspend"   // Suspensão feita por um administrador
+	AuditAccountResume   = "account.unsuspend" // Fim da suspensão
+	AuditPlanChange      = "account.plan_change"
+	AuditPlanCreate      = "plan.create"
+	AuditPlanUpdate      = "plan.update"
+	AuditPlanDelete      = "plan.delete"
+	AuditPlanRequest     = "account.plan_request" // Mudança de plano pedida pelo próprio usuário
+	AuditPlanCancel      = "account.plan_cancel"  // Mudança agendada cancelada
+)
+
+// Tipos de pagamento
+const (
+	PaymentSubscription = "subscription" // Período de um plano (mudança ou renovação)
+	PaymentOverage      = "overage"      // Excedente cobrado com a fatura mensal
+)
+
+// Situação de uma fatura
+const (
+	InvoicePaid = "paid" // Nada a cobrar ou cobrança aprovada
+	InvoiceOpen = "open" // A cobrança do excedente foi recusada
+)
+
+// Situação de uma mudança de plano
+const (
+	PlanChangePending  = "pending"  // Agendada para EffectiveAt
+	PlanChangeApplied  = "applied"  // Em vigor
+	PlanChangeCanceled = "canceled" // Cancelada pelo usuário ou substituída por outra
+	PlanChangeFailed   = "failed"   // A cobrança foi recusada
+)
+
+// Resultado de uma ação auditada
+const (
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+)
+
+// Escopos de uma chave de API
+const (
+	ScopeUpload = "upload" // Enviar arquivos
+	ScopeRead   = "read"   // Listar projetos e arquivos, gerar URLs assinadas
+	ScopeDelete = "delete" // Remover arquivos e projetos
+	ScopeAdmin  = "admin"  // Tudo acima, além de gerenciar chaves, sessões e configurações
+)
+
+// AllScopes lista os escopos válidos, em ordem de exibição
+var AllScopes = []string{ScopeUpload, ScopeRead, ScopeDelete, ScopeAdmin}
+
 // Plan representa um plano de assinatura
 type Plan struct {
-	ID           uuid.UUID `gorm:"type:uuid;primary_key;"`
-	Name         string    `gorm:"uniqueIndex;not null"`
-	Price        float64   `gorm:"not null;default:0"`
-	StorageLimit int64     `gorm:"not null"` // Em bytes
-	CreatedAt    time.Time `gorm:"autoCreateTime"`
+	ID               uuid.UUID `gorm:"type:uuid;primary_key;"`
+	Name             string    `gorm:"uniqueIndex;not null"`
+	Price            float64   `gorm:"not null;default:0"`
+	StorageLimit     int64     `gorm:"not null"` // Em bytes
+	MaxFileSize      int64     `gorm:"not null"` // Tamanho máximo por arquivo, em bytes
+	AllowedMimeTypes string    `gorm:"not null"` // Lista separada por vírgulas
+	DailyUploadLimit int       `gorm:"not null"` // Uploads por dia (UTC); 0 = ilimitado
+	RateLimit        string    `gorm:"not null"` // Requisições à API por usuário (ex.: "600/1m"); vazio usa RATE_LIMIT_API
+	// Tarifas de excedente cobradas na fatura mensal; 0 = não cobrado
+	StorageOverageRate float64   `gorm:"not null;default:0"` // Por GB-mês acima de StorageLimit
+	IncludedEgress     int64     `gorm:"not null;default:0"` // Bytes servidos em /files/ por mês incluídos no preço
+	EgressOverageRate  float64   `gorm:"not null;default:0"` // Por GB servido além de IncludedEgress
+	EgressLimit        int64     `gorm:"not null;default:0"` // Bytes servidos por mês antes de /files/ recusar; 0 = ilimitado
+	CreatedAt          time.Time `gorm:"autoCreateTime"`
+}
+
+// DailyUploadCount conta os uploads de um usuário em um dia (UTC)
+type DailyUploadCount struct {
+	UserID  uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Day     time.Time `gorm:"type:date;primaryKey"`
+	Uploads int       `gorm:"not null;default:0"`
 }
 
 // User representa um usuário no sistema
 type User struct {
+	ID                  uuid.UUID  `gorm:"type:uuid;primary_key;"`
+	Name                string     `gorm:"not null"`
+	WhatsappNumber      string     `gorm:"not null"`
+	Email               string     `gorm:"uniqueIndex;not null"`
+	Password            string     `gorm:"not null"`
+	StorageUsage        int64      `gorm:"default:0"` // Bytes armazenados de fato (blobs deduplicados), usado no limite do plano
+	LogicalStorageUsage int64      `gorm:"default:0"` // Soma do tamanho de todos os arquivos, contando duplicatas
+	PlanID              uuid.UUID  `gorm:"type:uuid"`
+	Plan                Plan       `gorm:"foreignKey:PlanID"`
+	IsAdmin             bool       `gorm:"not null;default:false"`
+	FailedLogins        int        `gorm:"not null;default:0"` // Falhas de login desde o último sucesso
+	LockedUntil         *time.Time // Bloqueio temporário do login
+	PermanentlyLocked   bool       `gorm:"not null;default:false"` // Só um administrador desbloqueia
+	EmailVerifiedAt     *time.Time // Nil até o usuário confirmar o e-mail
+	SuspendedAt         *time.Time // Contas suspensas por um administrador não acessam a API
+	SuspensionReason    string     `gorm:"not null;default:''"`
+	TOTPSecret          string     `gorm:"column:totp_secret;not null;default:''" json:"-"`   // Definido no cadastro do 2FA, antes da confirmação
+	TOTPEnabledAt       *time.Time `gorm:"column:totp_enabled_at"`                            // Nil enquanto o 2FA não estiver ativo
+	TOTPLastStep        int64      `gorm:"column:totp_last_step;not null;default:0" json:"-"` // Último intervalo aceito, contra reutilização de códigos
+	PlanRenewsAt        *time.Time // Fim do período pago; nil nos planos gratuitos
+	GraceEndsAt         *time.Time // Definido enquanto o uso passa do limite do plano: fim do prazo para voltar ao limite
+	QuotaRestrictedAt   *time.Time // Quando o prazo acabou e os arquivos deixaram de ser servidos
+	CreatedAt           time.Time  `gorm:"autoCreateTime"`
+	Projects            []Project  `gorm:"foreignKey:UserID"`
+}
+
+// Project representa um projeto de um usuário ou, quando OrganizationID está definido,
+// de uma organização. Nos projetos de organizações UserID é quem criou o projeto.
+type Project struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key;"`
+	Name           string     `gorm:"uniqueIndex:idx_user_project;not null"`
+	UserID         uuid.UUID  `gorm:"type:uuid;uniqueIndex:idx_user_project;not null"`
+	OrganizationID *uuid.UUID `gorm:"type:uuid;index"`
+	Visibility     string     `gorm:"not null;default:public"` // VisibilityPublic ou VisibilityPrivate
+	CreatedAt      time.Time  `gorm:"autoCreateTime"`
+	Files          []File     `gorm:"foreignKey:ProjectID"`
+}
+
+// Papéis de um membro em uma organização, do maior para o menor
+const (
+	RoleOwner  = "owner"  // Tudo, inclusive gerenciar donos e administradores e apagar a organização
+	RoleAdmin  = "admin"  // Gerenciar membros, apagar projetos e alterar sua visibilidade
+	RoleMember = "member" // Enviar e apagar arquivos
+	RoleViewer = "viewer" // Listar projetos e arquivos e gerar URLs assinadas
+)
+
+// AllRoles lista os papéis válidos, em ordem de exibição
+var AllRoles = []string{RoleOwner, RoleAdmin, RoleMember, RoleViewer}
+
+// RoleAllows indica se o papel concede as permissões de required
+func RoleAllows(role, required string) bool {
+	rank := func(r string) int {
+		for i, candidate := range AllRoles {
+			if candidate == r {
+				return len(AllRoles) - i
+			}
+		}
+		return 0
+	}
+	return rank(role) > 0 && rank(role) >= rank(required)
+}
+
+// Organization agrupa usuários que compartilham projetos. O armazenamento dos projetos
+// da organização conta no plano dela, não no de cada membro.
+type Organization struct {
+	ID                  uuid.UUID `gorm:"type:uuid;primary_key;"`
+	Name                string    `gorm:"not null"`
+	Slug                string    `gorm:"uniqueIndex;not null"` // Identificador usado no parâmetro org
+	PlanID              uuid.UUID `gorm:"type:uuid;not null"`
+	Plan                Plan      `gorm:"foreignKey:PlanID"`
+	StorageUsage        int64     `gorm:"not null;default:0"`
+	LogicalStorageUsage int64     `gorm:"not null;default:0"`
+	CreatedAt           time.Time `gorm:"autoCreateTime"`
+}
+
+// Membership liga um usuário a uma organização com um papel
+type Membership struct {
 	ID             uuid.UUID `gorm:"type:uuid;primary_key;"`
-	Name           string    `gorm:"not null"`
-	WhatsappNumber string    `gorm:"not null"`
-	Email          string    `gorm:"uniqueIndex;not null"`
-	Password       string    `gorm:"not null"`
-	ForgeAPIKey    string    `gorm:"uniqueIndex;not null"`
-	StorageUsage   int64     `gorm:"default:0"`
-	PlanID         uuid.UUID `gorm:"type:uuid"`
-	Plan           Plan      `gorm:"foreignKey:PlanID"`
+	OrganizationID uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_memberships_org_user;not null"`
+	UserID         uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_memberships_org_user;index;not null"`
+	Role           string    `gorm:"not null"`
+	User           User      `gorm:"foreignKey:UserID"`
 	CreatedAt      time.Time `gorm:"autoCreateTime"`
-	Projects       []Project `gorm:"foreignKey:UserID"`
 }
 
-// Project representa um projeto de um usuário
-type Project struct {
-	ID        uuid.UUID `gorm:"type:uuid;primary_key;"`
-	Name      string    `gorm:"uniqueIndex:idx_user_project;not null"`
-	UserID    uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_user_project;not null"`
-	CreatedAt time.Time `gorm:"autoCreateTime"`
-	Files     []File    `gorm:"foreignKey:ProjectID"`
+// Permissões de um colaborador em um projeto compartilhado, do maior para o menor
+const (
+	PermissionManage = "manage" // Também apagar o projeto e convidar ou remover colaboradores
+	PermissionUpload = "upload" // Também enviar e apagar arquivos
+	PermissionRead   = "read"   // Listar arquivos e gerar URLs assinadas
+)
+
+// AllPermissions lista as permissões válidas, em ordem de exibição
+var AllPermissions = []string{PermissionManage, PermissionUpload, PermissionRead}
+
+// PermissionRole retorna o papel equivalente à permissão, usado nas mesmas verificações
+// dos projetos de organizações
+func PermissionRole(permission string) string {
+	switch permission {
+	case PermissionManage:
+		return RoleAdmin
+	case PermissionUpload:
+		return RoleMember
+	case PermissionRead:
+		return RoleViewer
+	}
+	return ""
+}
+
+// ProjectMember dá a outro usuário acesso a um único projeto pessoal. O convite só vale
+// depois de aceito (AcceptedAt preenchido).
+type ProjectMember struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key;"`
+	ProjectID   uuid.UUID  `gorm:"type:uuid;uniqueIndex:idx_project_members_project_user;not null"`
+	UserID      uuid.UUID  `gorm:"type:uuid;uniqueIndex:idx_project_members_project_user;index;not null"`
+	Permission  string     `gorm:"not null"` // PermissionRead, PermissionUpload ou PermissionManage
+	InvitedByID *uuid.UUID `gorm:"type:uuid"`
+	AcceptedAt  *time.Time
+	Project     Project   `gorm:"foreignKey:ProjectID"`
+	User        User      `gorm:"foreignKey:UserID"`
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+}
+
+// PlanChange é um pedido de mudança de plano. Upgrades são cobrados e aplicados na
+// hora; downgrades ficam pendentes até o fim do período pago (EffectiveAt).
+type PlanChange struct {
+	ID            uuid.UUID `gorm:"type:uuid;primary_key;"`
+	UserID        uuid.UUID `gorm:"type:uuid;index;not null"`
+	FromPlanID    uuid.UUID `gorm:"type:uuid;not null"`
+	ToPlanID      uuid.UUID `gorm:"type:uuid;not null"`
+	Status        string    `gorm:"not null;default:pending"` // PlanChangePending, PlanChangeApplied, PlanChangeCanceled ou PlanChangeFailed
+	EffectiveAt   time.Time `gorm:"not null"`
+	AppliedAt     *time.Time
+	Amount        float64   `gorm:"not null;default:0"` // Valor cobrado ao aplicar
+	PaymentID     string    `gorm:"not null;default:''"`
+	FailureReason string    `gorm:"not null;default:''"`
+	FromPlan      Plan      `gorm:"foreignKey:FromPlanID"`
+	ToPlan        Plan      `gorm:"foreignKey:ToPlanID"`
+	CreatedAt     time.Time `gorm:"autoCreateTime"`
+}
+
+// Payment registra uma cobrança aprovada pelo provedor de pagamentos
+type Payment struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key;"`
+	UserID      uuid.UUID `gorm:"type:uuid;index;not null"`
+	Kind        string    `gorm:"not null"` // PaymentSubscription ou PaymentOverage
+	Amount      float64   `gorm:"not null"`
+	Currency    string    `gorm:"not null"`
+	Description string    `gorm:"not null"`
+	ProviderID  string    `gorm:"not null"` // ID da cobrança no provedor
+	CreatedAt   time.Time `gorm:"autoCreateTime;index"`
+}
+
+// StorageSnapshot guarda o uso de armazenamento de um usuário em um dia (UTC)
+type StorageSnapshot struct {
+	UserID       uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Day          time.Time `gorm:"type:date;primaryKey"`
+	StorageBytes int64     `gorm:"not null"`
+}
+
+// OrganizationStorageSnapshot guarda o uso de armazenamento de uma organização em um dia (UTC)
+type OrganizationStorageSnapshot struct {
+	OrganizationID uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Day            time.Time `gorm:"type:date;primaryKey"`
+	StorageBytes   int64     `gorm:"not null"`
+}
+
+// DailyEgress soma os bytes servidos em /files/ dos projetos de um usuário em um dia (UTC)
+type DailyEgress struct {
+	UserID uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Day    time.Time `gorm:"type:date;primaryKey"`
+	Bytes  int64     `gorm:"not null;default:0"`
+}
+
+// OrganizationDailyEgress soma os bytes servidos em /files/ dos projetos de uma organização em um dia (UTC)
+type OrganizationDailyEgress struct {
+	OrganizationID uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Day            time.Time `gorm:"type:date;primaryKey"`
+	Bytes          int64     `gorm:"not null;default:0"`
+}
+
+// UsageRecord consolida o uso de um mês fechado, com o plano em vigor no fechamento
+type UsageRecord struct {
+	ID               uuid.UUID `gorm:"type:uuid;primary_key;"`
+	UserID           uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_usage_records_user_month;not null"`
+	Month            time.Time `gorm:"type:date;uniqueIndex:idx_usage_records_user_month;not null"` // Primeiro dia do mês
+	PlanID           uuid.UUID `gorm:"type:uuid;not null"`
+	Days             int       `gorm:"not null"` // Dias com snapshot
+	StorageByteHours int64     `gorm:"not null"` // Cada snapshot vale 24 horas
+	OverageByteHours int64     `gorm:"not null"` // Parte acima do limite do plano
+	PeakStorage      int64     `gorm:"not null"`
+	EgressBytes      int64     `gorm:"not null"`
+	Plan             Plan      `gorm:"foreignKey:PlanID"`
+	CreatedAt        time.Time `gorm:"autoCreateTime"`
+}
+
+// Invoice é a fatura mensal de um usuário: os pagamentos de planos do mês e o
+// excedente de armazenamento e de tráfego, cobrado ao fechar o mês
+type Invoice struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primary_key;"`
+	UserID        uuid.UUID  `gorm:"type:uuid;uniqueIndex:idx_invoices_user_period;not null"`
+	Number        string     `gorm:"uniqueIndex;not null"`
+	UsageRecordID uuid.UUID  `gorm:"type:uuid;not null"`
+	PeriodStart   time.Time  `gorm:"uniqueIndex:idx_invoices_user_period;not null"`
+	PeriodEnd     time.Time  `gorm:"not null"` // Exclusivo
+	Currency      string     `gorm:"not null"`
+	Lines         string     `gorm:"type:jsonb;not null"` // []billing.Line
+	Total         float64    `gorm:"not null"`
+	AmountDue     float64    `gorm:"not null"` // Excedente cobrado com a fatura
+	Status        string     `gorm:"not null"` // InvoicePaid ou InvoiceOpen
+	PaymentID     *uuid.UUID `gorm:"type:uuid"`
+	CreatedAt     time.Time  `gorm:"autoCreateTime;index"`
 }
 
 // File representa um arquivo enviado para um projeto
 type File struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key;"`
+	Name       string     `gorm:"not null"`
+	Path       string     `gorm:"not null"`
+	Size       int64      `gorm:"not null"`
+	MimeType   string     `gorm:"not null"`
+	ProjectID  uuid.UUID  `gorm:"type:uuid;not null"`
+	BlobID     *uuid.UUID `gorm:"type:uuid;index"`        // Conteúdo compartilhado; nulo em arquivos anteriores à deduplicação
+	Status     string     `gorm:"not null;default:ready"` // FileStatusPending, FileStatusReady, FileStatusFailed ou FileStatusQuarantined
+	ScanResult string     // Assinatura encontrada quando o arquivo está em quarentena
+	// Acessos servidos em /files/, incluindo as variantes redimensionadas
+	Downloads   int64     `gorm:"not null;default:0"`
+	BytesServed int64     `gorm:"not null;default:0"`
+	UploadedAt  time.Time `gorm:"autoCreateTime"`
+}
+
+// Blob é o conteúdo de um arquivo, armazenado uma única vez por usuário (ou organização)
+// e identificado pelo SHA-256. RefCount conta quantos models.File apontam para ele; o objeto só é
+// removido do armazenamento quando a última referência é apagada.
+type Blob struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key;"`
+	UserID         uuid.UUID  `gorm:"type:uuid;uniqueIndex:idx_blobs_user_hash;not null"`
+	OrganizationID *uuid.UUID `gorm:"type:uuid"`                                // Blobs de projetos de organizações são deduplicados por organização
+	Hash           string     `gorm:"uniqueIndex:idx_blobs_user_hash;not null"` // SHA-256 (hex) do conteúdo
+	Size           int64      `gorm:"not null"`
+	MimeType       string     `gorm:"not null"`
+	StorageKey     string     `gorm:"not null"`
+	RefCount       int        `gorm:"not null;default:0"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime"`
+}
+
+// Variant é uma versão redimensionada de uma imagem, gerada no upload (miniaturas) ou
+// sob demanda em /files/...?w=&h=&fit=. Fica no cache local de variantes e conta no
+// uso de armazenamento do dono do arquivo.
+type Variant struct {
 	ID         uuid.UUID `gorm:"type:uuid;primary_key;"`
-	Name       string    `gorm:"not null"`
-	Path       string    `gorm:"not null"`
+	FileID     uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_variants_file_size;not null"`
+	Width      int       `gorm:"uniqueIndex:idx_variants_file_size;not null"` // Caixa pedida; 0 é proporcional
+	Height     int       `gorm:"uniqueIndex:idx_variants_file_size;not null"`
+	Fit        string    `gorm:"uniqueIndex:idx_variants_file_size;not null"`
+	StorageKey string    `gorm:"not null"`
 	Size       int64     `gorm:"not null"`
 	MimeType   string    `gorm:"not null"`
-	ProjectID  uuid.UUID `gorm:"type:uuid;not null"`
-	UploadedAt time.Time `gorm:"autoCreateTime"`
+	CreatedAt  time.Time `gorm:"autoCreateTime"`
+}
+
+// Job é uma tarefa executada em segundo plano pelos workers do pacote jobs. Falhas são
+// tentadas de novo com espera exponencial até MaxAttempts; depois disso o job fica
+// como JobDead, guardando o último erro.
+type Job struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key;"`
+	Type        string     `gorm:"not null;index"`
+	Payload     string     `gorm:"type:jsonb;not null"`
+	Status      string     `gorm:"not null;default:queued"`
+	Attempts    int        `gorm:"not null;default:0"`
+	MaxAttempts int        `gorm:"not null"`
+	RunAt       time.Time  `gorm:"not null"`
+	LockedAt    *time.Time // Início da execução atual
+	LastError   string
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime"`
+}
+
+// UploadSession representa um upload resumível (estilo tus) em andamento. Os bytes
+// recebidos ficam em um arquivo temporário até que UploadOffset alcance UploadLength.
+type UploadSession struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key;"`
+	UserID         uuid.UUID  `gorm:"type:uuid;index;not null"`
+	OrganizationID *uuid.UUID `gorm:"type:uuid;index"` // Organização dona do projeto de destino
+	ProjectName    string     `gorm:"not null"`
+	FileName       string     `gorm:"not null"`
+	MimeType       string     `gorm:"not null"` // Da extensão até os primeiros bytes chegarem; depois, o tipo detectado
+	UploadLength   int64      `gorm:"not null"` // Tamanho total declarado, em bytes
+	UploadOffset   int64      `gorm:"not null;default:0"`
+	ExpiresAt      time.Time  `gorm:"index;not null"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt      time.Time  `gorm:"autoUpdateTime"`
+}
+
+// RefreshToken é um refresh token emitido no login. A cada uso ele é trocado por um
+// novo token da mesma família (sessão); reutilizar um token já trocado revoga a família.
+type RefreshToken struct {
+	ID               uuid.UUID `gorm:"type:uuid;primary_key;"`
+	UserID           uuid.UUID `gorm:"type:uuid;index;not null"`
+	FamilyID         uuid.UUID `gorm:"type:uuid;index;not null"` // Identifica a sessão iniciada no login
+	TokenHash        string    `gorm:"uniqueIndex;not null" json:"-"`
+	AccessJTI        string    `gorm:"index;not null" json:"-"` // jti do access token emitido junto
+	UserAgent        string
+	IPAddress        string
+	SessionStartedAt time.Time `gorm:"not null"` // Momento do login que originou a família
+	ExpiresAt        time.Time `gorm:"not null"`
+	RevokedAt        *time.Time
+	CreatedAt        time.Time `gorm:"autoCreateTime"`
+}
+
+// Finalidades de UserToken
+const (
+	TokenEmailVerification = "email_verification"
+	TokenPasswordReset     = "password_reset"
+)
+
+// UserToken é um token de uso único enviado por e-mail (verificação da conta ou
+// redefinição de senha). Apenas o hash é armazenado.
+type UserToken struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;"`
+	UserID    uuid.UUID `gorm:"type:uuid;index;not null"`
+	Purpose   string    `gorm:"not null"`
+	TokenHash string    `gorm:"uniqueIndex;not null" json:"-"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// RecoveryCode é um código de recuperação de uso único do 2FA. Apenas o hash é armazenado.
+type RecoveryCode struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;"`
+	UserID    uuid.UUID `gorm:"type:uuid;index;not null"`
+	CodeHash  string    `gorm:"not null" json:"-"`
+	UsedAt    *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// LoginChallenge é o segundo passo de um login com 2FA: emitido depois da senha correta,
+// é trocado pelos tokens da sessão junto com um código válido
+type LoginChallenge struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;"`
+	UserID    uuid.UUID `gorm:"type:uuid;index;not null"`
+	TokenHash string    `gorm:"uniqueIndex;not null" json:"-"`
+	Attempts  int       `gorm:"not null;default:0"`
+	ExpiresAt time.Time `gorm:"not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// APIKey é uma chave de API nomeada de um usuário. Apenas o hash do segredo é
+// armazenado; Prefix é a parte inicial da chave, usada para localizá-la na autenticação
+// e identificá-la na listagem.
+type APIKey struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key;"`
+	UserID     uuid.UUID  `gorm:"type:uuid;index;not null"`
+	Name       string     `gorm:"not null"`
+	Prefix     string     `gorm:"index;not null"`
+	KeyHash    string     `gorm:"uniqueIndex;not null" json:"-"` // SHA-256 (hex) da chave completa
+	Scopes     string     `gorm:"not null"`                      // Lista separada por vírgulas
+	ProjectID  *uuid.UUID `gorm:"type:uuid;index"`               // Se definido, a chave só acessa este projeto
+	LastUsedAt *time.Time
+	ExpiresAt  *time.Time
+	CreatedAt  time.Time `gorm:"autoCreateTime"`
+}
+
+// Webhook é uma assinatura de eventos de um usuário. Sem ProjectID, recebe eventos de
+// todos os projetos do usuário. Secret assina cada entrega com HMAC-SHA256.
+type Webhook struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key;"`
+	UserID    uuid.UUID  `gorm:"type:uuid;index;not null"`
+	ProjectID *uuid.UUID `gorm:"type:uuid;index"`
+	URL       string     `gorm:"not null"`
+	Secret    string     `gorm:"not null" json:"-"`
+	Events    string     `gorm:"not null"` // Lista separada por vírgulas
+	Active    bool       `gorm:"not null;default:true"`
+	CreatedAt time.Time  `gorm:"autoCreateTime"`
+}
+
+// WebhookDelivery registra cada envio de um evento a um webhook e o resultado da
+// última tentativa
+type WebhookDelivery struct {
+	ID             uuid.UUID `gorm:"type:uuid;primary_key;"`
+	WebhookID      uuid.UUID `gorm:"type:uuid;index;not null"`
+	Event          string    `gorm:"not null"`
+	Payload        string    `gorm:"type:jsonb;not null"`
+	Status         string    `gorm:"not null;default:pending"`
+	Attempts       int       `gorm:"not null;default:0"`
+	ResponseStatus int       // Código HTTP da última tentativa; zero se não houve resposta
+	ResponseBody   string    // Início do corpo da última resposta
+	LastError      string
+	DeliveredAt    *time.Time
+	CreatedAt      time.Time `gorm:"autoCreateTime"`
+	UpdatedAt      time.Time `gorm:"autoUpdateTime"`
+}
+
+// AuditEvent registra uma ação relevante para a segurança da conta. UserID é o dono da
+// conta afetada e fica vazio quando ela não pôde ser identificada (por exemplo, um token
+// inválido).
+type AuditEvent struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key;"`
+	UserID    *uuid.UUID `gorm:"type:uuid;index"`
+	APIKeyID  *uuid.UUID `gorm:"type:uuid"` // Chave de API usada, quando houver
+	Actor     string     `gorm:"not null"`  // E-mail do usuário, "api_key:<prefixo>" ou "anonymous"
+	Action    string     `gorm:"index;not null"`
+	Target    string     // Projeto/arquivo, chave ou rota afetada
+	Outcome   string     `gorm:"not null"` // AuditSuccess ou AuditFailure
+	Detail    string     // Motivo da falha ou informação adicional
+	IP        string
+	UserAgent string
+	CreatedAt time.Time `gorm:"autoCreateTime;index"`
+}
+
+// IPLoginFailure conta as falhas de login vindas de um IP, para qualquer conta
+type IPLoginFailure struct {
+	IP          string `gorm:"primaryKey"`
+	Failures    int    `gorm:"not null;default:0"`
+	LockedUntil *time.Time
+	UpdatedAt   time.Time `gorm:"autoUpdateTime"`
+}
+
+// RevokedToken registra o jti de um access token revogado até sua expiração natural
+type RevokedToken struct {
+	JTI       string    `gorm:"primaryKey"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null"`
+	ExpiresAt time.Time `gorm:"index;not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
 }
 
 // BeforeCreate é um hook do GORM para gerar um UUID para o plano
@@ -62,19 +593,21 @@ func (p *Plan) BeforeCreate(tx *gorm.DB) (err error) {
 	return
 }
 
-// BeforeCreate é um hook do GORM para gerar a API key e hashear a senha antes de criar um usuário
+// BeforeCreate é um hook do GORM para gerar o ID e hashear a senha antes de criar um usuário
 func (u *User) BeforeCreate(tx *gorm.DB) (err error) {
 	u.ID = uuid.New()
-	// Gerar API Key
-	u.ForgeAPIKey = uuid.New().String()
 
 	// Hashear a senha
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(u.Password), 12)
+	return u.SetPassword(u.Password)
+}
+
+// SetPassword troca Password pelo hash bcrypt de password
+func (u *User) SetPassword(password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), 12)
 	if err != nil {
 		return err
 	}
 	u.Password = string(hashedPassword)
-
 	return nil
 }
 
@@ -84,12 +617,194 @@ func (p *Project) BeforeCreate(tx *gorm.DB) (err error) {
 	return
 }
 
+// BeforeCreate is a GORM hook to generate a UUID for the organization ID before creating a record
+func (o *Organization) BeforeCreate(tx *gorm.DB) (err error) {
+	o.ID = uuid.New()
+	return
+}
+
+// BeforeCreate is a GORM hook to generate a UUID for the membership ID before creating a record
+func (m *Membership) BeforeCreate(tx *gorm.DB) (err error) {
+	m.ID = uuid.New()
+	return
+}
+
+// BeforeCreate is a GORM hook to generate a UUID for the project member ID before creating a record
+func (m *ProjectMember) BeforeCreate(tx *gorm.DB) (err error) {
+	m.ID = uuid.New()
+	return
+}
+
+// BeforeCreate é um hook do GORM para gerar o ID da mudança de plano
+func (c *PlanChange) BeforeCreate(tx *gorm.DB) (err error) {
+	c.ID = uuid.New()
+	return
+}
+
+// BeforeCreate é um hook do GORM para gerar o ID do pagamento
+func (p *Payment) BeforeCreate(tx *gorm.DB) (err error) {
+	p.ID = uuid.New()
+	return
+}
+
+// BeforeCreate é um hook do GORM para gerar o ID do registro de uso
+func (u *UsageRecord) BeforeCreate(tx *gorm.DB) (err error) {
+	u.ID = uuid.New()
+	return
+}
+
+// BeforeCreate é um hook do GORM para gerar o ID da fatura
+func (i *Invoice) BeforeCreate(tx *gorm.DB) (err error) {
+	i.ID = uuid.New()
+	return
+}
+
 // BeforeCreate is a GORM hook to generate a UUID for the file ID before creating a record
 func (f *File) BeforeCreate(tx *gorm.DB) (err error) {
 	f.ID = uuid.New()
 	return
 }
 
+// BeforeCreate is a GORM hook to generate a UUID for the blob ID before creating a record
+func (b *Blob) BeforeCreate(tx *gorm.DB) (err error) {
+	b.ID = uuid.New()
+	return
+}
+
+// BeforeCreate is a GORM hook to generate a UUID for the variant ID before creating a record
+func (v *Variant) BeforeCreate(tx *gorm.DB) (err error) {
+	v.ID = uuid.New()
+	return
+}
+
+// BeforeCreate is a GORM hook to generate a UUID for the job ID before creating a record
+func (j *Job) BeforeCreate(tx *gorm.DB) (err error) {
+	j.ID = uuid.New()
+	return
+}
+
+// BeforeCreate is a GORM hook to generate a UUID for the upload session ID before creating a record
+func (s *UploadSession) BeforeCreate(tx *gorm.DB) (err error) {
+	s.ID = uuid.New()
+	return
+}
+
+// BeforeCreate is a GORM hook to generate a UUID for the refresh token ID before creating a record
+func (t *RefreshToken) BeforeCreate(tx *gorm.DB) (err error) {
+	t.ID = uuid.New()
+	return
+}
+
+// BeforeCreate is a GORM hook to generate a UUID for the user token ID before creating a record
+func (t *UserToken) BeforeCreate(tx *gorm.DB) (err error) {
+	t.ID = uuid.New()
+	return
+}
+
+// BeforeCreate is a GORM hook to generate a UUID for the recovery code ID before creating a record
+func (c *RecoveryCode) BeforeCreate(tx *gorm.DB) (err error) {
+	c.ID = uuid.New()
+	return
+}
+
+// BeforeCreate is a GORM hook to generate a UUID for the login challenge ID before creating a record
+func (c *LoginChallenge) BeforeCreate(tx *gorm.DB) (err error) {
+	c.ID = uuid.New()
+	return
+}
+
+// BeforeCreate is a GORM hook to generate a UUID for the API key ID before creating a record
+func (k *APIKey) BeforeCreate(tx *gorm.DB) (err error) {
+	k.ID = uuid.New()
+	return
+}
+
+// BeforeCreate is a GORM hook to generate a UUID for the audit event ID before creating a record
+func (e *AuditEvent) BeforeCreate(tx *gorm.DB) (err error) {
+	e.ID = uuid.New()
+	return
+}
+
+// BeforeCreate is a GORM hook to generate a UUID for the webhook ID before creating a record
+func (wh *Webhook) BeforeCreate(tx *gorm.DB) (err error) {
+	wh.ID = uuid.New()
+	return
+}
+
+// BeforeCreate is a GORM hook to generate a UUID for the delivery ID before creating a record
+func (d *WebhookDelivery) BeforeCreate(tx *gorm.DB) (err error) {
+	d.ID = uuid.New()
+	return
+}
+
+// EventList retorna os eventos assinados pelo webhook
+func (wh *Webhook) EventList() []string {
+	events := make([]string, 0)
+	for _, e := range strings.Split(wh.Events, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// Subscribes indica se o webhook recebe o evento
+func (wh *Webhook) Subscribes(event string) bool {
+	return slices.Contains(wh.EventList(), event)
+}
+
+// ScopeList retorna os escopos concedidos à chave
+func (k *APIKey) ScopeList() []string {
+	scopes := make([]string, 0)
+	for _, s := range strings.Split(k.Scopes, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes
+}
+
+// HasScope verifica se a chave concede o escopo; o escopo admin concede todos
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.ScopeList() {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// IsExpired indica se a chave já passou da data de expiração
+func (k *APIKey) IsExpired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// MimeTypes retorna os tipos de arquivo permitidos pelo plano
+func (p *Plan) MimeTypes() []string {
+	types := make([]string, 0)
+	for _, t := range strings.Split(p.AllowedMimeTypes, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// AllowsMimeType verifica se o plano aceita arquivos do tipo informado
+func (p *Plan) AllowsMimeType(mimeType string) bool {
+	for _, t := range p.MimeTypes() {
+		if t == mimeType {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPrivate indica se os arquivos do projeto exigem URL assinada
+func (p *Project) IsPrivate() bool {
+	return p.Visibility == VisibilityPrivate
+}
+
 // CheckPassword compara a senha fornecida com o hash armazenado
 func (u *User) CheckPassword(password string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
@@ -0,0 +1,56 @@
+package ratelimit
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PostgresBackend guarda os buckets na tabela rate_limits, compartilhada entre réplicas.
+// Cada Take é um único UPSERT atômico, usando o relógio do banco para que réplicas com
+// relógios diferentes contem o mesmo tempo.
+type PostgresBackend struct {
+	db *gorm.DB
+}
+
+func NewPostgresBackend(db *gorm.DB) *PostgresBackend {
+	return &PostgresBackend{db: db}
+}
+
+// refillSQL é a quantidade de tokens no bucket agora, sem passar do tamanho dele. No
+// SET do ON CONFLICT, rate_limits.* ainda se refere à linha antiga.
+const refillSQL = `LEAST(CAST(@burst AS double precision),
+	rate_limits.tokens + CAST(@rate AS double precision) * EXTRACT(EPOCH FROM now() - rate_limits.updated_at)::double precision)`
+
+// takeSQL repõe os tokens do tempo decorrido e consome um, se houver. Como o RETURNING só
+// enxerga a linha nova, a decisão também é gravada (allowed).
+var takeSQL = strings.ReplaceAll(`
+INSERT INTO rate_limits (key, tokens, allowed, updated_at)
+VALUES (@key, CAST(@burst AS double precision) - 1, true, now())
+ON CONFLICT (key) DO UPDATE SET
+	tokens = CASE WHEN {refill} >= 1 THEN {refill} - 1 ELSE {refill} END,
+	allowed = {refill} >= 1,
+	updated_at = now()
+RETURNING tokens, allowed`, "{refill}", refillSQL)
+
+func (p *PostgresBackend) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	var row struct {
+		Tokens  float64
+		Allowed bool
+	}
+	err := p.db.WithContext(ctx).Raw(takeSQL, map[string]interface{}{
+		"key":   key,
+		"burst": float64(limit.Requests),
+		"rate":  limit.rate(),
+	}).Scan(&row).Error
+	if err != nil {
+		return Result{}, err
+	}
+	return result(limit, row.Tokens, row.Allowed), nil
+}
+
+func (p *PostgresBackend) Prune(ctx context.Context, idle time.Duration) error {
+	return p.db.WithContext(ctx).Exec("DELETE FROM rate_limits WHERE updated_at < now() - make_interval(secs => ?)", idle.Seconds()).Error
+}
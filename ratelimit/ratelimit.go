@@ -0,0 +1,114 @@
+// Package ratelimit implementa limites de requisições por token bucket, com estado em
+// memória (uma réplica) ou no Postgres (compartilhado entre réplicas).
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Limit permite Requests requisições a cada Per, com rajadas de até Requests
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// String retorna a forma usada em configuração (ex.: "10/1m")
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s", l.Requests, l.Per)
+}
+
+// Disabled indica um limite vazio, que não restringe nada
+func (l Limit) Disabled() bool {
+	return l.Requests <= 0 || l.Per <= 0
+}
+
+// rate retorna quantos tokens voltam ao bucket por segundo
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// ParseLimit lê um limite no formato "<requisições>/<duração>", como "10/1m" ou "600/1h".
+// Vazio ou "0" desativa o limite.
+func ParseLimit(value string) (Limit, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "0" {
+		return Limit{}, nil
+	}
+	count, per, ok := strings.Cut(value, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: expected <requests>/<duration>, e.g. 10/1m", value)
+	}
+	requests, err := strconv.Atoi(count)
+	if err != nil || requests < 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: requests must be a non-negative number", value)
+	}
+	duration, err := time.ParseDuration(per)
+	if err != nil || duration <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: duration must be positive, e.g. 1m", value)
+	}
+	return Limit{Requests: requests, Per: duration}, nil
+}
+
+// Result é a decisão sobre uma requisição
+type Result struct {
+	Allowed    bool
+	Limit      int           // Tamanho do bucket
+	Remaining  int           // Requisições ainda disponíveis agora
+	RetryAfter time.Duration // Quando Allowed é falso, espera até o próximo token
+	Reset      time.Duration // Espera até o bucket voltar a ficar cheio
+}
+
+// Backend guarda os buckets. Take consome um token do bucket key, se houver.
+type Backend interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+	// Prune remove buckets sem uso há mais de idle
+	Prune(ctx context.Context, idle time.Duration) error
+}
+
+// NewBackend cria o backend pelo nome: "memory" ou "postgres"
+func NewBackend(name string, db *gorm.DB) (Backend, error) {
+	switch name {
+	case "memory", "":
+		return NewMemoryBackend(), nil
+	case "postgres":
+		return NewPostgresBackend(db), nil
+	}
+	return nil, errors.New("unknown rate limit backend: " + name)
+}
+
+// result monta a decisão a partir dos tokens que restaram no bucket
+func result(limit Limit, tokens float64, allowed bool) Result {
+	rate := limit.rate()
+	res := Result{
+		Allowed:   allowed,
+		Limit:     limit.Requests,
+		Remaining: max(int(math.Floor(tokens)), 0),
+		Reset:     time.Duration((float64(limit.Requests) - tokens) / rate * float64(time.Second)),
+	}
+	if !allowed {
+		res.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+	return res
+}
+
+// StartJanitor remove periodicamente os buckets sem uso há mais de um dia
+func StartJanitor(backend Backend, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := backend.Prune(context.Background(), 24*time.Hour); err != nil {
+				log.Printf("⚠️  Warning: Could not prune rate limit buckets: %v", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,82 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLimit(t *testing.T) {
+	l, err := ParseLimit("10/1m")
+	require.NoError(t, err)
+	assert.Equal(t, Limit{Requests: 10, Per: time.Minute}, l)
+	assert.Equal(t, "10/1m0s", l.String())
+
+	for _, disabled := range []string{"", "0", " "} {
+		l, err := ParseLimit(disabled)
+		require.NoError(t, err)
+		assert.True(t, l.Disabled())
+	}
+
+	for _, invalid := range []string{"10", "x/1m", "10/soon", "10/0s", "-1/1m"} {
+		_, err := ParseLimit(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestMemoryBackend(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	m := NewMemoryBackend()
+	m.now = func() time.Time { return now }
+	ctx := context.Background()
+	limit := Limit{Requests: 3, Per: 3 * time.Second}
+
+	// O bucket começa cheio, permitindo uma rajada do tamanho do limite
+	for i := 2; i >= 0; i-- {
+		res, err := m.Take(ctx, "k", limit)
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, i, res.Remaining)
+	}
+
+	res, _ := m.Take(ctx, "k", limit)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, time.Second, res.RetryAfter)
+	assert.Equal(t, 3*time.Second, res.Reset)
+
+	// Outras chaves têm buckets próprios
+	res, _ = m.Take(ctx, "other", limit)
+	assert.True(t, res.Allowed)
+
+	// Um token volta a cada segundo
+	now = now.Add(time.Second)
+	res, _ = m.Take(ctx, "k", limit)
+	assert.True(t, res.Allowed)
+	res, _ = m.Take(ctx, "k", limit)
+	assert.False(t, res.Allowed)
+
+	// Sem passar do tamanho do bucket
+	now = now.Add(time.Hour)
+	res, _ = m.Take(ctx, "k", limit)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 2, res.Remaining)
+}
+
+func TestMemoryBackendPrune(t *testing.T) {
+	now := time.Now()
+	m := NewMemoryBackend()
+	m.now = func() time.Time { return now }
+	ctx := context.Background()
+	limit := Limit{Requests: 1, Per: time.Hour}
+
+	m.Take(ctx, "old", limit)
+	now = now.Add(2 * time.Hour)
+	m.Take(ctx, "recent", limit)
+
+	require.NoError(t, m.Prune(ctx, time.Hour))
+	assert.NotContains(t, m.buckets, "old")
+	assert.Contains(t, m.buckets, "recent")
+}
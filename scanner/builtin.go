@@ -0,0 +1,95 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"io"
+)
+
+// eicar é o arquivo de teste padrão da indústria antivírus
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// signature é um padrão procurado em qualquer posição do conteúdo
+type signature struct {
+	name    string
+	pattern []byte
+	pdfOnly bool // Só vale para arquivos que começam com %PDF
+}
+
+var signatures = []signature{
+	{name: "Eicar-Test-Signature", pattern: []byte(eicar)},
+	// Imagens poliglotas: código PHP escondido em metadados ou após o fim da imagem
+	{name: "Heuristics.Embedded.PHP", pattern: []byte("<?php")},
+	// PDFs que executam scripts ou programas ao serem abertos
+	{name: "Heuristics.PDF.JavaScript", pattern: []byte("/JavaScript"), pdfOnly: true},
+	{name: "Heuristics.PDF.Launch", pattern: []byte("/Launch"), pdfOnly: true},
+}
+
+// magics são executáveis reconhecidos pelo início do arquivo
+var magics = []signature{
+	{name: "Heuristics.Executable.PE", pattern: []byte("MZ")},
+	{name: "Heuristics.Executable.ELF", pattern: []byte("\x7fELF")},
+	{name: "Heuristics.Executable.MachO", pattern: []byte{0xcf, 0xfa, 0xed, 0xfe}},
+	{name: "Heuristics.Executable.MachO", pattern: []byte{0xce, 0xfa, 0xed, 0xfe}},
+}
+
+// BuiltinScanner procura a assinatura EICAR, executáveis e construções perigosas comuns
+// em uploads (PHP embutido, PDFs com JavaScript ou /Launch). Não substitui um antivírus
+// completo, mas funciona sem dependências externas.
+type BuiltinScanner struct{}
+
+// NewBuiltinScanner cria o scanner embutido
+func NewBuiltinScanner() *BuiltinScanner {
+	return &BuiltinScanner{}
+}
+
+// Name identifica o backend nos logs
+func (b *BuiltinScanner) Name() string { return "builtin" }
+
+// Scan lê o conteúdo em blocos, mantendo uma sobreposição do tamanho do maior padrão
+// para encontrar assinaturas que cruzam a fronteira entre dois blocos
+func (b *BuiltinScanner) Scan(ctx context.Context, r io.Reader) (Result, error) {
+	overlap := 0
+	for _, s := range signatures {
+		overlap = max(overlap, len(s.pattern)-1)
+	}
+
+	buf := make([]byte, 64*1024+overlap)
+	carry, first, isPDF := 0, true, false
+	for {
+		if err := ctx.Err(); err != nil {
+			return Result{}, err
+		}
+		n, readErr := io.ReadFull(r, buf[carry:])
+		window := buf[:carry+n]
+
+		if first && len(window) > 0 {
+			first = false
+			for _, m := range magics {
+				if bytes.HasPrefix(window, m.pattern) {
+					return Result{Infected: true, Signature: m.name}, nil
+				}
+			}
+			isPDF = bytes.HasPrefix(window, []byte("%PDF"))
+		}
+
+		for _, s := range signatures {
+			if s.pdfOnly && !isPDF {
+				continue
+			}
+			if bytes.Contains(window, s.pattern) {
+				return Result{Infected: true, Signature: s.name}, nil
+			}
+		}
+
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			return Result{}, nil
+		}
+		if readErr != nil {
+			return Result{}, readErr
+		}
+
+		carry = min(overlap, len(window))
+		copy(buf, window[len(window)-carry:])
+	}
+}
@@ -0,0 +1,98 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// clamdChunkSize é o tamanho de cada bloco enviado no INSTREAM
+const clamdChunkSize = 64 * 1024
+
+// ClamdScanner envia o conteúdo a um clamd pelo comando INSTREAM via TCP
+type ClamdScanner struct {
+	addr    string
+	timeout time.Duration
+}
+
+// NewClamdScanner cria um cliente para o clamd em addr (ex.: "localhost:3310")
+func NewClamdScanner(addr string, timeout time.Duration) *ClamdScanner {
+	return &ClamdScanner{addr: addr, timeout: timeout}
+}
+
+// Name identifica o backend nos logs
+func (c *ClamdScanner) Name() string { return "clamd" }
+
+// Scan envia o conteúdo em blocos prefixados pelo tamanho (uint32 big-endian), termina
+// com um bloco vazio e lê a resposta: "stream: OK" ou "stream: <assinatura> FOUND".
+func (c *ClamdScanner) Scan(ctx context.Context, r io.Reader) (Result, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return Result{}, fmt.Errorf("clamd: %w", err)
+	}
+	defer conn.Close()
+	deadline, ok := ctx.Deadline()
+	if c.timeout > 0 && (!ok || time.Now().Add(c.timeout).Before(deadline)) {
+		deadline, ok = time.Now().Add(c.timeout), true
+	}
+	if ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return Result{}, fmt.Errorf("clamd: %w", err)
+	}
+
+	buf := make([]byte, clamdChunkSize)
+	header := make([]byte, 4)
+	for {
+		n, readErr := r.Read(buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(header, uint32(n))
+			if _, err := conn.Write(header); err != nil {
+				return Result{}, fmt.Errorf("clamd: %w", err)
+			}
+			if _, err := conn.Write(buf[:n]); err != nil {
+				// O clamd fecha a conexão ao passar de StreamMaxLength; a resposta explica o motivo
+				break
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return Result{}, readErr
+		}
+	}
+	binary.BigEndian.PutUint32(header, 0)
+	conn.Write(header)
+
+	reply, err := bufio.NewReader(conn).ReadBytes(0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return Result{}, fmt.Errorf("clamd: %w", err)
+	}
+	return parseClamdReply(string(bytes.TrimRight(reply, "\x00\n")))
+}
+
+// parseClamdReply interpreta a resposta de uma verificação INSTREAM
+func parseClamdReply(reply string) (Result, error) {
+	reply = strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case reply == "OK":
+		return Result{}, nil
+	case strings.HasSuffix(reply, " FOUND"):
+		return Result{Infected: true, Signature: strings.TrimSuffix(reply, " FOUND")}, nil
+	case reply == "":
+		return Result{}, errors.New("clamd: empty reply")
+	default:
+		return Result{}, fmt.Errorf("clamd: %s", reply)
+	}
+}
@@ -0,0 +1,41 @@
+// Package scanner procura malware no conteúdo enviado. O backend é escolhido em
+// SCANNER: o cliente clamd (ClamAV) ou o scanner embutido de assinaturas e heurísticas.
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/config"
+)
+
+// Result é o veredito de uma verificação
+type Result struct {
+	Infected  bool
+	Signature string // Nome da assinatura ou heurística encontrada quando Infected
+}
+
+// Scanner é a interface comum aos backends de verificação
+type Scanner interface {
+	// Scan lê todo o conteúdo de r. Um erro significa que não foi possível verificar,
+	// não que o conteúdo é suspeito.
+	Scan(ctx context.Context, r io.Reader) (Result, error)
+	// Name identifica o backend nos logs
+	Name() string
+}
+
+// New cria o scanner configurado em cfg.Scanner
+func New(cfg *config.Config) (Scanner, error) {
+	switch strings.ToLower(cfg.Scanner) {
+	case "", "builtin":
+		return NewBuiltinScanner(), nil
+	case "clamd", "clamav":
+		return NewClamdScanner(cfg.ClamdAddress, cfg.ClamdTimeout), nil
+	case "none":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("scanner: unknown backend %q", cfg.Scanner)
+	}
+}
@@ -0,0 +1,146 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClamd é um substituto mínimo do clamd: entende apenas o comando zINSTREAM e
+// responde FOUND quando o conteúdo contém a assinatura EICAR.
+type fakeClamd struct {
+	listener net.Listener
+	maxSize  int // Simula StreamMaxLength; zero é ilimitado
+}
+
+func newFakeClamd(t *testing.T, maxSize int) *fakeClamd {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	f := &fakeClamd{listener: l, maxSize: maxSize}
+	go f.serve()
+	t.Cleanup(func() { l.Close() })
+	return f
+}
+
+func (f *fakeClamd) addr() string { return f.listener.Addr().String() }
+
+func (f *fakeClamd) serve() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeClamd) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	cmd, err := r.ReadString(0)
+	if err != nil {
+		return
+	}
+	if cmd != "zINSTREAM\x00" {
+		conn.Write([]byte("UNKNOWN COMMAND\x00"))
+		return
+	}
+
+	var data bytes.Buffer
+	for {
+		var size uint32
+		if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+			return
+		}
+		if size == 0 {
+			break
+		}
+		if _, err := io.CopyN(&data, r, int64(size)); err != nil {
+			return
+		}
+		if f.maxSize > 0 && data.Len() > f.maxSize {
+			conn.Write([]byte("INSTREAM size limit exceeded. ERROR\x00"))
+			return
+		}
+	}
+
+	if bytes.Contains(data.Bytes(), []byte(eicar)) {
+		conn.Write([]byte("stream: Win.Test.EICAR_HDB-1 FOUND\x00"))
+		return
+	}
+	conn.Write([]byte("stream: OK\x00"))
+}
+
+func TestClamdScanner(t *testing.T) {
+	clamd := newFakeClamd(t, 0)
+	s := NewClamdScanner(clamd.addr(), 5*time.Second)
+
+	// Conteúdo maior que um bloco, com a assinatura no segundo
+	infected := strings.Repeat("a", clamdChunkSize+10) + eicar
+	res, err := s.Scan(context.Background(), strings.NewReader(infected))
+	require.NoError(t, err)
+	assert.True(t, res.Infected)
+	assert.Equal(t, "Win.Test.EICAR_HDB-1", res.Signature)
+
+	res, err = s.Scan(context.Background(), strings.NewReader("%PDF-1.7 clean"))
+	require.NoError(t, err)
+	assert.False(t, res.Infected)
+}
+
+func TestClamdScannerErrors(t *testing.T) {
+	clamd := newFakeClamd(t, 10)
+	s := NewClamdScanner(clamd.addr(), 5*time.Second)
+	_, err := s.Scan(context.Background(), strings.NewReader(strings.Repeat("a", 100)))
+	assert.ErrorContains(t, err, "size limit exceeded")
+
+	// Sem clamd não há veredito: o erro deve ser tratado como "tente de novo"
+	clamd.listener.Close()
+	_, err = s.Scan(context.Background(), strings.NewReader("x"))
+	assert.Error(t, err)
+}
+
+func TestBuiltinScanner(t *testing.T) {
+	s := NewBuiltinScanner()
+	tests := []struct {
+		name      string
+		content   string
+		signature string
+	}{
+		{"clean image", "\x89PNG\r\n\x1a\n" + strings.Repeat("\x00", 1000), ""},
+		{"eicar", eicar, "Eicar-Test-Signature"},
+		{"eicar across chunks", strings.Repeat("b", 64*1024-10) + eicar, "Eicar-Test-Signature"},
+		{"windows executable", "MZ\x90\x00\x03", "Heuristics.Executable.PE"},
+		{"elf executable", "\x7fELF\x02\x01", "Heuristics.Executable.ELF"},
+		{"php in image", "\xff\xd8\xff\xe0 <?php system($_GET['c']); ?>", "Heuristics.Embedded.PHP"},
+		{"pdf with javascript", "%PDF-1.7\n<< /S /JavaScript /JS (app.alert(1)) >>", "Heuristics.PDF.JavaScript"},
+		{"javascript outside pdf", "notes about /JavaScript", ""},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := s.Scan(context.Background(), strings.NewReader(tt.content))
+			require.NoError(t, err)
+			assert.Equal(t, tt.signature != "", res.Infected)
+			assert.Equal(t, tt.signature, res.Signature)
+		})
+	}
+}
+
+func TestParseClamdReply(t *testing.T) {
+	res, err := parseClamdReply("stream: OK")
+	assert.NoError(t, err)
+	assert.False(t, res.Infected)
+
+	_, err = parseClamdReply("")
+	assert.Error(t, err)
+}
@@ -0,0 +1,181 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LocalDriver armazena objetos no sistema de arquivos local
+type LocalDriver struct {
+	root string
+}
+
+// NewLocalDriver cria um driver local com raiz em root, criando o diretório se necessário
+func NewLocalDriver(root string) (*LocalDriver, error) {
+	if err := os.MkdirAll(root, os.ModePerm); err != nil {
+		return nil, err
+	}
+	return &LocalDriver{root: root}, nil
+}
+
+func (d *LocalDriver) fullPath(key string) (string, string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", "", err
+	}
+	return key, filepath.Join(d.root, filepath.FromSlash(key)), nil
+}
+
+// Put grava o objeto em um arquivo temporário e o renomeia, para que leitores
+// concorrentes nunca vejam um arquivo pela metade.
+func (d *LocalDriver) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	_, fullPath, err := d.fullPath(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(fullPath), os.ModePerm); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(fullPath), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // Sem efeito após o rename
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), fullPath)
+}
+
+// Get abre o arquivo. O *os.File retornado implementa io.ReadSeeker.
+func (d *LocalDriver) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	_, fullPath, err := d.fullPath(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(fullPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info, err := f.Stat(); err == nil && info.IsDir() {
+		f.Close()
+		return nil, ErrNotExist
+	}
+	return f, nil
+}
+
+// Delete remove o arquivo e os diretórios pais que ficarem vazios
+func (d *LocalDriver) Delete(ctx context.Context, key string) error {
+	_, fullPath, err := d.fullPath(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(fullPath); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return ErrNotExist
+		}
+		return err
+	}
+
+	for dir := filepath.Dir(fullPath); ; dir = filepath.Dir(dir) {
+		rel, err := filepath.Rel(d.root, dir)
+		if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			break
+		}
+		if os.Remove(dir) != nil {
+			break // Diretório não está vazio
+		}
+	}
+	return nil
+}
+
+// Stat retorna os metadados do arquivo
+func (d *LocalDriver) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	key, fullPath, err := d.fullPath(key)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	info, err := os.Stat(fullPath)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && info.IsDir()) {
+		return ObjectInfo{}, ErrNotExist
+	}
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	return ObjectInfo{
+		Key:         key,
+		Size:        info.Size(),
+		ContentType: mime.TypeByExtension(path.Ext(key)),
+		ModTime:     info.ModTime(),
+	}, nil
+}
+
+// List percorre a raiz a partir do diretório mais profundo contido em prefix
+func (d *LocalDriver) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	prefix = strings.ReplaceAll(prefix, `\`, "/")
+	startDir := ""
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		startDir = prefix[:i]
+	}
+	if startDir != "" {
+		if _, err := cleanKey(startDir); err != nil {
+			return nil, err
+		}
+	}
+
+	objects := make([]ObjectInfo, 0)
+	walkRoot := filepath.Join(d.root, filepath.FromSlash(startDir))
+	err := filepath.WalkDir(walkRoot, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".upload-") {
+			return nil
+		}
+		rel, err := filepath.Rel(d.root, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, ObjectInfo{
+			Key:         key,
+			Size:        info.Size(),
+			ContentType: mime.TypeByExtension(path.Ext(key)),
+			ModTime:     info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
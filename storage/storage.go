@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/config"
+)
+
+// ErrNotExist é retornado quando o objeto solicitado não existe no driver
+var ErrNotExist = errors.New("storage: object does not exist")
+
+// ErrInvalidKey é retornado quando a chave do objeto é vazia ou tenta escapar da raiz
+var ErrInvalidKey = errors.New("storage: invalid object key")
+
+// ObjectInfo descreve um objeto armazenado
+type ObjectInfo struct {
+	Key         string
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
+// Driver é a interface comum a todos os backends de armazenamento.
+// As chaves usam "/" como separador, por exemplo "user_<id>/<projeto>/<arquivo>".
+type Driver interface {
+	// Put grava o conteúdo de r na chave informada. size pode ser -1 quando desconhecido.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get abre o objeto para leitura. O chamador deve fechar o reader.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete remove o objeto. Retorna ErrNotExist se ele não existir.
+	Delete(ctx context.Context, key string) error
+	// Stat retorna os metadados do objeto sem ler o conteúdo.
+	Stat(ctx context.Context, key string) (ObjectInfo, error)
+	// List retorna todos os objetos cuja chave começa com prefix.
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+}
+
+// New cria o driver configurado em cfg.StorageDriver
+func New(cfg *config.Config) (Driver, error) {
+	switch strings.ToLower(cfg.StorageDriver) {
+	case "", "local":
+		return NewLocalDriver(cfg.StorageLocalPath)
+	case "s3", "minio":
+		return NewS3Driver(S3Options{
+			Endpoint:     cfg.S3Endpoint,
+			Region:       cfg.S3Region,
+			Bucket:       cfg.S3Bucket,
+			AccessKey:    cfg.S3AccessKey,
+			SecretKey:    cfg.S3SecretKey,
+			UsePathStyle: cfg.S3UsePathStyle,
+		})
+	default:
+		return nil, fmt.Errorf("storage: unknown driver %q", cfg.StorageDriver)
+	}
+}
+
+// cleanKey normaliza a chave e rejeita caminhos absolutos ou com ".."
+func cleanKey(key string) (string, error) {
+	key = strings.ReplaceAll(key, `\`, "/")
+	if key == "" || strings.HasPrefix(key, "/") {
+		return "", ErrInvalidKey
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == ".." {
+			return "", ErrInvalidKey
+		}
+	}
+	cleaned := path.Clean(key)
+	if cleaned == "." {
+		return "", ErrInvalidKey
+	}
+	return cleaned, nil
+}
@@ -0,0 +1,205 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	fakeAccessKey = "minioadmin"
+	fakeSecretKey = "minioadmin-secret"
+	fakeBucket    = "forge"
+)
+
+// fakeS3 é um substituto mínimo do MinIO: guarda objetos em memória e valida a
+// assinatura SigV4 de cada requisição path-style.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]fakeObject
+}
+
+type fakeObject struct {
+	data        []byte
+	contentType string
+	modTime     time.Time
+}
+
+func newFakeS3(t *testing.T) *httptest.Server {
+	f := &fakeS3{objects: map[string]fakeObject{}}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !f.validSignature(r) {
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, "<Error><Code>SignatureDoesNotMatch</Code></Error>")
+		return
+	}
+
+	bucketPrefix := "/" + fakeBucket + "/"
+	if !strings.HasPrefix(r.URL.Path, bucketPrefix) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, bucketPrefix)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodGet && key == "" && r.URL.Query().Get("list-type") == "2":
+		f.list(w, r.URL.Query().Get("prefix"))
+	case r.Method == http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.objects[key] = fakeObject{data: data, contentType: r.Header.Get("Content-Type"), modTime: time.Now()}
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		obj, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", obj.contentType)
+		w.Header().Set("Last-Modified", obj.modTime.UTC().Format(http.TimeFormat))
+		w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+		if r.Method == http.MethodGet {
+			w.Write(obj.data)
+		}
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *fakeS3) list(w http.ResponseWriter, prefix string) {
+	type content struct {
+		Key          string
+		Size         int64
+		LastModified time.Time
+	}
+	result := struct {
+		XMLName     xml.Name `xml:"ListBucketResult"`
+		Contents    []content
+		IsTruncated bool
+	}{}
+	for key, obj := range f.objects {
+		if strings.HasPrefix(key, prefix) {
+			result.Contents = append(result.Contents, content{Key: key, Size: int64(len(obj.data)), LastModified: obj.modTime})
+		}
+	}
+	sort.Slice(result.Contents, func(i, j int) bool { return result.Contents[i].Key < result.Contents[j].Key })
+	xml.NewEncoder(w).Encode(result)
+}
+
+// validSignature recalcula a assinatura a partir do que chegou no servidor
+func (f *fakeS3) validSignature(r *http.Request) bool {
+	auth := r.Header.Get("Authorization")
+	amzDate := r.Header.Get("X-Amz-Date")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential="+fakeAccessKey+"/") || len(amzDate) != 16 {
+		return false
+	}
+	scope := amzDate[:8] + "/us-east-1/s3/aws4_request"
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		canonicalQuery(r.URL.Query()),
+		"host:" + r.Host + "\nx-amz-content-sha256:" + r.Header.Get("X-Amz-Content-Sha256") + "\nx-amz-date:" + amzDate + "\n",
+		"host;x-amz-content-sha256;x-amz-date",
+		r.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hexSHA256([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+fakeSecretKey), amzDate[:8])
+	key = hmacSHA256(key, "us-east-1")
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return strings.HasSuffix(auth, "Signature="+hex.EncodeToString(hmacSHA256(key, stringToSign)))
+}
+
+func newTestDrivers(t *testing.T) map[string]Driver {
+	local, err := NewLocalDriver(t.TempDir())
+	require.NoError(t, err)
+
+	srv := newFakeS3(t)
+	s3, err := NewS3Driver(S3Options{
+		Endpoint:     srv.URL,
+		Bucket:       fakeBucket,
+		AccessKey:    fakeAccessKey,
+		SecretKey:    fakeSecretKey,
+		UsePathStyle: true,
+	})
+	require.NoError(t, err)
+
+	return map[string]Driver{"local": local, "s3": s3}
+}
+
+func TestDriverRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	for name, driver := range newTestDrivers(t) {
+		t.Run(name, func(t *testing.T) {
+			content := []byte("%PDF-1.4 conteúdo de teste")
+			key := "user_1/meu projeto/relatório (final).pdf"
+
+			require.NoError(t, driver.Put(ctx, key, bytes.NewReader(content), int64(len(content)), "application/pdf"))
+			require.NoError(t, driver.Put(ctx, "user_1/outro/logo.png", strings.NewReader("png"), -1, "image/png"))
+			require.NoError(t, driver.Put(ctx, "user_2/default/vazio.txt", strings.NewReader(""), 0, "text/plain"))
+
+			info, err := driver.Stat(ctx, key)
+			require.NoError(t, err)
+			assert.Equal(t, int64(len(content)), info.Size)
+			assert.Equal(t, "application/pdf", info.ContentType)
+
+			rc, err := driver.Get(ctx, key)
+			require.NoError(t, err)
+			got, err := io.ReadAll(rc)
+			rc.Close()
+			require.NoError(t, err)
+			assert.Equal(t, content, got)
+
+			objects, err := driver.List(ctx, "user_1/")
+			require.NoError(t, err)
+			keys := make([]string, 0, len(objects))
+			for _, o := range objects {
+				keys = append(keys, o.Key)
+			}
+			assert.Equal(t, []string{"user_1/meu projeto/relatório (final).pdf", "user_1/outro/logo.png"}, keys)
+
+			require.NoError(t, driver.Delete(ctx, key))
+			_, err = driver.Stat(ctx, key)
+			assert.True(t, errors.Is(err, ErrNotExist))
+			_, err = driver.Get(ctx, key)
+			assert.True(t, errors.Is(err, ErrNotExist))
+			assert.True(t, errors.Is(driver.Delete(ctx, key), ErrNotExist))
+		})
+	}
+}
+
+func TestDriverRejectsInvalidKeys(t *testing.T) {
+	ctx := context.Background()
+	for name, driver := range newTestDrivers(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, key := range []string{"", "/etc/passwd", "../fora.txt", "user_1/../../fora.txt"} {
+				err := driver.Put(ctx, key, strings.NewReader("x"), 1, "text/plain")
+				assert.True(t, errors.Is(err, ErrInvalidKey), "key %q", key)
+			}
+		})
+	}
+}
@@ -1,6 +1,12 @@
 package util
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/config"
@@ -16,19 +22,99 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
-// GenerateJWT cria um novo token JWT para um usuário
-func GenerateJWT(user *models.User) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour)
+// GenerateJWT cria um novo access token para um usuário. Cada token recebe um jti
+// único, usado para revogá-lo antes da expiração.
+func GenerateJWT(user *models.User) (string, *Claims, error) {
+	now := time.Now()
 	claims := &Claims{
 		UserID: user.ID,
 		Email:  user.Email,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        uuid.New().String(),
+			ExpiresAt: jwt.NewNumericDate(now.Add(config.AppConfig.AccessTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
 			Issuer:    "forge-uploader",
 		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(config.AppConfig.JWTSecret))
+	signed, err := token.SignedString([]byte(config.AppConfig.JWTSecret))
+	if err != nil {
+		return "", nil, err
+	}
+	return signed, claims, nil
+}
+
+// ParseJWT valida a assinatura e a expiração de um access token e retorna suas claims.
+// Tokens sem jti (emitidos antes da revogação existir) são recusados.
+func ParseJWT(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(config.AppConfig.JWTSecret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return nil, err
+	}
+	if claims.ID == "" {
+		return nil, errors.New("token has no jti")
+	}
+	return claims, nil
+}
+
+// GenerateOpaqueToken gera um token aleatório (ex.: refresh token) e o hash que deve
+// ser guardado no banco. O valor em texto só é mostrado ao cliente.
+func GenerateOpaqueToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashToken(token), nil
+}
+
+// HashToken retorna o SHA-256 (hex) de um token opaco
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKeyPrefix identifica as chaves de API emitidas pelo modelo APIKey
+const APIKeyPrefix = "fk_"
+
+// GenerateAPIKey gera uma nova chave de API, o prefixo público usado para localizá-la
+// e o hash que deve ser guardado no banco
+func GenerateAPIKey() (key, prefix, hash string, err error) {
+	token, _, err := GenerateOpaqueToken()
+	if err != nil {
+		return "", "", "", err
+	}
+	key = APIKeyPrefix + token
+	return key, APIKeyLookupPrefix(key), HashToken(key), nil
+}
+
+// APIKeyLookupPrefix retorna a parte pública de uma chave: "fk_" mais 8 caracteres nas
+// chaves atuais, ou os 8 primeiros caracteres das antigas ForgeAPIKey (UUIDs)
+func APIKeyLookupPrefix(key string) string {
+	n := 8
+	if strings.HasPrefix(key, APIKeyPrefix) {
+		n += len(APIKeyPrefix)
+	}
+	if len(key) < n {
+		return key
+	}
+	return key[:n]
+}
+
+// LockoutDuration retorna por quanto tempo bloquear o login depois da failures-ésima falha
+// seguida: a cada threshold falhas há um bloqueio de base, dobrando a cada novo bloqueio
+// até maxDuration. Retorna zero quando esta falha não gera bloqueio.
+func LockoutDuration(failures, threshold int, base, maxDuration time.Duration) time.Duration {
+	if threshold <= 0 || failures <= 0 || failures%threshold != 0 {
+		return 0
+	}
+	d := base
+	for i := 1; i < failures/threshold && d < maxDuration; i++ {
+		d *= 2
+	}
+	return min(d, maxDuration)
 }
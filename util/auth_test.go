@@ -0,0 +1,73 @@
+package util
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/config"
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/models"
+)
+
+func TestGenerateAndParseJWT(t *testing.T) {
+	config.AppConfig = &config.Config{JWTSecret: "test-secret", AccessTokenTTL: 15 * time.Minute}
+	user := &models.User{ID: uuid.New(), Email: "test@example.com"}
+
+	token, claims, err := GenerateJWT(user)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, claims.ID)
+
+	parsed, err := ParseJWT(token)
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, parsed.UserID)
+	assert.Equal(t, claims.ID, parsed.ID)
+
+	// Tokens antigos, sem jti, não podem ser revogados e por isso são recusados
+	legacy, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{
+		UserID:           user.ID,
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))},
+	}).SignedString([]byte("test-secret"))
+	_, err = ParseJWT(legacy)
+	assert.Error(t, err)
+
+	config.AppConfig.JWTSecret = "other-secret"
+	_, err = ParseJWT(token)
+	assert.Error(t, err)
+}
+
+func TestGenerateOpaqueToken(t *testing.T) {
+	token, hash, err := GenerateOpaqueToken()
+	assert.NoError(t, err)
+	assert.Equal(t, HashToken(token), hash)
+	assert.NotEqual(t, token, hash)
+
+	other, _, _ := GenerateOpaqueToken()
+	assert.NotEqual(t, token, other)
+}
+
+func TestGenerateAPIKey(t *testing.T) {
+	key, prefix, hash, err := GenerateAPIKey()
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key, APIKeyPrefix))
+	assert.Equal(t, APIKeyLookupPrefix(key), prefix)
+	assert.Len(t, prefix, len(APIKeyPrefix)+8)
+	assert.Equal(t, HashToken(key), hash)
+
+	// As ForgeAPIKey antigas (UUIDs) migradas usam os 8 primeiros caracteres
+	assert.Equal(t, "3f2a9c1e", APIKeyLookupPrefix("3f2a9c1e-7b4d-4e8a-9f0c-1d2e3f4a5b6c"))
+}
+
+func TestLockoutDuration(t *testing.T) {
+	base, maxDuration := 30*time.Minute, 24*time.Hour
+	assert.Zero(t, LockoutDuration(9, 10, base, maxDuration))
+	assert.Equal(t, 30*time.Minute, LockoutDuration(10, 10, base, maxDuration))
+	assert.Zero(t, LockoutDuration(11, 10, base, maxDuration))
+	assert.Equal(t, time.Hour, LockoutDuration(20, 10, base, maxDuration))
+	assert.Equal(t, 2*time.Hour, LockoutDuration(30, 10, base, maxDuration))
+	assert.Equal(t, maxDuration, LockoutDuration(1000, 10, base, maxDuration))
+	assert.Zero(t, LockoutDuration(10, 0, base, maxDuration))
+}
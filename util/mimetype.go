@@ -0,0 +1,63 @@
+package util
+
+import (
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SniffLen é a quantidade de bytes iniciais analisada por DetectMimeType
+const SniffLen = 512
+
+// mimeExtensions lista as extensões aceitas para cada tipo detectado pelo conteúdo
+var mimeExtensions = map[string][]string{
+	"image/jpeg":      {".jpg", ".jpeg", ".jpe", ".jfif"},
+	"image/png":       {".png"},
+	"image/gif":       {".gif"},
+	"image/webp":      {".webp"},
+	"application/pdf": {".pdf"},
+}
+
+// DetectMimeType identifica o tipo do arquivo pelos primeiros bytes (magic bytes),
+// ignorando o que o cliente declarou. Retorna "application/octet-stream" quando
+// o conteúdo não é reconhecido.
+func DetectMimeType(head []byte) string {
+	if len(head) > SniffLen {
+		head = head[:SniffLen]
+	}
+	mimeType := http.DetectContentType(head)
+	if i := strings.Index(mimeType, ";"); i >= 0 {
+		mimeType = mimeType[:i] // Remove parâmetros como "; charset=utf-8"
+	}
+	return mimeType
+}
+
+// MimeTypeForExtension retorna o tipo esperado para a extensão do arquivo, ou "" se desconhecida
+func MimeTypeForExtension(filename string) string {
+	ext := strings.ToLower(filepath.Ext(filename))
+	for mimeType, exts := range mimeExtensions {
+		for _, e := range exts {
+			if e == ext {
+				return mimeType
+			}
+		}
+	}
+	return ""
+}
+
+// SupportedMimeTypes lista, em ordem alfabética, os tipos que podem ser aceitos em um
+// plano: os que têm extensões conhecidas
+func SupportedMimeTypes() []string {
+	types := make([]string, 0, len(mimeExtensions))
+	for mimeType := range mimeExtensions {
+		types = append(types, mimeType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// ExtensionMatchesMimeType verifica se a extensão do arquivo é compatível com o tipo detectado
+func ExtensionMatchesMimeType(filename, mimeType string) bool {
+	return MimeTypeForExtension(filename) == mimeType
+}
@@ -0,0 +1,56 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectMimeType(t *testing.T) {
+	tests := []struct {
+		name string
+		head []byte
+		want string
+	}{
+		{"png", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), "image/png"},
+		{"jpeg", []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00"), "image/jpeg"},
+		{"pdf", []byte("%PDF-1.7\n%\xe2\xe3\xcf\xd3"), "application/pdf"},
+		{"windows executable", []byte("MZ\x90\x00\x03\x00\x00\x00\x04\x00\x00\x00\xff\xff"), "application/octet-stream"},
+		{"elf executable", []byte("\x7fELF\x02\x01\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00"), "application/octet-stream"},
+		{"plain text", []byte("hello world"), "text/plain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, DetectMimeType(tt.head))
+		})
+	}
+}
+
+func TestExtensionMatchesMimeType(t *testing.T) {
+	tests := []struct {
+		filename string
+		mimeType string
+		want     bool
+	}{
+		{"logo.png", "image/png", true},
+		{"FOTO.JPG", "image/jpeg", true},
+		{"foto.jpeg", "image/jpeg", true},
+		{"contrato.pdf", "application/pdf", true},
+		{"logo.png", "image/jpeg", false},
+		{"virus.exe", "application/octet-stream", false},
+		{"sem-extensao", "image/png", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filename+"/"+tt.mimeType, func(t *testing.T) {
+			assert.Equal(t, tt.want, ExtensionMatchesMimeType(tt.filename, tt.mimeType))
+		})
+	}
+}
+
+func TestSupportedMimeTypes(t *testing.T) {
+	types := SupportedMimeTypes()
+	assert.Equal(t, []string{"application/pdf", "image/gif", "image/jpeg", "image/png", "image/webp"}, types)
+	for _, mimeType := range types {
+		assert.NotEmpty(t, mimeExtensions[mimeType])
+	}
+}
@@ -0,0 +1,60 @@
+package util
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/golang-samples/run/helloworld/config"
+)
+
+// ClientIP retorna o IP do cliente. Por padrão é o endereço da conexão; X-Forwarded-For só
+// é considerado quando a conexão vem de um proxy confiável (TRUSTED_PROXIES), já que
+// qualquer cliente pode enviar o header.
+func ClientIP(r *http.Request) string {
+	var trusted []*net.IPNet
+	if config.AppConfig != nil {
+		trusted = config.AppConfig.TrustedProxies
+	}
+	return clientIP(r, trusted)
+}
+
+// clientIP lê X-Forwarded-For da direita para a esquerda, pulando os proxies confiáveis:
+// o primeiro endereço que não é de um deles foi anotado por um proxy confiável e é o do
+// cliente. Os valores mais à esquerda são informados pelo próprio cliente e são ignorados.
+func clientIP(r *http.Request, trusted []*net.IPNet) string {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		ip = host
+	}
+	if !isTrustedProxy(ip, trusted) {
+		return ip
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			// Um valor inválido não pode ter sido anotado por um proxy confiável
+			break
+		}
+		ip = hop.String()
+		if !isTrustedProxy(ip, trusted) {
+			break
+		}
+	}
+	return ip
+}
+
+// isTrustedProxy informa se o IP pertence a uma das redes de proxies confiáveis
+func isTrustedProxy(ip string, trusted []*net.IPNet) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, network := range trusted {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package util
+
+import (
+	"net"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientIP(t *testing.T) {
+	_, proxies, _ := net.ParseCIDR("10.0.0.0/8")
+	trusted := []*net.IPNet{proxies}
+
+	request := func(remoteAddr string, forwarded ...string) string {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = remoteAddr
+		for _, f := range forwarded {
+			r.Header.Add("X-Forwarded-For", f)
+		}
+		return clientIP(r, trusted)
+	}
+
+	// Sem proxy confiável, o header enviado pelo cliente é ignorado
+	assert.Equal(t, "203.0.113.7", request("203.0.113.7:5123", "198.51.100.1"))
+	forged := httptest.NewRequest("GET", "/", nil)
+	forged.RemoteAddr = "10.0.0.2:80"
+	forged.Header.Set("X-Forwarded-For", "198.51.100.1")
+	assert.Equal(t, "10.0.0.2", clientIP(forged, nil))
+	assert.Equal(t, "192.0.2.1", request("192.0.2.1:80"))
+
+	// Atrás de proxies confiáveis, vale o primeiro endereço não confiável da direita
+	assert.Equal(t, "198.51.100.1", request("10.0.0.2:80", "198.51.100.1"))
+	assert.Equal(t, "198.51.100.1", request("10.0.0.2:80", "6.6.6.6, 198.51.100.1, 10.0.0.3"))
+	assert.Equal(t, "198.51.100.1", request("10.0.0.2:80", "6.6.6.6", "198.51.100.1"))
+
+	// Sem header ou com valores inválidos, vale o último proxy conhecido
+	assert.Equal(t, "10.0.0.2", request("10.0.0.2:80"))
+	assert.Equal(t, "10.0.0.3", request("10.0.0.2:80", "garbage, 10.0.0.3"))
+}
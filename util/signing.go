@@ -0,0 +1,99 @@
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrSignatureMissing indica que a URL não traz os parâmetros de assinatura
+	ErrSignatureMissing = errors.New("signature required")
+	// ErrSignatureInvalid indica que a assinatura não confere com o caminho e a expiração
+	ErrSignatureInvalid = errors.New("invalid signature")
+	// ErrSignatureExpired indica que a URL assinada já expirou
+	ErrSignatureExpired = errors.New("signature expired")
+)
+
+// urlSignature calcula o HMAC-SHA256 de "<caminho>\n<expiração unix>"
+func urlSignature(secret, path string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(path + "\n" + strconv.FormatInt(expires, 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// SignURLPath retorna a query string (expires=...&signature=...) que autoriza o acesso
+// ao caminho informado até o instante de expiração
+func SignURLPath(secret, path string, expiresAt time.Time) string {
+	expires := expiresAt.Unix()
+	q := url.Values{}
+	q.Set("expires", strconv.FormatInt(expires, 10))
+	q.Set("signature", urlSignature(secret, path, expires))
+	return q.Encode()
+}
+
+// VerifyURLSignature confere a assinatura e a expiração presentes na query de uma URL assinada
+func VerifyURLSignature(secret, path string, query url.Values, now time.Time) error {
+	expiresParam, signature := query.Get("expires"), query.Get("signature")
+	if expiresParam == "" || signature == "" {
+		return ErrSignatureMissing
+	}
+	expires, err := strconv.ParseInt(expiresParam, 10, 64)
+	if err != nil {
+		return ErrSignatureInvalid
+	}
+	if !hmac.Equal([]byte(signature), []byte(urlSignature(secret, path, expires))) {
+		return ErrSignatureInvalid
+	}
+	if now.Unix() > expires {
+		return ErrSignatureExpired
+	}
+	return nil
+}
+
+// webhookSignature calcula o HMAC-SHA256 (hex) de "<timestamp unix>.<corpo>"
+func webhookSignature(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignWebhookPayload retorna o valor do cabeçalho X-Forge-Signature de uma entrega de
+// webhook: "t=<timestamp unix>,v1=<assinatura>"
+func SignWebhookPayload(secret string, body []byte, now time.Time) string {
+	timestamp := now.Unix()
+	return "t=" + strconv.FormatInt(timestamp, 10) + ",v1=" + webhookSignature(secret, timestamp, body)
+}
+
+// VerifyWebhookSignature confere o cabeçalho X-Forge-Signature de uma entrega, recusando
+// assinaturas mais antigas que tolerance para impedir que entregas sejam reenviadas
+func VerifyWebhookSignature(secret, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var timestamp int64
+	var signature string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			timestamp, _ = strconv.ParseInt(value, 10, 64)
+		case "v1":
+			signature = value
+		}
+	}
+	if timestamp == 0 || signature == "" {
+		return ErrSignatureMissing
+	}
+	if !hmac.Equal([]byte(signature), []byte(webhookSignature(secret, timestamp, body))) {
+		return ErrSignatureInvalid
+	}
+	if now.Sub(time.Unix(timestamp, 0)) > tolerance {
+		return ErrSignatureExpired
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package util
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyURLSignature(t *testing.T) {
+	const secret = "test-secret"
+	const path = "user_1/fotos/logo-20240101-120000.png"
+	now := time.Unix(1700000000, 0)
+
+	signed, err := url.ParseQuery(SignURLPath(secret, path, now.Add(time.Hour)))
+	assert.NoError(t, err)
+
+	assert.NoError(t, VerifyURLSignature(secret, path, signed, now))
+	assert.ErrorIs(t, VerifyURLSignature(secret, path, signed, now.Add(2*time.Hour)), ErrSignatureExpired)
+	assert.ErrorIs(t, VerifyURLSignature("other-secret", path, signed, now), ErrSignatureInvalid)
+	assert.ErrorIs(t, VerifyURLSignature(secret, "user_1/fotos/outro.png", signed, now), ErrSignatureInvalid)
+	assert.ErrorIs(t, VerifyURLSignature(secret, path, url.Values{}, now), ErrSignatureMissing)
+
+	// Estender a expiração sem reassinar invalida a URL
+	tampered := url.Values{"expires": {"9999999999"}, "signature": {signed.Get("signature")}}
+	assert.ErrorIs(t, VerifyURLSignature(secret, path, tampered, now), ErrSignatureInvalid)
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	const secret = "whsec_test"
+	body := []byte(`{"event":"file.uploaded"}`)
+	now := time.Unix(1700000000, 0)
+
+	header := SignWebhookPayload(secret, body, now)
+	assert.Regexp(t, `^t=1700000000,v1=[0-9a-f]{64}$`, header)
+
+	assert.NoError(t, VerifyWebhookSignature(secret, header, body, 5*time.Minute, now.Add(time.Minute)))
+	assert.ErrorIs(t, VerifyWebhookSignature(secret, header, body, 5*time.Minute, now.Add(time.Hour)), ErrSignatureExpired)
+	assert.ErrorIs(t, VerifyWebhookSignature("other", header, body, 5*time.Minute, now), ErrSignatureInvalid)
+	assert.ErrorIs(t, VerifyWebhookSignature(secret, header, []byte(`{}`), 5*time.Minute, now), ErrSignatureInvalid)
+	assert.ErrorIs(t, VerifyWebhookSignature(secret, "", body, 5*time.Minute, now), ErrSignatureMissing)
+}
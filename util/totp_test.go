@@ -0,0 +1,88 @@
+package util
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Segredo dos vetores de teste SHA-1 da RFC 6238 ("12345678901234567890")
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	// Os 6 últimos dígitos dos códigos de 8 dígitos da RFC
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, want := range vectors {
+		code, err := TOTPCode(rfcSecret, TOTPStep(time.Unix(unix, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, want, code, "t=%d", unix)
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := TOTPStep(now)
+
+	got, ok := ValidateTOTP(rfcSecret, "050471", now, 1, 0)
+	assert.True(t, ok)
+	assert.Equal(t, step, got)
+
+	// Código do intervalo anterior é aceito com skew 1, mas não com skew 0
+	previous, _ := TOTPCode(rfcSecret, step-1)
+	_, ok = ValidateTOTP(rfcSecret, previous, now, 1, 0)
+	assert.True(t, ok)
+	_, ok = ValidateTOTP(rfcSecret, previous, now, 0, 0)
+	assert.False(t, ok)
+
+	// Um código já usado (intervalo <= lastStep) não vale de novo
+	_, ok = ValidateTOTP(rfcSecret, "050471", now, 1, step)
+	assert.False(t, ok)
+
+	_, ok = ValidateTOTP(rfcSecret, "000000", now, 1, 0)
+	assert.False(t, ok)
+	_, ok = ValidateTOTP(rfcSecret, "12345", now, 1, 0)
+	assert.False(t, ok)
+	_, ok = ValidateTOTP(rfcSecret, "050 471", now, 1, 0)
+	assert.True(t, ok)
+}
+
+func TestGenerateTOTPSecretAndURI(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	require.NoError(t, err)
+	assert.Len(t, secret, 32)
+	_, err = TOTPCode(secret, 1)
+	assert.NoError(t, err)
+
+	uri, err := url.Parse(TOTPURI("Forge Uploader", "ana@example.com", secret))
+	require.NoError(t, err)
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/Forge Uploader:ana@example.com", uri.Path)
+	assert.Equal(t, secret, uri.Query().Get("secret"))
+	assert.Equal(t, "Forge Uploader", uri.Query().Get("issuer"))
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	require.NoError(t, err)
+	assert.Len(t, codes, 10)
+	seen := map[string]bool{}
+	for _, c := range codes {
+		assert.Regexp(t, `^[a-z2-7]{5}-[a-z2-7]{5}$`, c)
+		assert.False(t, seen[c])
+		seen[c] = true
+	}
+	assert.Equal(t, NormalizeRecoveryCode(codes[0]), NormalizeRecoveryCode(" "+codes[0][:5]+codes[0][6:]+" "))
+	assert.Equal(t, "abcdefghij", NormalizeRecoveryCode("ABCDE-FGHIJ"))
+}